@@ -20,6 +20,20 @@ import (
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
+// passwordPolicy checks admin passwords; main replaces it with one that includes the configured breached-password list
+var passwordPolicy = user.NewPasswordPolicy(adminPasswordPolicyConfig(config.PasswordPolicyConfig{}))
+
+// adminPasswordPolicyConfig tightens the configured policy for admin accounts:
+// every character class is required and passwords are at least 8 characters long
+func adminPasswordPolicyConfig(cfg config.PasswordPolicyConfig) *config.PasswordPolicyConfig {
+	cfg.MinLength = max(cfg.GetMinLength(), config.DefaultPasswordMinLength)
+	cfg.RequireUppercase = true
+	cfg.RequireLowercase = true
+	cfg.RequireDigit = true
+	cfg.RequireSpecial = true
+	return &cfg
+}
+
 func validateEmail(email string) error {
 	if email == "" {
 		return fmt.Errorf("email cannot be empty")
@@ -78,6 +92,11 @@ func registerAndPromoteUser(ctx context.Context, service user.Service, email, pa
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// 管理员账号由运维人员直接创建，无需邮箱验证
+	if err := service.MarkEmailVerified(ctx, newUser.ID); err != nil {
+		return nil, fmt.Errorf("failed to mark email verified: %w", err)
+	}
+
 	if err := service.PromoteToAdmin(ctx, newUser.ID); err != nil {
 		return nil, fmt.Errorf("failed to promote user to admin: %w", err)
 	}
@@ -103,8 +122,13 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	passwordPolicy, err = user.LoadPasswordPolicy(adminPasswordPolicyConfig(cfg.PasswordPolicy))
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+
 	repo := user.NewRepository(db)
-	service := user.NewService(repo)
+	service := user.NewService(repo, user.WithPasswordPolicy(passwordPolicy), user.WithPasswordHasher(user.NewPasswordHasher(&cfg.PasswordHash)))
 
 	ctx := context.Background()
 
@@ -141,7 +165,7 @@ func createNewAdmin(ctx context.Context, service user.Service) {
 	}
 
 	fmt.Println("\nPassword requirements:")
-	fmt.Println("  • Minimum 8 characters")
+	fmt.Printf("  • Minimum %d characters\n", passwordPolicy.MinLength())
 	fmt.Println("  • At least one uppercase letter (A-Z)")
 	fmt.Println("  • At least one lowercase letter (a-z)")
 	fmt.Println("  • At least one digit (0-9)")
@@ -149,7 +173,7 @@ func createNewAdmin(ctx context.Context, service user.Service) {
 	fmt.Println()
 
 	password := readPassword("Enter admin password: ")
-	if err := validatePassword(password); err != nil {
+	if err := validatePassword(password, email, name); err != nil {
 		log.Fatalf("Invalid password: %v", err)
 	}
 
@@ -194,31 +218,7 @@ func readPassword(prompt string) string {
 	return strings.TrimSpace(string(bytePassword))
 }
 
-func validatePassword(password string) error {
-	if len(password) < 8 {
-		return fmt.Errorf("password must be at least 8 characters long")
-	}
-
-	// Enforce strong password policy for admin accounts
-	var (
-		hasUpper   = regexp.MustCompile(`[A-Z]`).MatchString(password)
-		hasLower   = regexp.MustCompile(`[a-z]`).MatchString(password)
-		hasDigit   = regexp.MustCompile(`[0-9]`).MatchString(password)
-		hasSpecial = regexp.MustCompile(`[!@#$%^&*()_+\-=\[\]{};':"\\|,.<>\/?]`).MatchString(password)
-	)
-
-	if !hasUpper {
-		return fmt.Errorf("password must contain at least one uppercase letter")
-	}
-	if !hasLower {
-		return fmt.Errorf("password must contain at least one lowercase letter")
-	}
-	if !hasDigit {
-		return fmt.Errorf("password must contain at least one digit")
-	}
-	if !hasSpecial {
-		return fmt.Errorf("password must contain at least one special character")
-	}
-
-	return nil
+// validatePassword checks an admin password against the admin password policy
+func validatePassword(password, email, name string) error {
+	return passwordPolicy.Validate(password, email, name)
 }
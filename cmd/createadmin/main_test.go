@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
 	"github.com/yeegeek/go-rest-api-starter/internal/user"
 )
 
@@ -47,8 +49,8 @@ func (m *MockService) UpdateUser(ctx context.Context, id uint, req user.UpdateUs
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
-func (m *MockService) DeleteUser(ctx context.Context, id uint) error {
-	args := m.Called(ctx, id)
+func (m *MockService) DeleteUser(ctx context.Context, actorID, id uint) error {
+	args := m.Called(ctx, actorID, id)
 	return args.Error(0)
 }
 
@@ -65,6 +67,269 @@ func (m *MockService) PromoteToAdmin(ctx context.Context, userID uint) error {
 	return args.Error(0)
 }
 
+func (m *MockService) UnlockUser(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) ChangePassword(ctx context.Context, id uint, currentPassword, newPassword string) (*user.User, error) {
+	args := m.Called(ctx, id, currentPassword, newPassword)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockService) ConfirmPasswordReset(ctx context.Context, token, newPassword string) (uint, error) {
+	args := m.Called(ctx, token, newPassword)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *MockService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockService) ResendVerificationEmail(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockService) MarkEmailVerified(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) CheckLoginAllowed(user *user.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockService) GetMFAStatus(ctx context.Context, userID uint) (*user.MFAStatus, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.MFAStatus), args.Error(1)
+}
+
+func (m *MockService) BeginTOTPEnrollment(ctx context.Context, userID uint) (*user.TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) StartMFAChallenge(ctx context.Context, u *user.User) (*user.MFAPendingLogin, error) {
+	args := m.Called(ctx, u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.MFAPendingLogin), args.Error(1)
+}
+
+func (m *MockService) VerifyMFAChallenge(ctx context.Context, token, code string) (*user.User, error) {
+	args := m.Called(ctx, token, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockService) BeginChallengeEnrollment(ctx context.Context, token string) (*user.TOTPEnrollment, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) CompleteChallengeEnrollment(ctx context.Context, token, code string) (*user.User, []string, error) {
+	args := m.Called(ctx, token, code)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*user.User), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockService) LoginWithIdentity(ctx context.Context, identity *oidc.Identity) (*user.User, error) {
+	args := m.Called(ctx, identity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockService) LinkIdentity(ctx context.Context, userID uint, identity *oidc.Identity) (*user.UserIdentity, error) {
+	args := m.Called(ctx, userID, identity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserIdentity), args.Error(1)
+}
+
+func (m *MockService) ListIdentities(ctx context.Context, userID uint) ([]user.UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]user.UserIdentity), args.Error(1)
+}
+
+func (m *MockService) UnlinkIdentity(ctx context.Context, userID uint, provider string) error {
+	args := m.Called(ctx, userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockService) CreateAPIKey(ctx context.Context, userID uint, req user.CreateAPIKeyRequest) (*user.APIKey, string, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*user.APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockService) ListAPIKeys(ctx context.Context, userID uint) ([]user.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]user.APIKey), args.Error(1)
+}
+
+func (m *MockService) GetAPIKey(ctx context.Context, userID, id uint) (*user.APIKey, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.APIKey), args.Error(1)
+}
+
+func (m *MockService) UpdateAPIKey(ctx context.Context, userID, id uint, req user.UpdateAPIKeyRequest) (*user.APIKey, error) {
+	args := m.Called(ctx, userID, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.APIKey), args.Error(1)
+}
+
+func (m *MockService) RevokeAPIKey(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.APIKeyPrincipal), args.Error(1)
+}
+
+func (m *MockService) ListRoles(ctx context.Context) ([]user.Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]user.Role), args.Error(1)
+}
+
+func (m *MockService) GetRole(ctx context.Context, name string) (*user.Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.Role), args.Error(1)
+}
+
+func (m *MockService) CreateRole(ctx context.Context, req user.CreateRoleRequest) (*user.Role, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.Role), args.Error(1)
+}
+
+func (m *MockService) UpdateRole(ctx context.Context, name string, req user.UpdateRoleRequest) (*user.Role, error) {
+	args := m.Called(ctx, name, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.Role), args.Error(1)
+}
+
+func (m *MockService) DeleteRole(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *MockService) ListPermissions(ctx context.Context) ([]user.Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]user.Permission), args.Error(1)
+}
+
+func (m *MockService) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	args := m.Called(ctx, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) GrantRole(ctx context.Context, userID uint, roleName string) (*user.User, bool, error) {
+	args := m.Called(ctx, userID, roleName)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*user.User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockService) RevokeRole(ctx context.Context, actorID, userID uint, roleName string) (*user.User, bool, error) {
+	args := m.Called(ctx, actorID, userID, roleName)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*user.User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockService) StartImpersonation(ctx context.Context, actorID, userID uint) (*user.Impersonation, error) {
+	args := m.Called(ctx, actorID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.Impersonation), args.Error(1)
+}
+
 func TestValidatePassword(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -126,7 +391,7 @@ func TestValidatePassword(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePassword(tt.password)
+			err := validatePassword(tt.password, "", "")
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -361,7 +626,7 @@ func TestPasswordValidationEdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePassword(tt.password)
+			err := validatePassword(tt.password, "", "")
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -448,7 +713,7 @@ func TestPasswordRequirementsCombinations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePassword(tt.password)
+			err := validatePassword(tt.password, "", "")
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.errMsg)
@@ -550,7 +815,7 @@ func TestPasswordLengthBoundaries(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePassword(tt.password)
+			err := validatePassword(tt.password, "", "")
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -866,6 +1131,7 @@ func TestRegisterAndPromoteUser(t *testing.T) {
 						req.Password == "Password123!" &&
 						req.Name == "New Admin"
 				})).Return(newUser, nil)
+				ms.On("MarkEmailVerified", mock.Anything, uint(1)).Return(nil)
 				ms.On("PromoteToAdmin", mock.Anything, uint(1)).Return(nil)
 			},
 			wantErr: false,
@@ -893,6 +1159,7 @@ func TestRegisterAndPromoteUser(t *testing.T) {
 					Name:  "New User",
 				}
 				ms.On("RegisterUser", mock.Anything, mock.Anything).Return(newUser, nil)
+				ms.On("MarkEmailVerified", mock.Anything, uint(2)).Return(nil)
 				ms.On("PromoteToAdmin", mock.Anything, uint(2)).Return(fmt.Errorf("role assignment failed"))
 			},
 			wantErr: true,
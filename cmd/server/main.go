@@ -13,10 +13,15 @@ import (
 	"gorm.io/gorm"
 
 	_ "github.com/yeegeek/go-rest-api-starter/api/docs"
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
 	"github.com/yeegeek/go-rest-api-starter/internal/auth"
 	"github.com/yeegeek/go-rest-api-starter/internal/config"
 	"github.com/yeegeek/go-rest-api-starter/internal/db"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
 	"github.com/yeegeek/go-rest-api-starter/internal/migrate"
+	"github.com/yeegeek/go-rest-api-starter/internal/mongodb"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+	"github.com/yeegeek/go-rest-api-starter/internal/redis"
 	"github.com/yeegeek/go-rest-api-starter/internal/server"
 	"github.com/yeegeek/go-rest-api-starter/internal/user"
 )
@@ -41,6 +46,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Type "ApiKey" followed by a space and a personal API key.
+
 func main() {
 	if err := run(); err != nil {
 		os.Exit(1)
@@ -78,12 +88,68 @@ func run() error {
 		}
 	}
 
-	authService := auth.NewServiceWithRepo(&cfg.JWT, database)
-	userRepo := user.NewRepository(database)
-	userService := user.NewService(userRepo)
-	userHandler := user.NewHandler(userService, authService)
+	// 访问令牌撤销列表、登录失败记录和 OIDC 授权请求：启用 Redis 时在副本间共享，否则使用进程内存
+	denylist := auth.NewMemoryDenylist()
+	loginAttempts := auth.NewMemoryLoginAttemptStore()
+	oidcStates := oidc.NewMemoryStateStore()
+	if cfg.Redis.Enabled {
+		redisClient, err := redis.NewClient(redis.Config{
+			Host:     cfg.Redis.Host,
+			Port:     cfg.Redis.Port,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		if err != nil {
+			logger.Error("Failed to connect to redis", "error", err)
+			return err
+		}
+		defer redisClient.Close()
+		denylist = auth.NewRedisDenylist(redisClient)
+		loginAttempts = auth.NewRedisLoginAttemptStore(redisClient)
+		oidcStates = oidc.NewRedisStateStore(redisClient)
+	}
 
-	router := server.SetupRouter(userHandler, authService, cfg, database)
+	// 审计日志：默认写入 audit_events 表，配置为 mongodb 时写入 MongoDB 集合
+	auditRepo := audit.NewRepository(database)
+	if cfg.Audit.GetSink() == config.AuditSinkMongoDB {
+		mongoClient, err := mongodb.NewClient(mongodb.Config{URI: cfg.MongoDB.URI, Database: cfg.MongoDB.Database})
+		if err != nil {
+			logger.Error("Failed to connect to mongodb", "error", err)
+			return err
+		}
+		defer mongoClient.Close(context.Background())
+		auditRepo = audit.NewMongoRepository(mongoClient, cfg.Audit.GetCollection())
+	}
+	auditService := audit.NewService(auditRepo)
+
+	authService := auth.NewService(&cfg.JWT,
+		auth.WithDatabase(database),
+		auth.WithDenylist(denylist),
+		auth.WithAuditRecorder(auditService),
+	)
+	passwordPolicy, err := user.LoadPasswordPolicy(&cfg.PasswordPolicy)
+	if err != nil {
+		logger.Error("Failed to load password policy", "error", err)
+		return err
+	}
+
+	userRepo := user.NewRepository(database)
+	loginGuard := auth.NewLoginGuard(&cfg.LoginProtection, loginAttempts)
+	userService := user.NewService(userRepo,
+		user.WithMailer(mail.NewSender(&cfg.Mail, logger)),
+		user.WithPasswordReset(&cfg.PasswordReset),
+		user.WithEmailVerification(&cfg.EmailVerification),
+		user.WithPasswordPolicy(passwordPolicy),
+		user.WithPasswordHasher(user.NewPasswordHasher(&cfg.PasswordHash)),
+		user.WithLoginGuard(loginGuard),
+		user.WithMFA(&cfg.MFA),
+		user.WithAPIKeys(&cfg.APIKeys),
+		user.WithImpersonation(&cfg.Impersonation),
+		user.WithAuditRecorder(auditService),
+	)
+	userHandler := user.NewHandlerWithOIDC(userService, authService, oidc.NewClient(&cfg.OIDC, oidcStates))
+
+	router := server.SetupRouter(userHandler, authService, cfg, database, server.WithAuditService(auditService))
 
 	port := cfg.Server.Port
 	if port == "" {
@@ -117,12 +183,21 @@ func run() error {
 		}
 	}()
 
+	// 后台任务：SIGHUP 密钥热加载、刷新令牌定时清理
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go reloadKeysOnSignal(workerCtx, authService, logger)
+	if cfg.TokenCleanup.Enabled {
+		go auth.NewTokenJanitor(database, &cfg.TokenCleanup, logger).Run(workerCtx)
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-quit
 
 	logger.Info("Received shutdown signal", "signal", sig)
 	logger.Info("Shutting down server gracefully...")
+	stopWorkers()
 
 	sqlDB, err := database.DB()
 	if err == nil {
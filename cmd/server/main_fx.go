@@ -11,11 +11,14 @@ import (
 	"gorm.io/gorm"
 
 	_ "github.com/yeegeek/go-rest-api-starter/api/docs"
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
 	"github.com/yeegeek/go-rest-api-starter/internal/auth"
 	"github.com/yeegeek/go-rest-api-starter/internal/config"
 	"github.com/yeegeek/go-rest-api-starter/internal/db"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
 	"github.com/yeegeek/go-rest-api-starter/internal/migrate"
 	"github.com/yeegeek/go-rest-api-starter/internal/mongodb"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
 	"github.com/yeegeek/go-rest-api-starter/internal/redis"
 	"github.com/yeegeek/go-rest-api-starter/internal/server"
 	"github.com/yeegeek/go-rest-api-starter/internal/user"
@@ -74,10 +77,28 @@ func mainWithFx() {
 			},
 		),
 
+		// 提供审计日志服务，按配置写入数据库或 MongoDB
+		fx.Provide(
+			func(cfg *config.Config, db *gorm.DB, mongoClient *mongodb.Client) audit.Service {
+				if cfg.Audit.GetSink() == config.AuditSinkMongoDB && mongoClient != nil {
+					return audit.NewService(audit.NewMongoRepository(mongoClient, cfg.Audit.GetCollection()))
+				}
+				return audit.NewService(audit.NewRepository(db))
+			},
+		),
+
 		// 提供 Auth Service
 		fx.Provide(
-			func(cfg *config.Config, db *gorm.DB) auth.Service {
-				return auth.NewServiceWithRepo(&cfg.JWT, db)
+			func(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, auditService audit.Service) auth.Service {
+				denylist := auth.NewMemoryDenylist()
+				if redisClient != nil {
+					denylist = auth.NewRedisDenylist(redisClient)
+				}
+				return auth.NewService(&cfg.JWT,
+					auth.WithDatabase(db),
+					auth.WithDenylist(denylist),
+					auth.WithAuditRecorder(auditService),
+				)
 			},
 		),
 
@@ -95,13 +116,57 @@ func mainWithFx() {
 			},
 		),
 		fx.Provide(
-			func(repo user.Repository) user.Service {
-				return user.NewService(repo)
+			func(cfg *config.Config, logger *slog.Logger) mail.Sender {
+				return mail.NewSender(&cfg.Mail, logger)
+			},
+		),
+		fx.Provide(
+			func(cfg *config.Config) (*user.PasswordPolicy, error) {
+				return user.LoadPasswordPolicy(&cfg.PasswordPolicy)
+			},
+		),
+		fx.Provide(
+			func(cfg *config.Config) user.PasswordHasher {
+				return user.NewPasswordHasher(&cfg.PasswordHash)
+			},
+		),
+		fx.Provide(
+			func(cfg *config.Config, redisClient *redis.Client) *auth.LoginGuard {
+				store := auth.NewMemoryLoginAttemptStore()
+				if redisClient != nil {
+					store = auth.NewRedisLoginAttemptStore(redisClient)
+				}
+				return auth.NewLoginGuard(&cfg.LoginProtection, store)
 			},
 		),
 		fx.Provide(
-			func(userService user.Service, authService auth.Service) *user.Handler {
-				return user.NewHandler(userService, authService)
+			func(cfg *config.Config, repo user.Repository, mailer mail.Sender, policy *user.PasswordPolicy, hasher user.PasswordHasher, guard *auth.LoginGuard, auditService audit.Service) user.Service {
+				return user.NewService(repo,
+					user.WithMailer(mailer),
+					user.WithPasswordReset(&cfg.PasswordReset),
+					user.WithEmailVerification(&cfg.EmailVerification),
+					user.WithPasswordPolicy(policy),
+					user.WithPasswordHasher(hasher),
+					user.WithLoginGuard(guard),
+					user.WithMFA(&cfg.MFA),
+					user.WithAPIKeys(&cfg.APIKeys),
+					user.WithImpersonation(&cfg.Impersonation),
+					user.WithAuditRecorder(auditService),
+				)
+			},
+		),
+		fx.Provide(
+			func(cfg *config.Config, redisClient *redis.Client) *oidc.Client {
+				store := oidc.NewMemoryStateStore()
+				if redisClient != nil {
+					store = oidc.NewRedisStateStore(redisClient)
+				}
+				return oidc.NewClient(&cfg.OIDC, store)
+			},
+		),
+		fx.Provide(
+			func(userService user.Service, authService auth.Service, oidcClient *oidc.Client) *user.Handler {
+				return user.NewHandlerWithOIDC(userService, authService, oidcClient)
 			},
 		),
 
@@ -110,10 +175,11 @@ func mainWithFx() {
 			func(
 				userHandler *user.Handler,
 				authService auth.Service,
+				auditService audit.Service,
 				cfg *config.Config,
 				db *gorm.DB,
 			) *http.Server {
-				router := server.SetupRouter(userHandler, authService, cfg, db)
+				router := server.SetupRouter(userHandler, authService, cfg, db, server.WithAuditService(auditService))
 
 				port := cfg.Server.Port
 				if port == "" {
@@ -136,6 +202,21 @@ func mainWithFx() {
 			},
 		),
 
+		// SIGHUP 重新加载 JWT 密钥环
+		fx.Invoke(func(lc fx.Lifecycle, authService auth.Service, logger *slog.Logger) {
+			reloadCtx, stopReload := context.WithCancel(context.Background())
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					go reloadKeysOnSignal(reloadCtx, authService, logger)
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					stopReload()
+					return nil
+				},
+			})
+		}),
+
 		// 启动和停止钩子
 		fx.Invoke(func(lc fx.Lifecycle, srv *http.Server, cfg *config.Config, db *gorm.DB, logger *slog.Logger) {
 			lc.Append(fx.Hook{
@@ -190,6 +271,25 @@ func mainWithFx() {
 				},
 			})
 		}),
+
+		// 刷新令牌定时清理（在服务器钩子之后注册，停止时先于数据库关闭）
+		fx.Invoke(func(lc fx.Lifecycle, cfg *config.Config, db *gorm.DB, logger *slog.Logger) {
+			if !cfg.TokenCleanup.Enabled {
+				return
+			}
+			janitor := auth.NewTokenJanitor(db, &cfg.TokenCleanup, logger)
+			cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					go janitor.Run(cleanupCtx)
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					stopCleanup()
+					return nil
+				},
+			})
+		}),
 	)
 
 	app.Run()
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// reloadKeysOnSignal 收到 SIGHUP 时重新加载配置并轮换 JWT 密钥环，无需重启服务
+// 加载失败时保留当前密钥，已签发的令牌不受影响
+func reloadKeysOnSignal(ctx context.Context, authService auth.Service, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			cfg, err := config.LoadConfig("")
+			if err != nil {
+				logger.Error("Failed to reload configuration, keeping current JWT keys", "error", err)
+				continue
+			}
+			if err := authService.ReloadKeys(&cfg.JWT); err != nil {
+				logger.Error("Failed to reload JWT keys, keeping current keys", "error", err)
+				continue
+			}
+			logger.Info("JWT keys reloaded", "retired_keys", len(cfg.JWT.RetiredKeys))
+		}
+	}
+}
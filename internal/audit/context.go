@@ -0,0 +1,33 @@
+package audit
+
+import "context"
+
+// Source describes where an action came from
+type Source struct {
+	ActorID    uint
+	OnBehalfOf uint
+	RequestID  string
+	IPAddress  string
+	UserAgent  string
+}
+
+type sourceKey struct{}
+
+// WithSource attaches a fixed event source to the context (for jobs and CLI tools)
+func WithSource(ctx context.Context, source Source) context.Context {
+	return WithSourceFunc(ctx, func() Source { return source })
+}
+
+// WithSourceFunc attaches a source that is resolved when an event is recorded
+// WHY: HTTP requests attach it before authentication, so the actor is only known later
+func WithSourceFunc(ctx context.Context, resolve func() Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, resolve)
+}
+
+// SourceFromContext returns the source attached to the context, or an empty source
+func SourceFromContext(ctx context.Context) Source {
+	if resolve, ok := ctx.Value(sourceKey{}).(func() Source); ok {
+		return resolve()
+	}
+	return Source{}
+}
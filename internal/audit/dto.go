@@ -0,0 +1,53 @@
+package audit
+
+import "time"
+
+// DefaultPerPage is the page size of the audit event listing when per_page is omitted
+const DefaultPerPage = 20
+
+// ListEventsQuery represents the query parameters of the audit event listing
+type ListEventsQuery struct {
+	Page       int       `form:"page" binding:"omitempty,min=1"`
+	PerPage    int       `form:"per_page" binding:"omitempty,min=1,max=100"`
+	ActorID    uint      `form:"actor_id"`
+	Action     string    `form:"action" binding:"max=64"`
+	TargetType string    `form:"target_type" binding:"max=32"`
+	TargetID   string    `form:"target_id" binding:"max=64"`
+	RequestID  string    `form:"request_id" binding:"max=64"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// Filter converts the query into a repository filter
+func (q ListEventsQuery) Filter() Filter {
+	return Filter{
+		ActorID:    q.ActorID,
+		Action:     q.Action,
+		TargetType: q.TargetType,
+		TargetID:   q.TargetID,
+		RequestID:  q.RequestID,
+		From:       q.From,
+		To:         q.To,
+	}
+}
+
+// pagination returns the requested page and page size with defaults applied
+func (q ListEventsQuery) pagination() (int, int) {
+	page, perPage := q.Page, q.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	return page, perPage
+}
+
+// EventListResponse represents a paginated list of audit events
+type EventListResponse struct {
+	Events     []Event `json:"events"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page"`
+	PerPage    int     `json:"per_page"`
+	TotalPages int     `json:"total_pages"`
+}
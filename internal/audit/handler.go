@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// Handler serves the admin audit log endpoints
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new audit handler
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListEvents godoc
+// @Summary List audit events (Admin only)
+// @Description Get a paginated list of audit events, newest first. actor_id also matches events performed on behalf of that user during impersonation
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page (max 100)" default(20)
+// @Param actor_id query int false "Filter by acting user ID"
+// @Param action query string false "Filter by action (e.g. user.role_grant, auth.token_reuse)"
+// @Param target_type query string false "Filter by target type (user, role, session, api_key)"
+// @Param target_id query string false "Filter by target ID"
+// @Param request_id query string false "Filter by request ID"
+// @Param from query string false "Only events at or after this time (RFC 3339)"
+// @Param to query string false "Only events before this time (RFC 3339)"
+// @Success 200 {object} errors.Response{success=bool,data=EventListResponse} "Paginated audit events"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid parameters"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list audit events"
+// @Router /api/v1/admin/audit-events [get]
+func (h *Handler) ListEvents(c *gin.Context) {
+	var query ListEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		_ = c.Error(apiErrors.BadRequest("from must be before to"))
+		return
+	}
+
+	page, perPage := query.pagination()
+	events, total, err := h.service.List(c.Request.Context(), query.Filter(), page, perPage)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	if events == nil {
+		events = []Event{}
+	}
+
+	totalPages := int(total) / perPage
+	if int(total)%perPage > 0 {
+		totalPages++
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(EventListResponse{
+		Events:     events,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: totalPages,
+	}))
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_ListEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := setupTestService(t)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		svc.Record(ctx, Event{Action: ActionRoleGrant, ActorID: 1, TargetType: TargetUser, TargetID: ID(uint(i + 2))})
+	}
+	svc.Record(ctx, Event{Action: ActionTokenReuse, TargetType: TargetUser, TargetID: "9"})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  int64
+		expectedCount  int
+		expectedPages  int
+	}{
+		{name: "defaults", expectedStatus: http.StatusOK, expectedTotal: 4, expectedCount: 4, expectedPages: 1},
+		{name: "filtered", query: "?action=auth.token_reuse", expectedStatus: http.StatusOK, expectedTotal: 1, expectedCount: 1, expectedPages: 1},
+		{name: "by actor with paging", query: "?actor_id=1&page=2&per_page=2", expectedStatus: http.StatusOK, expectedTotal: 3, expectedCount: 1, expectedPages: 2},
+		{name: "time range", query: "?from=" + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339) + "&to=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339), expectedStatus: http.StatusOK, expectedTotal: 4, expectedCount: 4, expectedPages: 1},
+		{name: "per page too large", query: "?per_page=500", expectedStatus: http.StatusBadRequest},
+		{name: "invalid time", query: "?from=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "empty range", query: "?from=2026-10-02T00:00:00Z&to=2026-10-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(apiErrors.ErrorHandler())
+			router.GET("/admin/audit-events", NewHandler(svc).ListEvents)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit-events"+tt.query, nil))
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data EventListResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedTotal, response.Data.Total)
+			assert.Len(t, response.Data.Events, tt.expectedCount)
+			assert.Equal(t, tt.expectedPages, response.Data.TotalPages)
+		})
+	}
+}
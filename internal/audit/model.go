@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Actions recorded by the user and auth services and the auth middleware
+const (
+	ActionLogin               = "auth.login"
+	ActionLoginFailed         = "auth.login_failed"
+	ActionTokenReuse          = "auth.token_reuse"
+	ActionTokensRevoked       = "auth.tokens_revoked"
+	ActionSessionRevoked      = "auth.session_revoked"
+	ActionImpersonationStart  = "auth.impersonation_start"
+	ActionImpersonationStop   = "auth.impersonation_stop"
+	ActionImpersonatedRequest = "auth.impersonated_request"
+	ActionUserCreate          = "user.create"
+	ActionUserUpdate          = "user.update"
+	ActionUserDelete          = "user.delete"
+	ActionUserUnlock          = "user.unlock"
+	ActionPasswordChange      = "user.password_change"
+	ActionPasswordReset       = "user.password_reset"
+	ActionMFAEnable           = "user.mfa_enable"
+	ActionMFADisable          = "user.mfa_disable"
+	ActionAPIKeyCreate        = "user.api_key_create"
+	ActionAPIKeyRevoke        = "user.api_key_revoke"
+	ActionIdentityLink        = "user.identity_link"
+	ActionIdentityUnlink      = "user.identity_unlink"
+	ActionRoleGrant           = "user.role_grant"
+	ActionRoleRevoke          = "user.role_revoke"
+	ActionRoleCreate          = "role.create"
+	ActionRoleUpdate          = "role.update"
+	ActionRoleDelete          = "role.delete"
+)
+
+// Target types
+const (
+	TargetUser    = "user"
+	TargetRole    = "role"
+	TargetSession = "session"
+	TargetAPIKey  = "api_key"
+)
+
+// Event is a single audit record: who did what to which resource, and what changed
+// ActorID is the user performing the action (the admin during impersonation), 0 for anonymous requests;
+// OnBehalfOf is the impersonated user when the action was taken in an impersonation session
+type Event struct {
+	ID         string                 `gorm:"primaryKey;type:varchar(36)" json:"id" bson:"_id"`
+	Action     string                 `gorm:"type:varchar(64);not null;index" json:"action" bson:"action"`
+	ActorID    uint                   `gorm:"not null;index" json:"actor_id" bson:"actor_id"`
+	OnBehalfOf uint                   `gorm:"not null;default:0" json:"on_behalf_of,omitempty" bson:"on_behalf_of,omitempty"`
+	TargetType string                 `gorm:"type:varchar(32);not null;index:idx_audit_events_target" json:"target_type" bson:"target_type"`
+	TargetID   string                 `gorm:"type:varchar(64);not null;index:idx_audit_events_target" json:"target_id" bson:"target_id"`
+	Changes    map[string]Change      `gorm:"serializer:json" json:"changes,omitempty" bson:"changes,omitempty"`
+	Metadata   map[string]interface{} `gorm:"serializer:json" json:"metadata,omitempty" bson:"metadata,omitempty"`
+	RequestID  string                 `gorm:"type:varchar(64);not null;default:''" json:"request_id,omitempty" bson:"request_id,omitempty"`
+	IPAddress  string                 `gorm:"type:varchar(45);not null;default:''" json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	UserAgent  string                 `gorm:"type:varchar(512);not null;default:''" json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	CreatedAt  time.Time              `gorm:"index" json:"created_at" bson:"created_at"`
+}
+
+// TableName specifies the table name for Event model
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+// Change holds the value of a field before and after an action
+type Change struct {
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// Diff returns the fields whose values differ between before and after
+// A nil before describes a creation and a nil after a deletion
+func Diff(before, after map[string]interface{}) map[string]Change {
+	keys := make(map[string]struct{}, len(before)+len(after))
+	for key := range before {
+		keys[key] = struct{}{}
+	}
+	for key := range after {
+		keys[key] = struct{}{}
+	}
+
+	changes := make(map[string]Change)
+	for key := range keys {
+		if !reflect.DeepEqual(before[key], after[key]) {
+			changes[key] = Change{Before: before[key], After: after[key]}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// SortedStrings returns a sorted copy so set-like values such as role names diff by content, not order
+func SortedStrings(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// ID formats a numeric resource ID as an event target
+func ID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
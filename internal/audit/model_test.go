@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   map[string]interface{}
+		after    map[string]interface{}
+		expected map[string]Change
+	}{
+		{
+			name:     "unchanged",
+			before:   map[string]interface{}{"name": "Ada", "roles": []string{"user"}},
+			after:    map[string]interface{}{"name": "Ada", "roles": []string{"user"}},
+			expected: nil,
+		},
+		{
+			name:     "changed field",
+			before:   map[string]interface{}{"name": "Ada", "email": "ada@example.com"},
+			after:    map[string]interface{}{"name": "Ada Lovelace", "email": "ada@example.com"},
+			expected: map[string]Change{"name": {Before: "Ada", After: "Ada Lovelace"}},
+		},
+		{
+			name:     "creation",
+			after:    map[string]interface{}{"name": "Ada"},
+			expected: map[string]Change{"name": {Before: nil, After: "Ada"}},
+		},
+		{
+			name:     "deletion",
+			before:   map[string]interface{}{"roles": []string{"admin", "user"}},
+			expected: map[string]Change{"roles": {Before: []string{"admin", "user"}, After: nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Diff(tt.before, tt.after))
+		})
+	}
+}
+
+func TestSortedStrings(t *testing.T) {
+	roles := []string{"user", "admin"}
+	assert.Equal(t, []string{"admin", "user"}, SortedStrings(roles))
+	assert.Equal(t, []string{"user", "admin"}, roles, "input must not be reordered")
+	assert.Equal(t, []string{}, SortedStrings(nil))
+}
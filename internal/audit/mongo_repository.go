@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/mongodb"
+)
+
+type mongoRepository struct {
+	client     *mongodb.Client
+	collection string
+}
+
+// NewMongoRepository creates an audit repository backed by a MongoDB collection
+func NewMongoRepository(client *mongodb.Client, collection string) Repository {
+	return &mongoRepository{client: client, collection: collection}
+}
+
+// Create stores an event
+func (r *mongoRepository) Create(ctx context.Context, event *Event) error {
+	_, err := r.client.InsertOne(ctx, r.collection, event)
+	return err
+}
+
+// List returns a page of matching events, newest first, and the total number of matches
+func (r *mongoRepository) List(ctx context.Context, filter Filter, page, perPage int) ([]Event, int64, error) {
+	query := mongoFilter(filter)
+
+	total, err := r.client.CountDocuments(ctx, r.collection, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * perPage)).
+		SetLimit(int64(perPage))
+	cursor, err := r.client.Find(ctx, r.collection, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find audit events: %w", err)
+	}
+
+	events := []Event{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode audit events: %w", err)
+	}
+	return events, total, nil
+}
+
+// mongoFilter translates a Filter into a MongoDB query document
+func mongoFilter(filter Filter) bson.M {
+	query := bson.M{}
+	if filter.ActorID != 0 {
+		query["$or"] = bson.A{
+			bson.M{"actor_id": filter.ActorID},
+			bson.M{"on_behalf_of": filter.ActorID},
+		}
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+	if filter.RequestID != "" {
+		query["request_id"] = filter.RequestID
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		createdAt := bson.M{}
+		if !filter.From.IsZero() {
+			createdAt["$gte"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			createdAt["$lt"] = filter.To
+		}
+		query["created_at"] = createdAt
+	}
+	return query
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMongoFilter(t *testing.T) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected bson.M
+	}{
+		{name: "empty", expected: bson.M{}},
+		{
+			name:   "actor",
+			filter: Filter{ActorID: 4},
+			expected: bson.M{"$or": bson.A{
+				bson.M{"actor_id": uint(4)},
+				bson.M{"on_behalf_of": uint(4)},
+			}},
+		},
+		{
+			name:     "fields",
+			filter:   Filter{Action: ActionUserDelete, TargetType: TargetUser, TargetID: "7", RequestID: "req-1"},
+			expected: bson.M{"action": ActionUserDelete, "target_type": TargetUser, "target_id": "7", "request_id": "req-1"},
+		},
+		{name: "from only", filter: Filter{From: from}, expected: bson.M{"created_at": bson.M{"$gte": from}}},
+		{name: "time range", filter: Filter{From: from, To: to}, expected: bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, mongoFilter(tt.filter))
+		})
+	}
+}
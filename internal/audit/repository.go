@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filter narrows an audit event query; zero values match everything
+type Filter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+}
+
+// Repository stores and queries audit events
+type Repository interface {
+	Create(ctx context.Context, event *Event) error
+	List(ctx context.Context, filter Filter, page, perPage int) ([]Event, int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates an audit repository backed by the audit_events table
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create stores an event
+func (r *repository) Create(ctx context.Context, event *Event) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// List returns a page of matching events, newest first, and the total number of matches
+func (r *repository) List(ctx context.Context, filter Filter, page, perPage int) ([]Event, int64, error) {
+	query := r.db.WithContext(ctx).Model(&Event{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ? OR on_behalf_of = ?", filter.ActorID, filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []Event
+	err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recorder records audit events
+type Recorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// Service records audit events and queries them for admins
+type Service interface {
+	Recorder
+	List(ctx context.Context, filter Filter, page, perPage int) ([]Event, int64, error)
+}
+
+// Discard is a Recorder that drops every event, used when auditing isn't wired in
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Record(context.Context, Event) {}
+
+type service struct {
+	repo Repository
+}
+
+// NewService creates a new audit service
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// Record fills in the event ID, timestamp and request source, then stores the event
+// Storage errors are logged rather than returned: a failing audit sink must not fail the audited operation
+func (s *service) Record(ctx context.Context, event Event) {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	source := SourceFromContext(ctx)
+	if event.ActorID == 0 {
+		event.ActorID = source.ActorID
+		if event.OnBehalfOf == 0 {
+			event.OnBehalfOf = source.OnBehalfOf
+		}
+	}
+	if event.RequestID == "" {
+		event.RequestID = source.RequestID
+	}
+	if event.IPAddress == "" {
+		event.IPAddress = source.IPAddress
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = source.UserAgent
+	}
+
+	// The audited operation has already happened, so the write must not be cancelled with the request
+	if err := s.repo.Create(context.WithoutCancel(ctx), &event); err != nil {
+		slog.ErrorContext(ctx, "Failed to record audit event",
+			"action", event.Action,
+			"actor_id", event.ActorID,
+			"target_type", event.TargetType,
+			"target_id", event.TargetID,
+			"error", err,
+		)
+	}
+}
+
+// List returns a page of events matching the filter, newest first
+func (s *service) List(ctx context.Context, filter Filter, page, perPage int) ([]Event, int64, error) {
+	return s.repo.List(ctx, filter, page, perPage)
+}
+
+// newEventID returns a time-ordered UUID so IDs sort roughly by creation time
+func newEventID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestService(t *testing.T) Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Event{}))
+	return NewService(NewRepository(db))
+}
+
+func TestService_Record(t *testing.T) {
+	svc := setupTestService(t)
+	ctx := WithSource(context.Background(), Source{ActorID: 1, OnBehalfOf: 7, RequestID: "req-1", IPAddress: "203.0.113.5", UserAgent: "curl/8.0"})
+
+	svc.Record(ctx, Event{
+		Action:     ActionUserUpdate,
+		TargetType: TargetUser,
+		TargetID:   ID(7),
+		Changes:    Diff(map[string]interface{}{"name": "Max"}, map[string]interface{}{"name": "Maxine"}),
+		Metadata:   map[string]interface{}{"reason": "typo"},
+	})
+	// An explicit actor wins over the request source
+	svc.Record(ctx, Event{Action: ActionLogin, ActorID: 9, TargetType: TargetUser, TargetID: ID(9)})
+
+	events, total, err := svc.List(context.Background(), Filter{}, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(2), total)
+
+	login, update := events[0], events[1]
+	assert.Equal(t, ActionLogin, login.Action)
+	assert.Equal(t, uint(9), login.ActorID)
+	assert.Zero(t, login.OnBehalfOf)
+	assert.Equal(t, "req-1", login.RequestID)
+
+	assert.Equal(t, ActionUserUpdate, update.Action)
+	assert.Len(t, update.ID, 36)
+	assert.Equal(t, uint(1), update.ActorID)
+	assert.Equal(t, uint(7), update.OnBehalfOf)
+	assert.Equal(t, "203.0.113.5", update.IPAddress)
+	assert.Equal(t, "curl/8.0", update.UserAgent)
+	assert.Equal(t, map[string]Change{"name": {Before: "Max", After: "Maxine"}}, update.Changes)
+	assert.Equal(t, "typo", update.Metadata["reason"])
+	assert.WithinDuration(t, time.Now(), update.CreatedAt, 5*time.Second)
+}
+
+func TestService_RecordResolvesSourceLazily(t *testing.T) {
+	svc := setupTestService(t)
+
+	var actorID uint
+	ctx := WithSourceFunc(context.Background(), func() Source { return Source{ActorID: actorID} })
+	actorID = 3
+	svc.Record(ctx, Event{Action: ActionTokensRevoked, TargetType: TargetUser, TargetID: ID(3)})
+
+	events, _, err := svc.List(context.Background(), Filter{}, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint(3), events[0].ActorID)
+}
+
+type failingRepository struct{ Repository }
+
+func (failingRepository) Create(context.Context, *Event) error {
+	return errors.New("sink unavailable")
+}
+
+func TestService_RecordIgnoresSinkErrors(t *testing.T) {
+	svc := NewService(failingRepository{})
+	assert.NotPanics(t, func() {
+		svc.Record(context.Background(), Event{Action: ActionUserDelete, TargetType: TargetUser, TargetID: ID(1)})
+	})
+}
+
+func TestService_List(t *testing.T) {
+	svc := setupTestService(t)
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour)
+
+	svc.Record(ctx, Event{Action: ActionRoleGrant, ActorID: 1, TargetType: TargetUser, TargetID: "2", RequestID: "req-a", CreatedAt: start})
+	svc.Record(ctx, Event{Action: ActionRoleRevoke, ActorID: 1, TargetType: TargetUser, TargetID: "3", CreatedAt: start.Add(10 * time.Minute)})
+	svc.Record(ctx, Event{Action: ActionUserUpdate, ActorID: 1, OnBehalfOf: 4, TargetType: TargetUser, TargetID: "4", CreatedAt: start.Add(20 * time.Minute)})
+	svc.Record(ctx, Event{Action: ActionRoleDelete, ActorID: 5, TargetType: TargetRole, TargetID: "auditor", CreatedAt: start.Add(30 * time.Minute)})
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{name: "all, newest first", expected: []string{ActionRoleDelete, ActionUserUpdate, ActionRoleRevoke, ActionRoleGrant}},
+		{name: "by actor", filter: Filter{ActorID: 1}, expected: []string{ActionUserUpdate, ActionRoleRevoke, ActionRoleGrant}},
+		{name: "actor matches on behalf of", filter: Filter{ActorID: 4}, expected: []string{ActionUserUpdate}},
+		{name: "by action", filter: Filter{Action: ActionRoleRevoke}, expected: []string{ActionRoleRevoke}},
+		{name: "by target", filter: Filter{TargetType: TargetRole, TargetID: "auditor"}, expected: []string{ActionRoleDelete}},
+		{name: "by request", filter: Filter{RequestID: "req-a"}, expected: []string{ActionRoleGrant}},
+		{name: "by time range", filter: Filter{From: start.Add(5 * time.Minute), To: start.Add(30 * time.Minute)}, expected: []string{ActionUserUpdate, ActionRoleRevoke}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, total, err := svc.List(ctx, tt.filter, 1, 20)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.expected)), total)
+			actions := make([]string, len(events))
+			for i, event := range events {
+				actions[i] = event.Action
+			}
+			assert.Equal(t, tt.expected, actions)
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		events, total, err := svc.List(ctx, Filter{}, 2, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), total)
+		require.Len(t, events, 1)
+		assert.Equal(t, ActionRoleGrant, events[0].Action)
+	})
+}
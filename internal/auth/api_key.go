@@ -0,0 +1,36 @@
+package auth
+
+import "context"
+
+// APIKeyScheme is the Authorization header scheme for personal API keys
+const APIKeyScheme = "ApiKey"
+
+// API key scopes: read allows safe methods (GET, HEAD, OPTIONS), write allows every other method
+const (
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+)
+
+// APIKeyPrincipal is the caller identified by a valid API key
+type APIKeyPrincipal struct {
+	KeyID  uint
+	UserID uint
+	Roles  []string
+	Scopes []string
+}
+
+// HasScope reports whether the key was granted scope
+func (p *APIKeyPrincipal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyAuthenticator resolves an API key to its owner
+// Implementations return an error for unknown, expired or revoked keys
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error)
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+)
+
+// recordingAuditor keeps recorded audit events in memory
+type recordingAuditor struct {
+	events []audit.Event
+}
+
+func (r *recordingAuditor) Record(_ context.Context, event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestService_AuditEvents(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("refresh token reuse", func(t *testing.T) {
+		svc, _ := setupServiceTest(t)
+		recorder := &recordingAuditor{}
+		svc.auditor = recorder
+
+		pair, err := svc.GenerateTokenPair(ctx, 1, "test@example.com", "Test User")
+		require.NoError(t, err)
+		_, err = svc.RefreshAccessToken(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+		_, err = svc.RefreshAccessToken(ctx, pair.RefreshToken)
+		require.ErrorIs(t, err, ErrTokenReuse)
+
+		require.Len(t, recorder.events, 1)
+		event := recorder.events[0]
+		assert.Equal(t, audit.ActionTokenReuse, event.Action)
+		assert.Equal(t, audit.TargetUser, event.TargetType)
+		assert.Equal(t, "1", event.TargetID)
+		assert.Equal(t, pair.TokenFamily.String(), event.Metadata["token_family"])
+	})
+
+	t.Run("revoke all tokens and sessions", func(t *testing.T) {
+		svc, _ := setupServiceTest(t)
+		recorder := &recordingAuditor{}
+		svc.auditor = recorder
+
+		pair, err := svc.GenerateTokenPair(ctx, 1, "test@example.com", "Test User")
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeSession(ctx, 1, pair.TokenFamily))
+		require.NoError(t, svc.RevokeAllUserTokens(ctx, 1))
+
+		require.Len(t, recorder.events, 2)
+		assert.Equal(t, audit.ActionSessionRevoked, recorder.events[0].Action)
+		assert.Equal(t, pair.TokenFamily.String(), recorder.events[0].TargetID)
+		assert.Equal(t, audit.ActionTokensRevoked, recorder.events[1].Action)
+		assert.Equal(t, "1", recorder.events[1].TargetID)
+	})
+
+	t.Run("impersonation start and stop", func(t *testing.T) {
+		svc, _ := setupServiceTest(t)
+		svc.denylist = NewMemoryDenylist()
+		recorder := &recordingAuditor{}
+		svc.auditor = recorder
+
+		token, err := svc.GenerateImpersonationToken(ctx, Actor{UserID: 2}, 1, "test@example.com", "Test User", time.Minute)
+		require.NoError(t, err)
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeAccessToken(ctx, claims))
+
+		require.Len(t, recorder.events, 2)
+		for i, action := range []string{audit.ActionImpersonationStart, audit.ActionImpersonationStop} {
+			event := recorder.events[i]
+			assert.Equal(t, action, event.Action)
+			assert.Equal(t, uint(2), event.ActorID)
+			assert.Equal(t, uint(1), event.OnBehalfOf)
+			assert.Equal(t, "1", event.TargetID)
+			assert.Equal(t, claims.ID, event.Metadata["jti"])
+		}
+	})
+
+	t.Run("regular token revocation is not audited", func(t *testing.T) {
+		svc, _ := setupServiceTest(t)
+		svc.denylist = NewMemoryDenylist()
+		recorder := &recordingAuditor{}
+		svc.auditor = recorder
+
+		token, err := svc.GenerateToken(1, "test@example.com", "Test User")
+		require.NoError(t, err)
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeAccessToken(ctx, claims))
+
+		assert.Empty(t, recorder.events)
+	})
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestService_ClientToken(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Secret: "test-secret", TTLHours: 1})
+
+	t.Run("round trip", func(t *testing.T) {
+		token, err := svc.GenerateClientToken("svc_reports", []string{"users:read", "reports:write"}, time.Minute)
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateClientToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "svc_reports", claims.ClientID)
+		assert.Equal(t, []string{"users:read", "reports:write"}, claims.Scopes)
+		assert.True(t, claims.HasScope("users:read"))
+		assert.False(t, claims.HasScope("users:write"))
+		assert.NotEmpty(t, claims.ID)
+	})
+
+	t.Run("client token is not a user token", func(t *testing.T) {
+		token, err := svc.GenerateClientToken("42", []string{"users:read"}, time.Minute)
+		require.NoError(t, err)
+
+		_, err = svc.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("user token is not a client token", func(t *testing.T) {
+		token, err := svc.GenerateToken(42, "user@example.com", "User")
+		require.NoError(t, err)
+
+		_, err = svc.ValidateClientToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token, err := svc.GenerateClientToken("svc_reports", []string{"users:read"}, -time.Minute)
+		require.NoError(t, err)
+
+		_, err = svc.ValidateClientToken(token)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/redis"
+)
+
+const (
+	denylistTokenPrefix = "auth:denylist:jti:"
+	denylistUserPrefix  = "auth:denylist:user:"
+)
+
+// Denylist records access tokens revoked before their expiry.
+// Entries only need to live as long as the tokens they revoke.
+type Denylist interface {
+	// RevokeToken revokes a single access token by its jti until it expires
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// RevokeUserTokens revokes every access token of a user issued before the given time.
+	// The cutoff is compared inclusively against the sub-millisecond issue time from tokenIssuedAt.
+	RevokeUserTokens(ctx context.Context, userID uint, before time.Time, ttl time.Duration) error
+	// IsRevoked reports whether a token has been revoked by jti or by user
+	IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+// redisDenylist stores revocations in Redis so all replicas share them
+type redisDenylist struct {
+	client *redis.Client
+}
+
+// NewRedisDenylist creates a denylist backed by Redis
+func NewRedisDenylist(client *redis.Client) Denylist {
+	return &redisDenylist{client: client}
+}
+
+func (d *redisDenylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return d.client.Set(ctx, denylistTokenPrefix+jti, "1", ttl)
+}
+
+func (d *redisDenylist) RevokeUserTokens(ctx context.Context, userID uint, before time.Time, ttl time.Duration) error {
+	key := fmt.Sprintf("%s%d", denylistUserPrefix, userID)
+	return d.client.Set(ctx, key, strconv.FormatInt(before.UnixNano(), 10), ttl)
+}
+
+func (d *redisDenylist) IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		count, err := d.client.Exists(ctx, denylistTokenPrefix+jti)
+		if err != nil {
+			return false, fmt.Errorf("failed to check token denylist: %w", err)
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	value, err := d.client.Get(ctx, fmt.Sprintf("%s%d", denylistUserPrefix, userID))
+	if err != nil {
+		return false, fmt.Errorf("failed to check user denylist: %w", err)
+	}
+	if value == "" {
+		return false, nil
+	}
+
+	revokedBefore, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid user denylist entry: %w", err)
+	}
+	return issuedAt.UnixNano() <= revokedBefore, nil
+}
+
+// memoryDenylist keeps revocations in process memory.
+// WHY: Used when Redis is disabled; revocations are not shared between replicas
+// and are lost on restart.
+type memoryDenylist struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[uint]memoryUserRevocation
+	now    func() time.Time
+}
+
+type memoryUserRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryDenylist creates an in-memory denylist
+func NewMemoryDenylist() Denylist {
+	return &memoryDenylist{
+		tokens: make(map[string]time.Time),
+		users:  make(map[uint]memoryUserRevocation),
+		now:    time.Now,
+	}
+}
+
+func (d *memoryDenylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.purgeExpired()
+	d.tokens[jti] = expiresAt
+	return nil
+}
+
+func (d *memoryDenylist) RevokeUserTokens(ctx context.Context, userID uint, before time.Time, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.purgeExpired()
+	d.users[userID] = memoryUserRevocation{before: before, expiresAt: d.now().Add(ttl)}
+	return nil
+}
+
+func (d *memoryDenylist) IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if expiresAt, ok := d.tokens[jti]; ok && now.Before(expiresAt) {
+		return true, nil
+	}
+	if revocation, ok := d.users[userID]; ok && now.Before(revocation.expiresAt) {
+		return !issuedAt.After(revocation.before), nil
+	}
+	return false, nil
+}
+
+// purgeExpired drops entries whose tokens have expired anyway; caller must hold mu
+func (d *memoryDenylist) purgeExpired() {
+	now := d.now()
+	for jti, expiresAt := range d.tokens {
+		if !now.Before(expiresAt) {
+			delete(d.tokens, jti)
+		}
+	}
+	for userID, revocation := range d.users {
+		if !now.Before(revocation.expiresAt) {
+			delete(d.users, userID)
+		}
+	}
+}
+
+// newTokenID returns a UUIDv7 jti, whose embedded timestamp records the issue time
+func newTokenID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// tokenIssuedAt returns the issue time of a token with sub-millisecond precision.
+// WHY: iat has second precision, so a user-wide revocation could not tell apart tokens minted
+// in the same second before and after it (e.g. logout-all followed by re-login). The UUIDv7 jti
+// carries the issue time; it is only used when it agrees with iat. Tokens without it are treated as
+// issued at the end of their iat second, so a token minted right after a revocation stays valid.
+func tokenIssuedAt(jti string, iat time.Time) time.Time {
+	fallback := time.Unix(iat.Unix(), 0).Add(time.Second - time.Nanosecond)
+	id, err := uuid.Parse(jti)
+	if err != nil || id.Version() != 7 {
+		return fallback
+	}
+	issuedAt := uuidV7Time(id)
+	if issuedAt.Unix() != iat.Unix() {
+		return fallback
+	}
+	return issuedAt
+}
+
+// uuidV7Time returns the time embedded in a UUIDv7 from newTokenID.
+// WHY: uuid.NewV7 fills rand_a with the sub-millisecond fraction in 256ns steps and keeps it
+// increasing (RFC 9562 method 3), so tokens revoked and re-issued in the same millisecond
+// still order correctly without waiting for the next millisecond.
+func uuidV7Time(id uuid.UUID) time.Time {
+	milli := int64(id[0])<<40 | int64(id[1])<<32 | int64(id[2])<<24 | int64(id[3])<<16 | int64(id[4])<<8 | int64(id[5])
+	fraction := int64(id[6]&0x0f)<<8 | int64(id[7])
+	return time.UnixMilli(milli).Add(time.Duration(fraction << 8))
+}
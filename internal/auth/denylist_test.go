@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestMemoryDenylist_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+	denylist := NewMemoryDenylist().(*memoryDenylist)
+	now := time.Now()
+	denylist.now = func() time.Time { return now }
+
+	require.NoError(t, denylist.RevokeToken(ctx, "jti-1", now.Add(time.Minute)))
+
+	revoked, err := denylist.IsRevoked(ctx, "jti-1", 1, now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = denylist.IsRevoked(ctx, "jti-2", 1, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 令牌过期后条目失效并被清理
+	now = now.Add(2 * time.Minute)
+	revoked, err = denylist.IsRevoked(ctx, "jti-1", 1, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, denylist.RevokeToken(ctx, "jti-3", now.Add(time.Minute)))
+	assert.NotContains(t, denylist.tokens, "jti-1")
+}
+
+func TestMemoryDenylist_RevokeUserTokens(t *testing.T) {
+	ctx := context.Background()
+	denylist := NewMemoryDenylist().(*memoryDenylist)
+	now := time.Now()
+	denylist.now = func() time.Time { return now }
+
+	require.NoError(t, denylist.RevokeUserTokens(ctx, 1, now, 15*time.Minute))
+
+	tests := []struct {
+		name     string
+		userID   uint
+		issuedAt time.Time
+		expected bool
+	}{
+		{name: "token issued before revocation", userID: 1, issuedAt: now.Add(-time.Minute), expected: true},
+		{name: "token issued after revocation", userID: 1, issuedAt: now.Add(time.Second), expected: false},
+		{name: "token issued just before revocation", userID: 1, issuedAt: now.Add(-time.Microsecond), expected: true},
+		{name: "token issued at the revocation", userID: 1, issuedAt: now, expected: true},
+		{name: "token issued just after revocation", userID: 1, issuedAt: now.Add(time.Microsecond), expected: false},
+		{name: "other user unaffected", userID: 2, issuedAt: now.Add(-time.Minute), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := denylist.IsRevoked(ctx, "", tt.userID, tt.issuedAt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, revoked)
+		})
+	}
+}
+
+func TestService_RevokeAccessToken(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+
+	tokenString, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+	otherToken, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	claims, err := svc.ValidateToken(tokenString)
+	require.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+	assert.False(t, claims.ExpiresAt.IsZero())
+
+	require.NoError(t, svc.RevokeAccessToken(context.Background(), claims))
+
+	_, err = svc.ValidateToken(tokenString)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = svc.ValidateToken(otherToken)
+	assert.NoError(t, err, "revoking one jti must not affect other tokens")
+}
+
+func TestService_RevokeAllUserTokensDenylistsAccessTokens(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := &config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!", AccessTokenTTL: 15 * time.Minute}
+	svc := NewServiceWithRepo(cfg, db)
+
+	key, err := NewSigningKey(cfg)
+	require.NoError(t, err)
+	issueToken := func(userID string, issuedAt time.Time) string {
+		tokenString, err := key.Sign(jwt.MapClaims{
+			"sub": userID,
+			"jti": userID + issuedAt.String(),
+			"iat": issuedAt.Unix(),
+			"exp": time.Now().Add(10 * time.Minute).Unix(),
+		})
+		require.NoError(t, err)
+		return tokenString
+	}
+
+	// iat 为秒级精度，使用一分钟前签发的令牌模拟撤销前的令牌
+	userToken := issueToken("1", time.Now().Add(-time.Minute))
+	otherUserToken := issueToken("2", time.Now().Add(-time.Minute))
+
+	require.NoError(t, svc.RevokeAllUserTokens(context.Background(), 1))
+
+	_, err = svc.ValidateToken(userToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = svc.ValidateToken(otherUserToken)
+	assert.NoError(t, err)
+
+	_, err = svc.ValidateToken(issueToken("1", time.Now()))
+	assert.NoError(t, err, "tokens issued after the revocation stay valid")
+}
+
+func TestService_RevokeAllUserTokensThenReissue(t *testing.T) {
+	_, db := setupServiceTest(t)
+	svc := NewServiceWithRepo(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!", AccessTokenTTL: 15 * time.Minute}, db)
+
+	// 撤销后立即重新签发（如修改密码），旧令牌失效而新令牌有效，无需等待
+	for i := 0; i < 20; i++ {
+		before, err := svc.GenerateToken(1, "user@example.com", "User")
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeAllUserTokens(context.Background(), 1))
+		after, err := svc.GenerateToken(1, "user@example.com", "User")
+		require.NoError(t, err)
+
+		_, err = svc.ValidateToken(before)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		_, err = svc.ValidateToken(after)
+		assert.NoError(t, err)
+	}
+}
+
+func TestUUIDV7Time(t *testing.T) {
+	start := time.Now()
+	var previous time.Time
+	for i := 0; i < 1000; i++ {
+		id, err := uuid.NewV7()
+		require.NoError(t, err)
+		issuedAt := uuidV7Time(id)
+
+		assert.True(t, time.Unix(id.Time().UnixTime()).Equal(issuedAt.Truncate(time.Millisecond)), "milliseconds match the uuid timestamp")
+		assert.False(t, issuedAt.Before(start.Add(-256*time.Nanosecond)))
+		assert.True(t, issuedAt.After(previous), "ids issued in sequence keep their order")
+		previous = issuedAt
+	}
+}
+
+func TestTokenIssuedAt(t *testing.T) {
+	id, err := uuid.NewV7()
+	require.NoError(t, err)
+	precise := uuidV7Time(id)
+	iat := time.Unix(precise.Unix(), 0)
+	endOfSecond := iat.Add(time.Second - time.Nanosecond)
+
+	tests := []struct {
+		name     string
+		jti      string
+		iat      time.Time
+		expected time.Time
+	}{
+		{name: "uuidv7 jti gives sub-millisecond precision", jti: id.String(), iat: iat, expected: precise},
+		{name: "uuidv7 jti disagreeing with iat is ignored", jti: id.String(), iat: iat.Add(-time.Minute), expected: endOfSecond.Add(-time.Minute)},
+		{name: "uuidv4 jti falls back to iat", jti: uuid.NewString(), iat: iat, expected: endOfSecond},
+		{name: "opaque jti falls back to iat", jti: "legacy", iat: iat, expected: endOfSecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(tokenIssuedAt(tt.jti, tt.iat)))
+		})
+	}
+}
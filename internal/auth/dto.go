@@ -1,11 +1,17 @@
 package auth
 
+import "time"
+
 // Claims represents JWT token claims
 type Claims struct {
-	UserID uint     `json:"user_id"`
-	Email  string   `json:"email"`
-	Name   string   `json:"name"`
-	Roles  []string `json:"roles"`
+	UserID    uint      `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+	ID        string    `json:"jti,omitempty"`
+	IssuedAt  time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+	Actor     *Actor    `json:"act,omitempty"`
 }
 
 // TokenResponse represents token response (deprecated: use TokenPairResponse)
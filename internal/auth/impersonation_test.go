@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestService_ImpersonationToken(t *testing.T) {
+	cfg := &config.JWTConfig{Secret: "test-secret", TTLHours: 1}
+	admin := Actor{UserID: 1, Email: "admin@example.com"}
+
+	t.Run("round trip carries actor", func(t *testing.T) {
+		svc := NewService(cfg)
+		token, err := svc.GenerateImpersonationToken(context.Background(), admin, 42, "user@example.com", "User", 15*time.Minute)
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, uint(42), claims.UserID)
+		assert.Equal(t, "user@example.com", claims.Email)
+		assert.True(t, claims.IsImpersonation())
+		assert.Equal(t, &admin, claims.Actor)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), claims.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("act claim follows RFC 8693", func(t *testing.T) {
+		svc := NewService(cfg)
+		token, err := svc.GenerateImpersonationToken(context.Background(), admin, 42, "user@example.com", "User", time.Minute)
+		require.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		require.NoError(t, err)
+		act, ok := parsed.Claims.(jwt.MapClaims)["act"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "1", act["sub"])
+	})
+
+	t.Run("regular token has no actor", func(t *testing.T) {
+		svc := NewService(cfg)
+		token, err := svc.GenerateToken(42, "user@example.com", "User")
+		require.NoError(t, err)
+
+		claims, err := svc.ValidateToken(token)
+		require.NoError(t, err)
+		assert.False(t, claims.IsImpersonation())
+		assert.Nil(t, claims.Actor)
+	})
+
+	t.Run("revoking the actor ends the session", func(t *testing.T) {
+		denylist := NewMemoryDenylist()
+		svc := NewService(cfg, WithDenylist(denylist))
+		token, err := svc.GenerateImpersonationToken(context.Background(), admin, 42, "user@example.com", "User", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, denylist.RevokeUserTokens(context.Background(), admin.UserID, time.Now().Add(time.Second), time.Hour))
+
+		_, err = svc.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	})
+
+	t.Run("malformed act claim", func(t *testing.T) {
+		svc := NewService(cfg).(*service)
+		key, err := svc.key()
+		require.NoError(t, err)
+		token, err := key.Sign(jwt.MapClaims{
+			"sub": "42",
+			"act": "1",
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"iat": time.Now().Unix(),
+		})
+		require.NoError(t, err)
+
+		_, err = svc.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
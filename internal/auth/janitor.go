@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// tokenCleanupLockKey is the Postgres advisory lock key held while a cleanup runs
+const tokenCleanupLockKey int64 = 0x72656672657368 // "refresh"
+
+const (
+	defaultCleanupInterval  = time.Hour
+	defaultCleanupBatchSize = 1000
+)
+
+// CleanupResult describes a single janitor run
+type CleanupResult struct {
+	Deleted  int64
+	Batches  int
+	Duration time.Duration
+	Skipped  bool // another replica held the lock
+}
+
+// TokenJanitor periodically purges expired refresh tokens once the retention has passed.
+// Used and revoked tokens are kept until they expire so replaying them still triggers reuse detection.
+// Rows are deleted in batches so a large backlog does not hold long locks on refresh_tokens.
+type TokenJanitor struct {
+	db        *gorm.DB
+	repo      RefreshTokenRepository
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// NewTokenJanitor creates a refresh token janitor from cleanup config
+func NewTokenJanitor(db *gorm.DB, cfg *config.TokenCleanupConfig, logger *slog.Logger) *TokenJanitor {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &TokenJanitor{
+		db:        db,
+		repo:      NewRefreshTokenRepository(db),
+		interval:  interval,
+		batchSize: batchSize,
+		retention: cfg.Retention,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Run cleans up once immediately and then on every interval until ctx is cancelled
+func (j *TokenJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.runAndLog(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *TokenJanitor) runAndLog(ctx context.Context) {
+	result, err := j.RunOnce(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		j.logger.Error("Refresh token cleanup failed", "error", err, "deleted", result.Deleted, "batches", result.Batches, "duration", result.Duration)
+		return
+	}
+	if result.Skipped {
+		j.logger.Debug("Refresh token cleanup skipped, another instance holds the lock")
+		return
+	}
+	j.logger.Info("Refresh token cleanup finished", "deleted", result.Deleted, "batches", result.Batches, "duration", result.Duration, "retention", j.retention)
+}
+
+// RunOnce deletes stale tokens batch by batch while holding the cleanup lock
+func (j *TokenJanitor) RunOnce(ctx context.Context) (CleanupResult, error) {
+	start := j.now()
+	cutoff := start.Add(-j.retention)
+
+	var result CleanupResult
+	acquired, err := j.withLock(ctx, func(repo RefreshTokenRepository) error {
+		for {
+			deleted, err := repo.DeleteStale(ctx, cutoff, j.batchSize)
+			if err != nil {
+				return err
+			}
+			result.Batches++
+			result.Deleted += deleted
+			if deleted < int64(j.batchSize) {
+				return nil
+			}
+		}
+	})
+	result.Skipped = !acquired
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// withLock runs fn while holding a Postgres session advisory lock.
+// WHY: Session locks belong to a connection, so the lock, the deletes and the unlock
+// all run on one pooled connection. Other databases (SQLite in tests) run without a lock.
+func (j *TokenJanitor) withLock(ctx context.Context, fn func(repo RefreshTokenRepository) error) (bool, error) {
+	if j.db.Dialector.Name() != "postgres" {
+		return true, fn(j.repo)
+	}
+
+	acquired := false
+	err := j.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", tokenCleanupLockKey).Scan(&acquired).Error; err != nil {
+			return fmt.Errorf("failed to acquire cleanup lock: %w", err)
+		}
+		if !acquired {
+			return nil
+		}
+		defer func() {
+			// 使用独立 context，确保 ctx 取消后仍能释放锁
+			if err := conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", tokenCleanupLockKey).Error; err != nil {
+				j.logger.Error("Failed to release refresh token cleanup lock", "error", err)
+			}
+		}()
+		return fn(NewRefreshTokenRepository(conn))
+	})
+	return acquired, err
+}
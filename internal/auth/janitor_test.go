@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestRefreshTokenRepository_DeleteStale(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	cutoff := now.Add(-24 * time.Hour)
+	tokens := []*RefreshToken{
+		{TokenHash: "active", ExpiresAt: now.Add(time.Hour)},
+		{TokenHash: "recently-used", ExpiresAt: now.Add(time.Hour), UsedAt: ptrTime(now.Add(-time.Hour))},
+		{TokenHash: "recently-expired", ExpiresAt: now.Add(-time.Hour)},
+		{TokenHash: "old-used", ExpiresAt: now.Add(time.Hour), UsedAt: ptrTime(now.Add(-48 * time.Hour))},
+		{TokenHash: "old-revoked", ExpiresAt: now.Add(time.Hour), RevokedAt: ptrTime(now.Add(-48 * time.Hour))},
+		{TokenHash: "old-expired", ExpiresAt: now.Add(-48 * time.Hour)},
+		{TokenHash: "old-expired-used", ExpiresAt: now.Add(-48 * time.Hour), UsedAt: ptrTime(now.Add(-72 * time.Hour))},
+		{TokenHash: "old-expired-revoked", ExpiresAt: now.Add(-48 * time.Hour), RevokedAt: ptrTime(now.Add(-72 * time.Hour))},
+	}
+	for _, token := range tokens {
+		token.UserID = 1
+		token.TokenFamily = uuid.New()
+		require.NoError(t, repo.Create(ctx, token))
+	}
+
+	deleted, err := repo.DeleteStale(ctx, cutoff, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted, "deletes at most one batch")
+
+	deleted, err = repo.DeleteStale(ctx, cutoff, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var remaining []string
+	require.NoError(t, db.Model(&RefreshToken{}).Order("token_hash").Pluck("token_hash", &remaining).Error)
+	assert.Equal(t, []string{"active", "old-revoked", "old-used", "recently-expired", "recently-used"}, remaining,
+		"used and revoked tokens are kept until they expire so replays still trigger reuse detection")
+}
+
+func TestTokenJanitor_RunOnce(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	usedAt := time.Now().Add(-72 * time.Hour)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, repo.Create(ctx, &RefreshToken{
+			UserID:      1,
+			TokenHash:   fmt.Sprintf("stale-%d", i),
+			TokenFamily: uuid.New(),
+			ExpiresAt:   time.Now().Add(-48 * time.Hour),
+			UsedAt:      &usedAt,
+		}))
+	}
+	require.NoError(t, repo.Create(ctx, &RefreshToken{
+		UserID:      1,
+		TokenHash:   "active",
+		TokenFamily: uuid.New(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}))
+
+	janitor := NewTokenJanitor(db, &config.TokenCleanupConfig{BatchSize: 2, Retention: 24 * time.Hour}, nil)
+
+	result, err := janitor.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, int64(5), result.Deleted)
+	assert.Equal(t, 3, result.Batches)
+
+	var count int64
+	require.NoError(t, db.Model(&RefreshToken{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestTokenJanitor_RunStopsOnCancel(t *testing.T) {
+	db := setupTestDB(t)
+	janitor := NewTokenJanitor(db, &config.TokenCleanupConfig{Interval: time.Hour}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("janitor did not stop after context cancellation")
+	}
+}
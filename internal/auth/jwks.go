@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler serves the public signing keys at /.well-known/jwks.json
+// so that gateways and other services can verify tokens without the private key.
+// The key set is returned as raw JSON (not wrapped in the API envelope) as required by RFC 7517.
+func JWKSHandler(authService Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, authService.JWKS())
+	}
+}
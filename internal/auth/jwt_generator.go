@@ -18,19 +18,16 @@ type JWTGenerator interface {
 }
 
 type jwtGenerator struct {
-	jwtSecret       string
+	keyring         *Keyring
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 	db              *gorm.DB
+	issuer          string
+	audiences       []string
 }
 
 // NewJWTGenerator 创建新的 JWT 生成器
 func NewJWTGenerator(cfg *config.JWTConfig, db *gorm.DB) JWTGenerator {
-	jwtSecret := cfg.Secret
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-change-in-production"
-	}
-
 	accessTokenTTL := cfg.AccessTokenTTL
 	if accessTokenTTL == 0 {
 		if cfg.TTLHours > 0 {
@@ -46,10 +43,12 @@ func NewJWTGenerator(cfg *config.JWTConfig, db *gorm.DB) JWTGenerator {
 	}
 
 	return &jwtGenerator{
-		jwtSecret:       jwtSecret,
+		keyring:         loadKeyring(cfg),
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
 		db:              db,
+		issuer:          cfg.Issuer,
+		audiences:       cfg.Audiences,
 	}
 }
 
@@ -74,6 +73,7 @@ func (g *jwtGenerator) GenerateAccessToken(userID uint, email string, name strin
 
 	claims := jwt.MapClaims{
 		"sub":   fmt.Sprintf("%d", userID),
+		"jti":   newTokenID(),
 		"email": email,
 		"name":  name,
 		"roles": roles,
@@ -81,9 +81,14 @@ func (g *jwtGenerator) GenerateAccessToken(userID uint, email string, name strin
 		"iat":   now.Unix(),
 		"nbf":   now.Unix(),
 	}
+	setRegisteredClaims(claims, g.issuer, g.audiences)
+
+	key, err := g.keyring.Active()
+	if err != nil {
+		return "", fmt.Errorf("signing key unavailable: %w", err)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(g.jwtSecret))
+	tokenString, err := key.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -103,9 +108,14 @@ func (g *jwtGenerator) GenerateRefreshToken(userID uint) (string, error) {
 		"iat":  now.Unix(),
 		"nbf":  now.Unix(),
 	}
+	setRegisteredClaims(claims, g.issuer, g.audiences)
+
+	key, err := g.keyring.Active()
+	if err != nil {
+		return "", fmt.Errorf("signing key unavailable: %w", err)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(g.jwtSecret))
+	tokenString, err := key.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign refresh token: %w", err)
 	}
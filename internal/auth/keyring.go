@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// ErrNoSigningKey is returned when the keyring has no usable signing key
+var ErrNoSigningKey = errors.New("no signing key loaded")
+
+// retiredKey is a verify-only key accepted until its expiry
+type retiredKey struct {
+	key         *SigningKey
+	acceptUntil time.Time
+}
+
+// Keyring holds the active signing key and retired keys still accepted for verification.
+// It is safe for concurrent use and can be reloaded at runtime to rotate keys.
+type Keyring struct {
+	mu      sync.RWMutex
+	active  *SigningKey
+	retired map[string]retiredKey
+	now     func() time.Time
+}
+
+// NewKeyring loads the active and retired keys from JWT config.
+// WHY: On error the returned keyring is empty so token operations fail closed
+// until a successful Reload.
+func NewKeyring(cfg *config.JWTConfig) (*Keyring, error) {
+	keyring := &Keyring{
+		retired: make(map[string]retiredKey),
+		now:     time.Now,
+	}
+	return keyring, keyring.Reload(cfg)
+}
+
+// Reload rebuilds the keyring from config.
+// The swap is atomic: if any key fails to load, the current keys stay in use.
+func (k *Keyring) Reload(cfg *config.JWTConfig) error {
+	active, err := NewSigningKey(cfg)
+	if err != nil {
+		return err
+	}
+
+	retired := make(map[string]retiredKey, len(cfg.RetiredKeys))
+	for i := range cfg.RetiredKeys {
+		keyCfg := &cfg.RetiredKeys[i]
+		if keyCfg.KeyID == "" || keyCfg.KeyID == active.ID {
+			return fmt.Errorf("retired key %d: key_id must be set and differ from the active key", i)
+		}
+
+		acceptUntil, err := keyCfg.GetAcceptUntil()
+		if err != nil {
+			return fmt.Errorf("retired key %s: %w", keyCfg.KeyID, err)
+		}
+
+		key, err := NewVerificationKey(keyCfg)
+		if err != nil {
+			return fmt.Errorf("retired key %s: %w", keyCfg.KeyID, err)
+		}
+		retired[key.ID] = retiredKey{key: key, acceptUntil: acceptUntil}
+	}
+
+	k.mu.Lock()
+	k.active = active
+	k.retired = retired
+	k.mu.Unlock()
+
+	slog.Info("JWT keyring loaded", "active_kid", active.ID, "algorithm", active.Method.Alg(), "retired_keys", len(retired))
+	return nil
+}
+
+// Active returns the key used to sign new tokens
+func (k *Keyring) Active() (*SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active == nil {
+		return nil, ErrNoSigningKey
+	}
+	return k.active, nil
+}
+
+// VerificationKey selects the verification key by the token's kid header.
+// Tokens without a kid are verified with the active key only.
+func (k *Keyring) VerificationKey(token *jwt.Token) (interface{}, error) {
+	k.mu.RLock()
+	active := k.active
+	kid, hasKID := token.Header["kid"].(string)
+	retired, isRetired := k.retired[kid]
+	k.mu.RUnlock()
+
+	if active == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	if !hasKID || kid == active.ID {
+		return active.VerificationKey(token)
+	}
+
+	if !isRetired {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	if k.now().After(retired.acceptUntil) {
+		return nil, fmt.Errorf("key %s is no longer accepted", kid)
+	}
+	return retired.key.VerificationKey(token)
+}
+
+// PublicJWKs returns the public keys of the active key and unexpired retired keys
+func (k *Keyring) PublicJWKs() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := []JWK{}
+	if k.active != nil {
+		if jwk, ok := k.active.PublicJWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+
+	kids := make([]string, 0, len(k.retired))
+	for kid := range k.retired {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	now := k.now()
+	for _, kid := range kids {
+		retired := k.retired[kid]
+		if now.After(retired.acceptUntil) {
+			continue
+		}
+		if jwk, ok := retired.key.PublicJWK(); ok {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+const (
+	oldSecret = "old-secret-key-at-least-32-characters!"
+	newSecret = "new-secret-key-at-least-32-characters!"
+)
+
+// writePublicKey writes the PKIX public key of a private key PEM file and returns its path
+func writePublicKey(t *testing.T, privateKeyPath string) string {
+	t.Helper()
+
+	key, err := NewSigningKey(&config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: privateKeyPath})
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(key.verifyKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestKeyring_VerificationKey(t *testing.T) {
+	oldService := NewService(&config.JWTConfig{Secret: oldSecret})
+	oldToken, err := oldService.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		retired     []config.RetiredKeyConfig
+		now         time.Time
+		expectValid bool
+	}{
+		{
+			name:        "retired key accepted before accept_until",
+			retired:     []config.RetiredKeyConfig{{KeyID: "default", Secret: oldSecret, AcceptUntil: "2099-01-01"}},
+			now:         time.Now(),
+			expectValid: true,
+		},
+		{
+			name:        "retired key rejected after accept_until",
+			retired:     []config.RetiredKeyConfig{{KeyID: "default", Secret: oldSecret, AcceptUntil: "2020-01-01T00:00:00Z"}},
+			now:         time.Now(),
+			expectValid: false,
+		},
+		{
+			name:        "token rejected once key is dropped from keyring",
+			retired:     nil,
+			now:         time.Now(),
+			expectValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := NewKeyring(&config.JWTConfig{Secret: newSecret, KeyID: "2026-10", RetiredKeys: tt.retired})
+			require.NoError(t, err)
+			keyring.now = func() time.Time { return tt.now }
+
+			_, err = jwt.Parse(oldToken, keyring.VerificationKey)
+			if tt.expectValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestKeyring_TokenWithoutKIDUsesActiveKey(t *testing.T) {
+	keyring, err := NewKeyring(&config.JWTConfig{
+		Secret:      newSecret,
+		KeyID:       "2026-10",
+		RetiredKeys: []config.RetiredKeyConfig{{KeyID: "default", Secret: oldSecret, AcceptUntil: "2099-01-01"}},
+	})
+	require.NoError(t, err)
+
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	oldLegacy, err := legacy.SignedString([]byte(oldSecret))
+	require.NoError(t, err)
+	newLegacy, err := legacy.SignedString([]byte(newSecret))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(oldLegacy, keyring.VerificationKey)
+	assert.Error(t, err, "tokens without kid must not fall through to retired keys")
+	_, err = jwt.Parse(newLegacy, keyring.VerificationKey)
+	assert.NoError(t, err)
+}
+
+func TestKeyring_ReloadKeepsKeysOnError(t *testing.T) {
+	keyring, err := NewKeyring(&config.JWTConfig{Secret: oldSecret})
+	require.NoError(t, err)
+
+	err = keyring.Reload(&config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+
+	active, err := keyring.Active()
+	require.NoError(t, err)
+	assert.Equal(t, "HS256", active.Method.Alg())
+	assert.Equal(t, defaultHMACKeyID, active.ID)
+}
+
+func TestKeyring_PublicJWKsIncludeRetiredKeys(t *testing.T) {
+	retiredPath := writeEd25519Key(t)
+	keyring, err := NewKeyring(&config.JWTConfig{
+		Algorithm:      "EdDSA",
+		PrivateKeyPath: writeEd25519Key(t),
+		KeyID:          "current",
+		RetiredKeys: []config.RetiredKeyConfig{
+			{KeyID: "previous", Algorithm: "EdDSA", PublicKeyPath: writePublicKey(t, retiredPath), AcceptUntil: "2099-01-01"},
+			{KeyID: "expired", Algorithm: "EdDSA", PublicKeyPath: writePublicKey(t, retiredPath), AcceptUntil: "2020-01-01"},
+			{KeyID: "old-hmac", Secret: oldSecret, AcceptUntil: "2099-01-01"},
+		},
+	})
+	require.NoError(t, err)
+
+	var kids []string
+	for _, jwk := range keyring.PublicJWKs() {
+		kids = append(kids, jwk.Kid)
+	}
+	assert.Equal(t, []string{"current", "previous"}, kids)
+}
+
+func TestService_ReloadKeys(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Secret: oldSecret})
+	oldToken, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	err = svc.ReloadKeys(&config.JWTConfig{
+		Secret:      newSecret,
+		KeyID:       "2026-10",
+		RetiredKeys: []config.RetiredKeyConfig{{KeyID: "default", Secret: oldSecret, AcceptUntil: "2099-01-01"}},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.ValidateToken(oldToken)
+	assert.NoError(t, err, "tokens signed before rotation stay valid")
+
+	newToken, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+	unverified, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", unverified.Header["kid"])
+
+	err = svc.ReloadKeys(&config.JWTConfig{Secret: newSecret, KeyID: "2026-10"})
+	require.NoError(t, err)
+
+	_, err = svc.ValidateToken(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = svc.ValidateToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestService_ReloadKeysRecoversFromLoadError(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: filepath.Join(t.TempDir(), "missing.pem")})
+	_, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.Error(t, err)
+
+	require.NoError(t, svc.ReloadKeys(&config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: writeEd25519Key(t)}))
+
+	tokenString, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(tokenString)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/redis"
+)
+
+const (
+	loginFailuresPrefix = "auth:login:failures:"
+	loginBlockPrefix    = "auth:login:block:"
+	loginLockMarker     = "lock:"
+)
+
+// LoginBlockedError is returned when a login is rejected before checking the password
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	// Locked is true for a lockout after too many failures, false for a progressive delay
+	Locked bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "login temporarily locked after too many failed attempts"
+	}
+	return "login attempted too soon after a failed attempt"
+}
+
+// RetryAfterSeconds returns the wait rounded up to whole seconds
+func (e *LoginBlockedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// LoginAttemptStore keeps failed login counters and blocks, keyed by account or IP
+type LoginAttemptStore interface {
+	// IncrementFailures records a failed attempt and returns the failures within the window
+	IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error)
+	// Block rejects attempts for key until the given time; locked marks a lockout rather than a delay
+	Block(ctx context.Context, key string, until time.Time, locked bool) error
+	// BlockedUntil returns when the current block ends, or the zero time when key is not blocked
+	BlockedUntil(ctx context.Context, key string) (time.Time, bool, error)
+	// Reset clears the failures and block of key
+	Reset(ctx context.Context, key string) error
+}
+
+// LoginGuard throttles password guessing per account and per IP.
+// Each failed login for an account delays its next attempt (base_delay, doubling up to max_delay);
+// after max_attempts failures within the window the account is locked for lock_duration.
+// IPs are only locked, after ip_max_attempts failures, so users behind a shared NAT are not slowed down.
+type LoginGuard struct {
+	store         LoginAttemptStore
+	enabled       bool
+	maxAttempts   int
+	ipMaxAttempts int
+	window        time.Duration
+	lockDuration  time.Duration
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	now           func() time.Time
+}
+
+// NewLoginGuard creates a login guard; when cfg.Enabled is false every check passes
+func NewLoginGuard(cfg *config.LoginProtectionConfig, store LoginAttemptStore) *LoginGuard {
+	return &LoginGuard{
+		store:         store,
+		enabled:       cfg.Enabled,
+		maxAttempts:   cfg.GetMaxAttempts(),
+		ipMaxAttempts: cfg.GetIPMaxAttempts(),
+		window:        cfg.GetWindow(),
+		lockDuration:  cfg.GetLockDuration(),
+		baseDelay:     cfg.GetBaseDelay(),
+		maxDelay:      cfg.GetMaxDelay(),
+		now:           time.Now,
+	}
+}
+
+// Check returns a *LoginBlockedError if the account or the IP may not attempt a login yet
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	if !g.enabled {
+		return nil
+	}
+
+	var blocked *LoginBlockedError
+	for _, key := range g.keys(email, ip) {
+		until, locked, err := g.store.BlockedUntil(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		wait := until.Sub(g.now())
+		if wait <= 0 {
+			continue
+		}
+		if blocked == nil || wait > blocked.RetryAfter {
+			blocked = &LoginBlockedError{RetryAfter: wait, Locked: locked}
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the IP and blocks them as configured.
+// Unknown emails are counted too, so responses do not reveal which accounts exist.
+func (g *LoginGuard) RecordFailure(ctx context.Context, email, ip string) error {
+	if !g.enabled {
+		return nil
+	}
+
+	now := g.now()
+	accountKey := accountLoginKey(email)
+	failures, err := g.store.IncrementFailures(ctx, accountKey, g.window)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if failures >= g.maxAttempts {
+		err = g.store.Block(ctx, accountKey, now.Add(g.lockDuration), true)
+	} else {
+		err = g.store.Block(ctx, accountKey, now.Add(g.delay(failures)), false)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to block login: %w", err)
+	}
+
+	if ip == "" {
+		return nil
+	}
+	ipKey := ipLoginKey(ip)
+	failures, err = g.store.IncrementFailures(ctx, ipKey, g.window)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if failures >= g.ipMaxAttempts {
+		if err := g.store.Block(ctx, ipKey, now.Add(g.lockDuration), true); err != nil {
+			return fmt.Errorf("failed to block login: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's failures after a successful login.
+// The IP counter is kept so one valid account cannot be used to reset it.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, email string) error {
+	if !g.enabled {
+		return nil
+	}
+	return g.Unlock(ctx, email)
+}
+
+// Unlock clears the failures and any lock of an account
+func (g *LoginGuard) Unlock(ctx context.Context, email string) error {
+	if err := g.store.Reset(ctx, accountLoginKey(email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+// CheckSecondFactor returns a *LoginBlockedError while a user may not submit second-factor codes,
+// either on the mfa step of a login or from a signed-in session.
+// Unlike logins this limit applies even when login protection is disabled: whoever reaches
+// the second factor already knows the password or holds a session and has no reason to keep guessing codes.
+func (g *LoginGuard) CheckSecondFactor(ctx context.Context, userID uint) error {
+	until, locked, err := g.store.BlockedUntil(ctx, secondFactorKey(userID))
+	if err != nil {
+		return fmt.Errorf("failed to check second factor attempts: %w", err)
+	}
+	if wait := until.Sub(g.now()); wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait, Locked: locked}
+	}
+	return nil
+}
+
+// RecordSecondFactorFailure counts a wrong code of a user, whichever mfa token or session it came from;
+// after max_attempts failures within the window their second-factor checks are locked for lock_duration
+func (g *LoginGuard) RecordSecondFactorFailure(ctx context.Context, userID uint) error {
+	key := secondFactorKey(userID)
+	failures, err := g.store.IncrementFailures(ctx, key, g.window)
+	if err != nil {
+		return fmt.Errorf("failed to record second factor failure: %w", err)
+	}
+	if failures >= g.maxAttempts {
+		if err := g.store.Block(ctx, key, g.now().Add(g.lockDuration), true); err != nil {
+			return fmt.Errorf("failed to block second factor: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordSecondFactorSuccess clears the second-factor failures of a user after a correct code
+func (g *LoginGuard) RecordSecondFactorSuccess(ctx context.Context, userID uint) error {
+	if err := g.store.Reset(ctx, secondFactorKey(userID)); err != nil {
+		return fmt.Errorf("failed to reset second factor failures: %w", err)
+	}
+	return nil
+}
+
+// delay returns the wait after the given number of consecutive failures: base, 2×base, 4×base, ... up to maxDelay
+func (g *LoginGuard) delay(failures int) time.Duration {
+	delay := g.baseDelay
+	for i := 1; i < failures && delay < g.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.maxDelay)
+}
+
+func (g *LoginGuard) keys(email, ip string) []string {
+	keys := []string{accountLoginKey(email)}
+	if ip != "" {
+		keys = append(keys, ipLoginKey(ip))
+	}
+	return keys
+}
+
+func accountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+func secondFactorKey(userID uint) string {
+	return "mfa:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// redisLoginAttemptStore shares login attempts between replicas through Redis
+type redisLoginAttemptStore struct {
+	client *redis.Client
+}
+
+// NewRedisLoginAttemptStore creates a login attempt store backed by Redis
+func NewRedisLoginAttemptStore(client *redis.Client) LoginAttemptStore {
+	return &redisLoginAttemptStore{client: client}
+}
+
+func (s *redisLoginAttemptStore) IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error) {
+	failures, err := s.client.Incr(ctx, loginFailuresPrefix+key)
+	if err != nil {
+		return 0, err
+	}
+	// 窗口从第一次失败开始计算
+	if failures == 1 {
+		if err := s.client.Expire(ctx, loginFailuresPrefix+key, window); err != nil {
+			return 0, err
+		}
+	}
+	return int(failures), nil
+}
+
+func (s *redisLoginAttemptStore) Block(ctx context.Context, key string, until time.Time, locked bool) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	value := strconv.FormatInt(until.UnixMilli(), 10)
+	if locked {
+		value = loginLockMarker + value
+	}
+	return s.client.Set(ctx, loginBlockPrefix+key, value, ttl)
+}
+
+func (s *redisLoginAttemptStore) BlockedUntil(ctx context.Context, key string) (time.Time, bool, error) {
+	value, err := s.client.Get(ctx, loginBlockPrefix+key)
+	if err != nil || value == "" {
+		return time.Time{}, false, err
+	}
+
+	locked := strings.HasPrefix(value, loginLockMarker)
+	millis, err := strconv.ParseInt(strings.TrimPrefix(value, loginLockMarker), 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid login block entry: %w", err)
+	}
+	return time.UnixMilli(millis), locked, nil
+}
+
+func (s *redisLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.client.Delete(ctx, loginFailuresPrefix+key, loginBlockPrefix+key)
+}
+
+// memoryLoginAttemptStore keeps login attempts in process memory.
+// WHY: Used when Redis is disabled; attempts are not shared between replicas and are lost on restart.
+type memoryLoginAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryLoginAttempts
+	now     func() time.Time
+}
+
+type memoryLoginAttempts struct {
+	failures     int
+	windowEnds   time.Time
+	blockedUntil time.Time
+	locked       bool
+}
+
+// NewMemoryLoginAttemptStore creates an in-memory login attempt store
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{
+		entries: make(map[string]*memoryLoginAttempts),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryLoginAttemptStore) IncrementFailures(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired()
+	now := s.now()
+	entry := s.entry(key)
+	if !now.Before(entry.windowEnds) {
+		entry.failures = 0
+		entry.windowEnds = now.Add(window)
+	}
+	entry.failures++
+	return entry.failures, nil
+}
+
+func (s *memoryLoginAttemptStore) Block(ctx context.Context, key string, until time.Time, locked bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entry(key)
+	entry.blockedUntil = until
+	entry.locked = locked
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) BlockedUntil(ctx context.Context, key string) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !s.now().Before(entry.blockedUntil) {
+		return time.Time{}, false, nil
+	}
+	return entry.blockedUntil, entry.locked, nil
+}
+
+func (s *memoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *memoryLoginAttemptStore) entry(key string) *memoryLoginAttempts {
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryLoginAttempts{}
+		s.entries[key] = entry
+	}
+	return entry
+}
+
+// purgeExpired drops entries whose window and block have both ended. Callers must hold mu.
+func (s *memoryLoginAttemptStore) purgeExpired() {
+	now := s.now()
+	for key, entry := range s.entries {
+		if !now.Before(entry.windowEnds) && !now.Before(entry.blockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
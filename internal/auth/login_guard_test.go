@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// newTestLoginGuard returns a guard on an in-memory store whose clock is advanced through the returned pointer
+func newTestLoginGuard(cfg *config.LoginProtectionConfig) (*LoginGuard, *time.Time) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	store := NewMemoryLoginAttemptStore().(*memoryLoginAttemptStore)
+	store.now = clock
+	guard := NewLoginGuard(cfg, store)
+	guard.now = clock
+	return guard, &now
+}
+
+func requireBlocked(t *testing.T, err error, locked bool, retryAfter time.Duration, msgAndArgs ...interface{}) {
+	t.Helper()
+
+	var blocked *LoginBlockedError
+	require.ErrorAs(t, err, &blocked, msgAndArgs...)
+	assert.Equal(t, locked, blocked.Locked)
+	assert.Equal(t, retryAfter, blocked.RetryAfter)
+}
+
+func TestLoginGuard_ProgressiveDelayThenLock(t *testing.T) {
+	ctx := context.Background()
+	guard, now := newTestLoginGuard(&config.LoginProtectionConfig{
+		Enabled:      true,
+		MaxAttempts:  4,
+		LockDuration: 10 * time.Minute,
+		BaseDelay:    time.Second,
+		MaxDelay:     3 * time.Second,
+	})
+
+	require.NoError(t, guard.Check(ctx, "user@example.com", "10.0.0.1"))
+
+	// 延迟依次为 1s、2s，然后受 max_delay 限制为 3s
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		requireBlocked(t, guard.Check(ctx, "USER@example.com", "10.0.0.2"), false, delay)
+
+		*now = now.Add(delay)
+		require.NoError(t, guard.Check(ctx, "user@example.com", "10.0.0.1"))
+	}
+
+	require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+	requireBlocked(t, guard.Check(ctx, "user@example.com", "10.0.0.1"), true, 10*time.Minute)
+
+	*now = now.Add(10 * time.Minute)
+	assert.NoError(t, guard.Check(ctx, "user@example.com", "10.0.0.1"), "locks expire")
+}
+
+func TestLoginGuard_LocksIP(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newTestLoginGuard(&config.LoginProtectionConfig{
+		Enabled:       true,
+		IPMaxAttempts: 3,
+		LockDuration:  time.Minute,
+	})
+
+	// 每次使用不同账号，只有 IP 计数会累积
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		require.NoError(t, guard.RecordFailure(ctx, email, "10.0.0.1"))
+	}
+
+	requireBlocked(t, guard.Check(ctx, "d@example.com", "10.0.0.1"), true, time.Minute)
+	assert.NoError(t, guard.Check(ctx, "d@example.com", "10.0.0.2"))
+}
+
+func TestLoginGuard_SuccessAndUnlockClearAccount(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newTestLoginGuard(&config.LoginProtectionConfig{Enabled: true, MaxAttempts: 2, IPMaxAttempts: 2})
+
+	require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+	require.NoError(t, guard.RecordSuccess(ctx, "user@example.com"))
+	assert.NoError(t, guard.Check(ctx, "user@example.com", ""), "a successful login clears the account")
+
+	require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+	require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+	requireBlocked(t, guard.Check(ctx, "user@example.com", ""), true, config.DefaultLoginLockDuration)
+
+	require.NoError(t, guard.Unlock(ctx, "User@Example.com"))
+	assert.NoError(t, guard.Check(ctx, "user@example.com", ""))
+
+	requireBlocked(t, guard.Check(ctx, "other@example.com", "10.0.0.1"), true, config.DefaultLoginLockDuration, "unlocking an account keeps the IP lock")
+}
+
+func TestLoginGuard_Disabled(t *testing.T) {
+	ctx := context.Background()
+	guard, _ := newTestLoginGuard(&config.LoginProtectionConfig{MaxAttempts: 1})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, guard.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+	}
+	assert.NoError(t, guard.Check(ctx, "user@example.com", "10.0.0.1"))
+}
+
+func TestLoginGuard_SecondFactor(t *testing.T) {
+	ctx := context.Background()
+	// 即使未启用登录保护，会话内的验证码尝试也受限制
+	guard, now := newTestLoginGuard(&config.LoginProtectionConfig{MaxAttempts: 3, LockDuration: 10 * time.Minute})
+
+	require.NoError(t, guard.RecordSecondFactorFailure(ctx, 7))
+	require.NoError(t, guard.RecordSecondFactorSuccess(ctx, 7))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, guard.RecordSecondFactorFailure(ctx, 7))
+		require.NoError(t, guard.CheckSecondFactor(ctx, 7), "a success resets the count")
+	}
+
+	require.NoError(t, guard.RecordSecondFactorFailure(ctx, 7))
+	requireBlocked(t, guard.CheckSecondFactor(ctx, 7), true, 10*time.Minute)
+	assert.NoError(t, guard.CheckSecondFactor(ctx, 8), "locks are per user")
+	assert.NoError(t, guard.Check(ctx, "user@example.com", ""), "password logins are not affected")
+
+	*now = now.Add(10 * time.Minute)
+	assert.NoError(t, guard.CheckSecondFactor(ctx, 7), "locks expire")
+}
+
+func TestMemoryLoginAttemptStore_WindowExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLoginAttemptStore().(*memoryLoginAttemptStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	failures, err := store.IncrementFailures(ctx, "account:a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	failures, err = store.IncrementFailures(ctx, "account:a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, failures)
+
+	now = now.Add(time.Minute)
+	failures, err = store.IncrementFailures(ctx, "account:b", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	assert.NotContains(t, store.entries, "account:a", "expired entries are purged")
+}
+
+func TestLoginBlockedError_RetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 2, (&LoginBlockedError{RetryAfter: 1500 * time.Millisecond}).RetryAfterSeconds())
+	assert.Equal(t, 60, (&LoginBlockedError{RetryAfter: time.Minute}).RetryAfterSeconds())
+}
@@ -6,10 +6,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
 )
 
 // MockAuthService is a mock implementation of Service interface
@@ -46,6 +50,21 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*Claims, error) {
 	return args.Get(0).(*Claims), args.Error(1)
 }
 
+func (m *MockAuthService) JWKS() JWKSet {
+	args := m.Called()
+	return args.Get(0).(JWKSet)
+}
+
+func (m *MockAuthService) RevokeAccessToken(ctx context.Context, claims *Claims) error {
+	args := m.Called(ctx, claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ReloadKeys(cfg *config.JWTConfig) error {
+	args := m.Called(cfg)
+	return args.Error(0)
+}
+
 func (m *MockAuthService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
 	args := m.Called(ctx, refreshToken)
 	return args.Error(0)
@@ -61,6 +80,37 @@ func (m *MockAuthService) RevokeAllUserTokens(ctx context.Context, userID uint)
 	return args.Error(0)
 }
 
+func (m *MockAuthService) ListSessions(ctx context.Context, userID uint) ([]Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Session), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(ctx context.Context, userID uint, family uuid.UUID) error {
+	args := m.Called(ctx, userID, family)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) GenerateClientToken(clientID string, scopes []string, ttl time.Duration) (string, error) {
+	args := m.Called(clientID, scopes, ttl)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) GenerateImpersonationToken(ctx context.Context, actor Actor, userID uint, email string, name string, ttl time.Duration) (string, error) {
+	args := m.Called(ctx, actor, userID, email, name, ttl)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) ValidateClientToken(tokenString string) (*ClientClaims, error) {
+	args := m.Called(tokenString)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ClientClaims), args.Error(1)
+}
+
 func setupTestRouter(authService Service) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
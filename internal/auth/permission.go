@@ -0,0 +1,9 @@
+package auth
+
+import "context"
+
+// PermissionResolver resolves the permissions granted to a role, such as users:read
+// Implementations return an empty list for unknown roles
+type PermissionResolver interface {
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+}
@@ -24,6 +24,8 @@ type RefreshToken struct {
 	ExpiresAt   time.Time `gorm:"not null;index"`
 	UsedAt      *time.Time
 	RevokedAt   *time.Time
+	UserAgent   string    `gorm:"type:varchar(512)"`
+	IPAddress   string    `gorm:"type:varchar(45)"`
 	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
 
@@ -48,10 +50,12 @@ type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *RefreshToken) error
 	FindByTokenHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
 	FindByTokenFamily(ctx context.Context, tokenFamily uuid.UUID) ([]*RefreshToken, error)
+	FindActiveFamiliesByUserID(ctx context.Context, userID uint) ([]uuid.UUID, error)
 	MarkAsUsed(ctx context.Context, id uuid.UUID) error
 	RevokeTokenFamily(ctx context.Context, tokenFamily uuid.UUID) error
 	RevokeByUserID(ctx context.Context, userID uint) error
 	DeleteExpired(ctx context.Context) error
+	DeleteStale(ctx context.Context, cutoff time.Time, limit int) (int64, error)
 }
 
 type refreshTokenRepository struct {
@@ -96,6 +100,23 @@ func (r *refreshTokenRepository) FindByTokenFamily(ctx context.Context, tokenFam
 	return tokens, nil
 }
 
+// FindActiveFamiliesByUserID returns the families that still hold an unused, unrevoked, unexpired token
+func (r *refreshTokenRepository) FindActiveFamiliesByUserID(ctx context.Context, userID uint) ([]uuid.UUID, error) {
+	var families []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&RefreshToken{}).
+		Distinct("token_family").
+		Where("user_id = ?", userID).
+		Where("used_at IS NULL").
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Pluck("token_family", &families).Error
+	if err != nil {
+		return nil, err
+	}
+	return families, nil
+}
+
 func (r *refreshTokenRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
 	now := time.Now()
 	result := r.db.WithContext(ctx).
@@ -138,3 +159,20 @@ func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
 		Where("expires_at < ?", time.Now()).
 		Delete(&RefreshToken{}).Error
 }
+
+// DeleteStale deletes up to limit tokens that expired before cutoff.
+// WHY: 已使用或已撤销但未过期的记录不能删除：被盗的旧令牌仍在有效期内，删除后重放只会被当作未知令牌，
+// 不再触发令牌家族撤销，重用检测随之失效
+// Returns the number of rows deleted so callers can loop until a batch comes back short.
+func (r *refreshTokenRepository) DeleteStale(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	stale := r.db.WithContext(ctx).
+		Model(&RefreshToken{}).
+		Select("id").
+		Where("expires_at < ?", cutoff).
+		Limit(limit)
+
+	result := r.db.WithContext(ctx).
+		Where("id IN (?)", stale).
+		Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
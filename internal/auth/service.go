@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
 	"github.com/yeegeek/go-rest-api-starter/internal/config"
 )
 
@@ -23,8 +25,14 @@ var (
 	ErrExpiredToken = errors.New("token expired")
 	// ErrTokenReuse is returned when a refresh token is reused
 	ErrTokenReuse = errors.New("token reuse detected")
-	// ErrTokenRevoked is returned when a refresh token has been revoked
+	// ErrTokenRevoked is returned when a refresh or access token has been revoked
 	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrTokenNotValidYet is returned when a token is used before its nbf/iat
+	ErrTokenNotValidYet = errors.New("token not valid yet")
+	// ErrInvalidIssuer is returned when the iss claim doesn't match the configured issuer
+	ErrInvalidIssuer = errors.New("invalid token issuer")
+	// ErrInvalidAudience is returned when the aud claim contains none of the configured audiences
+	ErrInvalidAudience = errors.New("invalid token audience")
 )
 
 // TokenPair represents an access and refresh token pair
@@ -42,21 +50,62 @@ type Service interface {
 	GenerateTokenPair(ctx context.Context, userID uint, email string, name string) (*TokenPair, error)
 	RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenPair, error)
 	ValidateToken(tokenString string) (*Claims, error)
+	RevokeAccessToken(ctx context.Context, claims *Claims) error
+	JWKS() JWKSet
+	ReloadKeys(cfg *config.JWTConfig) error
 	RevokeRefreshToken(ctx context.Context, refreshToken string) error
 	RevokeUserRefreshToken(ctx context.Context, userID uint, refreshToken string) error
 	RevokeAllUserTokens(ctx context.Context, userID uint) error
+	ListSessions(ctx context.Context, userID uint) ([]Session, error)
+	RevokeSession(ctx context.Context, userID uint, family uuid.UUID) error
+	GenerateClientToken(clientID string, scopes []string, ttl time.Duration) (string, error)
+	GenerateImpersonationToken(ctx context.Context, actor Actor, userID uint, email string, name string, ttl time.Duration) (string, error)
+	ValidateClientToken(tokenString string) (*ClientClaims, error)
 }
 
 type service struct {
 	jwtSecret        string
+	keyring          *Keyring
+	denylist         Denylist
 	accessTokenTTL   time.Duration
 	refreshTokenTTL  time.Duration
 	refreshTokenRepo RefreshTokenRepository
 	db               *gorm.DB
+	issuer           string
+	audiences        []string
+	leeway           time.Duration
+	auditor          audit.Recorder
+}
+
+// Option configures optional dependencies of the authentication service
+type Option func(*service)
+
+// WithDatabase stores refresh tokens in db and loads user roles from it
+// Without a database only access tokens can be issued
+func WithDatabase(db *gorm.DB) Option {
+	return func(s *service) {
+		s.db = db
+		s.refreshTokenRepo = NewRefreshTokenRepository(db)
+	}
+}
+
+// WithDenylist revokes access tokens through denylist (use NewRedisDenylist to share revocations across replicas)
+func WithDenylist(denylist Denylist) Option {
+	return func(s *service) {
+		s.denylist = denylist
+	}
+}
+
+// WithAuditRecorder records token reuse and revocations with auditor
+func WithAuditRecorder(auditor audit.Recorder) Option {
+	return func(s *service) {
+		s.auditor = auditor
+	}
 }
 
 // NewService creates a new authentication service using typed config
-func NewService(cfg *config.JWTConfig) Service {
+// By default access tokens are revoked through an in-memory denylist and audit events are discarded
+func NewService(cfg *config.JWTConfig, opts ...Option) Service {
 	jwtSecret := cfg.Secret
 	if jwtSecret == "" {
 		jwtSecret = "default-secret-change-in-production"
@@ -76,41 +125,58 @@ func NewService(cfg *config.JWTConfig) Service {
 		refreshTokenTTL = 168 * time.Hour
 	}
 
-	return &service{
+	s := &service{
 		jwtSecret:       jwtSecret,
+		keyring:         loadKeyring(cfg),
+		denylist:        NewMemoryDenylist(),
 		accessTokenTTL:  accessTokenTTL,
 		refreshTokenTTL: refreshTokenTTL,
+		issuer:          cfg.Issuer,
+		audiences:       cfg.Audiences,
+		leeway:          cfg.Leeway,
+		auditor:         audit.Discard,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // NewServiceWithRepo creates a new authentication service with refresh token repository
+// and an in-memory access token denylist
 func NewServiceWithRepo(cfg *config.JWTConfig, db *gorm.DB) Service {
-	jwtSecret := cfg.Secret
-	if jwtSecret == "" {
-		jwtSecret = "default-secret-change-in-production"
-	}
+	return NewService(cfg, WithDatabase(db))
+}
 
-	accessTokenTTL := cfg.AccessTokenTTL
-	if accessTokenTTL == 0 {
-		if cfg.TTLHours > 0 {
-			accessTokenTTL = time.Duration(cfg.TTLHours) * time.Hour
-		} else {
-			accessTokenTTL = 15 * time.Minute
-		}
+// loadKeyring loads the configured signing keyring.
+// WHY: Failures are logged and the keyring stays empty so token operations fail
+// closed instead of silently falling back to the shared secret.
+func loadKeyring(cfg *config.JWTConfig) *Keyring {
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		slog.Error("Failed to load JWT signing keys", "algorithm", cfg.Algorithm, "error", err)
 	}
+	return keyring
+}
 
-	refreshTokenTTL := cfg.RefreshTokenTTL
-	if refreshTokenTTL == 0 {
-		refreshTokenTTL = 168 * time.Hour
+// key returns the active signing key
+func (s *service) key() (*SigningKey, error) {
+	if s.keyring == nil {
+		return newHMACKey(s.jwtSecret, ""), nil
 	}
+	key, err := s.keyring.Active()
+	if err != nil {
+		return nil, fmt.Errorf("signing key unavailable: %w", err)
+	}
+	return key, nil
+}
 
-	return &service{
-		jwtSecret:        jwtSecret,
-		accessTokenTTL:   accessTokenTTL,
-		refreshTokenTTL:  refreshTokenTTL,
-		refreshTokenRepo: NewRefreshTokenRepository(db),
-		db:               db,
+// verificationKey selects the key for a parsed token by its kid header
+func (s *service) verificationKey(token *jwt.Token) (interface{}, error) {
+	if s.keyring == nil {
+		return newHMACKey(s.jwtSecret, "").VerificationKey(token)
 	}
+	return s.keyring.VerificationKey(token)
 }
 
 // GenerateToken generates a JWT token for a user (deprecated: use GenerateTokenPair)
@@ -118,32 +184,29 @@ func (s *service) GenerateToken(userID uint, email string, name string) (string,
 	now := time.Now()
 	expirationTime := now.Add(s.accessTokenTTL)
 
-	var roles []string
-	if s.db != nil {
-		var roleNames []string
-		err := s.db.Table("roles").
-			Select("roles.name").
-			Joins("JOIN user_roles ON user_roles.role_id = roles.id").
-			Where("user_roles.user_id = ?", userID).
-			Find(&roleNames).Error
-		if err != nil {
-			// WHY: Security-critical - token with empty roles bypasses authorization
-			return "", fmt.Errorf("failed to fetch user roles: %w", err)
-		}
-		roles = roleNames
+	roles, err := s.userRoles(userID)
+	if err != nil {
+		return "", err
 	}
 
 	claims := jwt.MapClaims{
 		"sub":   fmt.Sprintf("%d", userID),
+		"jti":   newTokenID(),
 		"email": email,
 		"name":  name,
 		"roles": roles,
 		"exp":   expirationTime.Unix(),
 		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+	}
+	setRegisteredClaims(claims, s.issuer, s.audiences)
+
+	key, err := s.key()
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokenString, err := key.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -151,28 +214,35 @@ func (s *service) GenerateToken(userID uint, email string, name string) (string,
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func (s *service) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+// userRoles loads the role names embedded in a user's access tokens
+func (s *service) userRoles(userID uint) ([]string, error) {
+	if s.db == nil {
+		return nil, nil
+	}
 
+	var roleNames []string
+	err := s.db.Table("roles").
+		Select("roles.name").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Find(&roleNames).Error
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, ErrExpiredToken
-		}
-		return nil, ErrInvalidToken
+		// WHY: Security-critical - token with empty roles bypasses authorization
+		return nil, fmt.Errorf("failed to fetch user roles: %w", err)
 	}
+	return roleNames, nil
+}
 
-	if !token.Valid {
-		return nil, ErrInvalidToken
+// ValidateToken validates a user access token and returns the claims
+// Client credentials tokens are rejected; use ValidateClientToken for them
+func (s *service) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
+	// WHY: 客户端令牌的 sub 是 client_id，不能被当作用户 ID 解析
+	if _, isClient := claims[claimClientID]; isClient {
 		return nil, ErrInvalidToken
 	}
 
@@ -186,6 +256,36 @@ func (s *service) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	jti, _ := claims["jti"].(string)
+	var issuedAt, expiresAt time.Time
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = tokenIssuedAt(jti, iat.Time)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+	}
+
+	actor, err := parseActorClaim(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.denylist != nil {
+		// WHY: Fail closed - if the denylist can't be checked, a revoked token must not pass
+		revoked, err := s.denylist.IsRevoked(context.Background(), jti, uint(userID), issuedAt)
+		if err == nil && !revoked && actor != nil {
+			// WHY: 撤销管理员的全部令牌也必须结束其发起的模拟会话
+			revoked, err = s.denylist.IsRevoked(context.Background(), "", actor.UserID, issuedAt)
+		}
+		if err != nil {
+			slog.Error("Failed to check access token denylist", "error", err)
+			return nil, ErrInvalidToken
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
 	email, _ := claims["email"].(string)
 	name, _ := claims["name"].(string)
 
@@ -199,13 +299,90 @@ func (s *service) ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	return &Claims{
-		UserID: uint(userID),
-		Email:  email,
-		Name:   name,
-		Roles:  roles,
+		UserID:    uint(userID),
+		Email:     email,
+		Name:      name,
+		Roles:     roles,
+		ID:        jti,
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+		Actor:     actor,
 	}, nil
 }
 
+// parseClaims verifies the signature, time claims, issuer and audience of a token
+func (s *service) parseClaims(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.verificationKey, jwt.WithLeeway(s.leeway), jwt.WithIssuedAt())
+
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, ErrExpiredToken
+		case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+			return nil, ErrTokenNotValidYet
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	if s.issuer != "" {
+		if iss, _ := claims.GetIssuer(); iss != s.issuer {
+			return nil, ErrInvalidIssuer
+		}
+	}
+
+	if !hasAudience(claims, s.audiences) {
+		return nil, ErrInvalidAudience
+	}
+
+	return claims, nil
+}
+
+// RevokeAccessToken denylists a single access token until it expires
+func (s *service) RevokeAccessToken(ctx context.Context, claims *Claims) error {
+	if s.denylist == nil || claims == nil || claims.ID == "" {
+		return nil
+	}
+	if err := s.denylist.RevokeToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+		return err
+	}
+
+	if claims.IsImpersonation() {
+		s.recordAudit(ctx, audit.Event{
+			Action:     audit.ActionImpersonationStop,
+			ActorID:    claims.Actor.UserID,
+			OnBehalfOf: claims.UserID,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(claims.UserID),
+			Metadata:   map[string]interface{}{"jti": claims.ID},
+		})
+	}
+	return nil
+}
+
+// JWKS returns the public verification keys; empty for HMAC signing
+func (s *service) JWKS() JWKSet {
+	if s.keyring == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return JWKSet{Keys: s.keyring.PublicJWKs()}
+}
+
+// ReloadKeys rotates the signing keyring from config without a restart.
+// On error the previously loaded keys remain in use.
+// NewService always sets the keyring, even when the initial load fails, so the swap stays inside Keyring.Reload.
+func (s *service) ReloadKeys(cfg *config.JWTConfig) error {
+	return s.keyring.Reload(cfg)
+}
+
 // GenerateTokenPair generates both access and refresh tokens with rotation support
 func (s *service) GenerateTokenPair(ctx context.Context, userID uint, email string, name string) (*TokenPair, error) {
 	if s.refreshTokenRepo == nil {
@@ -225,11 +402,14 @@ func (s *service) GenerateTokenPair(ctx context.Context, userID uint, email stri
 	tokenFamily := uuid.New()
 	refreshTokenHash := HashToken(refreshToken)
 
+	clientInfo, _ := ClientInfoFromContext(ctx)
 	dbToken := &RefreshToken{
 		UserID:      userID,
 		TokenHash:   refreshTokenHash,
 		TokenFamily: tokenFamily,
 		ExpiresAt:   time.Now().Add(s.refreshTokenTTL),
+		UserAgent:   clientInfo.UserAgent,
+		IPAddress:   clientInfo.IPAddress,
 	}
 
 	if err := s.refreshTokenRepo.Create(ctx, dbToken); err != nil {
@@ -273,6 +453,13 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 		if err := s.refreshTokenRepo.RevokeTokenFamily(ctx, storedToken.TokenFamily); err != nil {
 			return nil, fmt.Errorf("failed to revoke token family: %w", err)
 		}
+		// 重放已使用的刷新令牌通常意味着令牌泄露，整个会话已被撤销
+		s.recordAudit(ctx, audit.Event{
+			Action:     audit.ActionTokenReuse,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(storedToken.UserID),
+			Metadata:   map[string]interface{}{"token_family": storedToken.TokenFamily.String()},
+		})
 		return nil, ErrTokenReuse
 	}
 
@@ -300,12 +487,20 @@ func (s *service) RefreshAccessToken(ctx context.Context, refreshToken string) (
 		return nil, fmt.Errorf("failed to generate new refresh token: %w", err)
 	}
 
+	// 刷新时记录最新的设备信息，缺失时沿用会话原有信息
+	clientInfo, ok := ClientInfoFromContext(ctx)
+	if !ok {
+		clientInfo = ClientInfo{UserAgent: storedToken.UserAgent, IPAddress: storedToken.IPAddress}
+	}
+
 	newTokenHash := HashToken(newRefreshToken)
 	newDBToken := &RefreshToken{
 		UserID:      storedToken.UserID,
 		TokenHash:   newTokenHash,
 		TokenFamily: storedToken.TokenFamily,
 		ExpiresAt:   time.Now().Add(s.refreshTokenTTL),
+		UserAgent:   clientInfo.UserAgent,
+		IPAddress:   clientInfo.IPAddress,
 	}
 
 	if err := s.refreshTokenRepo.Create(ctx, newDBToken); err != nil {
@@ -361,13 +556,67 @@ func (s *service) RevokeUserRefreshToken(ctx context.Context, userID uint, refre
 	return s.refreshTokenRepo.RevokeTokenFamily(ctx, storedToken.TokenFamily)
 }
 
-// RevokeAllUserTokens revokes all refresh tokens for a user
+// RevokeAllUserTokens revokes all refresh tokens for a user and every access token issued so far
 func (s *service) RevokeAllUserTokens(ctx context.Context, userID uint) error {
 	if s.refreshTokenRepo == nil {
 		return errors.New("refresh token repository not initialized")
 	}
 
-	return s.refreshTokenRepo.RevokeByUserID(ctx, userID)
+	if err := s.refreshTokenRepo.RevokeByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	if s.denylist != nil {
+		// 访问令牌无法逐个枚举，记录"此前签发的全部失效"，保留到最长的访问令牌过期为止
+		if err := s.denylist.RevokeUserTokens(ctx, userID, time.Now(), s.accessTokenTTL); err != nil {
+			return fmt.Errorf("failed to revoke access tokens: %w", err)
+		}
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionTokensRevoked,
+		TargetType: audit.TargetUser,
+		TargetID:   audit.ID(userID),
+	})
+	return nil
+}
+
+// recordAudit records an audit event; services built without a recorder drop it
+func (s *service) recordAudit(ctx context.Context, event audit.Event) {
+	if s.auditor != nil {
+		s.auditor.Record(ctx, event)
+	}
+}
+
+// setRegisteredClaims adds the configured iss and aud claims to a token
+func setRegisteredClaims(claims jwt.MapClaims, issuer string, audiences []string) {
+	if issuer != "" {
+		claims["iss"] = issuer
+	}
+	if len(audiences) > 0 {
+		claims["aud"] = audiences
+	}
+}
+
+// hasAudience reports whether the token's aud claim contains one of the accepted audiences.
+// With no audiences configured every token is accepted.
+func hasAudience(claims jwt.MapClaims, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+
+	audiences, err := claims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, aud := range audiences {
+		for _, want := range accepted {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // generateRandomToken generates a cryptographically secure random token
@@ -463,3 +463,94 @@ func TestService_GenerateToken_RoleFetchError(t *testing.T) {
 	assert.Empty(t, token)
 	assert.Contains(t, err.Error(), "failed to fetch user roles")
 }
+
+func TestService_ValidateToken_RegisteredClaims(t *testing.T) {
+	cfg := &config.JWTConfig{
+		Secret:    "test-secret-key-at-least-32-chars!",
+		Issuer:    "https://auth.example.com",
+		Audiences: []string{"orders-api", "billing-api"},
+		Leeway:    30 * time.Second,
+	}
+	svc := NewService(cfg)
+	key, err := NewSigningKey(cfg)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	baseClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "1",
+			"iss": "https://auth.example.com",
+			"aud": []string{"orders-api"},
+			"iat": now.Unix(),
+			"nbf": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(jwt.MapClaims)
+		expectedErr error
+	}{
+		{name: "valid claims", modify: func(c jwt.MapClaims) {}},
+		{name: "audience as single string", modify: func(c jwt.MapClaims) { c["aud"] = "billing-api" }},
+		{name: "wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, expectedErr: ErrInvalidIssuer},
+		{name: "missing issuer", modify: func(c jwt.MapClaims) { delete(c, "iss") }, expectedErr: ErrInvalidIssuer},
+		{name: "wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = []string{"search-api"} }, expectedErr: ErrInvalidAudience},
+		{name: "missing audience", modify: func(c jwt.MapClaims) { delete(c, "aud") }, expectedErr: ErrInvalidAudience},
+		{name: "not valid yet", modify: func(c jwt.MapClaims) { c["nbf"] = now.Add(5 * time.Minute).Unix() }, expectedErr: ErrTokenNotValidYet},
+		{name: "issued in the future", modify: func(c jwt.MapClaims) { c["iat"] = now.Add(5 * time.Minute).Unix() }, expectedErr: ErrTokenNotValidYet},
+		{name: "nbf within leeway", modify: func(c jwt.MapClaims) { c["nbf"] = now.Add(10 * time.Second).Unix() }},
+		{name: "expired within leeway", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() }},
+		{name: "expired beyond leeway", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, expectedErr: ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := baseClaims()
+			tt.modify(claims)
+			tokenString, err := key.Sign(claims)
+			assert.NoError(t, err)
+
+			_, err = svc.ValidateToken(tokenString)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestService_GenerateToken_RegisteredClaims(t *testing.T) {
+	svc := NewService(&config.JWTConfig{
+		Secret:    "test-secret-key-at-least-32-chars!",
+		Issuer:    "https://auth.example.com",
+		Audiences: []string{"orders-api", "billing-api"},
+	})
+
+	tokenString, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	assert.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	assert.NoError(t, err)
+	claims := parsed.Claims.(jwt.MapClaims)
+
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	audiences, err := claims.GetAudience()
+	assert.NoError(t, err)
+	assert.Equal(t, jwt.ClaimStrings{"orders-api", "billing-api"}, audiences)
+	assert.NotNil(t, claims["nbf"])
+
+	_, err = svc.ValidateToken(tokenString)
+	assert.NoError(t, err)
+
+	// 共享密钥但受众不同的服务拒绝该令牌
+	otherService := NewService(&config.JWTConfig{
+		Secret:    "test-secret-key-at-least-32-chars!",
+		Issuer:    "https://auth.example.com",
+		Audiences: []string{"search-api"},
+	})
+	_, err = otherService.ValidateToken(tokenString)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+}
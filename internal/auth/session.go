@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+)
+
+// ErrSessionNotFound is returned when a session doesn't exist or belongs to another user
+var ErrSessionNotFound = errors.New("session not found")
+
+const maxUserAgentLength = 512
+
+// Session is a login on one device, backed by a refresh-token family
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ClientInfo describes the device that requested a token
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo attaches device metadata to the context so token issuance can record it
+func WithClientInfo(ctx context.Context, userAgent, ipAddress string) context.Context {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return context.WithValue(ctx, clientInfoKey{}, ClientInfo{UserAgent: userAgent, IPAddress: ipAddress})
+}
+
+// ClientInfoFromContext returns the device metadata attached with WithClientInfo
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info, ok
+}
+
+// ListSessions returns the active sessions of a user, most recently used first
+func (s *service) ListSessions(ctx context.Context, userID uint) ([]Session, error) {
+	if s.refreshTokenRepo == nil {
+		return nil, errors.New("refresh token repository not initialized")
+	}
+
+	families, err := s.refreshTokenRepo.FindActiveFamiliesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(families))
+	for _, family := range families {
+		tokens, err := s.refreshTokenRepo.FindByTokenFamily(ctx, family)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session: %w", err)
+		}
+		if len(tokens) == 0 {
+			continue
+		}
+		sessions = append(sessions, sessionFromFamily(family, tokens))
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession revokes one session of a user by its refresh-token family
+func (s *service) RevokeSession(ctx context.Context, userID uint, family uuid.UUID) error {
+	if s.refreshTokenRepo == nil {
+		return errors.New("refresh token repository not initialized")
+	}
+
+	tokens, err := s.refreshTokenRepo.FindByTokenFamily(ctx, family)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	// WHY: Another user's family is reported as not found so session IDs can't be probed
+	if len(tokens) == 0 || tokens[0].UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	if err := s.refreshTokenRepo.RevokeTokenFamily(ctx, family); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionSessionRevoked,
+		TargetType: audit.TargetSession,
+		TargetID:   family.String(),
+		Metadata:   map[string]interface{}{"user_id": userID},
+	})
+	return nil
+}
+
+// sessionFromFamily summarises a family; tokens are ordered newest first.
+// Each refresh rotates in a new row, so the newest row carries the latest device metadata.
+func sessionFromFamily(family uuid.UUID, tokens []*RefreshToken) Session {
+	newest := tokens[0]
+	oldest := tokens[len(tokens)-1]
+	return Session{
+		ID:         family,
+		UserAgent:  newest.UserAgent,
+		IPAddress:  newest.IPAddress,
+		CreatedAt:  oldest.CreatedAt,
+		LastUsedAt: newest.CreatedAt,
+		ExpiresAt:  newest.ExpiresAt,
+	}
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithClientInfo(t *testing.T) {
+	ctx := WithClientInfo(context.Background(), strings.Repeat("a", 600), "203.0.113.7")
+
+	info, ok := ClientInfoFromContext(ctx)
+	require.True(t, ok)
+	assert.Len(t, info.UserAgent, maxUserAgentLength)
+	assert.Equal(t, "203.0.113.7", info.IPAddress)
+
+	_, ok = ClientInfoFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestService_ListSessions(t *testing.T) {
+	svc, _ := setupServiceTest(t)
+	laptop := WithClientInfo(context.Background(), "Firefox/130", "198.51.100.1")
+	phone := WithClientInfo(context.Background(), "Mobile Safari", "198.51.100.2")
+
+	laptopPair, err := svc.GenerateTokenPair(laptop, 1, "test@example.com", "Test User")
+	require.NoError(t, err)
+	phonePair, err := svc.GenerateTokenPair(phone, 1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	// 刷新后会话保持同一个家族，设备信息更新为最新请求
+	moved := WithClientInfo(context.Background(), "Firefox/131", "198.51.100.9")
+	_, err = svc.RefreshAccessToken(moved, laptopPair.RefreshToken)
+	require.NoError(t, err)
+
+	sessions, err := svc.ListSessions(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	byID := map[uuid.UUID]Session{}
+	for _, session := range sessions {
+		byID[session.ID] = session
+	}
+
+	laptopSession := byID[laptopPair.TokenFamily]
+	assert.Equal(t, "Firefox/131", laptopSession.UserAgent)
+	assert.Equal(t, "198.51.100.9", laptopSession.IPAddress)
+	assert.False(t, laptopSession.LastUsedAt.Before(laptopSession.CreatedAt))
+
+	phoneSession := byID[phonePair.TokenFamily]
+	assert.Equal(t, "Mobile Safari", phoneSession.UserAgent)
+	assert.Equal(t, "198.51.100.2", phoneSession.IPAddress)
+
+	otherUserSessions, err := svc.ListSessions(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, otherUserSessions)
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	svc, _ := setupServiceTest(t)
+	ctx := context.Background()
+
+	pair, err := svc.GenerateTokenPair(ctx, 1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		userID      uint
+		family      uuid.UUID
+		expectedErr error
+	}{
+		{name: "unknown session", userID: 1, family: uuid.New(), expectedErr: ErrSessionNotFound},
+		{name: "session of another user", userID: 2, family: pair.TokenFamily, expectedErr: ErrSessionNotFound},
+		{name: "own session", userID: 1, family: pair.TokenFamily},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.RevokeSession(ctx, tt.userID, tt.family)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	sessions, err := svc.ListSessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, err = svc.RefreshAccessToken(ctx, pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const defaultHMACKeyID = "default"
+
+// ErrUnsupportedAlgorithm is returned when the configured signing algorithm is unknown
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// SigningKey holds the algorithm and key material used to sign and verify JWTs
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewSigningKey builds a signing key from JWT config.
+// HS256 uses the shared secret; RS256 and EdDSA load a private key from a PEM file.
+func NewSigningKey(cfg *config.JWTConfig) (*SigningKey, error) {
+	switch normalizeAlgorithm(cfg.Algorithm) {
+	case AlgorithmHS256:
+		secret := cfg.Secret
+		if secret == "" {
+			secret = "default-secret-change-in-production"
+		}
+		return newHMACKey(secret, cfg.KeyID), nil
+	case AlgorithmRS256:
+		pemBytes, err := readKeyFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		return newAsymmetricKey(jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey, cfg.KeyID)
+	case AlgorithmEdDSA:
+		pemBytes, err := readKeyFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Ed25519 private key: %w", err)
+		}
+		privateKey, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse Ed25519 private key: unexpected key type %T", parsed)
+		}
+		return newAsymmetricKey(jwt.SigningMethodEdDSA, privateKey, privateKey.Public(), cfg.KeyID)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+}
+
+// NewVerificationKey builds a verify-only key for a retired keyring entry.
+// HS256 uses the old secret; RS256 and EdDSA load a public key from a PEM file.
+func NewVerificationKey(cfg *config.RetiredKeyConfig) (*SigningKey, error) {
+	switch normalizeAlgorithm(cfg.Algorithm) {
+	case AlgorithmHS256:
+		key := newHMACKey(cfg.Secret, cfg.KeyID)
+		key.signKey = nil
+		return key, nil
+	case AlgorithmRS256:
+		pemBytes, err := readKeyFile(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		}
+		return &SigningKey{ID: cfg.KeyID, Method: jwt.SigningMethodRS256, verifyKey: publicKey}, nil
+	case AlgorithmEdDSA:
+		pemBytes, err := readKeyFile(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := jwt.ParseEdPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Ed25519 public key: %w", err)
+		}
+		return &SigningKey{ID: cfg.KeyID, Method: jwt.SigningMethodEdDSA, verifyKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+}
+
+// newHMACKey creates a symmetric HS256 key
+func newHMACKey(secret, keyID string) *SigningKey {
+	if keyID == "" {
+		keyID = defaultHMACKeyID
+	}
+	return &SigningKey{
+		ID:        keyID,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// newAsymmetricKey creates an RS256/EdDSA key, deriving the kid from the
+// RFC 7638 thumbprint of the public key when none is configured
+func newAsymmetricKey(method jwt.SigningMethod, signKey crypto.Signer, verifyKey crypto.PublicKey, keyID string) (*SigningKey, error) {
+	key := &SigningKey{
+		ID:        keyID,
+		Method:    method,
+		signKey:   signKey,
+		verifyKey: verifyKey,
+	}
+
+	if key.ID == "" {
+		jwk, _ := key.PublicJWK()
+		thumbprint, err := jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = thumbprint
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims and stamps the key ID into the token header
+func (k *SigningKey) Sign(claims jwt.Claims) (string, error) {
+	if k.signKey == nil {
+		return "", fmt.Errorf("key %s is verify-only", k.ID)
+	}
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signKey)
+}
+
+// VerificationKey returns the key used to verify a parsed token.
+// The token algorithm must match the key algorithm to prevent algorithm confusion.
+func (k *SigningKey) VerificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if kid, ok := token.Header["kid"].(string); ok && kid != k.ID {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return k.verifyKey, nil
+}
+
+// IsAsymmetric reports whether the key can be published in a JWKS
+func (k *SigningKey) IsAsymmetric() bool {
+	_, isHMAC := k.Method.(*jwt.SigningMethodHMAC)
+	return !isHMAC
+}
+
+// PublicJWK returns the public half of the key as a JWK.
+// Returns false for symmetric keys, which must never be published.
+func (k *SigningKey) PublicJWK() (JWK, bool) {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Kid: k.ID,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Kid: k.ID,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// JWK is a JSON Web Key (RFC 7517) describing a public verification key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Thumbprint computes the RFC 7638 JWK thumbprint
+func (j JWK) Thumbprint() (string, error) {
+	var members interface{}
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	default:
+		return "", fmt.Errorf("cannot compute thumbprint for key type %q", j.Kty)
+	}
+
+	// WHY: encoding/json emits struct fields in declaration order, which is the
+	// lexicographic order RFC 7638 requires
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// normalizeAlgorithm maps configured algorithm names to canonical JWT names
+func normalizeAlgorithm(alg string) string {
+	switch strings.ToUpper(alg) {
+	case "", "HS256":
+		return AlgorithmHS256
+	case "RS256":
+		return AlgorithmRS256
+	case "EDDSA", "ED25519":
+		return AlgorithmEdDSA
+	default:
+		return alg
+	}
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("key path is required for asymmetric signing")
+	}
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return pemBytes, nil
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// writeRSAKey writes a PKCS#1 RSA private key PEM file and returns its path
+func writeRSAKey(t *testing.T) string {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "rsa.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	require.NoError(t, os.WriteFile(path, pemBytes, 0o600))
+	return path
+}
+
+// writeEd25519Key writes a PKCS#8 Ed25519 private key PEM file and returns its path
+func writeEd25519Key(t *testing.T) string {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ed25519.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(path, pemBytes, 0o600))
+	return path
+}
+
+func TestNewSigningKey(t *testing.T) {
+	rsaPath := writeRSAKey(t)
+	edPath := writeEd25519Key(t)
+
+	tests := []struct {
+		name        string
+		cfg         *config.JWTConfig
+		expectedAlg string
+		asymmetric  bool
+		expectError bool
+	}{
+		{
+			name:        "default algorithm is HS256",
+			cfg:         &config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"},
+			expectedAlg: "HS256",
+		},
+		{
+			name:        "RS256 from PEM file",
+			cfg:         &config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: rsaPath},
+			expectedAlg: "RS256",
+			asymmetric:  true,
+		},
+		{
+			name:        "EdDSA from PEM file",
+			cfg:         &config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: edPath},
+			expectedAlg: "EdDSA",
+			asymmetric:  true,
+		},
+		{
+			name:        "algorithm is case-insensitive",
+			cfg:         &config.JWTConfig{Algorithm: "ed25519", PrivateKeyPath: edPath},
+			expectedAlg: "EdDSA",
+			asymmetric:  true,
+		},
+		{
+			name:        "missing key file",
+			cfg:         &config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: filepath.Join(t.TempDir(), "missing.pem")},
+			expectError: true,
+		},
+		{
+			name:        "key type does not match algorithm",
+			cfg:         &config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: edPath},
+			expectError: true,
+		},
+		{
+			name:        "unsupported algorithm",
+			cfg:         &config.JWTConfig{Algorithm: "none"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewSigningKey(tt.cfg)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAlg, key.Method.Alg())
+			assert.Equal(t, tt.asymmetric, key.IsAsymmetric())
+			assert.NotEmpty(t, key.ID)
+		})
+	}
+}
+
+func TestService_AsymmetricSigning(t *testing.T) {
+	for _, cfg := range []*config.JWTConfig{
+		{Algorithm: "RS256", PrivateKeyPath: writeRSAKey(t), KeyID: "rsa-1"},
+		{Algorithm: "EdDSA", PrivateKeyPath: writeEd25519Key(t)},
+	} {
+		t.Run(cfg.Algorithm, func(t *testing.T) {
+			svc := NewService(cfg)
+
+			tokenString, err := svc.GenerateToken(1, "test@example.com", "Test User")
+			require.NoError(t, err)
+
+			unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, cfg.Algorithm, unverified.Header["alg"])
+			assert.NotEmpty(t, unverified.Header["kid"])
+			if cfg.KeyID != "" {
+				assert.Equal(t, cfg.KeyID, unverified.Header["kid"])
+			}
+
+			claims, err := svc.ValidateToken(tokenString)
+			require.NoError(t, err)
+			assert.Equal(t, uint(1), claims.UserID)
+
+			jwks := svc.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, unverified.Header["kid"], jwks.Keys[0].Kid)
+			assert.Equal(t, cfg.Algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestService_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaService := NewService(&config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: writeRSAKey(t)})
+	jwk := rsaService.JWKS().Keys[0]
+
+	// 使用公开的 JWK 内容作为 HMAC 密钥伪造令牌
+	claims := jwt.MapClaims{
+		"sub": "1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = jwk.Kid
+	forged, err := token.SignedString([]byte(jwk.N))
+	require.NoError(t, err)
+
+	_, err = rsaService.ValidateToken(forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestService_RejectsUnknownKeyID(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: writeEd25519Key(t)})
+	other := NewService(&config.JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: writeEd25519Key(t)})
+
+	tokenString, err := other.GenerateToken(1, "test@example.com", "Test User")
+	require.NoError(t, err)
+
+	_, err = svc.ValidateToken(tokenString)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestService_FailsClosedOnKeyLoadError(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Algorithm: "RS256", PrivateKeyPath: filepath.Join(t.TempDir(), "missing.pem")})
+
+	_, err := svc.GenerateToken(1, "test@example.com", "Test User")
+	assert.Error(t, err)
+	assert.Empty(t, svc.JWKS().Keys)
+}
+
+func TestService_JWKSEmptyForHMAC(t *testing.T) {
+	svc := NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	assert.Empty(t, svc.JWKS().Keys)
+}
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// RFC 7638 section 3.1 example
+	jwk := JWK{
+		Kty: "RSA",
+		E:   "AQAB",
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+	thumbprint, err := jwk.Thumbprint()
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,16 +13,31 @@ import (
 )
 
 type Config struct {
-	App        AppConfig        `mapstructure:"app" yaml:"app"`
-	Database   DatabaseConfig   `mapstructure:"database" yaml:"database"`
-	Redis      RedisConfig      `mapstructure:"redis" yaml:"redis"`
-	MongoDB    MongoDBConfig    `mapstructure:"mongodb" yaml:"mongodb"`
-	JWT        JWTConfig        `mapstructure:"jwt" yaml:"jwt"`
-	Server     ServerConfig     `mapstructure:"server" yaml:"server"`
-	Logging    LoggingConfig    `mapstructure:"logging" yaml:"logging"`
-	Ratelimit  RateLimitConfig  `mapstructure:"ratelimit" yaml:"ratelimit"`
-	Migrations MigrationsConfig `mapstructure:"migrations" yaml:"migrations"`
-	Health     HealthConfig     `mapstructure:"health" yaml:"health"`
+	App               AppConfig               `mapstructure:"app" yaml:"app"`
+	Database          DatabaseConfig          `mapstructure:"database" yaml:"database"`
+	Redis             RedisConfig             `mapstructure:"redis" yaml:"redis"`
+	MongoDB           MongoDBConfig           `mapstructure:"mongodb" yaml:"mongodb"`
+	JWT               JWTConfig               `mapstructure:"jwt" yaml:"jwt"`
+	Auth              AuthConfig              `mapstructure:"auth" yaml:"auth"`
+	Server            ServerConfig            `mapstructure:"server" yaml:"server"`
+	Logging           LoggingConfig           `mapstructure:"logging" yaml:"logging"`
+	Ratelimit         RateLimitConfig         `mapstructure:"ratelimit" yaml:"ratelimit"`
+	Migrations        MigrationsConfig        `mapstructure:"migrations" yaml:"migrations"`
+	Health            HealthConfig            `mapstructure:"health" yaml:"health"`
+	TokenCleanup      TokenCleanupConfig      `mapstructure:"token_cleanup" yaml:"token_cleanup"`
+	Mail              MailConfig              `mapstructure:"mail" yaml:"mail"`
+	PasswordReset     PasswordResetConfig     `mapstructure:"password_reset" yaml:"password_reset"`
+	EmailVerification EmailVerificationConfig `mapstructure:"email_verification" yaml:"email_verification"`
+	PasswordPolicy    PasswordPolicyConfig    `mapstructure:"password_policy" yaml:"password_policy"`
+	PasswordHash      PasswordHashConfig      `mapstructure:"password_hash" yaml:"password_hash"`
+	LoginProtection   LoginProtectionConfig   `mapstructure:"login_protection" yaml:"login_protection"`
+	MFA               MFAConfig               `mapstructure:"mfa" yaml:"mfa"`
+	OIDC              OIDCConfig              `mapstructure:"oidc" yaml:"oidc"`
+	APIKeys           APIKeyConfig            `mapstructure:"api_keys" yaml:"api_keys"`
+	OAuth             OAuthConfig             `mapstructure:"oauth" yaml:"oauth"`
+	Gateway           GatewayConfig           `mapstructure:"gateway" yaml:"gateway"`
+	Impersonation     ImpersonationConfig     `mapstructure:"impersonation" yaml:"impersonation"`
+	Audit             AuditConfig             `mapstructure:"audit" yaml:"audit"`
 }
 
 type AppConfig struct {
@@ -38,9 +54,9 @@ type DatabaseConfig struct {
 	Password        string `mapstructure:"password" yaml:"password"`
 	Name            string `mapstructure:"name" yaml:"name"`
 	SSLMode         string `mapstructure:"sslmode" yaml:"sslmode"`
-	MaxOpenConns    int    `mapstructure:"max_open_conns" yaml:"max_open_conns"`       // 最大开放连接数
-	MaxIdleConns    int    `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`       // 最大空闲连接数
-	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime" yaml:"conn_max_lifetime"` // 连接最大生命周期（秒）
+	MaxOpenConns    int    `mapstructure:"max_open_conns" yaml:"max_open_conns"`         // 最大开放连接数
+	MaxIdleConns    int    `mapstructure:"max_idle_conns" yaml:"max_idle_conns"`         // 最大空闲连接数
+	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime" yaml:"conn_max_lifetime"`   // 连接最大生命周期（秒）
 	ConnMaxIdleTime int    `mapstructure:"conn_max_idle_time" yaml:"conn_max_idle_time"` // 连接最大空闲时间（秒）
 }
 
@@ -59,10 +75,65 @@ type MongoDBConfig struct {
 }
 
 type JWTConfig struct {
-	Secret          string        `mapstructure:"secret" yaml:"secret"`
-	AccessTokenTTL  time.Duration `mapstructure:"access_token_ttl" yaml:"access_token_ttl"`
-	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl"`
-	TTLHours        int           `mapstructure:"ttlhours" yaml:"ttlhours"` // Deprecated: kept for backward compatibility
+	Secret          string             `mapstructure:"secret" yaml:"secret"`
+	AccessTokenTTL  time.Duration      `mapstructure:"access_token_ttl" yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration      `mapstructure:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+	TTLHours        int                `mapstructure:"ttlhours" yaml:"ttlhours"`                 // Deprecated: kept for backward compatibility
+	Algorithm       string             `mapstructure:"algorithm" yaml:"algorithm"`               // 签名算法：HS256（默认）| RS256 | EdDSA
+	KeyID           string             `mapstructure:"key_id" yaml:"key_id"`                     // JWT 头中的 kid，留空时自动生成
+	PrivateKeyPath  string             `mapstructure:"private_key_path" yaml:"private_key_path"` // RS256/EdDSA 私钥 PEM 文件路径
+	RetiredKeys     []RetiredKeyConfig `mapstructure:"retired_keys" yaml:"retired_keys"`         // 已轮换的旧密钥，仅用于验证
+	Issuer          string             `mapstructure:"issuer" yaml:"issuer"`                     // iss 声明，设置后验证时必须匹配
+	Audiences       []string           `mapstructure:"audiences" yaml:"audiences"`               // aud 声明，设置后令牌须包含其中之一
+	Leeway          time.Duration      `mapstructure:"leeway" yaml:"leeway"`                     // exp/nbf/iat 允许的时钟偏差
+}
+
+// RetiredKeyConfig 已轮换下线的签名密钥
+// 在 AcceptUntil 之前仍可用于验证其签发的令牌，但不再用于签名
+type RetiredKeyConfig struct {
+	KeyID         string `mapstructure:"key_id" yaml:"key_id"`
+	Algorithm     string `mapstructure:"algorithm" yaml:"algorithm"`             // HS256（默认）| RS256 | EdDSA
+	Secret        string `mapstructure:"secret" yaml:"secret"`                   // HS256 旧密钥
+	PublicKeyPath string `mapstructure:"public_key_path" yaml:"public_key_path"` // RS256/EdDSA 公钥 PEM 文件路径
+	AcceptUntil   string `mapstructure:"accept_until" yaml:"accept_until"`       // RFC 3339 时间或 YYYY-MM-DD
+}
+
+// GetAcceptUntil parses AcceptUntil as RFC 3339 or a plain date (end of that day, UTC)
+func (r *RetiredKeyConfig) GetAcceptUntil() (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, r.AcceptUntil); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", r.AcceptUntil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid accept_until %q: expected RFC 3339 or YYYY-MM-DD", r.AcceptUntil)
+	}
+	return day.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// IsAsymmetric reports whether the retired key is verified with a public key
+func (r *RetiredKeyConfig) IsAsymmetric() bool {
+	return (&JWTConfig{Algorithm: r.Algorithm}).IsAsymmetric()
+}
+
+// IsAsymmetric reports whether the configured algorithm uses a key pair instead of the shared secret
+func (j *JWTConfig) IsAsymmetric() bool {
+	switch strings.ToUpper(j.Algorithm) {
+	case "RS256", "EDDSA", "ED25519":
+		return true
+	default:
+		return false
+	}
+}
+
+// 认证模式
+const (
+	AuthModeGateway = "gateway" // 信任网关传递的 X-User-ID / X-User-Role 头
+	AuthModeJWT     = "jwt"     // 服务自行验证 Bearer JWT
+	AuthModeBoth    = "both"    // 优先验证 Bearer JWT，缺失时回退到网关头
+)
+
+type AuthConfig struct {
+	Mode string `mapstructure:"mode" yaml:"mode"` // gateway | jwt | both
 }
 
 type ServerConfig struct {
@@ -95,6 +166,470 @@ type HealthConfig struct {
 	DatabaseCheckEnabled bool `mapstructure:"database_check_enabled" yaml:"database_check_enabled"`
 }
 
+// TokenCleanupConfig 过期刷新令牌的定时清理
+// 已使用/已撤销的令牌保留到过期为止，重用检测依赖这些记录
+type TokenCleanupConfig struct {
+	Enabled   bool          `mapstructure:"enabled" yaml:"enabled"`
+	Interval  time.Duration `mapstructure:"interval" yaml:"interval"`     // 两次清理之间的间隔
+	BatchSize int           `mapstructure:"batch_size" yaml:"batch_size"` // 每批删除的最大行数
+	Retention time.Duration `mapstructure:"retention" yaml:"retention"`   // 令牌过期后再保留多久才删除
+}
+
+// 邮件发送方式
+const (
+	MailDriverLog  = "log"  // 写入应用日志
+	MailDriverFile = "file" // 每封邮件写入 file_dir 下的一个 .eml 文件
+)
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver  string `mapstructure:"driver" yaml:"driver"`     // log（默认）| file
+	From    string `mapstructure:"from" yaml:"from"`         // 发件人地址
+	FileDir string `mapstructure:"file_dir" yaml:"file_dir"` // driver 为 file 时的输出目录
+}
+
+// GetDriver returns the configured mail driver, defaulting to the log driver
+func (m *MailConfig) GetDriver() string {
+	if m.Driver == "" {
+		return MailDriverLog
+	}
+	return strings.ToLower(m.Driver)
+}
+
+// PasswordResetConfig 密码重置配置
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl" yaml:"token_ttl"` // 重置令牌有效期
+	URL      string        `mapstructure:"url" yaml:"url"`             // 邮件中的重置页面地址，令牌以 ?token= 附加
+}
+
+// 邮箱未验证时的限制策略
+const (
+	EmailVerificationPolicyNone          = "none"           // 只发送验证邮件，不做限制
+	EmailVerificationPolicyBlockLogin    = "block_login"    // 验证前禁止登录，注册时不签发令牌
+	EmailVerificationPolicyRestrictRoles = "restrict_roles" // 验证前不授予默认角色
+)
+
+// EmailVerificationConfig 邮箱验证配置
+type EmailVerificationConfig struct {
+	Enabled  bool          `mapstructure:"enabled" yaml:"enabled"`     // 注册和修改邮箱时发送验证邮件
+	Policy   string        `mapstructure:"policy" yaml:"policy"`       // none（默认）| block_login | restrict_roles
+	TokenTTL time.Duration `mapstructure:"token_ttl" yaml:"token_ttl"` // 验证令牌有效期
+	URL      string        `mapstructure:"url" yaml:"url"`             // 邮件中的验证地址，令牌以 ?token= 附加
+}
+
+// GetPolicy returns the configured verification policy, defaulting to none
+func (e *EmailVerificationConfig) GetPolicy() string {
+	if e.Policy == "" {
+		return EmailVerificationPolicyNone
+	}
+	return strings.ToLower(e.Policy)
+}
+
+// 密码长度默认值
+const (
+	DefaultPasswordMinLength = 8
+	BcryptMaxPasswordBytes   = 72 // bcrypt 只使用密码的前 72 字节
+)
+
+// PasswordPolicyConfig 密码策略配置，注册、修改密码、重置密码和 createadmin 共用
+type PasswordPolicyConfig struct {
+	MinLength            int    `mapstructure:"min_length" yaml:"min_length"`                         // 最少字符数，默认 8
+	MaxLength            int    `mapstructure:"max_length" yaml:"max_length"`                         // 最多字节数，默认且不超过 72
+	RequireUppercase     bool   `mapstructure:"require_uppercase" yaml:"require_uppercase"`           // 必须包含大写字母
+	RequireLowercase     bool   `mapstructure:"require_lowercase" yaml:"require_lowercase"`           // 必须包含小写字母
+	RequireDigit         bool   `mapstructure:"require_digit" yaml:"require_digit"`                   // 必须包含数字
+	RequireSpecial       bool   `mapstructure:"require_special" yaml:"require_special"`               // 必须包含特殊字符
+	DisallowPersonalInfo bool   `mapstructure:"disallow_personal_info" yaml:"disallow_personal_info"` // 禁止包含邮箱用户名或姓名
+	BreachedListPath     string `mapstructure:"breached_list_path" yaml:"breached_list_path"`         // 常见/泄露密码列表文件，每行一个
+}
+
+// GetMinLength returns the minimum password length, defaulting to DefaultPasswordMinLength
+func (p *PasswordPolicyConfig) GetMinLength() int {
+	if p.MinLength <= 0 {
+		return DefaultPasswordMinLength
+	}
+	return p.MinLength
+}
+
+// GetMaxLength returns the maximum password length in bytes, defaulting to the bcrypt limit
+func (p *PasswordPolicyConfig) GetMaxLength() int {
+	if p.MaxLength <= 0 {
+		return BcryptMaxPasswordBytes
+	}
+	return p.MaxLength
+}
+
+// 密码哈希算法
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+// 密码哈希参数默认值（argon2id 参照 OWASP 推荐的最低配置）
+const (
+	DefaultBcryptCost        = 13
+	DefaultArgon2Memory      = 19 * 1024 // KiB
+	DefaultArgon2Iterations  = 2
+	DefaultArgon2Parallelism = 1
+)
+
+// PasswordHashConfig 密码哈希配置
+// 修改算法或参数后，旧哈希会在用户下次登录成功时自动升级
+type PasswordHashConfig struct {
+	Algorithm         string `mapstructure:"algorithm" yaml:"algorithm"`                   // bcrypt | argon2id，默认 bcrypt
+	BcryptCost        int    `mapstructure:"bcrypt_cost" yaml:"bcrypt_cost"`               // 默认 13
+	Argon2Memory      int    `mapstructure:"argon2_memory" yaml:"argon2_memory"`           // 内存用量（KiB），默认 19456
+	Argon2Iterations  int    `mapstructure:"argon2_iterations" yaml:"argon2_iterations"`   // 迭代次数，默认 2
+	Argon2Parallelism int    `mapstructure:"argon2_parallelism" yaml:"argon2_parallelism"` // 并行度，默认 1
+}
+
+// GetAlgorithm returns the hash algorithm for new passwords, defaulting to bcrypt
+func (p *PasswordHashConfig) GetAlgorithm() string {
+	if p.Algorithm == "" {
+		return PasswordHashBcrypt
+	}
+	return strings.ToLower(p.Algorithm)
+}
+
+// GetBcryptCost returns the bcrypt cost, defaulting to DefaultBcryptCost
+func (p *PasswordHashConfig) GetBcryptCost() int {
+	if p.BcryptCost <= 0 {
+		return DefaultBcryptCost
+	}
+	return p.BcryptCost
+}
+
+// GetArgon2Memory returns the argon2id memory cost in KiB, defaulting to DefaultArgon2Memory
+func (p *PasswordHashConfig) GetArgon2Memory() int {
+	if p.Argon2Memory <= 0 {
+		return DefaultArgon2Memory
+	}
+	return p.Argon2Memory
+}
+
+// GetArgon2Iterations returns the argon2id time cost, defaulting to DefaultArgon2Iterations
+func (p *PasswordHashConfig) GetArgon2Iterations() int {
+	if p.Argon2Iterations <= 0 {
+		return DefaultArgon2Iterations
+	}
+	return p.Argon2Iterations
+}
+
+// GetArgon2Parallelism returns the argon2id parallelism, defaulting to DefaultArgon2Parallelism
+func (p *PasswordHashConfig) GetArgon2Parallelism() int {
+	if p.Argon2Parallelism <= 0 {
+		return DefaultArgon2Parallelism
+	}
+	return p.Argon2Parallelism
+}
+
+// 登录保护默认值
+const (
+	DefaultLoginMaxAttempts   = 5
+	DefaultLoginIPMaxAttempts = 20
+	DefaultLoginWindow        = 15 * time.Minute
+	DefaultLoginLockDuration  = 15 * time.Minute
+	DefaultLoginBaseDelay     = time.Second
+	DefaultLoginMaxDelay      = 30 * time.Second
+)
+
+// LoginProtectionConfig 登录暴力破解防护配置
+// 启用 Redis 时失败记录在副本间共享，否则保存在进程内存中
+type LoginProtectionConfig struct {
+	Enabled       bool          `mapstructure:"enabled" yaml:"enabled"`
+	MaxAttempts   int           `mapstructure:"max_attempts" yaml:"max_attempts"`       // 同一账号失败多少次后锁定，默认 5
+	IPMaxAttempts int           `mapstructure:"ip_max_attempts" yaml:"ip_max_attempts"` // 同一 IP 失败多少次后锁定，默认 20
+	Window        time.Duration `mapstructure:"window" yaml:"window"`                   // 失败次数的统计窗口，默认 15m
+	LockDuration  time.Duration `mapstructure:"lock_duration" yaml:"lock_duration"`     // 锁定时长，默认 15m
+	BaseDelay     time.Duration `mapstructure:"base_delay" yaml:"base_delay"`           // 账号首次失败后需等待的时间，之后每次翻倍，默认 1s
+	MaxDelay      time.Duration `mapstructure:"max_delay" yaml:"max_delay"`             // 等待时间上限，默认 30s
+}
+
+// GetMaxAttempts returns the per-account failure limit, defaulting to DefaultLoginMaxAttempts
+func (l *LoginProtectionConfig) GetMaxAttempts() int {
+	if l.MaxAttempts <= 0 {
+		return DefaultLoginMaxAttempts
+	}
+	return l.MaxAttempts
+}
+
+// GetIPMaxAttempts returns the per-IP failure limit, defaulting to DefaultLoginIPMaxAttempts
+func (l *LoginProtectionConfig) GetIPMaxAttempts() int {
+	if l.IPMaxAttempts <= 0 {
+		return DefaultLoginIPMaxAttempts
+	}
+	return l.IPMaxAttempts
+}
+
+// GetWindow returns the failure counting window, defaulting to DefaultLoginWindow
+func (l *LoginProtectionConfig) GetWindow() time.Duration {
+	if l.Window <= 0 {
+		return DefaultLoginWindow
+	}
+	return l.Window
+}
+
+// GetLockDuration returns how long a lock lasts, defaulting to DefaultLoginLockDuration
+func (l *LoginProtectionConfig) GetLockDuration() time.Duration {
+	if l.LockDuration <= 0 {
+		return DefaultLoginLockDuration
+	}
+	return l.LockDuration
+}
+
+// GetBaseDelay returns the delay after the first failure, defaulting to DefaultLoginBaseDelay
+func (l *LoginProtectionConfig) GetBaseDelay() time.Duration {
+	if l.BaseDelay <= 0 {
+		return DefaultLoginBaseDelay
+	}
+	return l.BaseDelay
+}
+
+// GetMaxDelay returns the delay cap, defaulting to DefaultLoginMaxDelay
+func (l *LoginProtectionConfig) GetMaxDelay() time.Duration {
+	if l.MaxDelay <= 0 {
+		return DefaultLoginMaxDelay
+	}
+	return l.MaxDelay
+}
+
+// 两步验证默认值
+const (
+	DefaultMFAIssuer       = "Go REST API"
+	DefaultMFAChallengeTTL = 5 * time.Minute
+	DefaultMFAMaxAttempts  = 5
+)
+
+// MFAConfig TOTP 两步验证配置
+// 用户可自行启用；RequireForAdmins 为 true 时 admin 角色必须先完成绑定才能登录
+type MFAConfig struct {
+	Issuer           string        `mapstructure:"issuer" yaml:"issuer"`                         // 认证器 App 中显示的签发方名称
+	RequireForAdmins bool          `mapstructure:"require_for_admins" yaml:"require_for_admins"` // 要求 admin 角色启用两步验证
+	ChallengeTTL     time.Duration `mapstructure:"challenge_ttl" yaml:"challenge_ttl"`           // 登录后待验证令牌的有效期，默认 5m
+	MaxAttempts      int           `mapstructure:"max_attempts" yaml:"max_attempts"`             // 每个待验证令牌允许的错误验证码次数，默认 5
+}
+
+// GetIssuer returns the issuer shown in authenticator apps, defaulting to DefaultMFAIssuer
+func (m *MFAConfig) GetIssuer() string {
+	if m.Issuer == "" {
+		return DefaultMFAIssuer
+	}
+	return m.Issuer
+}
+
+// GetChallengeTTL returns how long an mfa pending token is valid, defaulting to DefaultMFAChallengeTTL
+func (m *MFAConfig) GetChallengeTTL() time.Duration {
+	if m.ChallengeTTL <= 0 {
+		return DefaultMFAChallengeTTL
+	}
+	return m.ChallengeTTL
+}
+
+// GetMaxAttempts returns the wrong codes allowed per mfa pending token, defaulting to DefaultMFAMaxAttempts
+func (m *MFAConfig) GetMaxAttempts() int {
+	if m.MaxAttempts <= 0 {
+		return DefaultMFAMaxAttempts
+	}
+	return m.MaxAttempts
+}
+
+// OIDC 登录默认值
+const (
+	DefaultOIDCStateTTL = 10 * time.Minute
+)
+
+// DefaultOIDCScopes are requested when a provider does not configure scopes
+var DefaultOIDCScopes = []string{"openid", "email", "profile"}
+
+// OIDCConfig OpenID Connect 第三方登录配置
+// 使用授权码 + PKCE 流程；首次登录自动创建用户，已登录用户可关联多个提供方
+type OIDCConfig struct {
+	Enabled        bool                 `mapstructure:"enabled" yaml:"enabled"`                 // 启用 /api/v1/auth/oidc 端点
+	StateTTL       time.Duration        `mapstructure:"state_ttl" yaml:"state_ttl"`             // 授权请求（state、nonce、PKCE）的有效期，默认 10m
+	InsecureCookie bool                 `mapstructure:"insecure_cookie" yaml:"insecure_cookie"` // state cookie 不带 Secure 标志，仅用于本地 HTTP 开发，生产环境禁止
+	Providers      []OIDCProviderConfig `mapstructure:"providers" yaml:"providers"`             // 提供方列表
+}
+
+// OIDCProviderConfig 单个 OpenID Connect 提供方
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name" yaml:"name"`                   // 路由中的标识，如 google，仅限小写字母、数字、- 和 _
+	Issuer       string   `mapstructure:"issuer" yaml:"issuer"`               // 发现文档位于 {issuer}/.well-known/openid-configuration
+	ClientID     string   `mapstructure:"client_id" yaml:"client_id"`         // 在提供方注册的客户端 ID
+	ClientSecret string   `mapstructure:"client_secret" yaml:"client_secret"` // 客户端密钥，公开客户端留空
+	RedirectURL  string   `mapstructure:"redirect_url" yaml:"redirect_url"`   // 回调地址，指向 /api/v1/auth/oidc/{name}/callback
+	Scopes       []string `mapstructure:"scopes" yaml:"scopes"`               // 请求的 scope，默认 openid email profile
+	LinkByEmail  bool     `mapstructure:"link_by_email" yaml:"link_by_email"` // 首次登录时关联邮箱相同的现有账号（要求提供方确认邮箱已验证）
+}
+
+// GetStateTTL returns how long an authorization request is valid, defaulting to DefaultOIDCStateTTL
+func (o *OIDCConfig) GetStateTTL() time.Duration {
+	if o.StateTTL <= 0 {
+		return DefaultOIDCStateTTL
+	}
+	return o.StateTTL
+}
+
+// GetScopes returns the scopes to request, defaulting to DefaultOIDCScopes
+func (p *OIDCProviderConfig) GetScopes() []string {
+	if len(p.Scopes) == 0 {
+		return DefaultOIDCScopes
+	}
+	return p.Scopes
+}
+
+// providerNames lists the configured provider names for logging
+func (o *OIDCConfig) providerNames() []string {
+	names := make([]string, len(o.Providers))
+	for i, provider := range o.Providers {
+		names[i] = provider.Name
+	}
+	return names
+}
+
+// API 密钥默认值
+const (
+	DefaultAPIKeyMaxPerUser = 10
+)
+
+// APIKeyConfig 个人 API 密钥配置
+// 密钥只在创建时返回一次，数据库中仅保存哈希
+type APIKeyConfig struct {
+	Enabled    bool          `mapstructure:"enabled" yaml:"enabled"`           // 启用 /api/v1/users/me/api-keys 端点和 Authorization: ApiKey 认证
+	MaxPerUser int           `mapstructure:"max_per_user" yaml:"max_per_user"` // 每个用户最多持有的密钥数量，默认 10
+	MaxTTL     time.Duration `mapstructure:"max_ttl" yaml:"max_ttl"`           // 密钥最长有效期，0 表示允许永不过期
+}
+
+// GetMaxPerUser returns how many keys a user may hold, defaulting to DefaultAPIKeyMaxPerUser
+func (a *APIKeyConfig) GetMaxPerUser() int {
+	if a.MaxPerUser <= 0 {
+		return DefaultAPIKeyMaxPerUser
+	}
+	return a.MaxPerUser
+}
+
+// OAuth 客户端凭证默认值
+const (
+	DefaultOAuthTokenTTL = time.Hour
+)
+
+// OAuthConfig 服务间调用的 OAuth2 客户端凭证配置
+// 客户端由管理员注册，通过 POST /oauth/token 换取带 scope 的访问令牌
+type OAuthConfig struct {
+	Enabled  bool          `mapstructure:"enabled" yaml:"enabled"`     // 启用 /oauth/token 和客户端管理端点
+	TokenTTL time.Duration `mapstructure:"token_ttl" yaml:"token_ttl"` // 客户端访问令牌有效期，默认 1h
+}
+
+// GetTokenTTL returns the lifetime of client access tokens, defaulting to DefaultOAuthTokenTTL
+func (o *OAuthConfig) GetTokenTTL() time.Duration {
+	if o.TokenTTL <= 0 {
+		return DefaultOAuthTokenTTL
+	}
+	return o.TokenTTL
+}
+
+// 网关身份头签名默认值
+const (
+	DefaultGatewayMaxClockSkew = 30 * time.Second
+)
+
+// GatewayConfig 网关身份头（X-User-ID / X-User-Role）的信任配置
+// 设置 signing_secret 后，网关必须对身份头和时间戳做 HMAC-SHA256 签名，未签名或过期的请求被拒绝
+type GatewayConfig struct {
+	SigningSecret  string        `mapstructure:"signing_secret" yaml:"signing_secret"`   // 与网关共享的签名密钥，至少 32 个字符；gateway 和 both 模式必填
+	MaxClockSkew   time.Duration `mapstructure:"max_clock_skew" yaml:"max_clock_skew"`   // 签名时间戳允许的最大偏差，默认 30s
+	TrustedProxies []string      `mapstructure:"trusted_proxies" yaml:"trusted_proxies"` // 允许发送身份头的网关地址（CIDR），为空时不限制
+}
+
+// GetMaxClockSkew returns how far a signature timestamp may drift, defaulting to DefaultGatewayMaxClockSkew
+func (g *GatewayConfig) GetMaxClockSkew() time.Duration {
+	if g.MaxClockSkew <= 0 {
+		return DefaultGatewayMaxClockSkew
+	}
+	return g.MaxClockSkew
+}
+
+// ParseTrustedProxies parses the trusted proxy CIDRs; a bare IP is treated as a single-address network
+func (g *GatewayConfig) ParseTrustedProxies() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(g.TrustedProxies))
+	for _, entry := range g.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// 管理员模拟登录默认值
+const (
+	DefaultImpersonationTokenTTL = 15 * time.Minute
+)
+
+// ImpersonationConfig 管理员模拟登录配置
+// 模拟令牌只签发访问令牌，不可刷新，并通过 act 声明标明实际操作的管理员
+type ImpersonationConfig struct {
+	Enabled  bool          `mapstructure:"enabled" yaml:"enabled"`     // 启用 POST /api/v1/admin/users/:id/impersonate 端点
+	TokenTTL time.Duration `mapstructure:"token_ttl" yaml:"token_ttl"` // 模拟令牌有效期，默认 15m，不超过访问令牌有效期
+}
+
+// GetTokenTTL returns the lifetime of impersonation tokens, defaulting to DefaultImpersonationTokenTTL
+func (i *ImpersonationConfig) GetTokenTTL() time.Duration {
+	if i.TokenTTL <= 0 {
+		return DefaultImpersonationTokenTTL
+	}
+	return i.TokenTTL
+}
+
+// 审计日志存储位置
+const (
+	AuditSinkDatabase = "database" // 写入主数据库的 audit_events 表
+	AuditSinkMongoDB  = "mongodb"  // 写入 MongoDB 集合，需启用 mongodb
+)
+
+// 审计日志默认值
+const (
+	DefaultAuditCollection = "audit_events"
+)
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Sink       string `mapstructure:"sink" yaml:"sink"`             // database（默认）| mongodb
+	Collection string `mapstructure:"collection" yaml:"collection"` // sink 为 mongodb 时使用的集合，默认 audit_events
+}
+
+// GetSink returns the configured audit sink, defaulting to database
+func (a *AuditConfig) GetSink() string {
+	if a.Sink == "" {
+		return AuditSinkDatabase
+	}
+	return strings.ToLower(a.Sink)
+}
+
+// GetCollection returns the MongoDB collection for audit events, defaulting to DefaultAuditCollection
+func (a *AuditConfig) GetCollection() string {
+	if a.Collection == "" {
+		return DefaultAuditCollection
+	}
+	return a.Collection
+}
+
 // LoadConfig loads configuration using Viper. If configPath is non-empty it
 // will be used as the exact config file path, otherwise Viper searches common locations.
 func LoadConfig(configPath string) (*Config, error) {
@@ -160,48 +695,107 @@ func LoadConfig(configPath string) (*Config, error) {
 
 func bindEnvVariables(v *viper.Viper) {
 	envBindings := map[string]string{
-		"app.name":                      "APP_NAME",
-		"app.version":                   "APP_VERSION",
-		"app.environment":               "APP_ENVIRONMENT",
-		"app.debug":                     "APP_DEBUG",
-		"database.host":                 "DATABASE_HOST",
-		"database.port":                 "DATABASE_PORT",
-		"database.user":                 "DATABASE_USER",
-		"database.password":             "DATABASE_PASSWORD",
-		"database.name":                 "DATABASE_NAME",
-			"database.sslmode":              "DATABASE_SSLMODE",
-			"database.max_open_conns":       "DATABASE_MAX_OPEN_CONNS",
-			"database.max_idle_conns":       "DATABASE_MAX_IDLE_CONNS",
-			"database.conn_max_lifetime":    "DATABASE_CONN_MAX_LIFETIME",
-			"database.conn_max_idle_time":   "DATABASE_CONN_MAX_IDLE_TIME",
-		"jwt.secret":                    "JWT_SECRET",
-		"jwt.access_token_ttl":          "JWT_ACCESS_TOKEN_TTL",
-		"jwt.refresh_token_ttl":         "JWT_REFRESH_TOKEN_TTL",
-		"jwt.ttlhours":                  "JWT_TTLHOURS",
-		"server.port":                   "SERVER_PORT",
-		"server.readtimeout":            "SERVER_READTIMEOUT",
-		"server.writetimeout":           "SERVER_WRITETIMEOUT",
-		"server.idletimeout":            "SERVER_IDLETIMEOUT",
-		"server.shutdowntimeout":        "SERVER_SHUTDOWNTIMEOUT",
-		"server.maxheaderbytes":         "SERVER_MAXHEADERBYTES",
-		"logging.level":                 "LOGGING_LEVEL",
-		"ratelimit.enabled":             "RATELIMIT_ENABLED",
-		"ratelimit.requests":            "RATELIMIT_REQUESTS",
-		"ratelimit.window":              "RATELIMIT_WINDOW",
-		"migrations.directory":          "MIGRATIONS_DIRECTORY",
-		"migrations.timeout":            "MIGRATIONS_TIMEOUT",
-		"migrations.locktimeout":        "MIGRATIONS_LOCKTIMEOUT",
-			"health.timeout":                "HEALTH_TIMEOUT",
-			"health.database_check_enabled": "HEALTH_DATABASE_CHECK_ENABLED",
-			"redis.enabled":                 "REDIS_ENABLED",
-			"redis.host":                    "REDIS_HOST",
-			"redis.port":                    "REDIS_PORT",
-			"redis.password":                "REDIS_PASSWORD",
-			"redis.db":                      "REDIS_DB",
-			"mongodb.enabled":               "MONGODB_ENABLED",
-			"mongodb.uri":                   "MONGODB_URI",
-			"mongodb.database":              "MONGODB_DATABASE",
-		}
+		"app.name":                               "APP_NAME",
+		"app.version":                            "APP_VERSION",
+		"app.environment":                        "APP_ENVIRONMENT",
+		"app.debug":                              "APP_DEBUG",
+		"database.host":                          "DATABASE_HOST",
+		"database.port":                          "DATABASE_PORT",
+		"database.user":                          "DATABASE_USER",
+		"database.password":                      "DATABASE_PASSWORD",
+		"database.name":                          "DATABASE_NAME",
+		"database.sslmode":                       "DATABASE_SSLMODE",
+		"database.max_open_conns":                "DATABASE_MAX_OPEN_CONNS",
+		"database.max_idle_conns":                "DATABASE_MAX_IDLE_CONNS",
+		"database.conn_max_lifetime":             "DATABASE_CONN_MAX_LIFETIME",
+		"database.conn_max_idle_time":            "DATABASE_CONN_MAX_IDLE_TIME",
+		"jwt.secret":                             "JWT_SECRET",
+		"jwt.access_token_ttl":                   "JWT_ACCESS_TOKEN_TTL",
+		"jwt.refresh_token_ttl":                  "JWT_REFRESH_TOKEN_TTL",
+		"jwt.ttlhours":                           "JWT_TTLHOURS",
+		"jwt.algorithm":                          "JWT_ALGORITHM",
+		"jwt.key_id":                             "JWT_KEY_ID",
+		"jwt.private_key_path":                   "JWT_PRIVATE_KEY_PATH",
+		"jwt.issuer":                             "JWT_ISSUER",
+		"jwt.audiences":                          "JWT_AUDIENCES",
+		"jwt.leeway":                             "JWT_LEEWAY",
+		"auth.mode":                              "AUTH_MODE",
+		"server.port":                            "SERVER_PORT",
+		"server.readtimeout":                     "SERVER_READTIMEOUT",
+		"server.writetimeout":                    "SERVER_WRITETIMEOUT",
+		"server.idletimeout":                     "SERVER_IDLETIMEOUT",
+		"server.shutdowntimeout":                 "SERVER_SHUTDOWNTIMEOUT",
+		"server.maxheaderbytes":                  "SERVER_MAXHEADERBYTES",
+		"logging.level":                          "LOGGING_LEVEL",
+		"ratelimit.enabled":                      "RATELIMIT_ENABLED",
+		"ratelimit.requests":                     "RATELIMIT_REQUESTS",
+		"ratelimit.window":                       "RATELIMIT_WINDOW",
+		"migrations.directory":                   "MIGRATIONS_DIRECTORY",
+		"migrations.timeout":                     "MIGRATIONS_TIMEOUT",
+		"migrations.locktimeout":                 "MIGRATIONS_LOCKTIMEOUT",
+		"health.timeout":                         "HEALTH_TIMEOUT",
+		"health.database_check_enabled":          "HEALTH_DATABASE_CHECK_ENABLED",
+		"redis.enabled":                          "REDIS_ENABLED",
+		"redis.host":                             "REDIS_HOST",
+		"redis.port":                             "REDIS_PORT",
+		"redis.password":                         "REDIS_PASSWORD",
+		"redis.db":                               "REDIS_DB",
+		"mongodb.enabled":                        "MONGODB_ENABLED",
+		"mongodb.uri":                            "MONGODB_URI",
+		"mongodb.database":                       "MONGODB_DATABASE",
+		"token_cleanup.enabled":                  "TOKEN_CLEANUP_ENABLED",
+		"token_cleanup.interval":                 "TOKEN_CLEANUP_INTERVAL",
+		"token_cleanup.batch_size":               "TOKEN_CLEANUP_BATCH_SIZE",
+		"token_cleanup.retention":                "TOKEN_CLEANUP_RETENTION",
+		"mail.driver":                            "MAIL_DRIVER",
+		"mail.from":                              "MAIL_FROM",
+		"mail.file_dir":                          "MAIL_FILE_DIR",
+		"password_reset.token_ttl":               "PASSWORD_RESET_TOKEN_TTL",
+		"password_reset.url":                     "PASSWORD_RESET_URL",
+		"email_verification.enabled":             "EMAIL_VERIFICATION_ENABLED",
+		"email_verification.policy":              "EMAIL_VERIFICATION_POLICY",
+		"email_verification.token_ttl":           "EMAIL_VERIFICATION_TOKEN_TTL",
+		"email_verification.url":                 "EMAIL_VERIFICATION_URL",
+		"password_policy.min_length":             "PASSWORD_POLICY_MIN_LENGTH",
+		"password_policy.max_length":             "PASSWORD_POLICY_MAX_LENGTH",
+		"password_policy.require_uppercase":      "PASSWORD_POLICY_REQUIRE_UPPERCASE",
+		"password_policy.require_lowercase":      "PASSWORD_POLICY_REQUIRE_LOWERCASE",
+		"password_policy.require_digit":          "PASSWORD_POLICY_REQUIRE_DIGIT",
+		"password_policy.require_special":        "PASSWORD_POLICY_REQUIRE_SPECIAL",
+		"password_policy.disallow_personal_info": "PASSWORD_POLICY_DISALLOW_PERSONAL_INFO",
+		"password_policy.breached_list_path":     "PASSWORD_POLICY_BREACHED_LIST_PATH",
+		"password_hash.algorithm":                "PASSWORD_HASH_ALGORITHM",
+		"password_hash.bcrypt_cost":              "PASSWORD_HASH_BCRYPT_COST",
+		"password_hash.argon2_memory":            "PASSWORD_HASH_ARGON2_MEMORY",
+		"password_hash.argon2_iterations":        "PASSWORD_HASH_ARGON2_ITERATIONS",
+		"password_hash.argon2_parallelism":       "PASSWORD_HASH_ARGON2_PARALLELISM",
+		"login_protection.enabled":               "LOGIN_PROTECTION_ENABLED",
+		"login_protection.max_attempts":          "LOGIN_PROTECTION_MAX_ATTEMPTS",
+		"login_protection.ip_max_attempts":       "LOGIN_PROTECTION_IP_MAX_ATTEMPTS",
+		"login_protection.window":                "LOGIN_PROTECTION_WINDOW",
+		"login_protection.lock_duration":         "LOGIN_PROTECTION_LOCK_DURATION",
+		"login_protection.base_delay":            "LOGIN_PROTECTION_BASE_DELAY",
+		"login_protection.max_delay":             "LOGIN_PROTECTION_MAX_DELAY",
+		"mfa.issuer":                             "MFA_ISSUER",
+		"mfa.require_for_admins":                 "MFA_REQUIRE_FOR_ADMINS",
+		"mfa.challenge_ttl":                      "MFA_CHALLENGE_TTL",
+		"mfa.max_attempts":                       "MFA_MAX_ATTEMPTS",
+		"oidc.enabled":                           "OIDC_ENABLED",
+		"oidc.state_ttl":                         "OIDC_STATE_TTL",
+		"oidc.insecure_cookie":                   "OIDC_INSECURE_COOKIE",
+		"api_keys.enabled":                       "API_KEYS_ENABLED",
+		"api_keys.max_per_user":                  "API_KEYS_MAX_PER_USER",
+		"api_keys.max_ttl":                       "API_KEYS_MAX_TTL",
+		"oauth.enabled":                          "OAUTH_ENABLED",
+		"oauth.token_ttl":                        "OAUTH_TOKEN_TTL",
+		"gateway.signing_secret":                 "GATEWAY_SIGNING_SECRET",
+		"gateway.max_clock_skew":                 "GATEWAY_MAX_CLOCK_SKEW",
+		"gateway.trusted_proxies":                "GATEWAY_TRUSTED_PROXIES",
+		"impersonation.enabled":                  "IMPERSONATION_ENABLED",
+		"impersonation.token_ttl":                "IMPERSONATION_TOKEN_TTL",
+		"audit.sink":                             "AUDIT_SINK",
+		"audit.collection":                       "AUDIT_COLLECTION",
+	}
 	for key, env := range envBindings {
 		_ = v.BindEnv(key, env)
 	}
@@ -222,6 +816,14 @@ func (l *LoggingConfig) GetLogLevel() slog.Level {
 	}
 }
 
+// GetMode returns the configured auth mode, defaulting to gateway mode
+func (a *AuthConfig) GetMode() string {
+	if a.Mode == "" {
+		return AuthModeGateway
+	}
+	return strings.ToLower(a.Mode)
+}
+
 func GetSkipPaths(env string) []string {
 	switch env {
 	case "production":
@@ -256,9 +858,24 @@ func (c *Config) LogSafeConfig(logger *slog.Logger) {
 	logger.Info("Loaded Configuration:")
 	logger.Info("App", "Name", c.App.Name, "Environment", c.App.Environment, "Debug", c.App.Debug)
 	logger.Info("Database", "Host", c.Database.Host, "Port", c.Database.Port, "User", c.Database.User, "Password", "<redacted>", "Name", c.Database.Name, "SSLMode", c.Database.SSLMode)
-	logger.Info("JWT", "Secret", "<redacted>", "AccessTokenTTL", c.JWT.AccessTokenTTL, "RefreshTokenTTL", c.JWT.RefreshTokenTTL)
+	logger.Info("JWT", "Secret", "<redacted>", "Algorithm", c.JWT.Algorithm, "KeyID", c.JWT.KeyID, "PrivateKeyPath", c.JWT.PrivateKeyPath, "RetiredKeys", len(c.JWT.RetiredKeys), "Issuer", c.JWT.Issuer, "Audiences", c.JWT.Audiences, "Leeway", c.JWT.Leeway, "AccessTokenTTL", c.JWT.AccessTokenTTL, "RefreshTokenTTL", c.JWT.RefreshTokenTTL)
+	logger.Info("Auth", "Mode", c.Auth.GetMode())
 	logger.Info("Server", "Port", c.Server.Port, "ReadTimeout", c.Server.ReadTimeout, "WriteTimeout", c.Server.WriteTimeout, "IdleTimeout", c.Server.IdleTimeout, "ShutdownTimeout", c.Server.ShutdownTimeout, "MaxHeaderBytes", c.Server.MaxHeaderBytes)
 	logger.Info("Logging", "Level", c.Logging.Level)
 	logger.Info("RateLimit", "Enabled", c.Ratelimit.Enabled, "Requests", c.Ratelimit.Requests, "Window", c.Ratelimit.Window)
 	logger.Info("Migrations", "Directory", c.Migrations.Directory, "Timeout", c.Migrations.Timeout, "LockTimeout", c.Migrations.LockTimeout)
+	logger.Info("TokenCleanup", "Enabled", c.TokenCleanup.Enabled, "Interval", c.TokenCleanup.Interval, "BatchSize", c.TokenCleanup.BatchSize, "Retention", c.TokenCleanup.Retention)
+	logger.Info("Mail", "Driver", c.Mail.GetDriver(), "From", c.Mail.From, "FileDir", c.Mail.FileDir)
+	logger.Info("PasswordReset", "TokenTTL", c.PasswordReset.TokenTTL, "URL", c.PasswordReset.URL)
+	logger.Info("PasswordPolicy", "MinLength", c.PasswordPolicy.GetMinLength(), "MaxLength", c.PasswordPolicy.GetMaxLength(), "RequireUppercase", c.PasswordPolicy.RequireUppercase, "RequireLowercase", c.PasswordPolicy.RequireLowercase, "RequireDigit", c.PasswordPolicy.RequireDigit, "RequireSpecial", c.PasswordPolicy.RequireSpecial, "DisallowPersonalInfo", c.PasswordPolicy.DisallowPersonalInfo, "BreachedListPath", c.PasswordPolicy.BreachedListPath)
+	logger.Info("PasswordHash", "Algorithm", c.PasswordHash.GetAlgorithm(), "BcryptCost", c.PasswordHash.GetBcryptCost(), "Argon2Memory", c.PasswordHash.GetArgon2Memory(), "Argon2Iterations", c.PasswordHash.GetArgon2Iterations(), "Argon2Parallelism", c.PasswordHash.GetArgon2Parallelism())
+	logger.Info("LoginProtection", "Enabled", c.LoginProtection.Enabled, "MaxAttempts", c.LoginProtection.GetMaxAttempts(), "IPMaxAttempts", c.LoginProtection.GetIPMaxAttempts(), "Window", c.LoginProtection.GetWindow(), "LockDuration", c.LoginProtection.GetLockDuration(), "BaseDelay", c.LoginProtection.GetBaseDelay(), "MaxDelay", c.LoginProtection.GetMaxDelay())
+	logger.Info("MFA", "Issuer", c.MFA.GetIssuer(), "RequireForAdmins", c.MFA.RequireForAdmins, "ChallengeTTL", c.MFA.GetChallengeTTL(), "MaxAttempts", c.MFA.GetMaxAttempts())
+	logger.Info("OIDC", "Enabled", c.OIDC.Enabled, "StateTTL", c.OIDC.GetStateTTL(), "InsecureCookie", c.OIDC.InsecureCookie, "Providers", c.OIDC.providerNames())
+	logger.Info("API keys", "Enabled", c.APIKeys.Enabled, "MaxPerUser", c.APIKeys.GetMaxPerUser(), "MaxTTL", c.APIKeys.MaxTTL)
+	logger.Info("OAuth", "Enabled", c.OAuth.Enabled, "TokenTTL", c.OAuth.GetTokenTTL())
+	logger.Info("Gateway", "SigningSecretSet", c.Gateway.SigningSecret != "", "MaxClockSkew", c.Gateway.GetMaxClockSkew(), "TrustedProxies", c.Gateway.TrustedProxies)
+	logger.Info("Impersonation", "Enabled", c.Impersonation.Enabled, "TokenTTL", c.Impersonation.GetTokenTTL())
+	logger.Info("Audit", "Sink", c.Audit.GetSink(), "Collection", c.Audit.GetCollection())
+	logger.Info("EmailVerification", "Enabled", c.EmailVerification.Enabled, "Policy", c.EmailVerification.GetPolicy(), "TokenTTL", c.EmailVerification.TokenTTL, "URL", c.EmailVerification.URL)
 }
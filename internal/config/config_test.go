@@ -2,9 +2,11 @@ package config
 
 import (
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -21,6 +23,9 @@ func createTempConfigFile(t *testing.T, dir, filename, content string) string {
 	return path
 }
 
+// testGatewaySecret satisfies the gateway signing requirement of the default auth mode
+const testGatewaySecret = "gateway-signing-secret-32-chars!"
+
 func TestLoadConfig_Comprehensive(t *testing.T) {
 	// Reset viper before each test to ensure a clean state
 	viper.Reset()
@@ -40,6 +45,8 @@ database:
   host: "testhost"
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		cfg, err := LoadConfig(path) // Pass the explicit path
 		assert.NoError(t, err)
@@ -58,6 +65,8 @@ database:
   port: 5432
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		// Set env vars that should override the file
 		t.Setenv("DATABASE_HOST", "envhost")
@@ -96,6 +105,8 @@ database:
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
   ttlhours: 24
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 server:
   port: "8080"
   readtimeout: 10
@@ -147,6 +158,8 @@ database:
 jwt:
   secret: "PRODabcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZabcdef"
   ttlhours: 24
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		t.Setenv("APP_ENVIRONMENT", "production")
 		t.Setenv("DATABASE_PASSWORD", "") // Explicitly empty
@@ -177,6 +190,8 @@ database:
 jwt:
   secret: "short"
   ttlhours: 24
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		_, err := LoadConfig(path)
 		assert.Error(t, err)
@@ -206,6 +221,8 @@ database:
   host: "testhost"
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		createTempConfigFile(t, configsDir, "config.production.yaml", `
 app:
@@ -221,6 +238,8 @@ database:
 jwt:
   secret: "qrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyzAB"
   ttlhours: 24
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 `)
 		// Temporarily change working directory so LoadConfig can find the "configs" folder
 		oldWd, err := os.Getwd()
@@ -423,6 +442,8 @@ database:
   password: "postgres"
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 server:
   port: "8080"
   readtimeout: 10
@@ -450,6 +471,8 @@ database:
   password: "postgres"
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 server:
   readtimeout: 10
   writetimeout: 10
@@ -482,6 +505,8 @@ database:
   password: "postgres"
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 server:
   readtimeout: 0
   writetimeout: 0
@@ -517,6 +542,9 @@ func TestValidate_TimeoutFields(t *testing.T) {
 				JWT: JWTConfig{
 					Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP",
 				},
+				Gateway: GatewayConfig{
+					SigningSecret: "gateway-signing-secret-32-chars!",
+				},
 				Server: ServerConfig{
 					ReadTimeout:     10,
 					WriteTimeout:    10,
@@ -622,6 +650,9 @@ func TestValidate_TimeoutFields(t *testing.T) {
 				JWT: JWTConfig{
 					Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP",
 				},
+				Gateway: GatewayConfig{
+					SigningSecret: "gateway-signing-secret-32-chars!",
+				},
 				Server: ServerConfig{
 					ReadTimeout:     0,
 					WriteTimeout:    0,
@@ -684,6 +715,8 @@ database:
 jwt:
   secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"
   ttlhours: 24
+gateway:
+  signing_secret: "gateway-signing-secret-32-chars!"
 server:
   port: "8080"
 `
@@ -814,6 +847,9 @@ func TestValidate_JWTSecret(t *testing.T) {
 				JWT: JWTConfig{
 					Secret: tt.jwtSecret,
 				},
+				Gateway: GatewayConfig{
+					SigningSecret: "gateway-signing-secret-32-chars!",
+				},
 			}
 
 			err := cfg.Validate()
@@ -826,3 +862,632 @@ func TestValidate_JWTSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate_AuthMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		expectError bool
+	}{
+		{name: "empty defaults to gateway", mode: "", expectError: false},
+		{name: "gateway mode", mode: AuthModeGateway, expectError: false},
+		{name: "jwt mode", mode: AuthModeJWT, expectError: false},
+		{name: "both mode", mode: AuthModeBoth, expectError: false},
+		{name: "mode is case-insensitive", mode: "JWT", expectError: false},
+		{name: "unknown mode", mode: "oauth", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				Auth:     AuthConfig{Mode: tt.mode},
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "auth.mode must be one of")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, AuthModeGateway, (&AuthConfig{}).GetMode())
+}
+
+func TestValidate_JWTAlgorithm(t *testing.T) {
+	tests := []struct {
+		name        string
+		jwt         JWTConfig
+		expectError string
+	}{
+		{name: "default HS256 with secret", jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"}},
+		{name: "RS256 with key path", jwt: JWTConfig{Algorithm: "RS256", PrivateKeyPath: "/etc/keys/jwt.pem"}},
+		{name: "EdDSA without secret", jwt: JWTConfig{Algorithm: "eddsa", PrivateKeyPath: "/etc/keys/jwt.pem"}},
+		{name: "RS256 without key path", jwt: JWTConfig{Algorithm: "RS256"}, expectError: "jwt.private_key_path is required"},
+		{name: "HS256 still requires secret", jwt: JWTConfig{Algorithm: "HS256"}, expectError: "JWT_SECRET"},
+		{name: "unknown algorithm", jwt: JWTConfig{Algorithm: "none"}, expectError: "jwt.algorithm must be one of"},
+		{name: "negative leeway", jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", Leeway: -time.Second}, expectError: "jwt.leeway must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      tt.jwt,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RetiredKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		jwt         JWTConfig
+		expectError string
+	}{
+		{
+			name: "HS256 rotation",
+			jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", KeyID: "2026-10", RetiredKeys: []RetiredKeyConfig{
+				{KeyID: "default", Secret: "old-secret", AcceptUntil: "2026-11-01"},
+			}},
+		},
+		{
+			name: "asymmetric retired key",
+			jwt: JWTConfig{Algorithm: "EdDSA", PrivateKeyPath: "/keys/new.pem", RetiredKeys: []RetiredKeyConfig{
+				{KeyID: "old", Algorithm: "EdDSA", PublicKeyPath: "/keys/old.pub", AcceptUntil: "2026-11-01T00:00:00Z"},
+			}},
+		},
+		{
+			name: "missing key_id",
+			jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", RetiredKeys: []RetiredKeyConfig{
+				{Secret: "old-secret", AcceptUntil: "2026-11-01"},
+			}},
+			expectError: "key_id is required",
+		},
+		{
+			name: "key_id clashes with default active kid",
+			jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", RetiredKeys: []RetiredKeyConfig{
+				{KeyID: "default", Secret: "old-secret", AcceptUntil: "2026-11-01"},
+			}},
+			expectError: "already in use",
+		},
+		{
+			name: "asymmetric retired key without public key",
+			jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", KeyID: "new", RetiredKeys: []RetiredKeyConfig{
+				{KeyID: "old", Algorithm: "RS256", AcceptUntil: "2026-11-01"},
+			}},
+			expectError: "public_key_path is required",
+		},
+		{
+			name: "invalid accept_until",
+			jwt: JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP", KeyID: "new", RetiredKeys: []RetiredKeyConfig{
+				{KeyID: "old", Secret: "old-secret", AcceptUntil: "next week"},
+			}},
+			expectError: "invalid accept_until",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      tt.jwt,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRetiredKeyConfig_GetAcceptUntil(t *testing.T) {
+	day := RetiredKeyConfig{AcceptUntil: "2026-11-01"}
+	until, err := day.GetAcceptUntil()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 11, 1, 23, 59, 59, 999999999, time.UTC), until)
+
+	exact := RetiredKeyConfig{AcceptUntil: "2026-11-01T08:00:00Z"}
+	until, err = exact.GetAcceptUntil()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC), until)
+}
+
+func TestValidate_TokenCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		cleanup     TokenCleanupConfig
+		expectError string
+	}{
+		{name: "disabled ignores zero values", cleanup: TokenCleanupConfig{}},
+		{name: "valid", cleanup: TokenCleanupConfig{Enabled: true, Interval: time.Hour, BatchSize: 1000, Retention: 24 * time.Hour}},
+		{name: "zero retention", cleanup: TokenCleanupConfig{Enabled: true, Interval: time.Hour, BatchSize: 1000}},
+		{name: "missing interval", cleanup: TokenCleanupConfig{Enabled: true, BatchSize: 1000}, expectError: "token_cleanup.interval must be positive"},
+		{name: "missing batch size", cleanup: TokenCleanupConfig{Enabled: true, Interval: time.Hour}, expectError: "token_cleanup.batch_size must be positive"},
+		{name: "negative retention", cleanup: TokenCleanupConfig{Enabled: true, Interval: time.Hour, BatchSize: 1000, Retention: -time.Hour}, expectError: "token_cleanup.retention must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:     DatabaseConfig{Host: "localhost"},
+				JWT:          JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				TokenCleanup: tt.cleanup,
+				Gateway:      GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Mail(t *testing.T) {
+	tests := []struct {
+		name        string
+		mail        MailConfig
+		expectError string
+	}{
+		{name: "empty defaults to log", mail: MailConfig{}},
+		{name: "file driver", mail: MailConfig{Driver: MailDriverFile, FileDir: "./tmp/mail"}},
+		{name: "file driver without directory", mail: MailConfig{Driver: MailDriverFile}, expectError: "mail.file_dir is required"},
+		{name: "unknown driver", mail: MailConfig{Driver: "smtp"}, expectError: "mail.driver must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				Mail:     tt.mail,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, MailDriverLog, (&MailConfig{}).GetDriver())
+}
+
+func TestValidate_EmailVerification(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification EmailVerificationConfig
+		expectError  string
+	}{
+		{name: "disabled by default", verification: EmailVerificationConfig{}},
+		{name: "enabled without policy", verification: EmailVerificationConfig{Enabled: true, TokenTTL: 24 * time.Hour}},
+		{name: "block login", verification: EmailVerificationConfig{Enabled: true, Policy: EmailVerificationPolicyBlockLogin}},
+		{name: "restrict roles", verification: EmailVerificationConfig{Enabled: true, Policy: EmailVerificationPolicyRestrictRoles}},
+		{name: "unknown policy", verification: EmailVerificationConfig{Enabled: true, Policy: "lock"}, expectError: "email_verification.policy must be one of"},
+		{name: "policy without verification emails", verification: EmailVerificationConfig{Policy: EmailVerificationPolicyBlockLogin}, expectError: "email_verification.enabled must be true"},
+		{name: "negative token ttl", verification: EmailVerificationConfig{Enabled: true, TokenTTL: -time.Minute}, expectError: "email_verification.token_ttl must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:          DatabaseConfig{Host: "localhost"},
+				JWT:               JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				EmailVerification: tt.verification,
+				Gateway:           GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, EmailVerificationPolicyNone, (&EmailVerificationConfig{}).GetPolicy())
+}
+
+func TestValidate_PasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      PasswordPolicyConfig
+		expectError string
+	}{
+		{name: "defaults", policy: PasswordPolicyConfig{}},
+		{name: "custom lengths", policy: PasswordPolicyConfig{MinLength: 12, MaxLength: 64}},
+		{name: "negative min length", policy: PasswordPolicyConfig{MinLength: -1}, expectError: "must be non-negative"},
+		{name: "max length above bcrypt limit", policy: PasswordPolicyConfig{MaxLength: 100}, expectError: "must not exceed 72 bytes"},
+		{name: "min length above max length", policy: PasswordPolicyConfig{MinLength: 20, MaxLength: 16}, expectError: "min_length must not exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:       DatabaseConfig{Host: "localhost"},
+				JWT:            JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				PasswordPolicy: tt.policy,
+				Gateway:        GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, DefaultPasswordMinLength, (&PasswordPolicyConfig{}).GetMinLength())
+	assert.Equal(t, BcryptMaxPasswordBytes, (&PasswordPolicyConfig{}).GetMaxLength())
+}
+
+func TestValidate_PasswordHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		hash        PasswordHashConfig
+		expectError string
+	}{
+		{name: "defaults to bcrypt", hash: PasswordHashConfig{}},
+		{name: "argon2id", hash: PasswordHashConfig{Algorithm: "argon2id", Argon2Memory: 65536, Argon2Iterations: 3, Argon2Parallelism: 4}},
+		{name: "algorithm is case-insensitive", hash: PasswordHashConfig{Algorithm: "Argon2ID"}},
+		{name: "unknown algorithm", hash: PasswordHashConfig{Algorithm: "md5"}, expectError: "password_hash.algorithm must be one of"},
+		{name: "bcrypt cost too low", hash: PasswordHashConfig{BcryptCost: 3}, expectError: "bcrypt_cost must be between 4 and 31"},
+		{name: "bcrypt cost too high", hash: PasswordHashConfig{BcryptCost: 32}, expectError: "bcrypt_cost must be between 4 and 31"},
+		{name: "negative argon2 memory", hash: PasswordHashConfig{Argon2Memory: -1}, expectError: "must be non-negative"},
+		{name: "argon2 parallelism too high", hash: PasswordHashConfig{Argon2Parallelism: 256, Argon2Memory: 1 << 20}, expectError: "must not exceed 255"},
+		{name: "argon2 memory too low for parallelism", hash: PasswordHashConfig{Argon2Memory: 16, Argon2Parallelism: 4}, expectError: "at least 8 KiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:     DatabaseConfig{Host: "localhost"},
+				JWT:          JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				PasswordHash: tt.hash,
+				Gateway:      GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_LoginProtection(t *testing.T) {
+	tests := []struct {
+		name        string
+		protection  LoginProtectionConfig
+		expectError string
+	}{
+		{name: "defaults", protection: LoginProtectionConfig{Enabled: true}},
+		{name: "custom limits", protection: LoginProtectionConfig{Enabled: true, MaxAttempts: 3, IPMaxAttempts: 50, Window: time.Hour, LockDuration: 30 * time.Minute, BaseDelay: 2 * time.Second, MaxDelay: time.Minute}},
+		{name: "negative attempts", protection: LoginProtectionConfig{MaxAttempts: -1}, expectError: "must be non-negative"},
+		{name: "negative lock duration", protection: LoginProtectionConfig{LockDuration: -time.Minute}, expectError: "durations must be non-negative"},
+		{name: "base delay above max delay", protection: LoginProtectionConfig{BaseDelay: time.Minute, MaxDelay: time.Second}, expectError: "base_delay must not exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:        DatabaseConfig{Host: "localhost"},
+				JWT:             JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				LoginProtection: tt.protection,
+				Gateway:         GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_MFA(t *testing.T) {
+	tests := []struct {
+		name        string
+		mfa         MFAConfig
+		expectError string
+	}{
+		{name: "defaults", mfa: MFAConfig{}},
+		{name: "required for admins", mfa: MFAConfig{Issuer: "Example", RequireForAdmins: true, ChallengeTTL: 10 * time.Minute, MaxAttempts: 3}},
+		{name: "negative challenge ttl", mfa: MFAConfig{ChallengeTTL: -time.Minute}, expectError: "must be non-negative"},
+		{name: "negative max attempts", mfa: MFAConfig{MaxAttempts: -1}, expectError: "must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				MFA:      tt.mfa,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_APIKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiKeys     APIKeyConfig
+		expectError string
+	}{
+		{name: "defaults", apiKeys: APIKeyConfig{}},
+		{name: "limited", apiKeys: APIKeyConfig{Enabled: true, MaxPerUser: 3, MaxTTL: 90 * 24 * time.Hour}},
+		{name: "negative max per user", apiKeys: APIKeyConfig{MaxPerUser: -1}, expectError: "must be non-negative"},
+		{name: "negative max ttl", apiKeys: APIKeyConfig{MaxTTL: -time.Hour}, expectError: "must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				APIKeys:  tt.apiKeys,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_OAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		oauth       OAuthConfig
+		expectError string
+	}{
+		{name: "defaults", oauth: OAuthConfig{}},
+		{name: "enabled", oauth: OAuthConfig{Enabled: true, TokenTTL: 30 * time.Minute}},
+		{name: "negative token ttl", oauth: OAuthConfig{TokenTTL: -time.Minute}, expectError: "must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				OAuth:    tt.oauth,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Gateway(t *testing.T) {
+	const secret = "gateway-signing-secret-32-chars!"
+
+	tests := []struct {
+		name        string
+		environment string
+		mode        string
+		gateway     GatewayConfig
+		expectError string
+	}{
+		{name: "unsigned in development", environment: "development", expectError: "gateway.signing_secret is required when auth.mode is gateway"},
+		{name: "unsigned in both mode", environment: "development", mode: AuthModeBoth, expectError: "gateway.signing_secret is required when auth.mode is both"},
+		{name: "unsigned with jwt mode", environment: "development", mode: AuthModeJWT},
+		{name: "signed with proxies", gateway: GatewayConfig{SigningSecret: secret, MaxClockSkew: time.Minute, TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"}}},
+		{name: "short secret", gateway: GatewayConfig{SigningSecret: "short"}, expectError: "at least 32 characters"},
+		{name: "negative skew", gateway: GatewayConfig{MaxClockSkew: -time.Second}, expectError: "must be non-negative"},
+		{name: "invalid proxy", gateway: GatewayConfig{TrustedProxies: []string{"10.0.0.0/33"}}, expectError: "gateway.trusted_proxies"},
+		{name: "unsigned in production", environment: "production", expectError: "gateway.signing_secret is required when auth.mode is gateway"},
+		{name: "unsigned in production with jwt mode", environment: "production", mode: AuthModeJWT},
+		{name: "signed in production", environment: "production", gateway: GatewayConfig{SigningSecret: secret}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				App:      AppConfig{Environment: tt.environment},
+				Database: DatabaseConfig{Host: "localhost", Password: "secure-password", SSLMode: "require"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				Auth:     AuthConfig{Mode: tt.mode},
+				Gateway:  tt.gateway,
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGatewayConfig_ParseTrustedProxies(t *testing.T) {
+	gateway := GatewayConfig{TrustedProxies: []string{"10.0.0.0/8", " 192.168.1.10 ", "::1", ""}}
+
+	networks, err := gateway.ParseTrustedProxies()
+	assert.NoError(t, err)
+	assert.Len(t, networks, 3)
+	assert.True(t, networks[0].Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, networks[1].Contains(net.ParseIP("192.168.1.10")))
+	assert.False(t, networks[1].Contains(net.ParseIP("192.168.1.11")))
+	assert.True(t, networks[2].Contains(net.ParseIP("::1")))
+
+	invalid := GatewayConfig{TrustedProxies: []string{"gateway.local"}}
+	_, err = invalid.ParseTrustedProxies()
+	assert.Error(t, err)
+}
+
+func TestValidate_Impersonation(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		impersonation ImpersonationConfig
+		expectError   string
+	}{
+		{name: "disabled in gateway mode"},
+		{name: "enabled in jwt mode", mode: AuthModeJWT, impersonation: ImpersonationConfig{Enabled: true, TokenTTL: 10 * time.Minute}},
+		{name: "enabled in both mode", mode: AuthModeBoth, impersonation: ImpersonationConfig{Enabled: true}},
+		{name: "enabled in gateway mode", impersonation: ImpersonationConfig{Enabled: true}, expectError: "impersonation requires auth.mode"},
+		{name: "negative ttl", mode: AuthModeJWT, impersonation: ImpersonationConfig{TokenTTL: -time.Minute}, expectError: "must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database:      DatabaseConfig{Host: "localhost"},
+				JWT:           JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				Auth:          AuthConfig{Mode: tt.mode},
+				Impersonation: tt.impersonation,
+				Gateway:       GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, DefaultImpersonationTokenTTL, (&ImpersonationConfig{}).GetTokenTTL())
+}
+
+func TestValidate_Audit(t *testing.T) {
+	tests := []struct {
+		name         string
+		audit        AuditConfig
+		mongoEnabled bool
+		expectError  string
+	}{
+		{name: "default sink"},
+		{name: "database sink", audit: AuditConfig{Sink: "database"}},
+		{name: "mongodb sink", audit: AuditConfig{Sink: "MongoDB", Collection: "events"}, mongoEnabled: true},
+		{name: "mongodb sink without mongodb", audit: AuditConfig{Sink: "mongodb"}, expectError: "requires mongodb.enabled"},
+		{name: "unknown sink", audit: AuditConfig{Sink: "file"}, expectError: "audit.sink must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Database: DatabaseConfig{Host: "localhost"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				MongoDB:  MongoDBConfig{Enabled: tt.mongoEnabled},
+				Audit:    tt.audit,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, AuditSinkDatabase, (&AuditConfig{}).GetSink())
+	assert.Equal(t, DefaultAuditCollection, (&AuditConfig{}).GetCollection())
+}
+
+func TestValidate_OIDC(t *testing.T) {
+	google := OIDCProviderConfig{Name: "google", Issuer: "https://accounts.google.com", ClientID: "client", RedirectURL: "http://localhost:8080/api/v1/auth/oidc/google/callback"}
+
+	tests := []struct {
+		name        string
+		environment string
+		oidc        OIDCConfig
+		expectError string
+	}{
+		{name: "disabled", oidc: OIDCConfig{Providers: []OIDCProviderConfig{{Name: "Not Valid"}}}},
+		{name: "insecure cookie in development", environment: "development", oidc: OIDCConfig{InsecureCookie: true}},
+		{name: "insecure cookie in production", environment: "production", oidc: OIDCConfig{InsecureCookie: true}, expectError: "oidc.insecure_cookie cannot be enabled in production"},
+		{name: "enabled with provider", oidc: OIDCConfig{Enabled: true, StateTTL: 5 * time.Minute, Providers: []OIDCProviderConfig{google}}},
+		{name: "negative state ttl", oidc: OIDCConfig{StateTTL: -time.Minute}, expectError: "must be non-negative"},
+		{name: "invalid name", oidc: OIDCConfig{Enabled: true, Providers: []OIDCProviderConfig{{Name: "Google", Issuer: google.Issuer, ClientID: "client", RedirectURL: google.RedirectURL}}}, expectError: "lowercase letters"},
+		{name: "duplicate name", oidc: OIDCConfig{Enabled: true, Providers: []OIDCProviderConfig{google, google}}, expectError: "already in use"},
+		{name: "missing client id", oidc: OIDCConfig{Enabled: true, Providers: []OIDCProviderConfig{{Name: "google", Issuer: google.Issuer, RedirectURL: google.RedirectURL}}}, expectError: "client_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				App:      AppConfig{Environment: tt.environment},
+				Database: DatabaseConfig{Host: "localhost", Password: "secret", SSLMode: "require"},
+				JWT:      JWTConfig{Secret: "hKLmNpQrStUvWxYzABCDEFGHIJKLMNOP"},
+				OIDC:     tt.oidc,
+				Gateway:  GatewayConfig{SigningSecret: testGatewaySecret},
+			}
+
+			err := cfg.Validate()
+			if tt.expectError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -2,18 +2,172 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 )
 
+var oidcProviderNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
 func (c *Config) Validate() error {
-	if c.JWT.Secret == "" {
-		return fmt.Errorf("JWT_SECRET environment variable is required - generate with: make generate-jwt-secret")
+	switch strings.ToUpper(c.JWT.Algorithm) {
+	case "", "HS256", "RS256", "EDDSA", "ED25519":
+	default:
+		return fmt.Errorf("jwt.algorithm must be one of HS256, RS256, EdDSA (current: %s)", c.JWT.Algorithm)
+	}
+
+	if c.JWT.IsAsymmetric() {
+		if c.JWT.PrivateKeyPath == "" {
+			return fmt.Errorf("jwt.private_key_path is required when jwt.algorithm is %s", c.JWT.Algorithm)
+		}
+	} else {
+		if c.JWT.Secret == "" {
+			return fmt.Errorf("JWT_SECRET environment variable is required - generate with: make generate-jwt-secret")
+		}
+
+		if len(c.JWT.Secret) < 32 {
+			return fmt.Errorf(
+				"JWT_SECRET must be at least 32 characters (current: %d)\nGenerate secure secret: make generate-jwt-secret",
+				len(c.JWT.Secret),
+			)
+		}
+	}
+
+	if c.JWT.Leeway < 0 {
+		return fmt.Errorf("jwt.leeway must be non-negative")
+	}
+
+	if err := c.JWT.validateRetiredKeys(); err != nil {
+		return err
+	}
+
+	switch c.Auth.GetMode() {
+	case AuthModeGateway, AuthModeJWT, AuthModeBoth:
+	default:
+		return fmt.Errorf("auth.mode must be one of gateway, jwt, both (current: %s)", c.Auth.Mode)
+	}
+
+	if c.TokenCleanup.Enabled {
+		if c.TokenCleanup.Interval <= 0 {
+			return fmt.Errorf("token_cleanup.interval must be positive")
+		}
+		if c.TokenCleanup.BatchSize <= 0 {
+			return fmt.Errorf("token_cleanup.batch_size must be positive")
+		}
+		if c.TokenCleanup.Retention < 0 {
+			return fmt.Errorf("token_cleanup.retention must be non-negative")
+		}
+	}
+
+	switch c.Mail.GetDriver() {
+	case MailDriverLog:
+	case MailDriverFile:
+		if c.Mail.FileDir == "" {
+			return fmt.Errorf("mail.file_dir is required when mail.driver is file")
+		}
+	default:
+		return fmt.Errorf("mail.driver must be one of log, file (current: %s)", c.Mail.Driver)
+	}
+
+	if c.PasswordReset.TokenTTL < 0 {
+		return fmt.Errorf("password_reset.token_ttl must be non-negative")
 	}
 
-	if len(c.JWT.Secret) < 32 {
-		return fmt.Errorf(
-			"JWT_SECRET must be at least 32 characters (current: %d)\nGenerate secure secret: make generate-jwt-secret",
-			len(c.JWT.Secret),
-		)
+	switch c.EmailVerification.GetPolicy() {
+	case EmailVerificationPolicyNone, EmailVerificationPolicyBlockLogin, EmailVerificationPolicyRestrictRoles:
+	default:
+		return fmt.Errorf("email_verification.policy must be one of none, block_login, restrict_roles (current: %s)", c.EmailVerification.Policy)
+	}
+	if c.EmailVerification.GetPolicy() != EmailVerificationPolicyNone && !c.EmailVerification.Enabled {
+		return fmt.Errorf("email_verification.enabled must be true when email_verification.policy is %s", c.EmailVerification.Policy)
+	}
+	if c.EmailVerification.TokenTTL < 0 {
+		return fmt.Errorf("email_verification.token_ttl must be non-negative")
+	}
+
+	if c.PasswordPolicy.MinLength < 0 || c.PasswordPolicy.MaxLength < 0 {
+		return fmt.Errorf("password_policy.min_length and password_policy.max_length must be non-negative")
+	}
+	if c.PasswordPolicy.GetMaxLength() > BcryptMaxPasswordBytes {
+		return fmt.Errorf("password_policy.max_length must not exceed %d bytes (bcrypt limit)", BcryptMaxPasswordBytes)
+	}
+	if c.PasswordPolicy.GetMinLength() > c.PasswordPolicy.GetMaxLength() {
+		return fmt.Errorf("password_policy.min_length must not exceed password_policy.max_length")
+	}
+
+	switch c.PasswordHash.GetAlgorithm() {
+	case PasswordHashBcrypt, PasswordHashArgon2id:
+	default:
+		return fmt.Errorf("password_hash.algorithm must be one of bcrypt, argon2id (current: %s)", c.PasswordHash.Algorithm)
+	}
+	// bcrypt 允许的 cost 范围为 4-31
+	if c.PasswordHash.BcryptCost != 0 && (c.PasswordHash.BcryptCost < 4 || c.PasswordHash.BcryptCost > 31) {
+		return fmt.Errorf("password_hash.bcrypt_cost must be between 4 and 31")
+	}
+	if c.PasswordHash.Argon2Memory < 0 || c.PasswordHash.Argon2Iterations < 0 || c.PasswordHash.Argon2Parallelism < 0 {
+		return fmt.Errorf("password_hash argon2 parameters must be non-negative")
+	}
+	if c.PasswordHash.GetArgon2Parallelism() > 255 {
+		return fmt.Errorf("password_hash.argon2_parallelism must not exceed 255")
+	}
+	if c.PasswordHash.GetArgon2Memory() < 8*c.PasswordHash.GetArgon2Parallelism() {
+		return fmt.Errorf("password_hash.argon2_memory must be at least 8 KiB per unit of parallelism")
+	}
+
+	if c.LoginProtection.MaxAttempts < 0 || c.LoginProtection.IPMaxAttempts < 0 {
+		return fmt.Errorf("login_protection.max_attempts and login_protection.ip_max_attempts must be non-negative")
+	}
+	if c.LoginProtection.Window < 0 || c.LoginProtection.LockDuration < 0 || c.LoginProtection.BaseDelay < 0 || c.LoginProtection.MaxDelay < 0 {
+		return fmt.Errorf("login_protection durations must be non-negative")
+	}
+	if c.LoginProtection.GetBaseDelay() > c.LoginProtection.GetMaxDelay() {
+		return fmt.Errorf("login_protection.base_delay must not exceed login_protection.max_delay")
+	}
+
+	if c.MFA.ChallengeTTL < 0 || c.MFA.MaxAttempts < 0 {
+		return fmt.Errorf("mfa.challenge_ttl and mfa.max_attempts must be non-negative")
+	}
+
+	if err := c.OIDC.validate(); err != nil {
+		return err
+	}
+
+	if c.APIKeys.MaxPerUser < 0 || c.APIKeys.MaxTTL < 0 {
+		return fmt.Errorf("api_keys.max_per_user and api_keys.max_ttl must be non-negative")
+	}
+
+	if c.OAuth.TokenTTL < 0 {
+		return fmt.Errorf("oauth.token_ttl must be non-negative")
+	}
+
+	if c.Gateway.SigningSecret != "" && len(c.Gateway.SigningSecret) < 32 {
+		return fmt.Errorf("gateway.signing_secret must be at least 32 characters (current: %d)", len(c.Gateway.SigningSecret))
+	}
+
+	if c.Gateway.MaxClockSkew < 0 {
+		return fmt.Errorf("gateway.max_clock_skew must be non-negative")
+	}
+
+	if _, err := c.Gateway.ParseTrustedProxies(); err != nil {
+		return fmt.Errorf("gateway.trusted_proxies: %w", err)
+	}
+
+	if c.Impersonation.TokenTTL < 0 {
+		return fmt.Errorf("impersonation.token_ttl must be non-negative")
+	}
+
+	// WHY: 模拟令牌是 JWT，网关模式下服务只信任网关头，act 声明无法传递
+	if c.Impersonation.Enabled && c.Auth.GetMode() == AuthModeGateway {
+		return fmt.Errorf("impersonation requires auth.mode %q or %q", AuthModeJWT, AuthModeBoth)
+	}
+
+	switch c.Audit.GetSink() {
+	case AuditSinkDatabase:
+	case AuditSinkMongoDB:
+		if !c.MongoDB.Enabled {
+			return fmt.Errorf("audit.sink %q requires mongodb.enabled", AuditSinkMongoDB)
+		}
+	default:
+		return fmt.Errorf("audit.sink must be one of database, mongodb (current: %s)", c.Audit.Sink)
 	}
 
 	if c.Database.Host == "" {
@@ -48,7 +202,83 @@ func (c *Config) Validate() error {
 		if c.Database.SSLMode == "disable" {
 			return fmt.Errorf("database SSL mode cannot be 'disable' in production")
 		}
+
+		if c.OIDC.InsecureCookie {
+			return fmt.Errorf("oidc.insecure_cookie cannot be enabled in production")
+		}
+	}
+
+	// WHY: 未签名的网关头可被任何能直连服务的人伪造成管理员；both 模式在缺少 Authorization 时同样信任网关头
+	if c.Auth.GetMode() != AuthModeJWT && c.Gateway.SigningSecret == "" {
+		return fmt.Errorf("gateway.signing_secret is required when auth.mode is %s - generate with: make generate-gateway-secret", c.Auth.GetMode())
+	}
+
+	return nil
+}
+
+// validateRetiredKeys checks that every retired key can be loaded and is uniquely identified
+func (j *JWTConfig) validateRetiredKeys() error {
+	seen := make(map[string]bool)
+	if j.KeyID != "" {
+		seen[j.KeyID] = true
+	} else if !j.IsAsymmetric() {
+		seen["default"] = true
 	}
 
+	for i := range j.RetiredKeys {
+		key := &j.RetiredKeys[i]
+		if key.KeyID == "" {
+			return fmt.Errorf("jwt.retired_keys[%d].key_id is required", i)
+		}
+		if seen[key.KeyID] {
+			return fmt.Errorf("jwt.retired_keys[%d].key_id %q is already in use", i, key.KeyID)
+		}
+		seen[key.KeyID] = true
+
+		switch strings.ToUpper(key.Algorithm) {
+		case "", "HS256", "RS256", "EDDSA", "ED25519":
+		default:
+			return fmt.Errorf("jwt.retired_keys[%d].algorithm must be one of HS256, RS256, EdDSA (current: %s)", i, key.Algorithm)
+		}
+
+		if key.IsAsymmetric() && key.PublicKeyPath == "" {
+			return fmt.Errorf("jwt.retired_keys[%d].public_key_path is required when algorithm is %s", i, key.Algorithm)
+		}
+		if !key.IsAsymmetric() && key.Secret == "" {
+			return fmt.Errorf("jwt.retired_keys[%d].secret is required for HS256", i)
+		}
+
+		if _, err := key.GetAcceptUntil(); err != nil {
+			return fmt.Errorf("jwt.retired_keys[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that every OIDC provider can start a login and is uniquely named
+func (o *OIDCConfig) validate() error {
+	if o.StateTTL < 0 {
+		return fmt.Errorf("oidc.state_ttl must be non-negative")
+	}
+	if !o.Enabled {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for i := range o.Providers {
+		provider := &o.Providers[i]
+		if !oidcProviderNamePattern.MatchString(provider.Name) {
+			return fmt.Errorf("oidc.providers[%d].name must contain only lowercase letters, digits, - and _ (current: %q)", i, provider.Name)
+		}
+		if seen[provider.Name] {
+			return fmt.Errorf("oidc.providers[%d].name %q is already in use", i, provider.Name)
+		}
+		seen[provider.Name] = true
+
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return fmt.Errorf("oidc.providers[%d]: issuer, client_id and redirect_url are required", i)
+		}
+	}
 	return nil
 }
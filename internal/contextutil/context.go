@@ -3,24 +3,59 @@ package contextutil
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
 )
 
 // Context keys
 const (
-	UserIDKey   = "user_id"
-	UserRoleKey = "user_role"
+	UserIDKey    = "user_id"
+	UserRoleKey  = "user_role"
+	UserRolesKey = "user_roles"
+	APIKeyIDKey  = "api_key_id"
+	ScopesKey    = "scopes"
+	ClientIDKey  = "client_id"
+	ActorIDKey   = "actor_id"
 )
 
+// GetUser 从上下文获取 JWT 声明（仅 JWT 认证模式下存在）
+// 返回 nil 表示未找到
+func GetUser(c *gin.Context) *auth.Claims {
+	value, exists := c.Get(auth.KeyUser)
+	if !exists {
+		return nil
+	}
+
+	claims, ok := value.(*auth.Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
+// MustGetUser 获取 JWT 声明或返回错误
+func MustGetUser(c *gin.Context) (*auth.Claims, error) {
+	claims := GetUser(c)
+	if claims == nil {
+		return nil, fmt.Errorf("user not found in context")
+	}
+	return claims, nil
+}
+
 // GetUserID 从上下文获取用户 ID
 // 返回 0 表示未找到
 func GetUserID(c *gin.Context) uint {
 	userIDValue, exists := c.Get(UserIDKey)
 	if !exists {
+		if claims := GetUser(c); claims != nil {
+			return claims.UserID
+		}
 		return 0
 	}
-	
+
 	switch v := userIDValue.(type) {
 	case uint:
 		return v
@@ -46,14 +81,35 @@ func MustGetUserID(c *gin.Context) (uint, error) {
 	return userID, nil
 }
 
-// GetUserRole 从上下文获取用户角色
+// GetEmail 从 JWT 声明获取用户邮箱
+// 返回空字符串表示未找到
+func GetEmail(c *gin.Context) string {
+	if claims := GetUser(c); claims != nil {
+		return claims.Email
+	}
+	return ""
+}
+
+// GetUserName 从 JWT 声明获取用户名
+// 返回空字符串表示未找到
+func GetUserName(c *gin.Context) string {
+	if claims := GetUser(c); claims != nil {
+		return claims.Name
+	}
+	return ""
+}
+
+// GetUserRole 从上下文获取用户的主角色（见 PrimaryRole）
 // 返回空字符串表示未找到
 func GetUserRole(c *gin.Context) string {
 	roleValue, exists := c.Get(UserRoleKey)
 	if !exists {
+		if claims := GetUser(c); claims != nil {
+			return PrimaryRole(claims.Roles)
+		}
 		return ""
 	}
-	
+
 	if role, ok := roleValue.(string); ok {
 		return role
 	}
@@ -83,10 +139,14 @@ func CanAccessUser(c *gin.Context, targetUserID uint) bool {
 	return authenticatedUserID == targetUserID
 }
 
-// HasRole 检查用户是否具有特定角色
+// HasRole 检查用户的角色集合中是否包含指定角色（不区分大小写）
 func HasRole(c *gin.Context, role string) bool {
-	userRole := GetUserRole(c)
-	return userRole == role
+	for _, userRole := range GetRoles(c) {
+		if strings.EqualFold(userRole, role) {
+			return true
+		}
+	}
+	return false
 }
 
 // IsAdmin 检查用户是否是管理员
@@ -94,13 +154,37 @@ func IsAdmin(c *gin.Context) bool {
 	return HasRole(c, "admin")
 }
 
-// GetRoles 获取用户角色列表（网关模式下只有一个角色）
+// GetRoles 获取用户的全部角色
+// 优先使用认证中间件写入的角色集合，其次是单个角色，最后是 JWT 声明中的角色
 func GetRoles(c *gin.Context) []string {
-	role := GetUserRole(c)
-	if role == "" {
-		return []string{}
+	if value, ok := c.Get(UserRolesKey); ok {
+		if roles, ok := value.([]string); ok {
+			return roles
+		}
+	}
+	if value, ok := c.Get(UserRoleKey); ok {
+		if role, ok := value.(string); ok && role != "" {
+			return []string{role}
+		}
+	}
+	if claims := GetUser(c); claims != nil && claims.Roles != nil {
+		return claims.Roles
+	}
+	return []string{}
+}
+
+// PrimaryRole 从角色列表中选出主角色
+// 管理员角色优先，其次取第一个角色，列表为空时返回空字符串
+func PrimaryRole(roles []string) string {
+	for _, role := range roles {
+		if strings.EqualFold(role, "admin") {
+			return "admin"
+		}
+	}
+	if len(roles) > 0 {
+		return roles[0]
 	}
-	return []string{role}
+	return ""
 }
 
 // SetUserID 设置用户 ID 到上下文
@@ -112,3 +196,85 @@ func SetUserID(c *gin.Context, userID uint) {
 func SetUserRole(c *gin.Context, role string) {
 	c.Set(UserRoleKey, role)
 }
+
+// SetUserRoles 设置用户的角色集合到上下文，并将主角色写入 UserRoleKey
+func SetUserRoles(c *gin.Context, roles []string) {
+	c.Set(UserRolesKey, roles)
+	c.Set(UserRoleKey, PrimaryRole(roles))
+}
+
+// GetAPIKeyID 获取认证所用 API 密钥的 ID
+// 返回 0 表示请求不是通过 API 密钥认证的
+func GetAPIKeyID(c *gin.Context) uint {
+	if id, ok := c.Get(APIKeyIDKey); ok {
+		if keyID, ok := id.(uint); ok {
+			return keyID
+		}
+	}
+	return 0
+}
+
+// SetAPIKeyID 设置 API 密钥 ID 到上下文
+func SetAPIKeyID(c *gin.Context, keyID uint) {
+	c.Set(APIKeyIDKey, keyID)
+}
+
+// GetScopes 获取请求被授予的 scope
+// 返回 nil 表示请求不受 scope 限制（JWT 或网关认证的用户）
+func GetScopes(c *gin.Context) []string {
+	if value, ok := c.Get(ScopesKey); ok {
+		if scopes, ok := value.([]string); ok {
+			return scopes
+		}
+	}
+	return nil
+}
+
+// SetScopes 设置 scope 到上下文
+func SetScopes(c *gin.Context, scopes []string) {
+	c.Set(ScopesKey, scopes)
+}
+
+// GetClientID 获取通过客户端凭证令牌认证的服务客户端 ID
+// 返回空字符串表示请求不是服务客户端发起的
+func GetClientID(c *gin.Context) string {
+	return c.GetString(ClientIDKey)
+}
+
+// SetClientID 设置服务客户端 ID 到上下文
+func SetClientID(c *gin.Context, clientID string) {
+	c.Set(ClientIDKey, clientID)
+}
+
+// GetActorID 获取模拟会话中实际操作的管理员 ID（JWT act 声明）
+// 返回 0 表示请求不是模拟会话；此时 GetUserID 即为实际操作者
+func GetActorID(c *gin.Context) uint {
+	if value, ok := c.Get(ActorIDKey); ok {
+		if actorID, ok := value.(uint); ok {
+			return actorID
+		}
+	}
+	if claims := GetUser(c); claims.IsImpersonation() {
+		return claims.Actor.UserID
+	}
+	return 0
+}
+
+// SetActorID 设置模拟会话的管理员 ID 到上下文
+func SetActorID(c *gin.Context, actorID uint) {
+	c.Set(ActorIDKey, actorID)
+}
+
+// IsImpersonating 检查请求是否来自管理员模拟的会话
+func IsImpersonating(c *gin.Context) bool {
+	return GetActorID(c) != 0
+}
+
+// GetActingUserID 获取实际发起请求的用户 ID
+// 模拟会话返回管理员 ID，其他请求与 GetUserID（生效用户）相同
+func GetActingUserID(c *gin.Context) uint {
+	if actorID := GetActorID(c); actorID != 0 {
+		return actorID
+	}
+	return GetUserID(c)
+}
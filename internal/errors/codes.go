@@ -9,4 +9,5 @@ const (
 	CodeValidation      = "VALIDATION_ERROR"
 	CodeConflict        = "CONFLICT"
 	CodeTooManyRequests = "TOO_MANY_REQUESTS"
+	CodeAccountLocked   = "ACCOUNT_LOCKED"
 )
@@ -94,6 +94,19 @@ func TooManyRequests(ra int) *RateLimitError {
 	}
 }
 
+// AccountLocked creates a 429 error for logins blocked after too many failed attempts, with retry-after seconds.
+func AccountLocked(ra int) *RateLimitError {
+	return &RateLimitError{
+		APIError: APIError{
+			Code:    CodeAccountLocked,
+			Message: "Too many failed login attempts",
+			Details: fmt.Sprintf("Login is temporarily locked. Please try again in %s seconds.", strconv.Itoa(ra)),
+			Status:  http.StatusTooManyRequests,
+		},
+		RetryAfter: ra,
+	}
+}
+
 // ValidationError creates a validation error with field-level details.
 func ValidationError(details interface{}) *APIError {
 	return &APIError{
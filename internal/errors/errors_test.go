@@ -87,6 +87,17 @@ func TestTooManyRequests(t *testing.T) {
 	assert.Contains(t, err.Details, "60 seconds")
 }
 
+func TestAccountLocked(t *testing.T) {
+	err := AccountLocked(900)
+
+	assert.IsType(t, &RateLimitError{}, err)
+	assert.Equal(t, CodeAccountLocked, err.Code)
+	assert.Equal(t, "Too many failed login attempts", err.Message)
+	assert.Equal(t, http.StatusTooManyRequests, err.Status)
+	assert.Equal(t, 900, err.RetryAfter)
+	assert.Contains(t, err.Details, "900 seconds")
+}
+
 func TestValidationError(t *testing.T) {
 	details := map[string]string{
 		"email":    "Invalid email format",
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+const defaultFrom = "no-reply@localhost"
+
+// Message 一封待发送的纯文本邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender 邮件发送接口
+// 默认实现只写日志或本地文件，便于离线开发；接入 SMTP 或第三方服务时实现此接口即可
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender 根据配置创建邮件发送器
+func NewSender(cfg *config.MailConfig, logger *slog.Logger) Sender {
+	from := cfg.From
+	if from == "" {
+		from = defaultFrom
+	}
+	if cfg.GetDriver() == config.MailDriverFile {
+		return NewFileSender(cfg.FileDir, from)
+	}
+	return NewLogSender(logger, from)
+}
+
+// logSender 把邮件内容写入日志
+type logSender struct {
+	logger *slog.Logger
+	from   string
+}
+
+// NewLogSender 创建写日志的邮件发送器
+func NewLogSender(logger *slog.Logger, from string) Sender {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &logSender{logger: logger, from: from}
+}
+
+func (s *logSender) Send(ctx context.Context, msg Message) error {
+	s.logger.InfoContext(ctx, "Email sent", "from", s.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// fileSender 把每封邮件写成目录下的一个 .eml 文件
+type fileSender struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// NewFileSender 创建写本地文件的邮件发送器
+func NewFileSender(dir, from string) Sender {
+	return &fileSender{dir: dir, from: from, now: time.Now}
+}
+
+func (s *fileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	now := s.now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405Z"), uuid.NewString())
+	path := filepath.Join(s.dir, name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	// WHY: 邮件中含有一次性令牌，仅允许当前用户读取
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestLogSender_Send(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(slog.New(slog.NewTextHandler(&buf, nil)), "noreply@example.com")
+
+	err := sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "reset link"})
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "to=user@example.com")
+	assert.Contains(t, buf.String(), "reset link")
+}
+
+func TestFileSender_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	sender := NewSender(&config.MailConfig{Driver: config.MailDriverFile, FileDir: dir, From: "noreply@example.com"}, nil)
+
+	err := sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "reset link"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "From: noreply@example.com\r\n")
+	assert.Contains(t, string(content), "To: user@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nreset link")
+
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestNewSender_DefaultsToLog(t *testing.T) {
+	sender := NewSender(&config.MailConfig{}, nil)
+	_, ok := sender.(*logSender)
+	assert.True(t, ok)
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+// AuditContext 将请求来源（操作者、请求 ID、IP、User-Agent）附加到请求上下文，供服务层记录审计事件
+// 需放在 Logger 之后；操作者在记录事件时才解析，因此认证中间件可以在它之后执行
+func AuditContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithSourceFunc(c.Request.Context(), func() audit.Source {
+			source := audit.Source{
+				ActorID:   contextutil.GetActingUserID(c),
+				RequestID: c.GetString("request_id"),
+				IPAddress: c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+			}
+			// 模拟会话中由管理员操作，被模拟的用户记为 OnBehalfOf
+			if contextutil.IsImpersonating(c) {
+				source.OnBehalfOf = contextutil.GetUserID(c)
+			}
+			return source
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestAuditContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	regular, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+	impersonated, err := authService.GenerateImpersonationToken(context.Background(), auth.Actor{UserID: 1}, 7, "user@example.com", "User", time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		expected audit.Source
+	}{
+		{name: "anonymous", expected: audit.Source{RequestID: "req-1", IPAddress: "192.0.2.10", UserAgent: "test-agent"}},
+		{name: "regular session", token: regular, expected: audit.Source{ActorID: 7, RequestID: "req-1", IPAddress: "192.0.2.10", UserAgent: "test-agent"}},
+		{name: "impersonated session", token: impersonated, expected: audit.Source{ActorID: 1, OnBehalfOf: 7, RequestID: "req-1", IPAddress: "192.0.2.10", UserAgent: "test-agent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var source audit.Source
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set("request_id", "req-1") })
+			router.Use(AuditContext())
+			// 认证在 AuditContext 之后执行，来源在记录时才解析
+			router.Use(func(c *gin.Context) {
+				if c.GetHeader(auth.AuthorizationHeader) != "" {
+					JWTAuthMiddleware(authService, nil)(c)
+				}
+			})
+			router.GET("/resource", func(c *gin.Context) {
+				source = audit.SourceFromContext(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+			req.RemoteAddr = "192.0.2.10:1234"
+			req.Header.Set("User-Agent", "test-agent")
+			if tt.token != "" {
+				req.Header.Set(auth.AuthorizationHeader, "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expected, source)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+// NewAuthMiddleware 根据认证模式选择认证中间件
+// 所有模式都会填充相同的 contextutil 键，处理器无需关心认证来源
+// gateway 控制网关头的签名校验和可信代理，为 nil 时信任所有网关头
+// apiKeys 不为 nil 时，任何模式下都接受 Authorization: ApiKey <key>
+// auditor 记录管理员模拟会话发起的修改请求，为 nil 时只写日志
+func NewAuthMiddleware(mode string, gateway *config.GatewayConfig, authService auth.Service, apiKeys auth.APIKeyAuthenticator, auditor audit.Recorder) gin.HandlerFunc {
+	modeAuth := newModeAuthMiddleware(mode, gateway, authService, auditor)
+	if apiKeys == nil {
+		return modeAuth
+	}
+
+	apiKeyAuth := APIKeyAuthMiddleware(apiKeys)
+	return func(c *gin.Context) {
+		if isAPIKeyHeader(c.GetHeader(auth.AuthorizationHeader)) {
+			apiKeyAuth(c)
+			return
+		}
+		modeAuth(c)
+	}
+}
+
+func newModeAuthMiddleware(mode string, gateway *config.GatewayConfig, authService auth.Service, auditor audit.Recorder) gin.HandlerFunc {
+	switch mode {
+	case config.AuthModeJWT:
+		return JWTAuthMiddleware(authService, auditor)
+	case config.AuthModeBoth:
+		jwtAuth := JWTAuthMiddleware(authService, auditor)
+		gatewayAuth := GatewayAuthMiddleware(gateway)
+		return func(c *gin.Context) {
+			if c.GetHeader(auth.AuthorizationHeader) != "" {
+				jwtAuth(c)
+				return
+			}
+			gatewayAuth(c)
+		}
+	default:
+		return GatewayAuthMiddleware(gateway)
+	}
+}
+
+// JWTAuthMiddleware JWT 认证中间件
+// 验证 Bearer 令牌，并将声明和用户信息写入上下文
+// 角色只取自令牌：没有角色的账户（如 restrict_roles 策略下未验证邮箱的用户）不会获得默认角色
+// 模拟会话的请求结束后写日志，修改请求同时记录到 auditor（为 nil 时不记录）
+func JWTAuthMiddleware(authService auth.Service, auditor audit.Recorder) gin.HandlerFunc {
+	if auditor == nil {
+		auditor = audit.Discard
+	}
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader(auth.AuthorizationHeader)
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization header required",
+			})
+			c.Abort()
+			return
+		}
+
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid authorization header format",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.ValidateToken(parts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set(auth.KeyUser, claims)
+		contextutil.SetUserID(c, claims.UserID)
+		contextutil.SetUserRoles(c, claims.Roles)
+
+		if claims.IsImpersonation() {
+			contextutil.SetActorID(c, claims.Actor.UserID)
+			c.Next()
+			auditImpersonatedRequest(c, claims, auditor)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// APIKeyAuthMiddleware API 密钥认证中间件
+// 验证 ApiKey 凭证并按 scope 限制请求方法：read 允许 GET、HEAD、OPTIONS，其余方法需要 write
+func APIKeyAuthMiddleware(apiKeys auth.APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader(auth.AuthorizationHeader)
+		if !isAPIKeyHeader(authHeader) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid authorization header format",
+			})
+			c.Abort()
+			return
+		}
+
+		ctx := auth.WithClientInfo(c.Request.Context(), c.Request.UserAgent(), c.ClientIP())
+		principal, err := apiKeys.AuthenticateAPIKey(ctx, strings.TrimSpace(authHeader[len(auth.APIKeyScheme)+1:]))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired API key",
+			})
+			c.Abort()
+			return
+		}
+
+		requiredScope := apiKeyScopeForMethod(c.Request.Method)
+		if !principal.HasScope(requiredScope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API key lacks the " + requiredScope + " scope",
+			})
+			c.Abort()
+			return
+		}
+
+		contextutil.SetUserID(c, principal.UserID)
+		contextutil.SetUserRoles(c, principal.Roles)
+		contextutil.SetAPIKeyID(c, principal.KeyID)
+		contextutil.SetScopes(c, principal.Scopes)
+
+		c.Next()
+	}
+}
+
+// isAPIKeyHeader 判断 Authorization 头是否使用 ApiKey 方案
+func isAPIKeyHeader(authHeader string) bool {
+	return len(authHeader) > len(auth.APIKeyScheme) &&
+		authHeader[len(auth.APIKeyScheme)] == ' ' &&
+		strings.EqualFold(authHeader[:len(auth.APIKeyScheme)], auth.APIKeyScheme)
+}
+
+// apiKeyScopeForMethod 返回请求方法所需的 API 密钥 scope
+func apiKeyScopeForMethod(method string) string {
+	if isReadMethod(method) {
+		return auth.APIKeyScopeRead
+	}
+	return auth.APIKeyScopeWrite
+}
+
+// isReadMethod 判断请求方法是否只读取数据
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// rolesOrDefault 返回写入上下文的角色集合，没有角色时使用默认角色
+// 仅用于网关头：网关是身份来源，未传角色头时沿用默认角色；JWT 和 API 密钥的角色来自数据库，不做补全
+func rolesOrDefault(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{"user"}
+	}
+	return roles
+}
@@ -0,0 +1,424 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+const testGatewaySecret = "gateway-signing-secret-32-chars!"
+
+// testGateway is the gateway configuration of tests that send signed identity headers
+var testGateway = &config.GatewayConfig{SigningSecret: testGatewaySecret}
+
+// signGatewayHeaders signs the identity headers of req for its method and path, as the gateway would
+func signGatewayHeaders(req *http.Request) {
+	timestamp := time.Now().Unix()
+	roles := strings.Join(req.Header.Values(HeaderUserRole), ",")
+	req.Header.Set(HeaderUserTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderUserSignature, GatewaySignature(testGatewaySecret, req.Method, req.URL.Path, req.Header.Get(HeaderUserID), roles, timestamp))
+}
+
+func TestNewAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	token, err := authService.GenerateToken(7, "jwt@example.com", "JWT User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		mode           string
+		headers        map[string]string
+		unsigned       bool
+		expectedStatus int
+		expectedUserID uint
+		expectedRole   string
+	}{
+		{
+			name:           "gateway mode accepts identity headers",
+			mode:           config.AuthModeGateway,
+			headers:        map[string]string{HeaderUserID: "42", HeaderUserRole: "admin"},
+			expectedStatus: http.StatusOK,
+			expectedUserID: 42,
+			expectedRole:   "admin",
+		},
+		{
+			name:           "gateway mode rejects unsigned identity headers",
+			mode:           config.AuthModeGateway,
+			headers:        map[string]string{HeaderUserID: "42", HeaderUserRole: "admin"},
+			unsigned:       true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "gateway mode ignores bearer token",
+			mode:           config.AuthModeGateway,
+			headers:        map[string]string{auth.AuthorizationHeader: "Bearer " + token},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "jwt mode accepts bearer token without default role",
+			mode:           config.AuthModeJWT,
+			headers:        map[string]string{auth.AuthorizationHeader: "Bearer " + token},
+			expectedStatus: http.StatusOK,
+			expectedUserID: 7,
+			expectedRole:   "",
+		},
+		{
+			name:           "jwt mode rejects gateway headers",
+			mode:           config.AuthModeJWT,
+			headers:        map[string]string{HeaderUserID: "42", HeaderUserRole: "admin"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "jwt mode rejects invalid token",
+			mode:           config.AuthModeJWT,
+			headers:        map[string]string{auth.AuthorizationHeader: "Bearer invalid"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "both mode prefers bearer token",
+			mode:           config.AuthModeBoth,
+			headers:        map[string]string{auth.AuthorizationHeader: "Bearer " + token, HeaderUserID: "42"},
+			expectedStatus: http.StatusOK,
+			expectedUserID: 7,
+			expectedRole:   "",
+		},
+		{
+			name:           "both mode falls back to gateway headers",
+			mode:           config.AuthModeBoth,
+			headers:        map[string]string{HeaderUserID: "42"},
+			expectedStatus: http.StatusOK,
+			expectedUserID: 42,
+			expectedRole:   "user",
+		},
+		{
+			name:           "both mode rejects unsigned gateway headers without bearer token",
+			mode:           config.AuthModeBoth,
+			headers:        map[string]string{HeaderUserID: "1", HeaderUserRole: "admin"},
+			unsigned:       true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "both mode rejects invalid token without fallback",
+			mode:           config.AuthModeBoth,
+			headers:        map[string]string{auth.AuthorizationHeader: "Bearer invalid", HeaderUserID: "42"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID uint
+			var gotRole string
+
+			router := gin.New()
+			router.Use(NewAuthMiddleware(tt.mode, testGateway, authService, nil, nil))
+			router.GET("/test", func(c *gin.Context) {
+				gotUserID = contextutil.GetUserID(c)
+				gotRole = contextutil.GetUserRole(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if req.Header.Get(HeaderUserID) != "" && !tt.unsigned {
+				signGatewayHeaders(req)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedUserID, gotUserID)
+				assert.Equal(t, tt.expectedRole, gotRole)
+			}
+		})
+	}
+}
+
+// stubAPIKeys accepts a single key with the given scopes
+type stubAPIKeys struct {
+	scopes []string
+}
+
+func (s stubAPIKeys) AuthenticateAPIKey(_ context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	if key != "sk_valid" {
+		return nil, errors.New("invalid api key")
+	}
+	return &auth.APIKeyPrincipal{KeyID: 5, UserID: 9, Roles: []string{"user", "admin"}, Scopes: s.scopes}, nil
+}
+
+func TestNewAuthMiddleware_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	token, err := authService.GenerateToken(7, "jwt@example.com", "JWT User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		mode           string
+		apiKeys        auth.APIKeyAuthenticator
+		method         string
+		authorization  string
+		expectedStatus int
+		expectedUserID uint
+	}{
+		{name: "read key on GET", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{scopes: []string{"read"}}, method: http.MethodGet, authorization: "ApiKey sk_valid", expectedStatus: http.StatusOK, expectedUserID: 9},
+		{name: "read key on POST", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{scopes: []string{"read"}}, method: http.MethodPost, authorization: "ApiKey sk_valid", expectedStatus: http.StatusForbidden},
+		{name: "write key on POST", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{scopes: []string{"write"}}, method: http.MethodPost, authorization: "ApiKey sk_valid", expectedStatus: http.StatusOK, expectedUserID: 9},
+		{name: "write key on GET", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{scopes: []string{"write"}}, method: http.MethodGet, authorization: "ApiKey sk_valid", expectedStatus: http.StatusForbidden},
+		{name: "scheme is case-insensitive", mode: config.AuthModeBoth, apiKeys: stubAPIKeys{scopes: []string{"read"}}, method: http.MethodGet, authorization: "apikey sk_valid", expectedStatus: http.StatusOK, expectedUserID: 9},
+		{name: "gateway mode accepts api keys", mode: config.AuthModeGateway, apiKeys: stubAPIKeys{scopes: []string{"read"}}, method: http.MethodGet, authorization: "ApiKey sk_valid", expectedStatus: http.StatusOK, expectedUserID: 9},
+		{name: "invalid key", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{scopes: []string{"read"}}, method: http.MethodGet, authorization: "ApiKey sk_invalid", expectedStatus: http.StatusUnauthorized},
+		{name: "bearer token still accepted", mode: config.AuthModeJWT, apiKeys: stubAPIKeys{}, method: http.MethodPost, authorization: "Bearer " + token, expectedStatus: http.StatusOK, expectedUserID: 7},
+		{name: "api keys disabled", mode: config.AuthModeJWT, apiKeys: nil, method: http.MethodGet, authorization: "ApiKey sk_valid", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID, gotKeyID uint
+			var gotRole string
+
+			router := gin.New()
+			router.Use(NewAuthMiddleware(tt.mode, nil, authService, tt.apiKeys, nil))
+			router.Handle(tt.method, "/test", func(c *gin.Context) {
+				gotUserID = contextutil.GetUserID(c)
+				gotRole = contextutil.GetUserRole(c)
+				gotKeyID = contextutil.GetAPIKeyID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			req.Header.Set(auth.AuthorizationHeader, tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedUserID, gotUserID)
+				if tt.expectedUserID == 9 {
+					assert.Equal(t, "admin", gotRole)
+					assert.Equal(t, uint(5), gotKeyID)
+				} else {
+					assert.Zero(t, gotKeyID)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayAuthMiddleware_MultipleRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		roleHeaders   []string
+		expectedRoles []string
+		expectedRole  string
+	}{
+		{name: "missing header uses default role", expectedRoles: []string{"user"}, expectedRole: "user"},
+		{name: "single role", roleHeaders: []string{"editor"}, expectedRoles: []string{"editor"}, expectedRole: "editor"},
+		{name: "comma separated", roleHeaders: []string{"user, editor,admin"}, expectedRoles: []string{"user", "editor", "admin"}, expectedRole: "admin"},
+		{name: "repeated headers", roleHeaders: []string{"user", "editor"}, expectedRoles: []string{"user", "editor"}, expectedRole: "user"},
+		{name: "duplicates and blanks dropped", roleHeaders: []string{"editor,,Editor", " ", "viewer"}, expectedRoles: []string{"editor", "viewer"}, expectedRole: "editor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRoles []string
+			var gotRole string
+
+			router := gin.New()
+			router.Use(GatewayAuthMiddleware(testGateway))
+			router.GET("/test", func(c *gin.Context) {
+				gotRoles = contextutil.GetRoles(c)
+				gotRole = contextutil.GetUserRole(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(HeaderUserID, "42")
+			for _, value := range tt.roleHeaders {
+				req.Header.Add(HeaderUserRole, value)
+			}
+			signGatewayHeaders(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedRoles, gotRoles)
+			assert.Equal(t, tt.expectedRole, gotRole)
+		})
+	}
+}
+
+// stubTokens accepts a single bearer token carrying the given roles
+type stubTokens struct {
+	auth.Service
+	roles []string
+}
+
+func (s stubTokens) ValidateToken(token string) (*auth.Claims, error) {
+	if token != "valid" {
+		return nil, auth.ErrInvalidToken
+	}
+	return &auth.Claims{UserID: 7, Roles: s.roles}, nil
+}
+
+func TestJWTAuthMiddleware_MultipleRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+		expectedRoles  []string
+	}{
+		{name: "required role among several", roles: []string{"user", "editor"}, expectedStatus: http.StatusOK, expectedRoles: []string{"user", "editor"}},
+		{name: "required role missing", roles: []string{"user", "viewer"}, expectedStatus: http.StatusForbidden},
+		{name: "no roles uses default role", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRoles []string
+
+			router := gin.New()
+			router.Use(JWTAuthMiddleware(stubTokens{roles: tt.roles}, nil))
+			router.GET("/test", RequireRole("editor"), func(c *gin.Context) {
+				gotRoles = contextutil.GetRoles(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(auth.AuthorizationHeader, "Bearer valid")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedRoles, gotRoles)
+		})
+	}
+}
+
+func TestGatewayAuthMiddleware_Signature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const secret = testGatewaySecret
+	now := time.Now().Unix()
+	cfg := &config.GatewayConfig{SigningSecret: secret, MaxClockSkew: 30 * time.Second}
+	proxied := &config.GatewayConfig{SigningSecret: secret, TrustedProxies: []string{"10.0.0.0/8"}}
+
+	signedFor := func(method, path, userID, roles string, timestamp int64) map[string]string {
+		return map[string]string{
+			HeaderUserID:        userID,
+			HeaderUserRole:      roles,
+			HeaderUserTimestamp: strconv.FormatInt(timestamp, 10),
+			HeaderUserSignature: GatewaySignature(secret, method, path, userID, roles, timestamp),
+		}
+	}
+	signed := func(userID, roles string, timestamp int64) map[string]string {
+		return signedFor(http.MethodGet, "/test", userID, roles, timestamp)
+	}
+
+	tests := []struct {
+		name           string
+		cfg            *config.GatewayConfig
+		headers        map[string]string
+		remoteAddr     string
+		expectedStatus int
+		expectedError  string
+	}{
+		{name: "valid signature", cfg: cfg, headers: signed("42", "user,admin", now), expectedStatus: http.StatusOK},
+		{name: "unsigned headers", cfg: cfg, headers: map[string]string{HeaderUserID: "42", HeaderUserRole: "admin"}, expectedStatus: http.StatusUnauthorized, expectedError: "missing gateway signature"},
+		{
+			name: "forged role", cfg: cfg,
+			headers:        func() map[string]string { h := signed("42", "user", now); h[HeaderUserRole] = "admin"; return h }(),
+			expectedStatus: http.StatusUnauthorized, expectedError: "invalid gateway signature",
+		},
+		{
+			name: "forged user id", cfg: cfg,
+			headers:        func() map[string]string { h := signed("42", "user", now); h[HeaderUserID] = "1"; return h }(),
+			expectedStatus: http.StatusUnauthorized, expectedError: "invalid gateway signature",
+		},
+		{name: "stale timestamp", cfg: cfg, headers: signed("42", "admin", now-60), expectedStatus: http.StatusUnauthorized, expectedError: "stale gateway signature"},
+		{name: "future timestamp", cfg: cfg, headers: signed("42", "admin", now+60), expectedStatus: http.StatusUnauthorized, expectedError: "stale gateway signature"},
+		{
+			name: "wrong secret", cfg: cfg,
+			headers: map[string]string{
+				HeaderUserID: "42", HeaderUserRole: "admin", HeaderUserTimestamp: strconv.FormatInt(now, 10),
+				HeaderUserSignature: GatewaySignature("another-secret-of-32-characters!", http.MethodGet, "/test", "42", "admin", now),
+			},
+			expectedStatus: http.StatusUnauthorized, expectedError: "invalid gateway signature",
+		},
+		{name: "replayed on another path", cfg: cfg, headers: signedFor(http.MethodGet, "/other", "42", "admin", now), expectedStatus: http.StatusUnauthorized, expectedError: "invalid gateway signature"},
+		{name: "replayed with another method", cfg: cfg, headers: signedFor(http.MethodDelete, "/test", "42", "admin", now), expectedStatus: http.StatusUnauthorized, expectedError: "invalid gateway signature"},
+		{name: "no signing secret", cfg: &config.GatewayConfig{}, headers: signed("42", "admin", now), expectedStatus: http.StatusUnauthorized, expectedError: "gateway signing secret not configured"},
+		{name: "nil config", headers: signed("42", "admin", now), expectedStatus: http.StatusUnauthorized, expectedError: "gateway signing secret not configured"},
+		{
+			name:           "trusted proxy",
+			cfg:            proxied,
+			headers:        signed("42", "", now),
+			remoteAddr:     "10.1.2.3:4567",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "untrusted proxy",
+			cfg:            proxied,
+			headers:        signed("42", "", now),
+			remoteAddr:     "203.0.113.7:4567",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "untrusted gateway",
+		},
+		{
+			name:           "untrusted proxy ignores forwarded headers",
+			cfg:            proxied,
+			headers:        func() map[string]string { h := signed("42", "", now); h["X-Forwarded-For"] = "10.1.2.3"; return h }(),
+			remoteAddr:     "203.0.113.7:4567",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "untrusted gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(GatewayAuthMiddleware(tt.cfg))
+			router.GET("/test", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+// ClientAuthMiddleware 服务客户端认证中间件
+// 验证 client_credentials 颁发的 Bearer 令牌，并将客户端 ID 和 scope 写入上下文
+// 用户令牌不能通过此中间件，客户端令牌也不能通过 JWTAuthMiddleware
+// SetupRouter 不挂载此中间件，供下游新增的服务间路由组使用
+func ClientAuthMiddleware(authService auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader(auth.AuthorizationHeader)
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization header required",
+			})
+			c.Abort()
+			return
+		}
+
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid authorization header format",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.ValidateClientToken(parts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		contextutil.SetClientID(c, claims.ClientID)
+		contextutil.SetScopes(c, claims.Scopes)
+
+		c.Next()
+	}
+}
+
+// RequireScope 要求请求被授予全部指定 scope 的中间件
+// 需放在 ClientAuthMiddleware 之后
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if contextutil.GetClientID(c) == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "client not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		granted := contextutil.GetScopes(c)
+		for _, required := range scopes {
+			if !containsString(granted, required) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "insufficient scope",
+					"scope": strings.Join(scopes, " "),
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
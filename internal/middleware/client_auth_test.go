@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	clientToken, err := authService.GenerateClientToken("svc_reports", []string{"users:read"}, time.Minute)
+	require.NoError(t, err)
+	userToken, err := authService.GenerateToken(7, "jwt@example.com", "JWT User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		authHeader     string
+		required       []string
+		expectedStatus int
+	}{
+		{name: "granted scope", authHeader: "Bearer " + clientToken, required: []string{"users:read"}, expectedStatus: http.StatusOK},
+		{name: "missing scope", authHeader: "Bearer " + clientToken, required: []string{"users:read", "users:write"}, expectedStatus: http.StatusForbidden},
+		{name: "user token", authHeader: "Bearer " + userToken, required: []string{"users:read"}, expectedStatus: http.StatusUnauthorized},
+		{name: "no credentials", required: []string{"users:read"}, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			var clientID string
+			router.GET("/reports", ClientAuthMiddleware(authService), RequireScope(tt.required...), func(c *gin.Context) {
+				clientID = contextutil.GetClientID(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			if tt.authHeader != "" {
+				req.Header.Set(auth.AuthorizationHeader, tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "svc_reports", clientID)
+			}
+		})
+	}
+}
@@ -1,18 +1,31 @@
 package middleware
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
 )
 
 const (
 	// HeaderUserID 用户 ID 头
 	HeaderUserID = "X-User-ID"
-	// HeaderUserRole 用户角色头
+	// HeaderUserRole 用户角色头，多个角色可用逗号分隔或重复该头
 	HeaderUserRole = "X-User-Role"
+	// HeaderUserTimestamp 网关签名时间（Unix 秒）
+	HeaderUserTimestamp = "X-User-Timestamp"
+	// HeaderUserSignature 网关对请求方法、路径、身份头和时间戳的 HMAC-SHA256 签名（十六进制）
+	HeaderUserSignature = "X-User-Signature"
 	// ContextKeyUserID 上下文中的用户 ID 键
 	ContextKeyUserID = "user_id"
 	// ContextKeyUserRole 上下文中的用户角色键
@@ -21,8 +34,32 @@ const (
 
 // GatewayAuthMiddleware 网关认证中间件
 // 从网关传递的 HTTP 头中提取用户信息
-func GatewayAuthMiddleware() gin.HandlerFunc {
+// 身份头必须带有效且未过期的签名，未配置签名密钥时拒绝所有请求；配置了可信代理时只接受来自这些地址的请求
+func GatewayAuthMiddleware(cfg *config.GatewayConfig) gin.HandlerFunc {
+	if cfg == nil {
+		cfg = &config.GatewayConfig{}
+	}
+	if cfg.SigningSecret == "" {
+		// 配置在加载时已校验；没有密钥就无法区分网关和伪造的身份头
+		slog.Error("Gateway signing secret is not configured, rejecting gateway requests")
+	}
+	trustedProxies, err := cfg.ParseTrustedProxies()
+	if err != nil {
+		// 配置在加载时已校验；直接构造的非法配置拒绝所有网关请求
+		slog.Error("Invalid gateway trusted proxies, rejecting gateway requests", "error", err)
+		trustedProxies = []*net.IPNet{}
+	}
+	restrictProxies := err != nil || len(trustedProxies) > 0
+
 	return func(c *gin.Context) {
+		if restrictProxies && !ipInNetworks(c.RemoteIP(), trustedProxies) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "untrusted gateway",
+			})
+			c.Abort()
+			return
+		}
+
 		userIDStr := c.GetHeader(HeaderUserID)
 		if userIDStr == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -41,19 +78,97 @@ func GatewayAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		userRole := c.GetHeader(HeaderUserRole)
-		if userRole == "" {
-			userRole = "user" // 默认角色
+		roleHeaders := c.Request.Header.Values(HeaderUserRole)
+		if message := verifyGatewaySignature(c, cfg, userIDStr, strings.Join(roleHeaders, ",")); message != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": message,
+			})
+			c.Abort()
+			return
 		}
 
 		// 将用户信息存储到上下文
-		c.Set(ContextKeyUserID, uint(userID))
-		c.Set(ContextKeyUserRole, userRole)
+		contextutil.SetUserID(c, uint(userID))
+		contextutil.SetUserRoles(c, rolesOrDefault(parseRoleHeaders(roleHeaders)))
 
 		c.Next()
 	}
 }
 
+// GatewaySignature 计算网关身份头签名
+// 签名内容为 "<方法>\n<路径>\n<X-User-ID>\n<X-User-Role>\n<X-User-Timestamp>"，路径为转发到本服务的路径（不含查询参数），
+// 多个 X-User-Role 头按出现顺序以逗号连接；包含方法和路径，截获的身份头不能在有效期内用于其他端点
+func GatewaySignature(secret, method, path, userID, roles string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + userID + "\n" + roles + "\n" + strconv.FormatInt(timestamp, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyGatewaySignature 校验签名和时间戳，失败时返回错误信息
+func verifyGatewaySignature(c *gin.Context, cfg *config.GatewayConfig, userID, roles string) string {
+	if cfg.SigningSecret == "" {
+		return "gateway signing secret not configured"
+	}
+
+	signature := c.GetHeader(HeaderUserSignature)
+	timestampStr := c.GetHeader(HeaderUserTimestamp)
+	if signature == "" || timestampStr == "" {
+		return "missing gateway signature"
+	}
+
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return "invalid gateway timestamp"
+	}
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > cfg.GetMaxClockSkew() {
+		return "stale gateway signature"
+	}
+
+	expected := GatewaySignature(cfg.SigningSecret, c.Request.Method, c.Request.URL.Path, userID, roles, timestamp)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return "invalid gateway signature"
+	}
+	return ""
+}
+
+// ipInNetworks 判断 IP 是否属于任一网络
+func ipInNetworks(ipStr string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRoleHeaders 解析角色头，支持逗号分隔和重复的头，去除空白和重复角色
+func parseRoleHeaders(values []string) []string {
+	roles := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		for _, role := range strings.Split(value, ",") {
+			role = strings.TrimSpace(role)
+			if role == "" {
+				continue
+			}
+			if _, ok := seen[strings.ToLower(role)]; ok {
+				continue
+			}
+			seen[strings.ToLower(role)] = struct{}{}
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
 // GetUserIDFromContext 从上下文获取用户 ID
 func GetUserIDFromContext(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get(ContextKeyUserID)
@@ -65,7 +180,7 @@ func GetUserIDFromContext(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
-// GetUserRoleFromContext 从上下文获取用户角色
+// GetUserRoleFromContext 从上下文获取用户的主角色，完整角色集合见 contextutil.GetRoles
 func GetUserRoleFromContext(c *gin.Context) (string, bool) {
 	role, exists := c.Get(ContextKeyUserRole)
 	if !exists {
@@ -76,36 +191,21 @@ func GetUserRoleFromContext(c *gin.Context) (string, bool) {
 	return roleStr, ok
 }
 
-// RequireRole 要求特定角色的中间件
+// RequireRole 要求用户至少拥有其中一个角色的中间件
+// 角色集合来自网关头或 JWT 声明，比较不区分大小写
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := GetUserRoleFromContext(c)
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "user role not found",
-			})
-			c.Abort()
-			return
-		}
-
-		// 检查用户角色是否在允许的角色列表中
-		hasRole := false
 		for _, role := range roles {
-			if strings.EqualFold(userRole, role) {
-				hasRole = true
-				break
+			if contextutil.HasRole(c, role) {
+				c.Next()
+				return
 			}
 		}
 
-		if !hasRole {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "insufficient permissions",
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "insufficient permissions",
+		})
+		c.Abort()
 	}
 }
 
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+func TestDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	regular, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+	impersonated, err := authService.GenerateImpersonationToken(context.Background(), auth.Actor{UserID: 1, Email: "admin@example.com"}, 7, "user@example.com", "User", time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		token           string
+		expectedStatus  int
+		expectedUserID  uint
+		expectedActorID uint
+	}{
+		{name: "regular session", token: regular, expectedStatus: http.StatusOK, expectedUserID: 7, expectedActorID: 7},
+		{name: "impersonated session", token: impersonated, expectedStatus: http.StatusForbidden, expectedUserID: 7, expectedActorID: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var effectiveID, actingID uint
+			router := gin.New()
+			router.Use(JWTAuthMiddleware(authService, nil))
+			router.GET("/me", func(c *gin.Context) {
+				effectiveID = contextutil.GetUserID(c)
+				actingID = contextutil.GetActingUserID(c)
+				c.Status(http.StatusOK)
+			})
+			router.PUT("/me/password", DenyImpersonation(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set(auth.AuthorizationHeader, "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedUserID, effectiveID)
+			assert.Equal(t, tt.expectedActorID, actingID)
+
+			req = httptest.NewRequest(http.MethodPut, "/me/password", nil)
+			req.Header.Set(auth.AuthorizationHeader, "Bearer "+tt.token)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestDenyAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	token, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "login session", authorization: "Bearer " + token, expectedStatus: http.StatusOK},
+		{name: "admin api key", authorization: "ApiKey sk_valid", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(NewAuthMiddleware(config.AuthModeJWT, nil, authService, stubAPIKeys{scopes: []string{"write"}}, nil))
+			router.POST("/admin/users/:id/roles/:role", DenyAPIKey(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/7/roles/admin", nil)
+			req.Header.Set(auth.AuthorizationHeader, tt.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+// recordingAuditor keeps recorded audit events in memory
+type recordingAuditor struct {
+	events []audit.Event
+}
+
+func (r *recordingAuditor) Record(_ context.Context, event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestJWTAuthMiddleware_AuditsImpersonatedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret-key-at-least-32-chars!"})
+	regular, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+	impersonated, err := authService.GenerateImpersonationToken(context.Background(), auth.Actor{UserID: 1, Email: "admin@example.com"}, 7, "user@example.com", "User", time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		method   string
+		recorded bool
+	}{
+		{name: "impersonated write", token: impersonated, method: http.MethodPost, recorded: true},
+		{name: "impersonated read", token: impersonated, method: http.MethodGet},
+		{name: "regular write", token: regular, method: http.MethodPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := &recordingAuditor{}
+			router := gin.New()
+			router.Use(JWTAuthMiddleware(authService, auditor))
+			router.Handle(tt.method, "/orders", func(c *gin.Context) {
+				c.Status(http.StatusCreated)
+			})
+
+			req := httptest.NewRequest(tt.method, "/orders", nil)
+			req.Header.Set(auth.AuthorizationHeader, "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+
+			if !tt.recorded {
+				assert.Empty(t, auditor.events)
+				return
+			}
+			require.Len(t, auditor.events, 1)
+			event := auditor.events[0]
+			assert.Equal(t, audit.ActionImpersonatedRequest, event.Action)
+			assert.Equal(t, uint(1), event.ActorID)
+			assert.Equal(t, uint(7), event.OnBehalfOf)
+			assert.Equal(t, audit.TargetUser, event.TargetType)
+			assert.Equal(t, "7", event.TargetID)
+			assert.Equal(t, http.MethodPost, event.Metadata["method"])
+			assert.Equal(t, "/orders", event.Metadata["path"])
+			assert.Equal(t, http.StatusCreated, event.Metadata["status"])
+		})
+	}
+}
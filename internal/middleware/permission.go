@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+// RequirePermission 要求调用方的角色拥有全部指定权限的中间件
+// 需放在认证中间件之后；权限由 resolver 按角色解析（user.Service 按角色缓存）
+func RequirePermission(resolver auth.PermissionResolver, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles := contextutil.GetRoles(c)
+		// 已认证但没有角色的用户（如未验证邮箱）没有任何权限，返回 403 而不是 401
+		if len(roles) == 0 && !contextutil.IsAuthenticated(c) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "user role not found",
+			})
+			c.Abort()
+			return
+		}
+
+		granted := make(map[string]struct{})
+		for _, role := range roles {
+			rolePermissions, err := resolver.RolePermissions(c.Request.Context(), role)
+			if err != nil {
+				slog.Error("Failed to resolve role permissions", "role", role, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "failed to resolve permissions",
+				})
+				c.Abort()
+				return
+			}
+			for _, permission := range rolePermissions {
+				granted[permission] = struct{}{}
+			}
+		}
+
+		for _, required := range permissions {
+			if _, ok := granted[required]; !ok {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "insufficient permissions",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
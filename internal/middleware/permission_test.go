@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+)
+
+// stubPermissions resolves permissions from a fixed role map
+type stubPermissions map[string][]string
+
+func (s stubPermissions) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	if role == "broken" {
+		return nil, errors.New("database unavailable")
+	}
+	return s[role], nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	resolver := stubPermissions{
+		"admin":   {"roles:read", "roles:write", "users:read"},
+		"support": {"users:read"},
+	}
+
+	tests := []struct {
+		name           string
+		role           string
+		userID         uint
+		required       []string
+		expectedStatus int
+	}{
+		{name: "granted", role: "support", required: []string{"users:read"}, expectedStatus: http.StatusOK},
+		{name: "all required", role: "admin", required: []string{"roles:read", "roles:write"}, expectedStatus: http.StatusOK},
+		{name: "missing one", role: "support", required: []string{"users:read", "roles:read"}, expectedStatus: http.StatusForbidden},
+		{name: "unknown role", role: "guest", required: []string{"users:read"}, expectedStatus: http.StatusForbidden},
+		{name: "no role", required: []string{"users:read"}, expectedStatus: http.StatusUnauthorized},
+		{name: "authenticated without roles", userID: 7, required: []string{"users:read"}, expectedStatus: http.StatusForbidden},
+		{name: "resolver error", role: "broken", required: []string{"users:read"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/users", func(c *gin.Context) {
+				if tt.userID != 0 {
+					contextutil.SetUserID(c, tt.userID)
+					contextutil.SetUserRoles(c, nil)
+				}
+				if tt.role != "" {
+					contextutil.SetUserRole(c, tt.role)
+				}
+				c.Next()
+			}, RequirePermission(resolver, tt.required...), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
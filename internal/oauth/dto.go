@@ -0,0 +1,60 @@
+package oauth
+
+// RegisterClientRequest represents service client registration payload
+type RegisterClientRequest struct {
+	Name   string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,required"`
+}
+
+// ClientResponse represents a service client without its secret
+type ClientResponse struct {
+	ClientID  string   `json:"client_id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// RegisteredClientResponse includes the secret, which is only returned once at registration
+type RegisteredClientResponse struct {
+	ClientResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+// TokenResponse is the successful token endpoint response (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse is the token introspection response (RFC 7662 section 2.2)
+// Inactive tokens only carry active=false; roles is an extension for user access tokens
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+}
+
+// ErrorResponse is the token endpoint error response (RFC 6749 section 5.2)
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ToClientResponse converts Client model to ClientResponse DTO
+func ToClientResponse(client *Client) ClientResponse {
+	return ClientResponse{
+		ClientID:  client.ClientID,
+		Name:      client.Name,
+		Scopes:    client.ScopeList(),
+		CreatedAt: client.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
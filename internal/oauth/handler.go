@@ -0,0 +1,303 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// OAuth2 error codes (RFC 6749 section 5.2)
+const (
+	errInvalidRequest       = "invalid_request"
+	errInvalidClient        = "invalid_client"
+	errInvalidScope         = "invalid_scope"
+	errInsufficientScope    = "insufficient_scope"
+	errUnauthorizedClient   = "unauthorized_client"
+	errUnsupportedGrantType = "unsupported_grant_type"
+	errServerError          = "server_error"
+)
+
+// Handler handles OAuth2 token and service client management requests
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new OAuth2 handler
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Token godoc
+// @Summary Issue a client credentials access token
+// @Description OAuth2 token endpoint (RFC 6749 section 4.4). Authenticate with HTTP Basic or client_id/client_secret form fields. Responses use the OAuth2 format, not the API envelope
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param scope formData string false "Space-separated scopes; defaults to all scopes of the client"
+// @Param client_id formData string false "Client ID when not using HTTP Basic"
+// @Param client_secret formData string false "Client secret when not using HTTP Basic"
+// @Success 200 {object} TokenResponse "Access token"
+// @Failure 400 {object} ErrorResponse "invalid_request, invalid_scope or unsupported_grant_type"
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Failure 500 {object} ErrorResponse "server_error"
+// @Router /oauth/token [post]
+func (h *Handler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	if grantType == "" {
+		tokenError(c, http.StatusBadRequest, errInvalidRequest, "grant_type is required")
+		return
+	}
+	if grantType != GrantTypeClientCredentials {
+		tokenError(c, http.StatusBadRequest, errUnsupportedGrantType, "only client_credentials is supported")
+		return
+	}
+
+	token, err := h.service.IssueToken(c.Request.Context(), client, c.PostForm("scope"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			tokenError(c, http.StatusBadRequest, errInvalidScope, err.Error())
+			return
+		}
+		tokenError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// Introspect godoc
+// @Summary Introspect a token
+// @Description Token introspection (RFC 7662). Reports whether a user or client access token or a refresh token is active. Requires client authentication and the token:introspect scope
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} IntrospectionResponse "Token state; inactive tokens only return active=false"
+// @Failure 400 {object} ErrorResponse "invalid_request"
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Failure 403 {object} ErrorResponse "insufficient_scope"
+// @Failure 500 {object} ErrorResponse "server_error"
+// @Router /oauth/introspect [post]
+func (h *Handler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		tokenError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	response, err := h.service.Introspect(c.Request.Context(), client, token, c.PostForm("token_type_hint"))
+	if err != nil {
+		if errors.Is(err, ErrInsufficientScope) {
+			tokenError(c, http.StatusForbidden, errInsufficientScope, "the client lacks the "+ScopeTokenIntrospect+" scope")
+			return
+		}
+		tokenError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke godoc
+// @Summary Revoke a token
+// @Description Token revocation (RFC 7009). Revoking a refresh token ends its session. Unknown or already invalid tokens also return 200. Clients may revoke their own client tokens; revoking user tokens requires the token:revoke scope
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Token to revoke"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 "Token revoked or already invalid"
+// @Failure 400 {object} ErrorResponse "invalid_request or unauthorized_client"
+// @Failure 401 {object} ErrorResponse "invalid_client"
+// @Failure 403 {object} ErrorResponse "insufficient_scope"
+// @Failure 500 {object} ErrorResponse "server_error"
+// @Router /oauth/revoke [post]
+func (h *Handler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		tokenError(c, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), client, token, c.PostForm("token_type_hint")); err != nil {
+		if errors.Is(err, ErrUnauthorizedClient) {
+			tokenError(c, http.StatusBadRequest, errUnauthorizedClient, "the token was issued to another client")
+			return
+		}
+		if errors.Is(err, ErrInsufficientScope) {
+			tokenError(c, http.StatusForbidden, errInsufficientScope, "the client lacks the "+ScopeTokenRevoke+" scope")
+			return
+		}
+		tokenError(c, http.StatusInternalServerError, errServerError, "")
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// ListClients godoc
+// @Summary List service clients
+// @Description List registered OAuth2 service clients. Secrets are never returned after registration
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]ClientResponse} "Service clients"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Forbidden - admin access required"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list clients"
+// @Router /api/v1/admin/oauth/clients [get]
+func (h *Handler) ListClients(c *gin.Context) {
+	clients, err := h.service.ListClients(c.Request.Context())
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	response := make([]ClientResponse, len(clients))
+	for i := range clients {
+		response[i] = ToClientResponse(&clients[i])
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// RegisterClient godoc
+// @Summary Register a service client
+// @Description Register an OAuth2 service client with the scopes it may request. The secret is only returned in this response
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body RegisterClientRequest true "Client name and allowed scopes"
+// @Success 201 {object} errors.Response{success=bool,data=RegisteredClientResponse} "Registered client including the secret"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid request or scope"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Forbidden - admin access required"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to register client"
+// @Router /api/v1/admin/oauth/clients [post]
+func (h *Handler) RegisterClient(c *gin.Context) {
+	var req RegisterClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	client, secret, err := h.service.RegisterClient(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			_ = c.Error(apiErrors.BadRequest(err.Error()))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(RegisteredClientResponse{
+		ClientResponse: ToClientResponse(client),
+		ClientSecret:   secret,
+	}))
+}
+
+// DeleteClient godoc
+// @Summary Delete a service client
+// @Description Delete an OAuth2 service client. It can no longer obtain tokens; tokens already issued expire normally
+// @Tags admin
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 204 "Client deleted"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Forbidden - admin access required"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Client not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to delete client"
+// @Router /api/v1/admin/oauth/clients/{client_id} [delete]
+func (h *Handler) DeleteClient(c *gin.Context) {
+	if err := h.service.DeleteClient(c.Request.Context(), c.Param("client_id")); err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			_ = c.Error(apiErrors.NotFound("Client not found"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// authenticateClient authenticates the calling client, writing the error response on failure
+func (h *Handler) authenticateClient(c *gin.Context) (*Client, bool) {
+	clientID, secret, ok := clientCredentials(c)
+	if !ok {
+		tokenError(c, http.StatusBadRequest, errInvalidRequest, "client credentials must be sent either with HTTP Basic or in the request body, not both")
+		return nil, false
+	}
+
+	client, err := h.service.AuthenticateClient(c.Request.Context(), clientID, secret)
+	if err != nil {
+		if errors.Is(err, ErrInvalidClient) {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			tokenError(c, http.StatusUnauthorized, errInvalidClient, "client authentication failed")
+			return nil, false
+		}
+		tokenError(c, http.StatusInternalServerError, errServerError, "")
+		return nil, false
+	}
+	return client, true
+}
+
+// clientCredentials reads the client credentials from HTTP Basic or the form body
+// RFC 6749 section 2.3.1: clients must not use more than one authentication method per request
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	formID, formSecret := c.PostForm("client_id"), c.PostForm("client_secret")
+
+	basicID, basicSecret, hasBasic := c.Request.BasicAuth()
+	if !hasBasic {
+		return formID, formSecret, true
+	}
+	if formSecret != "" {
+		return "", "", false
+	}
+
+	// Basic 凭证按 application/x-www-form-urlencoded 编码
+	id, err := url.QueryUnescape(basicID)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err := url.QueryUnescape(basicSecret)
+	if err != nil {
+		return "", "", false
+	}
+	if formID != "" && formID != id {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// tokenError writes an RFC 6749 error response
+func tokenError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, ErrorResponse{Error: code, ErrorDescription: description})
+}
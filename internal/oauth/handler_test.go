@@ -0,0 +1,166 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Token(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, _, _ := setupTestService(t)
+	client, secret, err := svc.RegisterClient(context.Background(), RegisterClientRequest{Name: "reports", Scopes: []string{"users:read"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		basicAuth      bool
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "basic auth",
+			form:           url.Values{"grant_type": {"client_credentials"}},
+			basicAuth:      true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "credentials in body",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {secret}, "scope": {"users:read"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "both authentication methods",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_secret": {secret}},
+			basicAuth:      true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  errInvalidRequest,
+		},
+		{
+			name:           "unsupported grant type",
+			form:           url.Values{"grant_type": {"password"}},
+			basicAuth:      true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  errUnsupportedGrantType,
+		},
+		{
+			name:           "wrong secret",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ClientID}, "client_secret": {"wrong"}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  errInvalidClient,
+		},
+		{
+			name:           "scope not allowed",
+			form:           url.Values{"grant_type": {"client_credentials"}, "scope": {"users:write"}},
+			basicAuth:      true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  errInvalidScope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.POST("/oauth/token", NewHandler(svc).Token)
+
+			req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basicAuth {
+				req.SetBasicAuth(url.QueryEscape(client.ClientID), url.QueryEscape(secret))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			if tt.expectedError != "" {
+				var response ErrorResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedError, response.Error)
+				if tt.expectedStatus == http.StatusUnauthorized {
+					assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+				}
+				return
+			}
+
+			var response TokenResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.NotEmpty(t, response.AccessToken)
+			assert.Equal(t, "Bearer", response.TokenType)
+			assert.Equal(t, "users:read", response.Scope)
+		})
+	}
+}
+
+func TestHandler_IntrospectAndRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc, authService, _ := setupTestService(t)
+	client, secret, err := svc.RegisterClient(context.Background(), RegisterClientRequest{Name: "gateway", Scopes: []string{ScopeTokenIntrospect, ScopeTokenRevoke}})
+	require.NoError(t, err)
+	reports, reportsSecret, err := svc.RegisterClient(context.Background(), RegisterClientRequest{Name: "reports", Scopes: []string{"users:read"}})
+	require.NoError(t, err)
+	userToken, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+
+	handler := NewHandler(svc)
+	router := gin.New()
+	router.POST("/oauth/introspect", handler.Introspect)
+	router.POST("/oauth/revoke", handler.Revoke)
+
+	postAs := func(clientID, path string, form url.Values, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	post := func(path string, form url.Values, secret string) *httptest.ResponseRecorder {
+		return postAs(client.ClientID, path, form, secret)
+	}
+	introspect := func(token string) map[string]interface{} {
+		w := post("/oauth/introspect", url.Values{"token": {token}}, secret)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	active := introspect(userToken)
+	assert.Equal(t, true, active["active"])
+	assert.Equal(t, "7", active["sub"])
+	assert.Equal(t, "user@example.com", active["username"])
+
+	assert.Equal(t, map[string]interface{}{"active": false}, introspect("garbage"))
+
+	w := post("/oauth/introspect", url.Values{"token": {userToken}}, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postAs(reports.ClientID, "/oauth/introspect", url.Values{"token": {userToken}}, reportsSecret)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "insufficient_scope")
+
+	w = postAs(reports.ClientID, "/oauth/revoke", url.Values{"token": {userToken}}, reportsSecret)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, true, introspect(userToken)["active"], "user tokens are only revoked by clients with the revoke scope")
+
+	w = post("/oauth/revoke", url.Values{}, secret)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = post("/oauth/revoke", url.Values{"token": {userToken}, "token_type_hint": {TokenTypeHintAccessToken}}, secret)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, false, introspect(userToken)["active"])
+
+	w = post("/oauth/revoke", url.Values{"token": {userToken}}, secret)
+	assert.Equal(t, http.StatusOK, w.Code, "revoking an invalid token is not an error")
+}
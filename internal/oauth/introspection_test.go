@@ -0,0 +1,149 @@
+package oauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+)
+
+func storeRefreshToken(t *testing.T, refreshTokens auth.RefreshTokenRepository, raw string, expiresAt time.Time) *auth.RefreshToken {
+	token := &auth.RefreshToken{
+		UserID:      7,
+		TokenHash:   auth.HashToken(raw),
+		TokenFamily: uuid.New(),
+		ExpiresAt:   expiresAt,
+	}
+	require.NoError(t, refreshTokens.Create(context.Background(), token))
+	return token
+}
+
+func TestService_Introspect(t *testing.T) {
+	svc, authService, refreshTokens := setupTestService(t)
+	ctx := context.Background()
+	caller := &Client{ClientID: "svc_gateway", Scopes: ScopeTokenIntrospect}
+
+	userToken, err := authService.GenerateToken(7, "user@example.com", "User")
+	require.NoError(t, err)
+	clientToken, err := authService.GenerateClientToken("svc_reports", []string{"users:read"}, time.Minute)
+	require.NoError(t, err)
+	storeRefreshToken(t, refreshTokens, "refresh-active", time.Now().Add(time.Hour))
+	storeRefreshToken(t, refreshTokens, "refresh-expired", time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name     string
+		token    string
+		hint     string
+		active   bool
+		sub      string
+		clientID string
+		scope    string
+	}{
+		{name: "user access token", token: userToken, active: true, sub: "7"},
+		{name: "client access token", token: clientToken, active: true, sub: "svc_reports", clientID: "svc_reports", scope: "users:read"},
+		{name: "refresh token", token: "refresh-active", hint: TokenTypeHintRefreshToken, active: true, sub: "7"},
+		{name: "refresh token with wrong hint", token: "refresh-active", hint: TokenTypeHintAccessToken, active: true, sub: "7"},
+		{name: "access token with refresh hint", token: userToken, hint: TokenTypeHintRefreshToken, active: true, sub: "7"},
+		{name: "expired refresh token", token: "refresh-expired"},
+		{name: "unknown token", token: "garbage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := svc.Introspect(ctx, caller, tt.token, tt.hint)
+			require.NoError(t, err)
+			assert.Equal(t, tt.active, response.Active)
+			assert.Equal(t, tt.sub, response.Sub)
+			assert.Equal(t, tt.clientID, response.ClientID)
+			assert.Equal(t, tt.scope, response.Scope)
+			if tt.active {
+				assert.NotZero(t, response.Exp)
+			}
+		})
+	}
+
+	t.Run("client without the introspect scope", func(t *testing.T) {
+		_, err := svc.Introspect(ctx, &Client{ClientID: "svc_reports", Scopes: "users:read"}, userToken, "")
+		assert.ErrorIs(t, err, ErrInsufficientScope)
+	})
+}
+
+func TestService_Revoke(t *testing.T) {
+	svc, authService, refreshTokens := setupTestService(t)
+	ctx := context.Background()
+	caller := &Client{ClientID: "svc_gateway", Scopes: ScopeTokenIntrospect + " " + ScopeTokenRevoke}
+	unprivileged := &Client{ClientID: "svc_reports", Scopes: "users:read"}
+
+	t.Run("user access token", func(t *testing.T) {
+		token, err := authService.GenerateToken(7, "user@example.com", "User")
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Revoke(ctx, caller, token, ""))
+		_, err = authService.ValidateToken(token)
+		assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	})
+
+	t.Run("refresh token revokes its session", func(t *testing.T) {
+		stored := storeRefreshToken(t, refreshTokens, "refresh-session", time.Now().Add(time.Hour))
+
+		require.NoError(t, svc.Revoke(ctx, caller, "refresh-session", TokenTypeHintRefreshToken))
+		family, err := refreshTokens.FindByTokenFamily(ctx, stored.TokenFamily)
+		require.NoError(t, err)
+		require.Len(t, family, 1)
+		assert.NotNil(t, family[0].RevokedAt)
+
+		response, err := svc.Introspect(ctx, caller, "refresh-session", "")
+		require.NoError(t, err)
+		assert.False(t, response.Active)
+	})
+
+	t.Run("own client token", func(t *testing.T) {
+		token, err := authService.GenerateClientToken(caller.ClientID, []string{"users:read"}, time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Revoke(ctx, caller, token, TokenTypeHintAccessToken))
+		_, err = authService.ValidateClientToken(token)
+		assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	})
+
+	t.Run("another client's token", func(t *testing.T) {
+		token, err := authService.GenerateClientToken("svc_reports", []string{"users:read"}, time.Minute)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, svc.Revoke(ctx, caller, token, ""), ErrUnauthorizedClient)
+		_, err = authService.ValidateClientToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("user tokens without the revoke scope", func(t *testing.T) {
+		token, err := authService.GenerateToken(7, "user@example.com", "User")
+		require.NoError(t, err)
+		storeRefreshToken(t, refreshTokens, "refresh-foreign", time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, svc.Revoke(ctx, unprivileged, token, ""), ErrInsufficientScope)
+		assert.ErrorIs(t, svc.Revoke(ctx, unprivileged, "refresh-foreign", TokenTypeHintRefreshToken), ErrInsufficientScope)
+		_, err = authService.ValidateToken(token)
+		assert.NoError(t, err)
+		response, err := svc.Introspect(ctx, caller, "refresh-foreign", "")
+		require.NoError(t, err)
+		assert.True(t, response.Active)
+	})
+
+	t.Run("own client token without the revoke scope", func(t *testing.T) {
+		token, err := authService.GenerateClientToken(unprivileged.ClientID, []string{"users:read"}, time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Revoke(ctx, unprivileged, token, ""))
+		_, err = authService.ValidateClientToken(token)
+		assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		assert.NoError(t, svc.Revoke(ctx, caller, "garbage", ""))
+	})
+}
@@ -0,0 +1,33 @@
+package oauth
+
+import (
+	"strings"
+	"time"
+)
+
+// Client is a registered service client allowed to use the client credentials grant
+// Only the SHA-256 hash of the secret is stored
+type Client struct {
+	ID         uint   `gorm:"primaryKey"`
+	ClientID   string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	SecretHash string `gorm:"type:varchar(64);not null"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Scopes     string `gorm:"type:varchar(1000);not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName specifies the table name for Client model
+func (Client) TableName() string {
+	return "oauth_clients"
+}
+
+// HasScope reports whether the client was registered with scope
+func (c *Client) HasScope(scope string) bool {
+	return containsScope(c.ScopeList(), scope)
+}
+
+// ScopeList returns the scopes the client may request
+func (c *Client) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
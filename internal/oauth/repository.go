@@ -0,0 +1,63 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Repository defines service client data access
+type Repository interface {
+	Create(ctx context.Context, client *Client) error
+	FindByClientID(ctx context.Context, clientID string) (*Client, error)
+	List(ctx context.Context) ([]Client, error)
+	Delete(ctx context.Context, clientID string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new service client repository
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create stores a new client
+func (r *repository) Create(ctx context.Context, client *Client) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+// FindByClientID finds a client by its client_id
+func (r *repository) FindByClientID(ctx context.Context, clientID string) (*Client, error) {
+	var client Client
+	result := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// List returns every client ordered by name
+func (r *repository) List(ctx context.Context) ([]Client, error) {
+	var clients []Client
+	err := r.db.WithContext(ctx).Order("name, id").Find(&clients).Error
+	return clients, err
+}
+
+// Delete removes a client
+// Returns gorm.ErrRecordNotFound if it doesn't exist
+func (r *repository) Delete(ctx context.Context, clientID string) error {
+	result := r.db.WithContext(ctx).Where("client_id = ?", clientID).Delete(&Client{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// ClientIDPrefix marks generated client IDs; it also keeps them from looking like user IDs
+const ClientIDPrefix = "svc_"
+
+// GrantTypeClientCredentials is the only grant supported by the token endpoint
+const GrantTypeClientCredentials = "client_credentials"
+
+// Token type hints accepted by the introspection and revocation endpoints (RFC 7009 section 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// Scopes that let a client call the introspection endpoint and revoke user tokens
+// Clients may always revoke their own client tokens (RFC 7009 section 2.1)
+const (
+	ScopeTokenIntrospect = "token:introspect"
+	ScopeTokenRevoke     = "token:revoke"
+)
+
+var (
+	// ErrInvalidClient is returned when the client is unknown or the secret doesn't match
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidScope is returned when a scope is malformed or not allowed for the client
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrClientNotFound is returned when managing a client that doesn't exist
+	ErrClientNotFound = errors.New("client not found")
+	// ErrUnauthorizedClient is returned when a client revokes a token issued to another client
+	ErrUnauthorizedClient = errors.New("token was issued to another client")
+	// ErrInsufficientScope is returned when a client introspects or revokes user tokens without the required scope
+	ErrInsufficientScope = errors.New("client lacks the required scope")
+)
+
+// scopeTokenPattern matches a scope-token as defined in RFC 6749 section 3.3
+var scopeTokenPattern = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+// Service defines service client registration and the client credentials grant
+type Service interface {
+	RegisterClient(ctx context.Context, req RegisterClientRequest) (*Client, string, error)
+	ListClients(ctx context.Context) ([]Client, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	AuthenticateClient(ctx context.Context, clientID, secret string) (*Client, error)
+	IssueToken(ctx context.Context, client *Client, scope string) (*TokenResponse, error)
+	Introspect(ctx context.Context, client *Client, token, tokenTypeHint string) (*IntrospectionResponse, error)
+	Revoke(ctx context.Context, client *Client, token, tokenTypeHint string) error
+}
+
+type service struct {
+	repo          Repository
+	authService   auth.Service
+	refreshTokens auth.RefreshTokenRepository
+	tokenTTL      time.Duration
+}
+
+// NewService creates a new service client service that signs tokens with authService
+// refreshTokens lets introspection and revocation handle refresh tokens; nil limits them to access tokens
+func NewService(repo Repository, authService auth.Service, refreshTokens auth.RefreshTokenRepository, cfg *config.OAuthConfig) Service {
+	return &service{
+		repo:          repo,
+		authService:   authService,
+		refreshTokens: refreshTokens,
+		tokenTTL:      cfg.GetTokenTTL(),
+	}
+}
+
+// RegisterClient creates a client and returns it with its secret, which is not stored
+func (s *service) RegisterClient(ctx context.Context, req RegisterClientRequest) (*Client, string, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomString(12)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate client id: %w", err)
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+
+	client := &Client{
+		ClientID:   ClientIDPrefix + id,
+		SecretHash: auth.HashToken(secret),
+		Name:       req.Name,
+		Scopes:     strings.Join(scopes, " "),
+	}
+	if err := s.repo.Create(ctx, client); err != nil {
+		return nil, "", fmt.Errorf("failed to create client: %w", err)
+	}
+	return client, secret, nil
+}
+
+// ListClients returns every registered client
+func (s *service) ListClients(ctx context.Context) ([]Client, error) {
+	clients, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient removes a client; tokens already issued stay valid until they expire
+func (s *service) DeleteClient(ctx context.Context, clientID string) error {
+	if err := s.repo.Delete(ctx, clientID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrClientNotFound
+		}
+		return fmt.Errorf("failed to delete client: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateClient checks a client's credentials
+func (s *service) AuthenticateClient(ctx context.Context, clientID, secret string) (*Client, error) {
+	if clientID == "" || secret == "" {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find client: %w", err)
+	}
+	if client == nil {
+		return nil, ErrInvalidClient
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// IssueToken issues an access token for the requested scopes (space-separated)
+// An empty scope grants every scope the client is registered with
+func (s *service) IssueToken(ctx context.Context, client *Client, scope string) (*TokenResponse, error) {
+	allowed := client.ScopeList()
+	granted := allowed
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		normalized, err := normalizeScopes(requested)
+		if err != nil {
+			return nil, err
+		}
+		for _, want := range normalized {
+			if !containsScope(allowed, want) {
+				return nil, fmt.Errorf("%w: %s is not allowed for this client", ErrInvalidScope, want)
+			}
+		}
+		granted = normalized
+	}
+
+	accessToken, err := s.authService.GenerateClientToken(client.ClientID, granted, s.tokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.tokenTTL.Seconds()),
+		Scope:       strings.Join(granted, " "),
+	}, nil
+}
+
+// normalizeScopes validates scope tokens and returns them sorted without duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	unique := make(map[string]struct{}, len(scopes))
+	for _, scope := range scopes {
+		if !scopeTokenPattern.MatchString(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		unique[scope] = struct{}{}
+	}
+
+	normalized := make([]string, 0, len(unique))
+	for scope := range unique {
+		normalized = append(normalized, scope)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,115 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func setupTestService(t *testing.T) (Service, auth.Service, auth.RefreshTokenRepository) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&Client{}, &auth.RefreshToken{}))
+
+	authService := auth.NewService(&config.JWTConfig{Secret: "test-secret", TTLHours: 1})
+	refreshTokens := auth.NewRefreshTokenRepository(db)
+	return NewService(NewRepository(db), authService, refreshTokens, &config.OAuthConfig{Enabled: true, TokenTTL: 10 * time.Minute}), authService, refreshTokens
+}
+
+func TestService_RegisterClient(t *testing.T) {
+	svc, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	client, secret, err := svc.RegisterClient(ctx, RegisterClientRequest{Name: "reports", Scopes: []string{"users:read", "reports:write", "users:read"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(client.ClientID, ClientIDPrefix))
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, secret, client.SecretHash)
+	assert.Equal(t, []string{"reports:write", "users:read"}, client.ScopeList())
+
+	_, _, err = svc.RegisterClient(ctx, RegisterClientRequest{Name: "bad", Scopes: []string{"users read"}})
+	assert.ErrorIs(t, err, ErrInvalidScope)
+
+	clients, err := svc.ListClients(ctx)
+	require.NoError(t, err)
+	assert.Len(t, clients, 1)
+
+	require.NoError(t, svc.DeleteClient(ctx, client.ClientID))
+	assert.ErrorIs(t, svc.DeleteClient(ctx, client.ClientID), ErrClientNotFound)
+}
+
+func TestService_AuthenticateClient(t *testing.T) {
+	svc, _, _ := setupTestService(t)
+	ctx := context.Background()
+
+	client, secret, err := svc.RegisterClient(ctx, RegisterClientRequest{Name: "reports", Scopes: []string{"users:read"}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+		wantErr  error
+	}{
+		{name: "valid credentials", clientID: client.ClientID, secret: secret},
+		{name: "wrong secret", clientID: client.ClientID, secret: secret + "x", wantErr: ErrInvalidClient},
+		{name: "unknown client", clientID: "svc_unknown", secret: secret, wantErr: ErrInvalidClient},
+		{name: "missing secret", clientID: client.ClientID, wantErr: ErrInvalidClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.AuthenticateClient(ctx, tt.clientID, tt.secret)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, client.ClientID, got.ClientID)
+		})
+	}
+}
+
+func TestService_IssueToken(t *testing.T) {
+	svc, authService, _ := setupTestService(t)
+	client := &Client{ClientID: "svc_reports", Scopes: "reports:write users:read"}
+
+	tests := []struct {
+		name       string
+		scope      string
+		wantScopes []string
+		wantErr    error
+	}{
+		{name: "defaults to all allowed scopes", wantScopes: []string{"reports:write", "users:read"}},
+		{name: "subset", scope: "users:read", wantScopes: []string{"users:read"}},
+		{name: "scope not allowed", scope: "users:read users:write", wantErr: ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := svc.IssueToken(context.Background(), client, tt.scope)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Bearer", token.TokenType)
+			assert.Equal(t, int64(600), token.ExpiresIn)
+			assert.Equal(t, strings.Join(tt.wantScopes, " "), token.Scope)
+
+			claims, err := authService.ValidateClientToken(token.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, "svc_reports", claims.ClientID)
+			assert.Equal(t, tt.wantScopes, claims.Scopes)
+		})
+	}
+}
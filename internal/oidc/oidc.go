@@ -0,0 +1,218 @@
+// Package oidc implements the relying party side of OpenID Connect login:
+// the authorization code flow with PKCE, provider discovery and ID token verification.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+var (
+	// ErrUnknownProvider is returned when no provider is configured under the given name
+	ErrUnknownProvider = errors.New("unknown oidc provider")
+	// ErrInvalidState is returned when the callback state is unknown, expired or already used
+	ErrInvalidState = errors.New("invalid or expired oidc state")
+	// ErrInvalidIDToken is returned when the ID token fails verification
+	ErrInvalidIDToken = errors.New("invalid oidc id token")
+	// ErrProviderRequest is returned when discovery, the token endpoint or the userinfo endpoint fails
+	ErrProviderRequest = errors.New("oidc provider request failed")
+)
+
+// Identity is an end-user authenticated by a provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// LinkByEmail reports whether the provider may be linked to an existing account with the same verified email
+	LinkByEmail bool
+}
+
+// AuthRequest is a pending authorization request, stored under its state until the callback
+type AuthRequest struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// LinkUserID is set when a signed-in user links the provider to their account
+	LinkUserID uint `json:"link_user_id,omitempty"`
+}
+
+// Client runs the authorization code flow against the configured providers.
+// Provider metadata and signing keys are discovered on first use, so an unreachable provider does not prevent startup.
+type Client struct {
+	providers map[string]*provider
+	names     []string
+	store     StateStore
+	stateTTL  time.Duration
+	// secureCookie marks the state cookie Secure; off only for local HTTP development
+	secureCookie bool
+}
+
+// NewClient creates a client for the providers in cfg; pending requests are kept in store
+func NewClient(cfg *config.OIDCConfig, store StateStore) *Client {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	c := &Client{
+		providers:    make(map[string]*provider, len(cfg.Providers)),
+		store:        store,
+		stateTTL:     cfg.GetStateTTL(),
+		secureCookie: !cfg.InsecureCookie,
+	}
+	for _, providerCfg := range cfg.Providers {
+		c.providers[providerCfg.Name] = newProvider(providerCfg, httpClient)
+		c.names = append(c.names, providerCfg.Name)
+	}
+	return c
+}
+
+// Providers returns the configured provider names in configuration order
+func (c *Client) Providers() []string {
+	return c.names
+}
+
+// StateTTL returns how long an authorization request started by AuthCodeURL stays valid
+func (c *Client) StateTTL() time.Duration {
+	return c.stateTTL
+}
+
+// SecureCookie reports whether the state cookie may only be sent over HTTPS
+func (c *Client) SecureCookie() bool {
+	return c.secureCookie
+}
+
+// AuthCodeURL starts an authorization request and returns the provider URL to redirect the user to,
+// with the binding that ties the request to the browser starting it (see StateBinding).
+// linkUserID is the signed-in user linking the provider, or 0 for a login.
+func (c *Client) AuthCodeURL(ctx context.Context, providerName string, linkUserID uint) (string, string, error) {
+	p, ok := c.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	req := &AuthRequest{Provider: providerName, Nonce: nonce, CodeVerifier: verifier, LinkUserID: linkUserID}
+	if err := c.store.Save(ctx, state, req, c.stateTTL); err != nil {
+		return "", "", fmt.Errorf("failed to store oidc state: %w", err)
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.GetScopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), StateBinding(state), nil
+}
+
+// Exchange completes the authorization request identified by state: it redeems the code,
+// verifies the ID token and returns the authenticated identity with the original request.
+// binding is the value returned by AuthCodeURL, kept by the browser; a callback from another browser is rejected.
+// Each state can be exchanged once.
+func (c *Client) Exchange(ctx context.Context, providerName, code, state, binding string) (*Identity, *AuthRequest, error) {
+	p, ok := c.providers[providerName]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	// WHY: state 只是服务端的键，不校验浏览器绑定时攻击者可以让受害者的浏览器完成攻击者发起的流程（登录 CSRF 或关联到错误账户）
+	// 在 Take 之前校验，伪造的回调不会消耗合法请求的 state
+	if binding == "" || subtle.ConstantTimeCompare([]byte(StateBinding(state)), []byte(binding)) != 1 {
+		return nil, nil, ErrInvalidState
+	}
+
+	req, err := c.store.Take(ctx, state)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load oidc state: %w", err)
+	}
+	if req == nil || req.Provider != providerName {
+		return nil, nil, ErrInvalidState
+	}
+
+	tokens, err := p.exchange(ctx, code, req.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, tokens.IDToken, req.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity := &Identity{
+		Provider:      providerName,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		LinkByEmail:   p.cfg.LinkByEmail,
+	}
+
+	// 部分提供方仅在 userinfo 端点返回邮箱
+	if identity.Email == "" && tokens.AccessToken != "" {
+		if err := p.fillFromUserInfo(ctx, tokens.AccessToken, identity); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return identity, req, nil
+}
+
+// StateBinding derives the value stored in the browser that started the authorization request for state.
+// Only the hash is stored, so the cookie cannot be replayed as the state itself.
+func StateBinding(state string) string {
+	sum := sha256.Sum256([]byte("oidc-state:" + state))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CodeChallenge derives the S256 PKCE code challenge from a code verifier (RFC 7636)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomToken returns 256 random bits, base64url encoded; also valid as a PKCE code verifier
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
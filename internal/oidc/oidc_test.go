@@ -0,0 +1,197 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc/oidctest"
+)
+
+const testRedirectURL = "http://localhost:8080/api/v1/auth/oidc/test/callback"
+
+func newTestClient(t *testing.T, providers ...config.OIDCProviderConfig) *Client {
+	t.Helper()
+	return NewClient(&config.OIDCConfig{Enabled: true, Providers: providers}, NewMemoryStateStore())
+}
+
+// login runs the browser part of the flow and returns the code and state from the callback with the browser binding
+func login(t *testing.T, client *Client, fake *oidctest.Provider, providerName string, linkUserID uint) (string, string, string) {
+	t.Helper()
+	authURL, binding, err := client.AuthCodeURL(context.Background(), providerName, linkUserID)
+	require.NoError(t, err)
+	callback := fake.Authorize(t, authURL)
+	return callback.Query().Get("code"), callback.Query().Get("state"), binding
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	fake := oidctest.NewProvider(t, "test-client", "test-secret")
+	client := newTestClient(t, fake.Config("test", testRedirectURL))
+	ctx := context.Background()
+
+	authURL, binding, err := client.AuthCodeURL(ctx, "test", 0)
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, fake.Issuer()+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "test-client", query.Get("client_id"))
+	assert.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+	assert.NotEmpty(t, query.Get("nonce"))
+	assert.Equal(t, StateBinding(query.Get("state")), binding, "the browser keeps a hash of the state")
+
+	callback := fake.Authorize(t, authURL)
+	code, state := callback.Query().Get("code"), callback.Query().Get("state")
+	assert.Equal(t, query.Get("state"), state)
+
+	identity, req, err := client.Exchange(ctx, "test", code, state, binding)
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Provider: "test", Subject: "user-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"}, identity)
+	assert.Equal(t, uint(0), req.LinkUserID)
+
+	_, _, err = client.Exchange(ctx, "test", code, state, binding)
+	assert.ErrorIs(t, err, ErrInvalidState, "each state completes a single login")
+}
+
+func TestClient_LinkAndPublicClient(t *testing.T) {
+	fake := oidctest.NewProvider(t, "public-client", "")
+	providerCfg := fake.Config("test", testRedirectURL)
+	providerCfg.LinkByEmail = true
+	client := newTestClient(t, providerCfg)
+
+	code, state, binding := login(t, client, fake, "test", 42)
+	identity, req, err := client.Exchange(context.Background(), "test", code, state, binding)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), req.LinkUserID)
+	assert.True(t, identity.LinkByEmail)
+}
+
+func TestClient_Exchange_Errors(t *testing.T) {
+	fake := oidctest.NewProvider(t, "test-client", "test-secret")
+	other := oidctest.NewProvider(t, "other-client", "other-secret")
+	client := newTestClient(t, fake.Config("test", testRedirectURL), other.Config("other", testRedirectURL))
+	ctx := context.Background()
+
+	_, _, err := client.AuthCodeURL(ctx, "missing", 0)
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, _, err = client.Exchange(ctx, "test", "code", "unknown-state", StateBinding("unknown-state"))
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	t.Run("state from another provider", func(t *testing.T) {
+		code, state, binding := login(t, client, other, "other", 0)
+		_, _, err := client.Exchange(ctx, "test", code, state, binding)
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("callback from another browser", func(t *testing.T) {
+		code, state, binding := login(t, client, fake, "test", 0)
+		_, otherState, otherBinding := login(t, client, fake, "test", 0)
+		require.NotEqual(t, binding, otherBinding)
+
+		_, _, err := client.Exchange(ctx, "test", code, state, "")
+		assert.ErrorIs(t, err, ErrInvalidState, "the browser must present the binding")
+		_, _, err = client.Exchange(ctx, "test", code, state, otherBinding)
+		assert.ErrorIs(t, err, ErrInvalidState, "a binding from another request is rejected")
+
+		_, _, err = client.Exchange(ctx, "test", code, state, binding)
+		assert.NoError(t, err, "rejected callbacks do not consume the state")
+		_, _, err = client.Exchange(ctx, "test", "code", otherState, binding)
+		assert.ErrorIs(t, err, ErrInvalidState)
+	})
+
+	t.Run("code rejected by provider", func(t *testing.T) {
+		_, state, binding := login(t, client, fake, "test", 0)
+		_, _, err := client.Exchange(ctx, "test", "forged-code", state, binding)
+		assert.ErrorIs(t, err, ErrProviderRequest)
+	})
+
+	t.Run("unreachable provider", func(t *testing.T) {
+		broken := newTestClient(t, config.OIDCProviderConfig{Name: "broken", Issuer: fake.Issuer() + "/missing", ClientID: "x", RedirectURL: testRedirectURL})
+		_, _, err := broken.AuthCodeURL(ctx, "broken", 0)
+		assert.ErrorIs(t, err, ErrProviderRequest)
+	})
+}
+
+func TestClient_VerifyIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+	}{
+		{name: "wrong nonce", modify: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{name: "wrong audience", modify: func(claims jwt.MapClaims) { claims["aud"] = "another-client" }},
+		{name: "wrong issuer", modify: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{name: "expired", modify: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "missing subject", modify: func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{name: "multiple audiences without azp", modify: func(claims jwt.MapClaims) {
+			claims["aud"] = []string{"test-client", "another-client"}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := oidctest.NewProvider(t, "test-client", "test-secret")
+			fake.ModifyIDToken = tt.modify
+			client := newTestClient(t, fake.Config("test", testRedirectURL))
+
+			code, state, binding := login(t, client, fake, "test", 0)
+			_, _, err := client.Exchange(context.Background(), "test", code, state, binding)
+			assert.ErrorIs(t, err, ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestClient_ClaimVariants(t *testing.T) {
+	t.Run("email from userinfo", func(t *testing.T) {
+		fake := oidctest.NewProvider(t, "test-client", "test-secret")
+		fake.EmailInUserInfoOnly = true
+		fake.SetUser(oidctest.User{Subject: "abc", Email: "info@example.com", EmailVerified: true, Name: "Info"})
+		client := newTestClient(t, fake.Config("test", testRedirectURL))
+
+		code, state, binding := login(t, client, fake, "test", 0)
+		identity, _, err := client.Exchange(context.Background(), "test", code, state, binding)
+		require.NoError(t, err)
+		assert.Equal(t, "info@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+	})
+
+	t.Run("email_verified as string", func(t *testing.T) {
+		fake := oidctest.NewProvider(t, "test-client", "test-secret")
+		fake.ModifyIDToken = func(claims jwt.MapClaims) { claims["email_verified"] = "true" }
+		client := newTestClient(t, fake.Config("test", testRedirectURL))
+
+		code, state, binding := login(t, client, fake, "test", 0)
+		identity, _, err := client.Exchange(context.Background(), "test", code, state, binding)
+		require.NoError(t, err)
+		assert.True(t, identity.EmailVerified)
+	})
+}
+
+func TestMemoryStateStore_Expiry(t *testing.T) {
+	store := NewMemoryStateStore().(*memoryStateStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, store.Save(ctx, "state", &AuthRequest{Provider: "test"}, time.Minute))
+	now = now.Add(2 * time.Minute)
+
+	req, err := store.Take(ctx, "state")
+	require.NoError(t, err)
+	assert.Nil(t, req)
+}
+
+func TestCodeChallenge_RFC7636Vector(t *testing.T) {
+	// RFC 7636 Appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
@@ -0,0 +1,395 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+const (
+	// maxResponseBytes bounds the provider responses read into memory
+	maxResponseBytes = 1 << 20
+	// keysRefreshInterval limits JWKS refetches triggered by unknown key IDs
+	keysRefreshInterval = time.Minute
+	// idTokenLeeway tolerates clock skew between this service and the provider
+	idTokenLeeway = time.Minute
+)
+
+// supportedAlgorithms are the ID token signature algorithms accepted from providers
+var supportedAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// providerMetadata is the subset of the discovery document used by the client
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// idTokenClaims are the ID token claims used to identify the user
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string       `json:"nonce"`
+	AuthorizedParty string       `json:"azp"`
+	Email           string       `json:"email"`
+	EmailVerified   flexibleBool `json:"email_verified"`
+	Name            string       `json:"name"`
+}
+
+type userInfoResponse struct {
+	Subject       string       `json:"sub"`
+	Email         string       `json:"email"`
+	EmailVerified flexibleBool `json:"email_verified"`
+	Name          string       `json:"name"`
+}
+
+// flexibleBool accepts JSON booleans and the strings "true"/"false", which some providers send for email_verified
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = flexibleBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*b = flexibleBool(parsed)
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// provider caches the discovery document and signing keys of one configured provider
+type provider struct {
+	cfg        config.OIDCProviderConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu            sync.Mutex
+	metadata      *providerMetadata
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func newProvider(cfg config.OIDCProviderConfig, httpClient *http.Client) *provider {
+	return &provider{
+		cfg:        cfg,
+		httpClient: httpClient,
+		now:        time.Now,
+	}
+}
+
+// discover fetches the discovery document once; failures are retried on the next call
+func (p *provider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var metadata providerMetadata
+	if err := p.getJSON(ctx, discoveryURL, "", &metadata); err != nil {
+		return nil, err
+	}
+
+	// WHY: OpenID Connect Discovery 1.0 §4.3 要求发现文档中的 issuer 与配置的 issuer 一致，防止伪造提供方
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("%w: discovery issuer %q does not match %q", ErrProviderRequest, metadata.Issuer, p.cfg.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document is missing required endpoints", ErrProviderRequest)
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// exchange redeems an authorization code at the token endpoint
+func (p *provider) exchange(ctx context.Context, code, verifier string) (*tokenResponse, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// RFC 6749 §2.3.1: client_secret_basic 的凭据需先做表单编码
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderRequest, err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&tokens); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("%w: invalid token response: %v", ErrProviderRequest, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("%w: token endpoint returned %d %s", ErrProviderRequest, resp.StatusCode, tokens.Error)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	return &tokens, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce of an ID token
+func (p *provider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*idTokenClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(supportedAlgorithms),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidIDToken)
+	}
+	// OpenID Connect Core §3.1.3.7: 多个受众时 azp 必须是本客户端
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp claim does not match client", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
+
+// fillFromUserInfo completes identity with the claims of the userinfo endpoint
+func (p *provider) fillFromUserInfo(ctx context.Context, accessToken string, identity *Identity) error {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return err
+	}
+	if metadata.UserinfoEndpoint == "" {
+		return nil
+	}
+
+	var info userInfoResponse
+	if err := p.getJSON(ctx, metadata.UserinfoEndpoint, accessToken, &info); err != nil {
+		return err
+	}
+	// OpenID Connect Core §5.3.2: userinfo 的 sub 必须与 ID 令牌一致，否则不可使用
+	if info.Subject != identity.Subject {
+		return fmt.Errorf("%w: userinfo subject does not match id token", ErrProviderRequest)
+	}
+
+	identity.Email = info.Email
+	identity.EmailVerified = bool(info.EmailVerified)
+	if identity.Name == "" {
+		identity.Name = info.Name
+	}
+	return nil
+}
+
+// key returns the provider's verification key with the given key ID.
+// The key set is refetched when the ID is unknown, at most once per keysRefreshInterval, to follow key rotation.
+func (p *provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.metadata.JWKSURI, "", &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, raw := range set.Keys {
+		keyID, key, err := parseJWK(raw)
+		if err != nil {
+			// 跳过无法使用的密钥（如加密用途或不支持的类型），不影响其余密钥
+			continue
+		}
+		keys[keyID] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = p.now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a cached key; tokens without kid may only use a key set of exactly one key. Callers must hold mu.
+func (p *provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON performs a GET request and decodes the JSON response; accessToken is sent as a bearer token when set
+func (p *provider) getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderRequest, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderRequest, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: GET %s returned %d", ErrProviderRequest, endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(out); err != nil {
+		return fmt.Errorf("%w: invalid response from %s: %v", ErrProviderRequest, endpoint, err)
+	}
+	return nil
+}
+
+// parseJWK converts a signing JWK (RSA or EC) into a public key usable by jwt
+func parseJWK(raw json.RawMessage) (string, interface{}, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return "", nil, err
+	}
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return "", nil, fmt.Errorf("key %q is not a signing key", jwk.Kid)
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return "", nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return "", nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return "", nil, errors.New("invalid RSA exponent")
+		}
+		return jwk.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return "", nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return "", nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return "", nil, err
+		}
+		return jwk.Kid, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,108 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/redis"
+)
+
+const oidcStatePrefix = "auth:oidc:state:"
+
+// StateStore keeps pending authorization requests between the redirect to the provider and the callback
+type StateStore interface {
+	// Save stores req under state until ttl elapses
+	Save(ctx context.Context, state string, req *AuthRequest, ttl time.Duration) error
+	// Take returns and removes the request stored under state, or nil when it is unknown or expired
+	Take(ctx context.Context, state string) (*AuthRequest, error)
+}
+
+// redisStateStore shares pending requests between replicas through Redis
+type redisStateStore struct {
+	client *redis.Client
+}
+
+// NewRedisStateStore creates a state store backed by Redis
+func NewRedisStateStore(client *redis.Client) StateStore {
+	return &redisStateStore{client: client}
+}
+
+func (s *redisStateStore) Save(ctx context.Context, state string, req *AuthRequest, ttl time.Duration) error {
+	value, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, oidcStatePrefix+state, value, ttl)
+}
+
+func (s *redisStateStore) Take(ctx context.Context, state string) (*AuthRequest, error) {
+	// WHY: GETDEL 原子地读取并删除，并发回调只有一个能拿到请求
+	value, err := s.client.GetDel(ctx, oidcStatePrefix+state)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var req AuthRequest
+	if err := json.Unmarshal([]byte(value), &req); err != nil {
+		return nil, fmt.Errorf("invalid oidc state entry: %w", err)
+	}
+	return &req, nil
+}
+
+// memoryStateStore keeps pending requests in process memory.
+// WHY: Used when Redis is disabled; a callback must reach the replica that started the login.
+type memoryStateStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStateEntry
+	now     func() time.Time
+}
+
+type memoryStateEntry struct {
+	req       AuthRequest
+	expiresAt time.Time
+}
+
+// NewMemoryStateStore creates an in-memory state store
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{
+		entries: make(map[string]memoryStateEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStateStore) Save(ctx context.Context, state string, req *AuthRequest, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeExpired()
+	s.entries[state] = memoryStateEntry{req: *req, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryStateStore) Take(ctx context.Context, state string) (*AuthRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.entries, state)
+	if !s.now().Before(entry.expiresAt) {
+		return nil, nil
+	}
+	return &entry.req, nil
+}
+
+// purgeExpired drops requests whose callback never arrived. Callers must hold mu.
+func (s *memoryStateStore) purgeExpired() {
+	now := s.now()
+	for state, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, state)
+		}
+	}
+}
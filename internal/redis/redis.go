@@ -59,6 +59,15 @@ func (c *Client) Set(ctx context.Context, key string, value interface{}, expirat
 	return c.client.Set(ctx, key, value, expiration).Err()
 }
 
+// GetDel 获取键值并删除，键不存在时返回空字符串（需要 Redis 6.2+）
+func (c *Client) GetDel(ctx context.Context, key string) (string, error) {
+	val, err := c.client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
+
 // Delete 删除键
 func (c *Client) Delete(ctx context.Context, keys ...string) error {
 	return c.client.Del(ctx, keys...).Err()
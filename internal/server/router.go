@@ -6,16 +6,41 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gorm.io/gorm"
 
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
 	"github.com/yeegeek/go-rest-api-starter/internal/auth"
 	"github.com/yeegeek/go-rest-api-starter/internal/config"
 	"github.com/yeegeek/go-rest-api-starter/internal/errors"
 	"github.com/yeegeek/go-rest-api-starter/internal/health"
 	"github.com/yeegeek/go-rest-api-starter/internal/middleware"
+	"github.com/yeegeek/go-rest-api-starter/internal/oauth"
 	"github.com/yeegeek/go-rest-api-starter/internal/user"
 )
 
+// Option configures optional dependencies of the router
+type Option func(*routerOptions)
+
+type routerOptions struct {
+	auditService audit.Service
+}
+
+// WithAuditService serves the admin audit log from auditService
+func WithAuditService(auditService audit.Service) Option {
+	return func(o *routerOptions) {
+		o.auditService = auditService
+	}
+}
+
 // SetupRouter creates and configures the Gin router
-func SetupRouter(userHandler *user.Handler, authService auth.Service, cfg *config.Config, db *gorm.DB) *gin.Engine {
+// Unless set with WithAuditService, audit events are listed from the audit_events table of db
+func SetupRouter(userHandler *user.Handler, authService auth.Service, cfg *config.Config, db *gorm.DB, opts ...Option) *gin.Engine {
+	options := routerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.auditService == nil {
+		options.auditService = audit.NewService(audit.NewRepository(db))
+	}
+
 	router := gin.New()
 
 	if cfg.App.Environment == "production" {
@@ -31,13 +56,15 @@ func SetupRouter(userHandler *user.Handler, authService auth.Service, cfg *confi
 	)
 	// 日志、错误处理和恢复中间件
 	router.Use(middleware.Logger(loggerConfig))
+	// 审计来源：服务层记录审计事件时从请求上下文读取操作者、请求 ID 和客户端信息
+	router.Use(middleware.AuditContext())
 	router.Use(errors.ErrorHandler())
 	router.Use(gin.Recovery())
 
 	// 输入验证中间件 - 防止 SQL 注入和 XSS 攻击
 	router.Use(middleware.InputValidationMiddleware())
 
-	// CORS 和 Rate Limiting 由 API 网关处理
+	// CORS 和全局 Rate Limiting 由 API 网关处理
 
 	var checkers []health.Checker
 	if cfg.Health.DatabaseCheckEnabled {
@@ -53,33 +80,163 @@ func SetupRouter(userHandler *user.Handler, authService auth.Service, cfg *confi
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// 公开 JWT 验证公钥，供网关和其他服务离线验证令牌
+	router.GET("/.well-known/jwks.json", auth.JWKSHandler(authService))
+
+	// 根据配置选择认证方式：网关头、JWT 或两者兼容；启用 API 密钥时额外接受 ApiKey 凭证
+	var apiKeys auth.APIKeyAuthenticator
+	if cfg.APIKeys.Enabled {
+		apiKeys = userHandler.APIKeyAuthenticator()
+	}
+	authMiddleware := middleware.NewAuthMiddleware(cfg.Auth.GetMode(), &cfg.Gateway, authService, apiKeys, options.auditService)
+	// 管理员模拟会话不能修改密码、邮箱、角色和凭证，也不能删除账户
+	denyImpersonation := middleware.DenyImpersonation()
+	// API 密钥只能访问普通业务端点，管理员端点和账户安全设置需要登录会话
+	denyAPIKey := middleware.DenyAPIKey()
+
+	// OAuth2 客户端凭证：服务间调用使用 /oauth/token 获取带 scope 的令牌
+	// 模板本身不挂载服务间路由；下游新增时用 middleware.ClientAuthMiddleware 和 middleware.RequireScope 保护
+	// 网关和其他服务通过 /oauth/introspect 和 /oauth/revoke 检查或撤销令牌
+	auditHandler := audit.NewHandler(options.auditService)
+
+	var oauthHandler *oauth.Handler
+	if cfg.OAuth.Enabled {
+		oauthService := oauth.NewService(oauth.NewRepository(db), authService, auth.NewRefreshTokenRepository(db), &cfg.OAuth)
+		oauthHandler = oauth.NewHandler(oauthService)
+
+		oauthGroup := router.Group("/oauth")
+		if cfg.Ratelimit.Enabled {
+			oauthGroup.Use(middleware.NewRateLimitMiddleware(
+				cfg.Ratelimit.Window,
+				cfg.Ratelimit.Requests,
+				func(c *gin.Context) string { return c.ClientIP() },
+				nil,
+			))
+		}
+		oauthGroup.POST("/token", oauthHandler.Token)
+		oauthGroup.POST("/introspect", oauthHandler.Introspect)
+		oauthGroup.POST("/revoke", oauthHandler.Revoke)
+	}
+
 	v1 := router.Group("/api/v1")
 	{
+		// 未认证的认证端点易受暴力破解，无网关部署时也需在服务内限流；
+		// 已认证端点由令牌本身约束，不占用限流额度
+		var authRateLimit []gin.HandlerFunc
+		if cfg.Ratelimit.Enabled {
+			authRateLimit = append(authRateLimit, middleware.NewRateLimitMiddleware(
+				cfg.Ratelimit.Window,
+				cfg.Ratelimit.Requests,
+				func(c *gin.Context) string { return c.ClientIP() },
+				nil,
+			))
+		}
+
 		// 公开端点（无需认证）
 		publicGroup := v1.Group("/public")
 		{
-			publicGroup.POST("/register", userHandler.Register)
+			// 兼容旧客户端的注册别名，与 /auth/register 共用限流
+			publicGroup.Group("", authRateLimit...).POST("/register", userHandler.Register)
+			publicGroup.GET("/verify-email", userHandler.VerifyEmail)
 		}
 
-		// 用户端点 - 需要网关认证
+		// 认证端点 - 登录和刷新公开，登出需要认证
+		authGroup := v1.Group("/auth")
+		{
+			unauthenticated := authGroup.Group("", authRateLimit...)
+			unauthenticated.POST("/register", userHandler.Register)
+			unauthenticated.POST("/login", userHandler.Login)
+			unauthenticated.POST("/refresh", userHandler.RefreshToken)
+			unauthenticated.POST("/password-reset/request", userHandler.RequestPasswordReset)
+			unauthenticated.POST("/password-reset/confirm", userHandler.ConfirmPasswordReset)
+			unauthenticated.POST("/verify-email/resend", userHandler.ResendVerificationEmail)
+			unauthenticated.POST("/mfa/verify", userHandler.VerifyMFA)
+			unauthenticated.POST("/mfa/enroll", userHandler.BeginMFAEnrollment)
+			unauthenticated.POST("/mfa/enroll/confirm", userHandler.ConfirmMFAEnrollment)
+			if cfg.OIDC.Enabled {
+				unauthenticated.GET("/oidc", userHandler.ListOIDCProviders)
+				unauthenticated.GET("/oidc/:provider", userHandler.OIDCLogin)
+				unauthenticated.GET("/oidc/:provider/callback", userHandler.OIDCCallback)
+			}
+			authGroup.POST("/logout", authMiddleware, userHandler.Logout)
+			authGroup.POST("/logout-all", authMiddleware, denyImpersonation, userHandler.LogoutAll)
+			authGroup.GET("/me", authMiddleware, userHandler.GetMe)
+			if cfg.Impersonation.Enabled {
+				authGroup.POST("/impersonation/stop", authMiddleware, userHandler.StopImpersonation)
+			}
+		}
+
+		// 用户端点 - 需要认证
 		usersGroup := v1.Group("/users")
-		usersGroup.Use(middleware.GatewayAuthMiddleware())
+		usersGroup.Use(authMiddleware)
 		{
 			usersGroup.GET("/me", userHandler.GetMe)
+			usersGroup.PUT("/me/password", denyImpersonation, denyAPIKey, userHandler.ChangeMyPassword)
+			usersGroup.GET("/me/sessions", userHandler.ListMySessions)
+			usersGroup.DELETE("/me/sessions/:family", denyImpersonation, userHandler.RevokeMySession)
+			usersGroup.GET("/me/mfa", userHandler.GetMyMFAStatus)
+			usersGroup.POST("/me/mfa/totp", denyImpersonation, denyAPIKey, userHandler.EnrollMyTOTP)
+			usersGroup.POST("/me/mfa/totp/confirm", denyImpersonation, denyAPIKey, userHandler.ConfirmMyTOTP)
+			usersGroup.DELETE("/me/mfa/totp", denyImpersonation, denyAPIKey, userHandler.DisableMyTOTP)
+			usersGroup.POST("/me/mfa/recovery-codes", denyImpersonation, denyAPIKey, userHandler.RegenerateMyRecoveryCodes)
+			if cfg.OIDC.Enabled {
+				usersGroup.GET("/me/identities", userHandler.ListMyIdentities)
+				usersGroup.POST("/me/identities/:provider", denyImpersonation, denyAPIKey, userHandler.LinkMyIdentity)
+				usersGroup.DELETE("/me/identities/:provider", denyImpersonation, denyAPIKey, userHandler.UnlinkMyIdentity)
+			}
+			if cfg.APIKeys.Enabled {
+				usersGroup.GET("/me/api-keys", denyAPIKey, userHandler.ListMyAPIKeys)
+				usersGroup.POST("/me/api-keys", denyImpersonation, denyAPIKey, userHandler.CreateMyAPIKey)
+				usersGroup.GET("/me/api-keys/:id", denyAPIKey, userHandler.GetMyAPIKey)
+				usersGroup.PATCH("/me/api-keys/:id", denyImpersonation, denyAPIKey, userHandler.UpdateMyAPIKey)
+				usersGroup.DELETE("/me/api-keys/:id", denyImpersonation, denyAPIKey, userHandler.RevokeMyAPIKey)
+			}
 			usersGroup.GET("/:id", userHandler.GetUser)
-			usersGroup.PUT("/:id", userHandler.UpdateUser)
-			usersGroup.DELETE("/:id", userHandler.DeleteUser)
+			usersGroup.PUT("/:id", denyImpersonation, userHandler.UpdateUser)
+			usersGroup.DELETE("/:id", denyImpersonation, userHandler.DeleteUser)
 		}
 
-		// 管理员端点 - 需要网关认证和管理员角色
+		// 管理员端点 - 需要认证和管理员角色
 		adminGroup := v1.Group("/admin")
-		adminGroup.Use(middleware.GatewayAuthMiddleware(), middleware.RequireAdminRole())
+		adminGroup.Use(authMiddleware, denyImpersonation, denyAPIKey, middleware.RequireAdminRole())
 		{
 			// 用户管理端点
 			adminGroup.GET("/users", userHandler.ListUsers)
 			adminGroup.GET("/users/:id", userHandler.GetUser)
 			adminGroup.PUT("/users/:id", userHandler.UpdateUser)
 			adminGroup.DELETE("/users/:id", userHandler.DeleteUser)
+			adminGroup.GET("/users/:id/sessions", userHandler.ListUserSessions)
+			adminGroup.DELETE("/users/:id/sessions/:family", userHandler.RevokeUserSession)
+			adminGroup.POST("/users/:id/unlock", userHandler.UnlockUser)
+			adminGroup.POST("/users/:id/roles/:role", userHandler.GrantUserRole)
+			adminGroup.DELETE("/users/:id/roles/:role", userHandler.RevokeUserRole)
+			if cfg.Impersonation.Enabled {
+				adminGroup.POST("/users/:id/impersonate", userHandler.StartImpersonation)
+			}
+
+			if oauthHandler != nil {
+				// 服务客户端管理端点
+				adminGroup.GET("/oauth/clients", oauthHandler.ListClients)
+				adminGroup.POST("/oauth/clients", oauthHandler.RegisterClient)
+				adminGroup.DELETE("/oauth/clients/:client_id", oauthHandler.DeleteClient)
+			}
+
+			// 审计日志查询端点
+			adminGroup.GET("/audit-events", auditHandler.ListEvents)
+		}
+
+		// 角色与权限管理端点 - 按权限而非角色名授权，自定义角色可被授予管理能力
+		permissions := userHandler.PermissionResolver()
+		rbacGroup := v1.Group("/admin")
+		rbacGroup.Use(authMiddleware, denyImpersonation, denyAPIKey)
+		{
+			rbacGroup.GET("/roles", middleware.RequirePermission(permissions, user.PermissionRolesRead), userHandler.ListRoles)
+			rbacGroup.POST("/roles", middleware.RequirePermission(permissions, user.PermissionRolesWrite), userHandler.CreateRole)
+			rbacGroup.GET("/roles/:name", middleware.RequirePermission(permissions, user.PermissionRolesRead), userHandler.GetRole)
+			rbacGroup.PUT("/roles/:name", middleware.RequirePermission(permissions, user.PermissionRolesWrite), userHandler.UpdateRole)
+			rbacGroup.DELETE("/roles/:name", middleware.RequirePermission(permissions, user.PermissionRolesWrite), userHandler.DeleteRole)
+			rbacGroup.GET("/roles/:name/users", middleware.RequirePermission(permissions, user.PermissionRolesRead, user.PermissionUsersRead), userHandler.ListRoleUsers)
+			rbacGroup.GET("/permissions", middleware.RequirePermission(permissions, user.PermissionRolesRead), userHandler.ListPermissions)
 		}
 	}
 
@@ -0,0 +1,186 @@
+// Package totp implements time-based one-time passwords (RFC 6238) on top of HOTP (RFC 4226).
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported HMAC algorithms
+const (
+	AlgorithmSHA1   = "SHA1"
+	AlgorithmSHA256 = "SHA256"
+	AlgorithmSHA512 = "SHA512"
+)
+
+// Defaults used by authenticator apps; most apps ignore any other values in the key URI
+const (
+	DefaultAlgorithm = AlgorithmSHA1
+	DefaultDigits    = 6
+	DefaultPeriod    = 30 * time.Second
+)
+
+// SecretSize is the length of generated secrets in bytes (160 bits, as recommended by RFC 4226)
+const SecretSize = 20
+
+// ErrInvalidSecret is returned when a secret is not valid base32
+var ErrInvalidSecret = errors.New("invalid TOTP secret")
+
+// secretEncoding is unpadded base32, the format authenticator apps expect
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Options configures code generation; zero values use the defaults
+type Options struct {
+	Algorithm string
+	Digits    int
+	Period    time.Duration
+}
+
+func (o Options) algorithm() string {
+	if o.Algorithm == "" {
+		return DefaultAlgorithm
+	}
+	return strings.ToUpper(o.Algorithm)
+}
+
+func (o Options) digits() int {
+	if o.Digits <= 0 {
+		return DefaultDigits
+	}
+	return o.Digits
+}
+
+func (o Options) period() time.Duration {
+	if o.Period <= 0 {
+		return DefaultPeriod
+	}
+	return o.Period
+}
+
+// GenerateSecret returns a random secret encoded as unpadded base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	return secretEncoding.EncodeToString(b), nil
+}
+
+// DecodeSecret decodes a base32 secret, ignoring case, spaces and padding as typed by users
+func DecodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := secretEncoding.DecodeString(strings.TrimRight(normalized, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// HOTP computes the RFC 4226 code for a counter
+func HOTP(key []byte, counter uint64, opts Options) (string, error) {
+	newHash, err := hashFunc(opts.algorithm())
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(newHash, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// 动态截断：取最后一个字节的低 4 位作为偏移量，读取 31 位整数
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	digits := opts.digits()
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod), nil
+}
+
+// Step returns the time step (counter) that t falls into
+func Step(t time.Time, opts Options) uint64 {
+	return uint64(t.Unix()) / uint64(opts.period()/time.Second)
+}
+
+// Code computes the RFC 6238 code for time t
+func Code(key []byte, t time.Time, opts Options) (string, error) {
+	return HOTP(key, Step(t, opts), opts)
+}
+
+// Validate checks code against the steps within skew of t and returns the matching step.
+// Callers should store the step and reject codes for the same or an earlier step, so a code cannot be replayed.
+func Validate(key []byte, code string, t time.Time, skew int, opts Options) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != opts.digits() {
+		return 0, false
+	}
+	if _, err := strconv.ParseUint(code, 10, 64); err != nil {
+		return 0, false
+	}
+
+	current := Step(t, opts)
+	for i := -skew; i <= skew; i++ {
+		if i < 0 && current < uint64(-i) {
+			continue
+		}
+		step := current + uint64(i)
+		expected, err := HOTP(key, step, opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// KeyURI returns the otpauth:// URI that authenticator apps import, usually shown as a QR code.
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func KeyURI(issuer, account, secret string, opts Options) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	if issuer != "" {
+		query.Set("issuer", issuer)
+	}
+	query.Set("algorithm", opts.algorithm())
+	query.Set("digits", strconv.Itoa(opts.digits()))
+	query.Set("period", strconv.Itoa(int(opts.period()/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported TOTP algorithm %q", algorithm)
+	}
+}
@@ -0,0 +1,144 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 Appendix B test vectors (8 digits, 30 second period)
+func TestCode_RFC6238Vectors(t *testing.T) {
+	keys := map[string][]byte{
+		AlgorithmSHA1:   []byte("12345678901234567890"),
+		AlgorithmSHA256: []byte("12345678901234567890123456789012"),
+		AlgorithmSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1111111109, AlgorithmSHA256, "68084774"},
+		{1111111109, AlgorithmSHA512, "25091201"},
+		{1111111111, AlgorithmSHA1, "14050471"},
+		{1111111111, AlgorithmSHA256, "67062674"},
+		{1111111111, AlgorithmSHA512, "99943326"},
+		{1234567890, AlgorithmSHA1, "89005924"},
+		{1234567890, AlgorithmSHA256, "91819424"},
+		{1234567890, AlgorithmSHA512, "93441116"},
+		{2000000000, AlgorithmSHA1, "69279037"},
+		{2000000000, AlgorithmSHA256, "90698825"},
+		{2000000000, AlgorithmSHA512, "38618901"},
+		{20000000000, AlgorithmSHA1, "65353130"},
+		{20000000000, AlgorithmSHA256, "77737706"},
+		{20000000000, AlgorithmSHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"/"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			opts := Options{Algorithm: tt.algorithm, Digits: 8}
+			code, err := Code(keys[tt.algorithm], time.Unix(tt.unix, 0), opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, code)
+		})
+	}
+}
+
+// RFC 4226 Appendix D test vectors (6 digits, SHA1)
+func TestHOTP_RFC4226Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, expected := range want {
+		code, err := HOTP(key, uint64(counter), Options{})
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "counter %d", counter)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := []byte("12345678901234567890")
+	opts := Options{Digits: 8}
+	now := time.Unix(1111111111, 0) // step 37037037, code 14050471
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantOK   bool
+		wantStep uint64
+	}{
+		{"current step", "14050471", 0, true, 37037037},
+		{"previous step within skew", "07081804", 1, true, 37037036},
+		{"previous step without skew", "07081804", 0, false, 0},
+		{"surrounding whitespace", " 14050471 ", 0, true, 37037037},
+		{"wrong code", "12345678", 1, false, 0},
+		{"wrong length", "1405047", 1, false, 0},
+		{"not numeric", "1405047a", 1, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(key, tt.code, now, tt.skew, opts)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantStep, step)
+		})
+	}
+}
+
+func TestGenerateSecret_RoundTrip(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32, "20 bytes encode to 32 unpadded base32 characters")
+
+	key, err := DecodeSecret(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, SecretSize)
+
+	other, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestDecodeSecret(t *testing.T) {
+	// base32("12345678901234567890")
+	want := []byte("12345678901234567890")
+
+	for _, secret := range []string{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+		"GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ",
+	} {
+		key, err := DecodeSecret(secret)
+		require.NoError(t, err, secret)
+		assert.Equal(t, want, key)
+	}
+
+	_, err := DecodeSecret("not base32!")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+	_, err = DecodeSecret("")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := KeyURI("Go REST API", "jane@example.com", "GEZDGNBVGY3TQOJQ", Options{})
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Go REST API:jane@example.com", parsed.Path)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQ", parsed.Query().Get("secret"))
+	assert.Equal(t, "Go REST API", parsed.Query().Get("issuer"))
+	assert.Equal(t, "SHA1", parsed.Query().Get("algorithm"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+	assert.Equal(t, "30", parsed.Query().Get("period"))
+}
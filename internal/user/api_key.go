@@ -0,0 +1,249 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+)
+
+// APIKeyPrefix marks personal API keys so they can be recognised in logs and by secret scanners
+const APIKeyPrefix = "sk_"
+
+const (
+	// apiKeyDisplayLength is how much of the key is stored in clear to tell keys apart
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+	// apiKeyTouchInterval limits last-used writes to one per key and interval
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when the key doesn't exist or belongs to another user
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey is returned when authenticating with an unknown or expired key
+	ErrInvalidAPIKey = errors.New("invalid or expired api key")
+	// ErrAPIKeyLimitReached is returned when the user already holds api_keys.max_per_user active keys
+	ErrAPIKeyLimitReached = errors.New("api key limit reached")
+	// ErrInvalidAPIKeyExpiry is returned when the expiry is in the past or beyond api_keys.max_ttl
+	ErrInvalidAPIKeyExpiry = errors.New("invalid api key expiry")
+)
+
+// APIKey is a long-lived secret a user creates for scripts and integrations
+// Only the SHA-256 hash of the key is stored
+type APIKey struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"type:varchar(100);not null"`
+	Prefix     string `gorm:"type:varchar(16);not null"`
+	KeyHash    string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Scopes     string `gorm:"type:varchar(255);not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"type:varchar(45)"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// TableName specifies the table name for APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the granted scopes
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// IsExpired reports whether the key can no longer be used at now
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKey creates a key for a user and returns it with the secret, which is not stored
+func (s *service) CreateAPIKey(ctx context.Context, userID uint, req CreateAPIKeyRequest) (*APIKey, string, error) {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	expiresAt, err := s.apiKeyExpiry(req.ExpiresAt, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	active, err := s.repo.CountActiveAPIKeys(ctx, userID, now)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count api keys: %w", err)
+	}
+	if active >= int64(s.apiKeys.GetMaxPerUser()) {
+		return nil, "", ErrAPIKeyLimitReached
+	}
+
+	secret, err := generateOneTimeToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := &APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   auth.HashToken(key),
+		Scopes:    joinScopes(req.Scopes),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateAPIKey(ctx, apiKey); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionAPIKeyCreate,
+		TargetType: audit.TargetAPIKey,
+		TargetID:   audit.ID(apiKey.ID),
+		Metadata:   map[string]interface{}{"user_id": userID, "name": apiKey.Name, "prefix": apiKey.Prefix, "scopes": apiKey.Scopes},
+	})
+	return apiKey, key, nil
+}
+
+// ListAPIKeys returns the keys of a user, newest first, including expired ones
+func (s *service) ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error) {
+	keys, err := s.repo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKey returns one key of a user
+func (s *service) GetAPIKey(ctx context.Context, userID, id uint) (*APIKey, error) {
+	key, err := s.repo.FindAPIKey(ctx, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	if key == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// UpdateAPIKey renames a key or replaces its scopes; the secret and expiry never change
+func (s *service) UpdateAPIKey(ctx context.Context, userID, id uint, req UpdateAPIKeyRequest) (*APIKey, error) {
+	key, err := s.GetAPIKey(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if len(req.Scopes) > 0 {
+		key.Scopes = joinScopes(req.Scopes)
+	}
+	if err := s.repo.UpdateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+	return key, nil
+}
+
+// RevokeAPIKey deletes a key of a user; requests using it fail immediately
+func (s *service) RevokeAPIKey(ctx context.Context, userID, id uint) error {
+	if err := s.repo.DeleteAPIKey(ctx, userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionAPIKeyRevoke,
+		TargetType: audit.TargetAPIKey,
+		TargetID:   audit.ID(id),
+		Metadata:   map[string]interface{}{"user_id": userID},
+	})
+	return nil
+}
+
+// AuthenticateAPIKey resolves a key to its owner and records its use
+// The client IP is taken from auth.WithClientInfo when present
+func (s *service) AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.FindAPIKeyByHash(ctx, auth.HashToken(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	now := time.Now()
+	if apiKey == nil || apiKey.IsExpired(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.findUser(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if err := s.CheckLoginAllowed(user); err != nil {
+		return nil, err
+	}
+
+	// 最近使用时间按分钟粒度记录，避免每个请求都写数据库
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		info, _ := auth.ClientInfoFromContext(ctx)
+		if err := s.repo.TouchAPIKey(ctx, apiKey.ID, now, info.IPAddress); err != nil {
+			slog.WarnContext(ctx, "Failed to record api key use", "api_key_id", apiKey.ID, "error", err)
+		}
+	}
+
+	return &auth.APIKeyPrincipal{
+		KeyID:  apiKey.ID,
+		UserID: user.ID,
+		Roles:  user.GetRoleNames(),
+		Scopes: apiKey.ScopeList(),
+	}, nil
+}
+
+// apiKeyExpiry validates the requested expiry against api_keys.max_ttl
+// Without a requested expiry, keys live for max_ttl or forever when it is 0
+func (s *service) apiKeyExpiry(requested *time.Time, now time.Time) (*time.Time, error) {
+	maxTTL := s.apiKeys.MaxTTL
+	if requested == nil {
+		if maxTTL <= 0 {
+			return nil, nil
+		}
+		expiresAt := now.Add(maxTTL)
+		return &expiresAt, nil
+	}
+
+	if !requested.After(now) || (maxTTL > 0 && requested.After(now.Add(maxTTL))) {
+		return nil, ErrInvalidAPIKeyExpiry
+	}
+	expiresAt := *requested
+	return &expiresAt, nil
+}
+
+// joinScopes stores scopes sorted and without duplicates
+func joinScopes(scopes []string) string {
+	unique := make(map[string]struct{}, len(scopes))
+	for _, scope := range scopes {
+		unique[scope] = struct{}{}
+	}
+	sorted := make([]string, 0, len(unique))
+	for scope := range unique {
+		sorted = append(sorted, scope)
+	}
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func setupAPIKeyTest(t *testing.T, apiKeyCfg config.APIKeyConfig) (Service, Repository, *User) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&APIKey{}))
+
+	repo := NewRepository(db)
+	svc := NewService(repo, WithMailer(&recordingSender{}), WithPasswordHasher(NewPasswordHasher(testBcryptConfig)), WithAPIKeys(&apiKeyCfg))
+
+	user, err := svc.RegisterUser(context.Background(), RegisterRequest{Name: "Jane", Email: "jane@example.com", Password: "password123"})
+	require.NoError(t, err)
+	return svc, repo, user
+}
+
+func TestService_APIKeyLifecycle(t *testing.T) {
+	svc, repo, user := setupAPIKeyTest(t, config.APIKeyConfig{})
+	ctx := context.Background()
+
+	apiKey, key, err := svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "ci", Scopes: []string{"write", "read", "write"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+	assert.Equal(t, key[:apiKeyDisplayLength], apiKey.Prefix)
+	assert.Equal(t, auth.HashToken(key), apiKey.KeyHash, "only the hash is stored")
+	assert.Equal(t, []string{"read", "write"}, apiKey.ScopeList())
+	assert.Nil(t, apiKey.ExpiresAt, "keys never expire without api_keys.max_ttl")
+
+	clientCtx := auth.WithClientInfo(ctx, "ci-runner", "203.0.113.7")
+	principal, err := svc.AuthenticateAPIKey(clientCtx, key)
+	require.NoError(t, err)
+	assert.Equal(t, &auth.APIKeyPrincipal{KeyID: apiKey.ID, UserID: user.ID, Roles: []string{RoleUser}, Scopes: []string{"read", "write"}}, principal)
+
+	stored, err := repo.FindAPIKey(ctx, user.ID, apiKey.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.Equal(t, "203.0.113.7", stored.LastUsedIP)
+
+	name := "deploy"
+	updated, err := svc.UpdateAPIKey(ctx, user.ID, apiKey.ID, UpdateAPIKeyRequest{Name: &name, Scopes: []string{"read"}})
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", updated.Name)
+	assert.Equal(t, []string{"read"}, updated.ScopeList())
+
+	_, err = svc.GetAPIKey(ctx, user.ID+1, apiKey.ID)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound, "keys of other users are not visible")
+
+	require.NoError(t, svc.RevokeAPIKey(ctx, user.ID, apiKey.ID))
+	assert.ErrorIs(t, svc.RevokeAPIKey(ctx, user.ID, apiKey.ID), ErrAPIKeyNotFound)
+
+	_, err = svc.AuthenticateAPIKey(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestService_AuthenticateAPIKey_Rejected(t *testing.T) {
+	svc, repo, user := setupAPIKeyTest(t, config.APIKeyConfig{})
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, repo.CreateAPIKey(ctx, &APIKey{UserID: user.ID, Name: "old", Prefix: "sk_expired", KeyHash: auth.HashToken("sk_expired"), Scopes: "read", ExpiresAt: &expired}))
+
+	_, key, err := svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteUser(ctx, 0, user.ID))
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "unknown key", key: "sk_unknown"},
+		{name: "missing prefix", key: strings.TrimPrefix(key, APIKeyPrefix)},
+		{name: "expired key", key: "sk_expired"},
+		{name: "deleted owner", key: key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.AuthenticateAPIKey(ctx, tt.key)
+			assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		})
+	}
+}
+
+func TestService_CreateAPIKey_Limits(t *testing.T) {
+	svc, _, user := setupAPIKeyTest(t, config.APIKeyConfig{MaxPerUser: 2, MaxTTL: 30 * 24 * time.Hour})
+	ctx := context.Background()
+
+	inTenDays := time.Now().Add(10 * 24 * time.Hour)
+	inSixtyDays := time.Now().Add(60 * 24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	_, _, err := svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "too long", Scopes: []string{"read"}, ExpiresAt: &inSixtyDays})
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyExpiry)
+	_, _, err = svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "past", Scopes: []string{"read"}, ExpiresAt: &yesterday})
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyExpiry)
+
+	defaulted, _, err := svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "default", Scopes: []string{"read"}})
+	require.NoError(t, err)
+	require.NotNil(t, defaulted.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), *defaulted.ExpiresAt, time.Minute, "expiry defaults to api_keys.max_ttl")
+
+	_, _, err = svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "explicit", Scopes: []string{"read"}, ExpiresAt: &inTenDays})
+	require.NoError(t, err)
+
+	_, _, err = svc.CreateAPIKey(ctx, user.ID, CreateAPIKeyRequest{Name: "third", Scopes: []string{"read"}})
+	assert.ErrorIs(t, err, ErrAPIKeyLimitReached)
+
+	_, _, err = svc.CreateAPIKey(ctx, 999, CreateAPIKeyRequest{Name: "ghost", Scopes: []string{"read"}})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"context"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+)
+
+// recordAudit records an audit event; services built without a recorder drop it
+func (s *service) recordAudit(ctx context.Context, event audit.Event) {
+	if s.auditor != nil {
+		s.auditor.Record(ctx, event)
+	}
+}
+
+// auditUserFields returns the user fields tracked in audit diffs; credentials are never included
+func auditUserFields(user *User) map[string]interface{} {
+	return map[string]interface{}{
+		"name":  user.Name,
+		"email": user.Email,
+		"roles": audit.SortedStrings(user.GetRoleNames()),
+	}
+}
+
+// auditRoleFields returns the role fields tracked in audit diffs
+func auditRoleFields(role *Role) map[string]interface{} {
+	return map[string]interface{}{
+		"description": role.Description,
+		"permissions": audit.SortedStrings(role.PermissionNames()),
+	}
+}
+
+// userEvent builds an event targeting a user
+func userEvent(action string, userID uint) audit.Event {
+	return audit.Event{Action: action, TargetType: audit.TargetUser, TargetID: audit.ID(userID)}
+}
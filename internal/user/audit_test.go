@@ -0,0 +1,165 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+)
+
+// recordingAuditor keeps recorded audit events in memory
+type recordingAuditor struct {
+	events []audit.Event
+}
+
+func (r *recordingAuditor) Record(_ context.Context, event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func newAuditedService(repo Repository, recorder audit.Recorder) Service {
+	return NewService(repo, WithPasswordHasher(NewPasswordHasher(&config.PasswordHashConfig{BcryptCost: bcrypt.MinCost})), WithAuditRecorder(recorder))
+}
+
+func TestService_AuditLogin(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		password       string
+		user           *User
+		expectedAction string
+		expectedActor  uint
+		expectedTarget string
+	}{
+		{name: "success", password: "password123", user: &User{ID: 1, Email: "john@example.com", PasswordHash: string(hash)}, expectedAction: audit.ActionLogin, expectedActor: 1, expectedTarget: "1"},
+		{name: "wrong password", password: "wrong", user: &User{ID: 1, Email: "john@example.com", PasswordHash: string(hash)}, expectedAction: audit.ActionLoginFailed, expectedTarget: "1"},
+		{name: "unknown email", password: "password123", expectedAction: audit.ActionLoginFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			if tt.user != nil {
+				mockRepo.On("FindByEmail", mock.Anything, "john@example.com").Return(tt.user, nil)
+			} else {
+				mockRepo.On("FindByEmail", mock.Anything, "john@example.com").Return(nil, nil)
+			}
+			recorder := &recordingAuditor{}
+			svc := newAuditedService(mockRepo, recorder)
+
+			_, _ = svc.AuthenticateUser(context.Background(), LoginRequest{Email: "john@example.com", Password: tt.password})
+
+			require.Len(t, recorder.events, 1)
+			event := recorder.events[0]
+			assert.Equal(t, tt.expectedAction, event.Action)
+			assert.Equal(t, tt.expectedActor, event.ActorID)
+			assert.Equal(t, audit.TargetUser, event.TargetType)
+			assert.Equal(t, tt.expectedTarget, event.TargetID)
+		})
+	}
+}
+
+func TestService_AuditUserChanges(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("update records changed fields", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+		mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*user.User")).Return(nil)
+		recorder := &recordingAuditor{}
+		svc := newAuditedService(mockRepo, recorder)
+
+		_, err := svc.UpdateUser(ctx, 1, UpdateUserRequest{Name: "Johnny"})
+		require.NoError(t, err)
+		_, err = svc.UpdateUser(ctx, 1, UpdateUserRequest{Name: "Johnny"})
+		require.NoError(t, err)
+
+		require.Len(t, recorder.events, 1, "an update without changes is not audited")
+		assert.Equal(t, audit.ActionUserUpdate, recorder.events[0].Action)
+		assert.Equal(t, map[string]audit.Change{"name": {Before: "John", After: "Johnny"}}, recorder.events[0].Changes)
+	})
+
+	t.Run("grant role records roles before and after", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindRoleByName", mock.Anything, "auditor").Return(&Role{ID: 3, Name: "auditor"}, nil)
+		mockRepo.On("FindByID", mock.Anything, uint(2)).Return(&User{ID: 2, Roles: []Role{{Name: RoleUser}}}, nil).Once()
+		mockRepo.On("AssignRole", mock.Anything, uint(2), "auditor").Return(nil)
+		mockRepo.On("FindByID", mock.Anything, uint(2)).Return(&User{ID: 2, Roles: []Role{{Name: RoleUser}, {Name: "auditor"}}}, nil).Once()
+		recorder := &recordingAuditor{}
+		svc := newAuditedService(mockRepo, recorder)
+
+		_, changed, err := svc.GrantRole(ctx, 2, "auditor")
+		require.NoError(t, err)
+		require.True(t, changed)
+
+		require.Len(t, recorder.events, 1)
+		event := recorder.events[0]
+		assert.Equal(t, audit.ActionRoleGrant, event.Action)
+		assert.Equal(t, "2", event.TargetID)
+		assert.Equal(t, "auditor", event.Metadata["role"])
+		assert.Equal(t, map[string]audit.Change{"roles": {Before: []string{"user"}, After: []string{"auditor", "user"}}}, event.Changes)
+	})
+
+	t.Run("delete is recorded only when it succeeds", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("FindByID", mock.Anything, uint(4)).Return(&User{ID: 4}, nil)
+		mockRepo.On("FindByID", mock.Anything, uint(5)).Return(&User{ID: 5}, nil)
+		mockRepo.On("Delete", mock.Anything, uint(4)).Return(nil)
+		mockRepo.On("Delete", mock.Anything, uint(5)).Return(errors.New("database error"))
+		recorder := &recordingAuditor{}
+		svc := newAuditedService(mockRepo, recorder)
+
+		require.NoError(t, svc.DeleteUser(ctx, 1, 4))
+		require.Error(t, svc.DeleteUser(ctx, 1, 5))
+
+		require.Len(t, recorder.events, 1)
+		assert.Equal(t, audit.ActionUserDelete, recorder.events[0].Action)
+		assert.Equal(t, "4", recorder.events[0].TargetID)
+	})
+}
+
+func TestService_AuditIdentities(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&UserIdentity{}))
+	recorder := &recordingAuditor{}
+	svc := newAuditedService(NewRepository(db), recorder)
+	ctx := context.Background()
+
+	actions := func() []string {
+		var names []string
+		for _, event := range recorder.events {
+			names = append(names, event.Action)
+		}
+		recorder.events = nil
+		return names
+	}
+
+	user, err := svc.LoginWithIdentity(ctx, &oidc.Identity{Provider: "google", Subject: "sub-1", Email: "jane@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	created := recorder.events[0]
+	assert.Equal(t, []string{audit.ActionUserCreate, audit.ActionLogin}, actions())
+	assert.Equal(t, audit.ID(user.ID), created.TargetID)
+	assert.Equal(t, "google", created.Metadata["provider"])
+
+	_, err = svc.LinkIdentity(ctx, user.ID, &oidc.Identity{Provider: "github", Subject: "gh-1"})
+	require.NoError(t, err)
+	linked := recorder.events[0]
+	assert.Equal(t, []string{audit.ActionIdentityLink}, actions())
+	assert.Equal(t, user.ID, linked.ActorID, "the callback is unauthenticated, so the linked user is the actor")
+	assert.Equal(t, "github", linked.Metadata["provider"])
+
+	require.NoError(t, svc.UnlinkIdentity(ctx, user.ID, "github"))
+	assert.Equal(t, []string{audit.ActionIdentityUnlink}, actions())
+
+	assert.ErrorIs(t, svc.UnlinkIdentity(ctx, user.ID, "github"), ErrIdentityNotFound)
+	assert.Empty(t, actions(), "failed unlinks are not recorded")
+}
@@ -1,10 +1,16 @@
 package user
 
+import (
+	"time"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+)
+
 // RegisterRequest represents registration request payload
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required,min=2,max=100"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 // LoginRequest represents login request payload
@@ -19,14 +25,83 @@ type UpdateUserRequest struct {
 	Email string `json:"email" binding:"omitempty,email"`
 }
 
+// ChangePasswordRequest represents password change request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	// RevokeOtherSessions signs out every other device; defaults to true when omitted
+	RevokeOtherSessions *bool `json:"revoke_other_sessions"`
+}
+
+// ResendVerificationRequest represents verification email resend payload
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// PasswordResetRequest represents password reset request payload
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// PasswordResetConfirmRequest represents password reset confirmation payload
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// MFAVerifyRequest completes a pending login with a TOTP or recovery code
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFATokenRequest identifies a pending login that must enroll two-factor authentication
+type MFATokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFACodeRequest carries a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CreateAPIKeyRequest represents API key creation payload
+// ExpiresAt is optional; keys without it never expire unless api_keys.max_ttl is set
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UpdateAPIKeyRequest represents API key update payload; omitted fields are left unchanged
+type UpdateAPIKeyRequest struct {
+	Name   *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	Scopes []string `json:"scopes" binding:"omitempty,min=1,dive,oneof=read write"`
+}
+
+// CreateRoleRequest represents role creation payload
+// Permissions have the form resource:action, e.g. users:read
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=1,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"dive,required,max=100"`
+}
+
+// UpdateRoleRequest replaces the description and permissions of a role
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required,dive,required,max=100"`
+}
+
 // UserResponse represents user response (without sensitive fields)
 type UserResponse struct {
-	ID        uint     `json:"id"`
-	Name      string   `json:"name"`
-	Email     string   `json:"email"`
-	Roles     []string `json:"roles"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	ID            uint     `json:"id"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
 }
 
 // AuthResponse represents authentication response
@@ -44,6 +119,121 @@ type LegacyAuthResponse struct {
 	User  UserResponse `json:"user"`
 }
 
+// VerificationPendingResponse is returned by registration when login requires a verified email
+type VerificationPendingResponse struct {
+	Message string       `json:"message"`
+	User    UserResponse `json:"user"`
+}
+
+// MFAPendingResponse is returned by login instead of tokens when a second factor is needed
+// EnrollmentRequired means the account must enroll TOTP (admin policy) before the login completes
+type MFAPendingResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// TOTPEnrollmentResponse contains the secret to add to an authenticator app
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse contains single-use recovery codes; they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ImpersonationResponse contains a short-lived access token for acting as another user
+// No refresh token is issued; ImpersonatedBy names the admin recorded in the token's act claim
+type ImpersonationResponse struct {
+	AccessToken    string       `json:"access_token"`
+	TokenType      string       `json:"token_type"`
+	ExpiresIn      int64        `json:"expires_in"`
+	User           UserResponse `json:"user"`
+	ImpersonatedBy UserResponse `json:"impersonated_by"`
+}
+
+// MFAEnrollmentAuthResponse completes a login that enrolled two-factor authentication
+type MFAEnrollmentAuthResponse struct {
+	AuthResponse
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse represents the two-factor authentication state of the current user
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// OIDCCallbackQuery holds the parameters the provider appends to the redirect URL
+type OIDCCallbackQuery struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// OIDCProvidersResponse lists the OpenID Connect providers available for login
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// OIDCAuthorizationResponse contains the provider URL to open to link an account
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// IdentityResponse represents a provider identity linked to the current user
+type IdentityResponse struct {
+	Provider    string `json:"provider"`
+	Email       string `json:"email,omitempty"`
+	LinkedAt    string `json:"linked_at"`
+	LastLoginAt string `json:"last_login_at"`
+}
+
+// APIKeyResponse represents a personal API key without its secret
+type APIKeyResponse struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at,omitempty"`
+	LastUsedAt *string  `json:"last_used_at,omitempty"`
+	LastUsedIP string   `json:"last_used_ip,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse includes the secret, which is only returned once at creation
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// RoleResponse represents a role and its permissions
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	Builtin     bool     `json:"builtin"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// PermissionResponse represents a permission of the catalog
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ChangePasswordResponse represents password change response
+// Tokens is set when other sessions were revoked, replacing the caller's revoked tokens
+type ChangePasswordResponse struct {
+	Message string                  `json:"message"`
+	Tokens  *auth.TokenPairResponse `json:"tokens,omitempty"`
+}
+
 // UserListResponse represents paginated user list response
 type UserListResponse struct {
 	Users      []UserResponse `json:"users"`
@@ -56,11 +246,63 @@ type UserListResponse struct {
 // ToUserResponse converts User model to UserResponse DTO
 func ToUserResponse(user *User) UserResponse {
 	return UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Roles:     user.GetRoleNames(),
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.IsEmailVerified(),
+		Roles:         user.GetRoleNames(),
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt:     user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// ToIdentityResponse converts UserIdentity model to IdentityResponse DTO
+func ToIdentityResponse(identity *UserIdentity) IdentityResponse {
+	return IdentityResponse{
+		Provider:    identity.Provider,
+		Email:       identity.Email,
+		LinkedAt:    identity.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		LastLoginAt: identity.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// ToAPIKeyResponse converts APIKey model to APIKeyResponse DTO
+func ToAPIKeyResponse(key *APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  formatOptionalTime(key.ExpiresAt),
+		LastUsedAt: formatOptionalTime(key.LastUsedAt),
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// ToRoleResponse converts Role model to RoleResponse DTO
+func ToRoleResponse(role *Role) RoleResponse {
+	return RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.PermissionNames(),
+		Builtin:     role.IsBuiltin(),
+		CreatedAt:   role.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// ToPermissionResponse converts Permission model to PermissionResponse DTO
+func ToPermissionResponse(permission *Permission) PermissionResponse {
+	return PermissionResponse{
+		Name:        permission.Name,
+		Description: permission.Description,
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
 	}
+	formatted := t.Format("2006-01-02T15:04:05Z")
+	return &formatted
 }
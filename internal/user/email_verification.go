@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
+)
+
+const defaultEmailVerificationTTL = 24 * time.Hour
+
+// ErrInvalidVerificationToken is returned when a verification token is unknown or expired
+var ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+
+// EmailVerificationToken proves ownership of the email address of a user
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// TableName specifies the table name for EmailVerificationToken model
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+// CheckLoginAllowed returns ErrEmailNotVerified when the policy blocks login until the email is verified
+func (s *service) CheckLoginAllowed(user *User) error {
+	if s.emailVerification.GetPolicy() == config.EmailVerificationPolicyBlockLogin && !user.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token and marks the owner's email as verified
+func (s *service) VerifyEmail(ctx context.Context, token string) error {
+	return s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		verificationToken, err := s.repo.FindEmailVerificationToken(txCtx, auth.HashToken(token))
+		if err != nil {
+			return fmt.Errorf("failed to find verification token: %w", err)
+		}
+		if verificationToken == nil || time.Now().After(verificationToken.ExpiresAt) {
+			return ErrInvalidVerificationToken
+		}
+
+		user, err := s.repo.FindByID(txCtx, verificationToken.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			return ErrInvalidVerificationToken
+		}
+
+		if err := s.repo.DeleteEmailVerificationTokens(txCtx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete verification tokens: %w", err)
+		}
+		return s.markVerified(txCtx, user)
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to an unverified account.
+// Unknown or already verified emails return nil so the endpoint does not reveal which accounts exist.
+func (s *service) ResendVerificationEmail(ctx context.Context, email string) error {
+	if !s.emailVerification.Enabled {
+		return nil
+	}
+
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil || user.IsEmailVerified() {
+		return nil
+	}
+
+	// WHY: 只有未验证的账户才会发信，返回发送错误会让调用方据此判断邮箱是否已注册
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// MarkEmailVerified marks a user's email as verified without a token, e.g. for accounts created by operators
+func (s *service) MarkEmailVerified(ctx context.Context, id uint) error {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	return s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		return s.markVerified(txCtx, user)
+	})
+}
+
+// markVerified sets email_verified_at and grants the default role withheld by the restrict_roles policy
+func (s *service) markVerified(ctx context.Context, user *User) error {
+	if !user.IsEmailVerified() {
+		if err := s.repo.MarkEmailVerified(ctx, user.ID, time.Now()); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to mark email verified: %w", err)
+		}
+	}
+
+	if s.emailVerification.GetPolicy() == config.EmailVerificationPolicyRestrictRoles && !user.HasRole(RoleUser) {
+		if err := s.repo.AssignRole(ctx, user.ID, RoleUser); err != nil {
+			return fmt.Errorf("failed to assign default role: %w", err)
+		}
+	}
+	return nil
+}
+
+// sendVerificationEmail replaces the user's pending verification tokens and emails the new link
+func (s *service) sendVerificationEmail(ctx context.Context, user *User) error {
+	token, err := generateOneTimeToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeleteEmailVerificationTokens(txCtx, user.ID); err != nil {
+			return err
+		}
+		return s.repo.CreateEmailVerificationToken(txCtx, &EmailVerificationToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(s.emailVerification.TokenTTL),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, verificationMessage(user, token, s.emailVerification.URL, s.emailVerification.TokenTTL)); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+func verificationMessage(user *User, token, verifyURL string, ttl time.Duration) mail.Message {
+	link := token
+	if verifyURL != "" {
+		link = verifyURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account, you can ignore this email.\n",
+			user.Name, ttl, link,
+		),
+	}
+}
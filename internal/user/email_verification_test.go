@@ -0,0 +1,128 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func setupEmailVerificationTest(t *testing.T, policy string) (Service, Repository, *recordingSender) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&EmailVerificationToken{}))
+
+	repo := NewRepository(db)
+	sender := &recordingSender{}
+	svc := NewService(repo, WithMailer(sender), WithEmailVerification(&config.EmailVerificationConfig{
+		Enabled: true,
+		Policy:  policy,
+		URL:     "https://api.example.com/api/v1/public/verify-email",
+	}))
+	return svc, repo, sender
+}
+
+func TestService_RegisterUser_SendsVerificationEmail(t *testing.T) {
+	svc, repo, sender := setupEmailVerificationTest(t, config.EmailVerificationPolicyNone)
+	ctx := context.Background()
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "New User", Email: "new@example.com", Password: "password123"})
+	require.NoError(t, err)
+	assert.False(t, user.IsEmailVerified())
+	assert.True(t, user.HasRole(RoleUser))
+
+	require.Len(t, sender.messages, 1)
+	assert.Equal(t, "new@example.com", sender.messages[0].To)
+	assert.Contains(t, sender.messages[0].Body, "24h0m0s")
+	token := tokenFromLink(t, sender.messages[0].Body)
+
+	stored, err := repo.FindEmailVerificationToken(ctx, auth.HashToken(token))
+	require.NoError(t, err)
+	require.NotNil(t, stored, "only the hash of the token is stored")
+
+	require.NoError(t, svc.VerifyEmail(ctx, token))
+	verified, err := svc.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, verified.IsEmailVerified())
+
+	assert.ErrorIs(t, svc.VerifyEmail(ctx, token), ErrInvalidVerificationToken, "tokens are single-use")
+	assert.ErrorIs(t, svc.VerifyEmail(ctx, "unknown-token"), ErrInvalidVerificationToken)
+
+	require.NoError(t, svc.ResendVerificationEmail(ctx, "new@example.com"))
+	assert.Len(t, sender.messages, 1, "verified accounts get no new email")
+}
+
+func TestService_EmailVerificationPolicies(t *testing.T) {
+	ctx := context.Background()
+	login := LoginRequest{Email: "policy@example.com", Password: "password123"}
+	register := RegisterRequest{Name: "Policy User", Email: login.Email, Password: login.Password}
+
+	t.Run("block_login", func(t *testing.T) {
+		svc, _, sender := setupEmailVerificationTest(t, config.EmailVerificationPolicyBlockLogin)
+
+		user, err := svc.RegisterUser(ctx, register)
+		require.NoError(t, err)
+		assert.ErrorIs(t, svc.CheckLoginAllowed(user), ErrEmailNotVerified)
+
+		_, err = svc.AuthenticateUser(ctx, login)
+		assert.ErrorIs(t, err, ErrEmailNotVerified)
+
+		_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: login.Email, Password: "wrongpassword"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials, "verification status is only revealed with the right password")
+
+		require.NoError(t, svc.VerifyEmail(ctx, tokenFromLink(t, sender.messages[0].Body)))
+		_, err = svc.AuthenticateUser(ctx, login)
+		assert.NoError(t, err)
+	})
+
+	t.Run("restrict_roles", func(t *testing.T) {
+		svc, _, sender := setupEmailVerificationTest(t, config.EmailVerificationPolicyRestrictRoles)
+
+		user, err := svc.RegisterUser(ctx, register)
+		require.NoError(t, err)
+		assert.Empty(t, user.Roles, "default role is withheld until verification")
+		assert.NoError(t, svc.CheckLoginAllowed(user))
+
+		require.NoError(t, svc.VerifyEmail(ctx, tokenFromLink(t, sender.messages[0].Body)))
+		verified, err := svc.GetUserByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, verified.HasRole(RoleUser))
+	})
+}
+
+func TestService_UpdateUser_EmailChangeRequiresVerification(t *testing.T) {
+	svc, _, sender := setupEmailVerificationTest(t, config.EmailVerificationPolicyNone)
+	ctx := context.Background()
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Mover", Email: "old@example.com", Password: "password123"})
+	require.NoError(t, err)
+	require.NoError(t, svc.MarkEmailVerified(ctx, user.ID))
+
+	updated, err := svc.UpdateUser(ctx, user.ID, UpdateUserRequest{Email: "new@example.com"})
+	require.NoError(t, err)
+	assert.False(t, updated.IsEmailVerified())
+
+	require.Len(t, sender.messages, 2)
+	assert.Equal(t, "new@example.com", sender.messages[1].To)
+}
+
+func TestService_VerifyEmail_Expired(t *testing.T) {
+	svc, repo, _ := setupEmailVerificationTest(t, config.EmailVerificationPolicyNone)
+	ctx := context.Background()
+
+	user := &User{Name: "Late User", Email: "late@example.com", PasswordHash: "unused"}
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.CreateEmailVerificationToken(ctx, &EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken("expired-token"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	assert.ErrorIs(t, svc.VerifyEmail(ctx, "expired-token"), ErrInvalidVerificationToken)
+}
@@ -1,9 +1,12 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,34 +14,44 @@ import (
 	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
 	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
 	"github.com/yeegeek/go-rest-api-starter/internal/middleware"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
 )
 
 // Handler handles user-related HTTP requests
 type Handler struct {
 	userService Service
 	authService auth.Service
+	oidc        *oidc.Client
 }
 
 // NewHandler creates a new user handler
+// OpenID Connect login is unavailable
 func NewHandler(userService Service, authService auth.Service) *Handler {
+	return NewHandlerWithOIDC(userService, authService, nil)
+}
+
+// NewHandlerWithOIDC creates a new user handler that signs users in through the given OpenID Connect client
+func NewHandlerWithOIDC(userService Service, authService auth.Service, oidcClient *oidc.Client) *Handler {
 	return &Handler{
 		userService: userService,
 		authService: authService,
+		oidc:        oidcClient,
 	}
 }
 
 // Register godoc
 // @Summary Register a new user
-// @Description Register a new user with name, email and password, returns access and refresh tokens
+// @Description Register a new user with name, email and password, returns access and refresh tokens. When login requires a verified email, no tokens are issued until the address is verified
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body RegisterRequest true "Registration request"
-// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens"
-// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error"
+// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens (VerificationPendingResponse when verification is required)"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error or password policy violation"
 // @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Email already exists"
 // @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to register user or generate token"
 // @Router /api/v1/auth/register [post]
+// @Router /api/v1/public/register [post]
 func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -52,11 +65,28 @@ func (h *Handler) Register(c *gin.Context) {
 			_ = c.Error(apiErrors.Conflict("Email already exists"))
 			return
 		}
+		var policyErr *PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			_ = c.Error(apiErrors.ValidationError(policyErr.Details()))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	if err := h.userService.CheckLoginAllowed(user); err != nil {
+		if errors.Is(err, ErrEmailNotVerified) {
+			c.JSON(http.StatusOK, apiErrors.Success(VerificationPendingResponse{
+				Message: "Registration successful. Please verify your email address before logging in",
+				User:    ToUserResponse(user),
+			}))
+			return
+		}
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
 
-	tokenPair, err := h.authService.GenerateTokenPair(c.Request.Context(), user.ID, user.Email, user.Name)
+	tokenPair, err := h.authService.GenerateTokenPair(clientContext(c), user.ID, user.Email, user.Name)
 	if err != nil {
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
@@ -73,14 +103,16 @@ func (h *Handler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user with email and password, returns access and refresh tokens
+// @Description Authenticate user with email and password, returns access and refresh tokens. When two-factor authentication is enabled (or required for admins), an mfa pending token is returned instead; exchange it at /api/v1/auth/mfa/verify
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login request"
-// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens"
+// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens (MFAPendingResponse when a second factor is needed)"
 // @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error"
 // @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid email or password"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Email address has not been verified"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Too many failed attempts (ACCOUNT_LOCKED) or retried too soon after a failure (TOO_MANY_REQUESTS)"
 // @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to authenticate user or generate token"
 // @Router /api/v1/auth/login [post]
 func (h *Handler) Login(c *gin.Context) {
@@ -90,29 +122,52 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.AuthenticateUser(c.Request.Context(), req)
+	user, err := h.userService.AuthenticateUser(clientContext(c), req)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
 			_ = c.Error(apiErrors.Unauthorized("Invalid email or password"))
 			return
 		}
+		var blocked *auth.LoginBlockedError
+		if errors.As(err, &blocked) {
+			h.loginBlocked(c, blocked)
+			return
+		}
+		if errors.Is(err, ErrEmailNotVerified) {
+			_ = c.Error(apiErrors.Forbidden("Email address has not been verified"))
+			return
+		}
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
 
-	tokenPair, err := h.authService.GenerateTokenPair(c.Request.Context(), user.ID, user.Email, user.Name)
+	h.completeLogin(c, user)
+}
+
+// completeLogin responds to an authenticated login with tokens, or with an mfa pending token
+// when the user must still pass the second factor
+func (h *Handler) completeLogin(c *gin.Context, user *User) {
+	pending, err := h.userService.StartMFAChallenge(c.Request.Context(), user)
 	if err != nil {
 		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
+	if pending != nil {
+		c.JSON(http.StatusOK, apiErrors.Success(MFAPendingResponse{
+			MFARequired:        true,
+			EnrollmentRequired: pending.EnrollmentRequired,
+			MFAToken:           pending.Token,
+			ExpiresIn:          int64(time.Until(pending.ExpiresAt).Seconds()),
+		}))
+		return
+	}
 
-	c.JSON(http.StatusOK, apiErrors.Success(AuthResponse{
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		TokenType:    tokenPair.TokenType,
-		ExpiresIn:    tokenPair.ExpiresIn,
-		User:         ToUserResponse(user),
-	}))
+	response, err := h.issueTokens(c, user)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
 }
 
 // GetUser godoc
@@ -219,8 +274,9 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 // @Security BearerAuth
 // @Success 204
 // @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID"
-// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Forbidden user ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Forbidden user ID or deleting your own admin account"
 // @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Cannot delete the last admin"
 // @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
 // @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to delete user"
 // @Router /api/v1/users/{id} [delete]
@@ -238,15 +294,25 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(c.Request.Context(), uint(id)); err != nil {
-		if errors.Is(err, ErrUserNotFound) {
+	if err := h.userService.DeleteUser(c.Request.Context(), contextutil.GetUserID(c), uint(id)); err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
 			_ = c.Error(apiErrors.NotFound("User not found"))
-			return
+		case errors.Is(err, ErrSelfDeletion):
+			_ = c.Error(apiErrors.Forbidden("You cannot delete your own admin account"))
+		case errors.Is(err, ErrLastAdmin):
+			_ = c.Error(apiErrors.Conflict("Cannot delete the last admin"))
+		default:
+			_ = c.Error(apiErrors.InternalServerError(err))
 		}
-		_ = c.Error(apiErrors.InternalServerError(err))
 		return
 	}
 
+	// 用户已删除：撤销其全部令牌，避免尚未过期的访问令牌继续可用
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), uint(id)); err != nil {
+		slog.Error("Failed to revoke tokens of deleted user", "user_id", id, "error", err)
+	}
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -270,7 +336,7 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	tokenPair, err := h.authService.RefreshAccessToken(c.Request.Context(), req.RefreshToken)
+	tokenPair, err := h.authService.RefreshAccessToken(clientContext(c), req.RefreshToken)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) {
 			_ = c.Error(apiErrors.Unauthorized("Invalid or expired refresh token"))
@@ -332,9 +398,59 @@ func (h *Handler) Logout(c *gin.Context) {
 		return
 	}
 
+	if err := h.revokeCurrentAccessToken(c); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
 	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Successfully logged out"}))
 }
 
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Description Revoke every refresh and access token of the authenticated user, ending all sessions
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=object} "Successfully logged out from all devices"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to logout"
+// @Router /api/v1/auth/logout-all [post]
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	if err := h.revokeCurrentAccessToken(c); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Successfully logged out from all devices"}))
+}
+
+// clientContext attaches the caller's device metadata so issued tokens can be listed as sessions
+func clientContext(c *gin.Context) context.Context {
+	return auth.WithClientInfo(c.Request.Context(), c.Request.UserAgent(), c.ClientIP())
+}
+
+// revokeCurrentAccessToken denylists the access token used for this request, if it carries a jti
+func (h *Handler) revokeCurrentAccessToken(c *gin.Context) error {
+	claims := contextutil.GetUser(c)
+	if claims == nil || claims.ID == "" {
+		return nil
+	}
+	return h.authService.RevokeAccessToken(c.Request.Context(), claims)
+}
+
 // GetMe godoc
 // @Summary Get current user
 // @Description Get the currently authenticated user's information with roles
@@ -398,6 +514,11 @@ func (h *Handler) ListUsers(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, apiErrors.Success(toUserListResponse(users, total, pagination)))
+}
+
+// toUserListResponse builds a page of users
+func toUserListResponse(users []User, total int64, pagination middleware.PaginationParams) UserListResponse {
 	userResponses := make([]UserResponse, len(users))
 	for i, user := range users {
 		userResponses[i] = ToUserResponse(&user)
@@ -408,13 +529,11 @@ func (h *Handler) ListUsers(c *gin.Context) {
 		totalPages++
 	}
 
-	response := UserListResponse{
+	return UserListResponse{
 		Users:      userResponses,
 		Total:      total,
 		Page:       pagination.Page,
 		PerPage:    pagination.PerPage,
 		TotalPages: totalPages,
 	}
-
-	c.JSON(http.StatusOK, apiErrors.Success(response))
 }
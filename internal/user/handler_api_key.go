@@ -0,0 +1,233 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// APIKeyAuthenticator returns the authenticator used by the Authorization: ApiKey middleware
+func (h *Handler) APIKeyAuthenticator() auth.APIKeyAuthenticator {
+	return h.userService
+}
+
+// ListMyAPIKeys godoc
+// @Summary List my API keys
+// @Description List the personal API keys of the authenticated user, newest first. Secrets are never returned after creation
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]APIKeyResponse} "API keys"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Authenticated with an API key"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list API keys"
+// @Router /api/v1/users/me/api-keys [get]
+func (h *Handler) ListMyAPIKeys(c *gin.Context) {
+	userID, ok := apiKeyOwner(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.userService.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		h.apiKeyError(c, err)
+		return
+	}
+
+	response := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		response[i] = ToAPIKeyResponse(&keys[i])
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// CreateMyAPIKey godoc
+// @Summary Create an API key
+// @Description Create a personal API key. Send it as "Authorization: ApiKey <key>". The key is only returned in this response
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Name, scopes (read, write) and optional expiry"
+// @Success 201 {object} errors.Response{success=bool,data=CreatedAPIKeyResponse} "Created API key including the secret"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid request or expiry"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Authenticated with an API key"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "API key limit reached"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to create API key"
+// @Router /api/v1/users/me/api-keys [post]
+func (h *Handler) CreateMyAPIKey(c *gin.Context) {
+	userID, ok := apiKeyOwner(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	apiKey, key, err := h.userService.CreateAPIKey(c.Request.Context(), userID, req)
+	if err != nil {
+		h.apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(CreatedAPIKeyResponse{
+		APIKeyResponse: ToAPIKeyResponse(apiKey),
+		Key:            key,
+	}))
+}
+
+// GetMyAPIKey godoc
+// @Summary Get an API key
+// @Description Get one personal API key of the authenticated user
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} errors.Response{success=bool,data=APIKeyResponse} "API key"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid API key ID"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Authenticated with an API key"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "API key not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to get API key"
+// @Router /api/v1/users/me/api-keys/{id} [get]
+func (h *Handler) GetMyAPIKey(c *gin.Context) {
+	userID, ok := apiKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	apiKey, err := h.userService.GetAPIKey(c.Request.Context(), userID, keyID)
+	if err != nil {
+		h.apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ToAPIKeyResponse(apiKey)))
+}
+
+// UpdateMyAPIKey godoc
+// @Summary Update an API key
+// @Description Rename a personal API key or replace its scopes. The secret and expiry cannot be changed
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Param request body UpdateAPIKeyRequest true "New name and/or scopes"
+// @Success 200 {object} errors.Response{success=bool,data=APIKeyResponse} "Updated API key"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid request"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Authenticated with an API key"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "API key not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to update API key"
+// @Router /api/v1/users/me/api-keys/{id} [patch]
+func (h *Handler) UpdateMyAPIKey(c *gin.Context) {
+	userID, ok := apiKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	apiKey, err := h.userService.UpdateAPIKey(c.Request.Context(), userID, keyID, req)
+	if err != nil {
+		h.apiKeyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ToAPIKeyResponse(apiKey)))
+}
+
+// RevokeMyAPIKey godoc
+// @Summary Revoke an API key
+// @Description Delete a personal API key; requests using it are rejected immediately
+// @Tags api-keys
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid API key ID"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Authenticated with an API key"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "API key not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to revoke API key"
+// @Router /api/v1/users/me/api-keys/{id} [delete]
+func (h *Handler) RevokeMyAPIKey(c *gin.Context) {
+	userID, ok := apiKeyOwner(c)
+	if !ok {
+		return
+	}
+	keyID, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeAPIKey(c.Request.Context(), userID, keyID); err != nil {
+		h.apiKeyError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// apiKeyOwner returns the authenticated user managing their keys
+// WHY: 泄露的 API 密钥不能用来创建新密钥或延续自身权限，密钥只能在登录会话中管理
+func apiKeyOwner(c *gin.Context) (uint, bool) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return 0, false
+	}
+	if contextutil.GetAPIKeyID(c) != 0 {
+		_ = c.Error(apiErrors.Forbidden("API keys cannot be managed with an API key"))
+		return 0, false
+	}
+	return userID, true
+}
+
+// apiKeyID parses the :id path parameter
+func apiKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid API key ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// apiKeyError maps API key errors to API errors
+func (h *Handler) apiKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrAPIKeyNotFound):
+		_ = c.Error(apiErrors.NotFound("API key not found"))
+	case errors.Is(err, ErrAPIKeyLimitReached):
+		_ = c.Error(apiErrors.Conflict("API key limit reached. Revoke an existing key first"))
+	case errors.Is(err, ErrInvalidAPIKeyExpiry):
+		_ = c.Error(apiErrors.BadRequest("expires_at must be in the future and within the allowed maximum lifetime"))
+	case errors.Is(err, ErrUserNotFound):
+		_ = c.Error(apiErrors.NotFound("User not found"))
+	default:
+		_ = c.Error(apiErrors.InternalServerError(err))
+	}
+}
@@ -0,0 +1,134 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_CreateMyAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		viaAPIKey      bool
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name: "created",
+			body: `{"name":"ci","scopes":["read"]}`,
+			setupMocks: func(ms *MockService) {
+				ms.On("CreateAPIKey", mock.Anything, uint(1), CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}}).
+					Return(&APIKey{ID: 3, Name: "ci", Prefix: "sk_abcdefgh", Scopes: "read", CreatedAt: time.Now()}, "sk_abcdefghsecret", nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unknown scope",
+			body:           `{"name":"ci","scopes":["admin"]}`,
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing scopes",
+			body:           `{"name":"ci"}`,
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "limit reached",
+			body: `{"name":"ci","scopes":["read","write"]}`,
+			setupMocks: func(ms *MockService) {
+				ms.On("CreateAPIKey", mock.Anything, uint(1), mock.Anything).Return(nil, "", ErrAPIKeyLimitReached)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "authenticated with an api key",
+			body:           `{"name":"ci","scopes":["read"]}`,
+			viaAPIKey:      true,
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/users/me/api-keys", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			contextutil.SetUserID(c, 1)
+			if tt.viaAPIKey {
+				contextutil.SetAPIKeyID(c, 9)
+			}
+
+			handler.CreateMyAPIKey(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data CreatedAPIKeyResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "sk_abcdefghsecret", response.Data.Key)
+				assert.Equal(t, []string{"read"}, response.Data.Scopes)
+				assert.Nil(t, response.Data.ExpiresAt)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_RevokeMyAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		err            error
+		expectedStatus int
+	}{
+		{name: "revoked", id: "3", expectedStatus: http.StatusNoContent},
+		{name: "not found", id: "3", err: ErrAPIKeyNotFound, expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			if tt.id == "3" {
+				mockService.On("RevokeAPIKey", mock.Anything, uint(1), uint(3)).Return(tt.err)
+			}
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/users/me/api-keys/"+tt.id, nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+			c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+
+			handler.RevokeMyAPIKey(c)
+			apiErrors.ErrorHandler()(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address of an account with the token from the verification email
+// @Tags auth
+// @Produce json
+// @Param token query string true "Email verification token"
+// @Success 200 {object} errors.Response{success=bool,data=object} "Email verified"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing, invalid or expired token"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to verify email"
+// @Router /api/v1/public/verify-email [get]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		_ = c.Error(apiErrors.BadRequest("Verification token is required"))
+		return
+	}
+
+	if err := h.userService.VerifyEmail(c.Request.Context(), token); err != nil {
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			_ = c.Error(apiErrors.BadRequest("Invalid or expired email verification token"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Email address has been verified"}))
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to an unverified account. Always succeeds so the response does not reveal whether the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Resend verification request"
+// @Success 200 {object} errors.Response{success=bool,data=object} "Verification email sent if the account exists and is unverified"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to send verification email"
+// @Router /api/v1/auth/verify-email/resend [post]
+func (h *Handler) ResendVerificationEmail(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.userService.ResendVerificationEmail(c.Request.Context(), req.Email); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "If the email is registered and unverified, a verification link has been sent"}))
+}
@@ -0,0 +1,120 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name:  "email verified",
+			query: "?token=verify-token",
+			setupMocks: func(ms *MockService) {
+				ms.On("VerifyEmail", mock.Anything, "verify-token").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			query:          "",
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid token",
+			query: "?token=verify-token",
+			setupMocks: func(ms *MockService) {
+				ms.On("VerifyEmail", mock.Anything, "verify-token").Return(ErrInvalidVerificationToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "service error",
+			query: "?token=verify-token",
+			setupMocks: func(ms *MockService) {
+				ms.On("VerifyEmail", mock.Anything, "verify-token").Return(errors.New("database down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/public/verify-email"+tt.query, nil)
+
+			handler.VerifyEmail(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ResendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           map[string]string
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name: "resend requested",
+			body: map[string]string{"email": "user@example.com"},
+			setupMocks: func(ms *MockService) {
+				ms.On("ResendVerificationEmail", mock.Anything, "user@example.com").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid email",
+			body:           map[string]string{"email": "not-an-email"},
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest("POST", "/auth/verify-email/resend", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.ResendVerificationEmail(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package user
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// StartImpersonation godoc
+// @Summary Impersonate a user (Admin only)
+// @Description Issue a short-lived, non-refreshable access token that acts as the given user. The token carries an RFC 8693 act claim naming the admin; impersonated sessions cannot change passwords, roles or credentials. Admins cannot be impersonated
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} errors.Response{success=bool,data=ImpersonationResponse} "Impersonation token"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID or own account"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required or target is an admin"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to issue token"
+// @Router /api/v1/admin/users/{id}/impersonate [post]
+func (h *Handler) StartImpersonation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	impersonation, err := h.userService.StartImpersonation(c.Request.Context(), contextutil.GetUserID(c), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			_ = c.Error(apiErrors.NotFound("User not found"))
+		case errors.Is(err, ErrSelfImpersonation):
+			_ = c.Error(apiErrors.BadRequest("You cannot impersonate yourself"))
+		case errors.Is(err, ErrImpersonateAdmin):
+			_ = c.Error(apiErrors.Forbidden("Admins cannot be impersonated"))
+		default:
+			_ = c.Error(apiErrors.InternalServerError(err))
+		}
+		return
+	}
+
+	actor, target := impersonation.Actor, impersonation.User
+	token, err := h.authService.GenerateImpersonationToken(c.Request.Context(), auth.Actor{UserID: actor.ID, Email: actor.Email}, target.ID, target.Email, target.Name, impersonation.TokenTTL)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "Impersonation started",
+		"actor_id", actor.ID,
+		"user_id", target.ID,
+		"ttl", impersonation.TokenTTL,
+		"ip", c.ClientIP(),
+		"request_id", c.GetString("request_id"),
+	)
+
+	c.JSON(http.StatusOK, apiErrors.Success(ImpersonationResponse{
+		AccessToken:    token,
+		TokenType:      "Bearer",
+		ExpiresIn:      int64(impersonation.TokenTTL.Seconds()),
+		User:           ToUserResponse(target),
+		ImpersonatedBy: ToUserResponse(actor),
+	}))
+}
+
+// StopImpersonation godoc
+// @Summary Stop impersonating a user
+// @Description Revoke the impersonation token used to call this endpoint. The admin's own session is not affected
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=object} "Impersonation stopped"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Not an impersonation session"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to revoke token"
+// @Router /api/v1/auth/impersonation/stop [post]
+func (h *Handler) StopImpersonation(c *gin.Context) {
+	claims := contextutil.GetUser(c)
+	if !claims.IsImpersonation() {
+		_ = c.Error(apiErrors.BadRequest("Not an impersonation session"))
+		return
+	}
+
+	if err := h.authService.RevokeAccessToken(c.Request.Context(), claims); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "Impersonation stopped",
+		"actor_id", claims.Actor.UserID,
+		"user_id", claims.UserID,
+		"jti", claims.ID,
+		"ip", c.ClientIP(),
+		"request_id", c.GetString("request_id"),
+	)
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Impersonation stopped"}))
+}
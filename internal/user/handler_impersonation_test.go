@@ -0,0 +1,163 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_StartImpersonation(t *testing.T) {
+	admin := &User{ID: 1, Name: "Ada", Email: "ada@example.com"}
+	member := &User{ID: 7, Name: "Max", Email: "max@example.com"}
+
+	tests := []struct {
+		name           string
+		userID         string
+		setupMocks     func(*MockService, *MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:   "token issued",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("StartImpersonation", mock.Anything, uint(1), uint(7)).Return(&Impersonation{Actor: admin, User: member, TokenTTL: 15 * time.Minute}, nil)
+				mas.On("GenerateImpersonationToken", mock.Anything, auth.Actor{UserID: 1, Email: "ada@example.com"}, uint(7), "max@example.com", "Max", 15*time.Minute).Return("impersonation-token", nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "token signing fails",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("StartImpersonation", mock.Anything, uint(1), uint(7)).Return(&Impersonation{Actor: admin, User: member, TokenTTL: 15 * time.Minute}, nil)
+				mas.On("GenerateImpersonationToken", mock.Anything, mock.Anything, uint(7), mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("no signing key"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "self",
+			userID: "1",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("StartImpersonation", mock.Anything, uint(1), uint(1)).Return(nil, ErrSelfImpersonation)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "admin target",
+			userID: "2",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("StartImpersonation", mock.Anything, uint(1), uint(2)).Return(nil, ErrImpersonateAdmin)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "user not found",
+			userID: "999",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("StartImpersonation", mock.Anything, uint(1), uint(999)).Return(nil, ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid user id",
+			userID:         "abc",
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuthService)
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/admin/users/"+tt.userID+"/impersonate", nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.userID}}
+			contextutil.SetUserID(c, 1)
+
+			handler.StartImpersonation(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data ImpersonationResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "impersonation-token", response.Data.AccessToken)
+				assert.Equal(t, int64(900), response.Data.ExpiresIn)
+				assert.Equal(t, uint(7), response.Data.User.ID)
+				assert.Equal(t, uint(1), response.Data.ImpersonatedBy.ID)
+			}
+			mockService.AssertExpectations(t)
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_StopImpersonation(t *testing.T) {
+	tests := []struct {
+		name           string
+		claims         *auth.Claims
+		setupMocks     func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:   "stopped",
+			claims: &auth.Claims{UserID: 7, ID: "jti-1", Actor: &auth.Actor{UserID: 1}},
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeAccessToken", mock.Anything, mock.MatchedBy(func(c *auth.Claims) bool { return c.ID == "jti-1" })).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "revocation fails",
+			claims: &auth.Claims{UserID: 7, ID: "jti-1", Actor: &auth.Actor{UserID: 1}},
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeAccessToken", mock.Anything, mock.Anything).Return(errors.New("redis down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "not impersonating",
+			claims:         &auth.Claims{UserID: 7, ID: "jti-1"},
+			setupMocks:     func(mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockAuthService)
+			handler := NewHandler(&MockService{}, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/auth/impersonation/stop", nil)
+			c.Set(auth.KeyUser, tt.claims)
+
+			handler.StopImpersonation(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
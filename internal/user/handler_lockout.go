@@ -0,0 +1,57 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// UnlockUser godoc
+// @Summary Unlock a user's login (Admin only)
+// @Description Clear the failed login attempts and any temporary lock of a user's account (requires admin role). IP locks expire on their own
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 204 "Account unlocked"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to unlock account"
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *Handler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	if err := h.userService.UnlockUser(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			_ = c.Error(apiErrors.NotFound("User not found"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// loginBlocked reports a throttled login: ACCOUNT_LOCKED after too many failures, TOO_MANY_REQUESTS during a delay
+func (h *Handler) loginBlocked(c *gin.Context, blocked *auth.LoginBlockedError) {
+	retryAfter := blocked.RetryAfterSeconds()
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	if blocked.Locked {
+		_ = c.Error(apiErrors.AccountLocked(retryAfter))
+		return
+	}
+	_ = c.Error(apiErrors.TooManyRequests(retryAfter))
+}
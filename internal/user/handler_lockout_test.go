@@ -0,0 +1,75 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_UnlockUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name: "unlocks account",
+			id:   "7",
+			setupMocks: func(ms *MockService) {
+				ms.On("UnlockUser", mock.Anything, uint(7)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid user ID",
+			id:             "abc",
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "user not found",
+			id:   "7",
+			setupMocks: func(ms *MockService) {
+				ms.On("UnlockUser", mock.Anything, uint(7)).Return(ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "store error",
+			id:   "7",
+			setupMocks: func(ms *MockService) {
+				ms.On("UnlockUser", mock.Anything, uint(7)).Return(errors.New("redis down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/admin/users/"+tt.id+"/unlock", nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler.UnlockUser(c)
+			apiErrors.ErrorHandler()(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// VerifyMFA godoc
+// @Summary Complete login with a second factor
+// @Description Exchange the mfa pending token returned by login and a TOTP or recovery code for access and refresh tokens. The token stops working after too many wrong codes, and the account's second-factor checks are locked after too many wrong codes across logins
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "MFA verification request"
+// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error or invalid code"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid or expired mfa token"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication must be enrolled first"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded or too many wrong codes"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to verify code or generate token"
+// @Router /api/v1/auth/mfa/verify [post]
+func (h *Handler) VerifyMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	user, err := h.userService.VerifyMFAChallenge(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	response, err := h.issueTokens(c, user)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// BeginMFAEnrollment godoc
+// @Summary Start required TOTP enrollment during login
+// @Description For a login held by the admin 2FA policy (enrollment_required), generate a TOTP secret and otpauth URI for the account
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFATokenRequest true "MFA pending token"
+// @Success 200 {object} errors.Response{success=bool,data=TOTPEnrollmentResponse} "TOTP secret and otpauth URI"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid or expired mfa token"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication is already enabled"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to start enrollment"
+// @Router /api/v1/auth/mfa/enroll [post]
+func (h *Handler) BeginMFAEnrollment(c *gin.Context) {
+	var req MFATokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	enrollment, err := h.userService.BeginChallengeEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(TOTPEnrollmentResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI}))
+}
+
+// ConfirmMFAEnrollment godoc
+// @Summary Confirm required TOTP enrollment and complete login
+// @Description Confirm the enrollment started at /api/v1/auth/mfa/enroll with a code from the authenticator app. Returns tokens and recovery codes, which are shown only once
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MFAVerifyRequest true "MFA pending token and TOTP code"
+// @Success 200 {object} errors.Response{success=bool,data=MFAEnrollmentAuthResponse} "Success response with user data, tokens and recovery codes"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, invalid code or no enrollment in progress"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid or expired mfa token"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication is already enabled"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded or too many wrong codes"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to confirm enrollment or generate token"
+// @Router /api/v1/auth/mfa/enroll/confirm [post]
+func (h *Handler) ConfirmMFAEnrollment(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	user, codes, err := h.userService.CompleteChallengeEnrollment(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	response, err := h.issueTokens(c, user)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(MFAEnrollmentAuthResponse{AuthResponse: response, RecoveryCodes: codes}))
+}
+
+// GetMyMFAStatus godoc
+// @Summary Get my two-factor authentication status
+// @Description Whether TOTP is enabled or required for the authenticated user, and how many recovery codes are left
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=MFAStatusResponse} "Two-factor authentication status"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to get status"
+// @Router /api/v1/users/me/mfa [get]
+func (h *Handler) GetMyMFAStatus(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	status, err := h.userService.GetMFAStatus(c.Request.Context(), userID)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(MFAStatusResponse{
+		Enabled:                status.Enabled,
+		Required:               status.Required,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+	}))
+}
+
+// EnrollMyTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a TOTP secret and otpauth URI for the authenticated user. Two-factor authentication is enabled once confirmed at /api/v1/users/me/mfa/totp/confirm
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=TOTPEnrollmentResponse} "TOTP secret and otpauth URI"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication is already enabled"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to start enrollment"
+// @Router /api/v1/users/me/mfa/totp [post]
+func (h *Handler) EnrollMyTOTP(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	enrollment, err := h.userService.BeginTOTPEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(TOTPEnrollmentResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI}))
+}
+
+// ConfirmMyTOTP godoc
+// @Summary Confirm TOTP enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app. Returns recovery codes, which are shown only once
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "TOTP code"
+// @Success 200 {object} errors.Response{success=bool,data=RecoveryCodesResponse} "Two-factor authentication enabled"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, invalid code or no enrollment in progress"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication is already enabled"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to confirm enrollment"
+// @Router /api/v1/users/me/mfa/totp/confirm [post]
+func (h *Handler) ConfirmMyTOTP(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	codes, err := h.userService.ConfirmTOTPEnrollment(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// DisableMyTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Remove the authenticator and recovery codes of the authenticated user after checking a TOTP or recovery code. Not allowed when the admin policy requires two-factor authentication
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "TOTP or recovery code"
+// @Success 204 "Two-factor authentication disabled"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, invalid code or two-factor authentication not enabled"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Two-factor authentication is required for this account"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Locked after too many wrong codes (ACCOUNT_LOCKED)"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to disable two-factor authentication"
+// @Router /api/v1/users/me/mfa/totp [delete]
+func (h *Handler) DisableMyTOTP(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.userService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateMyRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes of the authenticated user after checking a TOTP or recovery code. The new codes are shown only once
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} errors.Response{success=bool,data=RecoveryCodesResponse} "New recovery codes"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, invalid code or two-factor authentication not enabled"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Locked after too many wrong codes (ACCOUNT_LOCKED)"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to regenerate recovery codes"
+// @Router /api/v1/users/me/mfa/recovery-codes [post]
+func (h *Handler) RegenerateMyRecoveryCodes(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		h.mfaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(RecoveryCodesResponse{RecoveryCodes: codes}))
+}
+
+// issueTokens generates a token pair for a user who completed every login step
+func (h *Handler) issueTokens(c *gin.Context, user *User) (AuthResponse, error) {
+	tokenPair, err := h.authService.GenerateTokenPair(clientContext(c), user.ID, user.Email, user.Name)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	return AuthResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenPair.TokenType,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		User:         ToUserResponse(user),
+	}, nil
+}
+
+// mfaError maps two-factor authentication errors to API errors
+func (h *Handler) mfaError(c *gin.Context, err error) {
+	var blocked *auth.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		h.loginBlocked(c, blocked)
+	case errors.Is(err, ErrInvalidMFAChallenge):
+		_ = c.Error(apiErrors.Unauthorized("Invalid or expired mfa token"))
+	case errors.Is(err, ErrInvalidMFACode):
+		_ = c.Error(apiErrors.BadRequest("Invalid two-factor authentication code"))
+	case errors.Is(err, ErrMFANotEnabled):
+		_ = c.Error(apiErrors.BadRequest("Two-factor authentication is not enabled"))
+	case errors.Is(err, ErrMFANotEnrolling):
+		_ = c.Error(apiErrors.BadRequest("No two-factor authentication enrollment in progress"))
+	case errors.Is(err, ErrMFAAlreadyEnabled):
+		_ = c.Error(apiErrors.Conflict("Two-factor authentication is already enabled"))
+	case errors.Is(err, ErrMFARequired):
+		_ = c.Error(apiErrors.Forbidden("Two-factor authentication is required for this account"))
+	case errors.Is(err, ErrMFAEnrollmentRequired):
+		_ = c.Error(apiErrors.Forbidden("Two-factor authentication must be enrolled to complete login"))
+	case errors.Is(err, ErrUserNotFound):
+		_ = c.Error(apiErrors.NotFound("User not found"))
+	default:
+		_ = c.Error(apiErrors.InternalServerError(err))
+	}
+}
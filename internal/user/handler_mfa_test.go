@@ -0,0 +1,229 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_VerifyMFA(t *testing.T) {
+	user := &User{ID: 1, Name: "John Doe", Email: "john@example.com"}
+
+	tests := []struct {
+		name           string
+		body           map[string]string
+		setupMocks     func(*MockService, *MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name: "code accepted",
+			body: map[string]string{"mfa_token": "mfa-token", "code": "123456"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("VerifyMFAChallenge", mock.Anything, "mfa-token", "123456").Return(user, nil)
+				mas.On("GenerateTokenPair", mock.Anything, uint(1), "john@example.com", "John Doe").Return(&auth.TokenPair{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", ExpiresIn: 900}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing code",
+			body:           map[string]string{"mfa_token": "mfa-token"},
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong code",
+			body: map[string]string{"mfa_token": "mfa-token", "code": "000000"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("VerifyMFAChallenge", mock.Anything, "mfa-token", "000000").Return(nil, ErrInvalidMFACode)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "expired token",
+			body: map[string]string{"mfa_token": "mfa-token", "code": "123456"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("VerifyMFAChallenge", mock.Anything, "mfa-token", "123456").Return(nil, ErrInvalidMFAChallenge)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "enrollment required",
+			body: map[string]string{"mfa_token": "mfa-token", "code": "123456"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("VerifyMFAChallenge", mock.Anything, "mfa-token", "123456").Return(nil, ErrMFAEnrollmentRequired)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "token generation error",
+			body: map[string]string{"mfa_token": "mfa-token", "code": "123456"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("VerifyMFAChallenge", mock.Anything, "mfa-token", "123456").Return(user, nil)
+				mas.On("GenerateTokenPair", mock.Anything, uint(1), "john@example.com", "John Doe").Return(nil, errors.New("signing failed"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuth := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuth)
+			handler := NewHandler(mockService, mockAuth)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest("POST", "/auth/mfa/verify", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.VerifyMFA(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+			mockAuth.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ConfirmMFAEnrollment(t *testing.T) {
+	mockService := &MockService{}
+	mockAuth := &MockAuthService{}
+	user := &User{ID: 1, Name: "Admin", Email: "admin@example.com"}
+	mockService.On("CompleteChallengeEnrollment", mock.Anything, "mfa-token", "123456").Return(user, []string{"aaaa-bbbb-cccc-dddd"}, nil)
+	mockAuth.On("GenerateTokenPair", mock.Anything, uint(1), "admin@example.com", "Admin").Return(&auth.TokenPair{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", ExpiresIn: 900}, nil)
+	handler := NewHandler(mockService, mockAuth)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body, _ := json.Marshal(map[string]string{"mfa_token": "mfa-token", "code": "123456"})
+	c.Request = httptest.NewRequest("POST", "/auth/mfa/enroll/confirm", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.ConfirmMFAEnrollment(c)
+	apiErrors.ErrorHandler()(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, "access", data["access_token"])
+	assert.Equal(t, []interface{}{"aaaa-bbbb-cccc-dddd"}, data["recovery_codes"])
+	mockService.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestHandler_MyTOTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           map[string]string
+		authenticated  bool
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name:          "enroll",
+			method:        "POST",
+			authenticated: true,
+			setupMocks: func(ms *MockService) {
+				ms.On("BeginTOTPEnrollment", mock.Anything, uint(1)).Return(&TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/x"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:          "enroll when already enabled",
+			method:        "POST",
+			authenticated: true,
+			setupMocks: func(ms *MockService) {
+				ms.On("BeginTOTPEnrollment", mock.Anything, uint(1)).Return(nil, ErrMFAAlreadyEnabled)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "enroll unauthenticated",
+			method:         "POST",
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "disable",
+			method:        "DELETE",
+			body:          map[string]string{"code": "123456"},
+			authenticated: true,
+			setupMocks: func(ms *MockService) {
+				ms.On("DisableTOTP", mock.Anything, uint(1), "123456").Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:          "disable when required by policy",
+			method:        "DELETE",
+			body:          map[string]string{"code": "123456"},
+			authenticated: true,
+			setupMocks: func(ms *MockService) {
+				ms.On("DisableTOTP", mock.Anything, uint(1), "123456").Return(ErrMFARequired)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:          "disable locked after wrong codes",
+			method:        "DELETE",
+			body:          map[string]string{"code": "123456"},
+			authenticated: true,
+			setupMocks: func(ms *MockService) {
+				ms.On("DisableTOTP", mock.Anything, uint(1), "123456").Return(&auth.LoginBlockedError{RetryAfter: 15 * time.Minute, Locked: true})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "disable without code",
+			method:         "DELETE",
+			body:           map[string]string{},
+			authenticated:  true,
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest(tt.method, "/users/me/mfa/totp", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.authenticated {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+			}
+
+			if tt.method == "DELETE" {
+				handler.DisableMyTOTP(c)
+			} else {
+				handler.EnrollMyTOTP(c)
+			}
+			apiErrors.ErrorHandler()(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
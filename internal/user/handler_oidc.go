@@ -0,0 +1,252 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+)
+
+const (
+	// oidcStateCookie binds a pending authorization request to the browser that started it
+	oidcStateCookie = "oidc_state"
+	// oidcStateCookiePath limits the cookie to the callback route
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+// ListOIDCProviders godoc
+// @Summary List OpenID Connect providers
+// @Description Names of the providers that can be used at /api/v1/auth/oidc/{provider}
+// @Tags auth
+// @Produce json
+// @Success 200 {object} errors.Response{success=bool,data=OIDCProvidersResponse} "Configured providers"
+// @Router /api/v1/auth/oidc [get]
+func (h *Handler) ListOIDCProviders(c *gin.Context) {
+	providers := []string{}
+	if h.oidc != nil {
+		providers = append(providers, h.oidc.Providers()...)
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(OIDCProvidersResponse{Providers: providers}))
+}
+
+// OIDCLogin godoc
+// @Summary Start OpenID Connect login
+// @Description Redirect the browser to the provider's authorization endpoint (authorization code flow with PKCE). The provider redirects back to /api/v1/auth/oidc/{provider}/callback. Sets the HttpOnly oidc_state cookie the callback requires
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the provider"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unknown provider"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Provider unavailable"
+// @Router /api/v1/auth/oidc/{provider} [get]
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		_ = c.Error(apiErrors.NotFound("OIDC provider not found"))
+		return
+	}
+
+	authURL, binding, err := h.oidc.AuthCodeURL(c.Request.Context(), c.Param("provider"), 0)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	h.setOIDCStateCookie(c, binding, int(h.oidc.StateTTL().Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Complete OpenID Connect login
+// @Description Redirect target of the provider. Verifies the ID token and returns access and refresh tokens; the user is created on first login. When the flow was started by /api/v1/users/me/identities/{provider}, the identity is linked instead and returned
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State from the authorization request"
+// @Param error query string false "Error returned by the provider"
+// @Success 200 {object} errors.Response{success=bool,data=AuthResponse} "Success response with user data and tokens (MFAPendingResponse when a second factor is needed, IdentityResponse when linking)"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing code, invalid or expired state, state started by another browser, or no email shared by the provider"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Login denied at the provider or invalid ID token"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Email address has not been verified"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unknown provider"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Email used by an existing account, or identity linked to another account"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Provider unavailable or failed to generate token"
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		_ = c.Error(apiErrors.NotFound("OIDC provider not found"))
+		return
+	}
+
+	var query OIDCCallbackQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+	if query.Error != "" {
+		_ = c.Error(apiErrors.Unauthorized("Login was not completed at the provider: " + query.Error))
+		return
+	}
+	if query.Code == "" {
+		_ = c.Error(apiErrors.BadRequest("code is required"))
+		return
+	}
+
+	// 每个 state 只能使用一次，无论回调成功与否都清除浏览器绑定
+	binding, _ := c.Cookie(oidcStateCookie)
+	h.setOIDCStateCookie(c, "", -1)
+
+	identity, req, err := h.oidc.Exchange(c.Request.Context(), c.Param("provider"), query.Code, query.State, binding)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	if req.LinkUserID != 0 {
+		linked, err := h.userService.LinkIdentity(c.Request.Context(), req.LinkUserID, identity)
+		if err != nil {
+			h.oidcError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, apiErrors.Success(ToIdentityResponse(linked)))
+		return
+	}
+
+	user, err := h.userService.LoginWithIdentity(c.Request.Context(), identity)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	h.completeLogin(c, user)
+}
+
+// ListMyIdentities godoc
+// @Summary List my linked identities
+// @Description OpenID Connect provider accounts linked to the authenticated user
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]IdentityResponse} "Linked identities"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list identities"
+// @Router /api/v1/users/me/identities [get]
+func (h *Handler) ListMyIdentities(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	identities, err := h.userService.ListIdentities(c.Request.Context(), userID)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	response := make([]IdentityResponse, len(identities))
+	for i := range identities {
+		response[i] = ToIdentityResponse(&identities[i])
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// LinkMyIdentity godoc
+// @Summary Link an OpenID Connect provider
+// @Description Start linking a provider account to the authenticated user. Call it from the browser that opens the returned URL: it sets the HttpOnly oidc_state cookie the callback requires, and the callback links the identity
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name"
+// @Success 200 {object} errors.Response{success=bool,data=OIDCAuthorizationResponse} "Provider authorization URL"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unknown provider"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Provider unavailable"
+// @Router /api/v1/users/me/identities/{provider} [post]
+func (h *Handler) LinkMyIdentity(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+	if h.oidc == nil {
+		_ = c.Error(apiErrors.NotFound("OIDC provider not found"))
+		return
+	}
+
+	authURL, binding, err := h.oidc.AuthCodeURL(c.Request.Context(), c.Param("provider"), userID)
+	if err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	h.setOIDCStateCookie(c, binding, int(h.oidc.StateTTL().Seconds()))
+	c.JSON(http.StatusOK, apiErrors.Success(OIDCAuthorizationResponse{AuthorizationURL: authURL}))
+}
+
+// UnlinkMyIdentity godoc
+// @Summary Unlink an OpenID Connect provider
+// @Description Remove the provider account linked to the authenticated user. Users created through the provider should set a password (password reset) first
+// @Tags users
+// @Security BearerAuth
+// @Param provider path string true "Provider name"
+// @Success 204 "Identity unlinked"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "No identity linked for this provider"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to unlink identity"
+// @Router /api/v1/users/me/identities/{provider} [delete]
+func (h *Handler) UnlinkMyIdentity(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	if err := h.userService.UnlinkIdentity(c.Request.Context(), userID, c.Param("provider")); err != nil {
+		h.oidcError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// setOIDCStateCookie stores the browser binding of an authorization request; maxAge < 0 deletes it.
+// SameSite=Lax lets the cookie through on the provider's top-level redirect back to the callback.
+func (h *Handler) setOIDCStateCookie(c *gin.Context, binding string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, binding, maxAge, oidcStateCookiePath, "", h.oidc.SecureCookie(), true)
+}
+
+// oidcError maps OpenID Connect login and identity errors to API errors
+func (h *Handler) oidcError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider):
+		_ = c.Error(apiErrors.NotFound("OIDC provider not found"))
+	case errors.Is(err, oidc.ErrInvalidState):
+		_ = c.Error(apiErrors.BadRequest("Invalid or expired state"))
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		_ = c.Error(apiErrors.Unauthorized("Invalid ID token from provider"))
+	case errors.Is(err, ErrIdentityEmailRequired):
+		_ = c.Error(apiErrors.BadRequest("The provider did not share an email address"))
+	case errors.Is(err, ErrIdentityEmailInUse):
+		_ = c.Error(apiErrors.Conflict("An account with this email already exists. Log in and link the provider from your account"))
+	case errors.Is(err, ErrIdentityLinkedElsewhere):
+		_ = c.Error(apiErrors.Conflict("This provider account is linked to another user"))
+	case errors.Is(err, ErrProviderAlreadyLinked):
+		_ = c.Error(apiErrors.Conflict("Another account of this provider is already linked"))
+	case errors.Is(err, ErrIdentityNotFound):
+		_ = c.Error(apiErrors.NotFound("No identity linked for this provider"))
+	case errors.Is(err, ErrEmailNotVerified):
+		_ = c.Error(apiErrors.Forbidden("Email address has not been verified"))
+	case errors.Is(err, ErrUserNotFound):
+		_ = c.Error(apiErrors.NotFound("User not found"))
+	default:
+		_ = c.Error(apiErrors.InternalServerError(err))
+	}
+}
@@ -0,0 +1,228 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc/oidctest"
+)
+
+func newTestOIDCClient(t *testing.T) (*oidc.Client, *oidctest.Provider) {
+	t.Helper()
+	fake := oidctest.NewProvider(t, "test-client", "test-secret")
+	client := oidc.NewClient(&config.OIDCConfig{
+		Enabled:   true,
+		Providers: []config.OIDCProviderConfig{fake.Config("test", "http://localhost/api/v1/auth/oidc/test/callback")},
+	}, oidc.NewMemoryStateStore())
+	return client, fake
+}
+
+func TestHandler_OIDCLogin(t *testing.T) {
+	client, fake := newTestOIDCClient(t)
+
+	tests := []struct {
+		name           string
+		handler        *Handler
+		provider       string
+		expectedStatus int
+	}{
+		{name: "redirects to provider", handler: NewHandlerWithOIDC(&MockService{}, &MockAuthService{}, client), provider: "test", expectedStatus: http.StatusFound},
+		{name: "unknown provider", handler: NewHandlerWithOIDC(&MockService{}, &MockAuthService{}, client), provider: "missing", expectedStatus: http.StatusNotFound},
+		{name: "oidc not configured", handler: NewHandler(&MockService{}, &MockAuthService{}), provider: "test", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/auth/oidc/"+tt.provider, nil)
+			c.Params = gin.Params{{Key: "provider", Value: tt.provider}}
+
+			tt.handler.OIDCLogin(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusFound {
+				assert.True(t, strings.HasPrefix(w.Header().Get("Location"), fake.Issuer()+"/authorize?"))
+				cookie := w.Header().Get("Set-Cookie")
+				assert.Contains(t, cookie, oidcStateCookie+"=")
+				assert.Contains(t, cookie, "HttpOnly")
+				assert.Contains(t, cookie, "Secure")
+				assert.Contains(t, cookie, "SameSite=Lax")
+			}
+		})
+	}
+}
+
+func TestHandler_OIDCLogin_InsecureCookie(t *testing.T) {
+	fake := oidctest.NewProvider(t, "test-client", "test-secret")
+	client := oidc.NewClient(&config.OIDCConfig{
+		Enabled:        true,
+		InsecureCookie: true,
+		Providers:      []config.OIDCProviderConfig{fake.Config("test", "http://localhost/api/v1/auth/oidc/test/callback")},
+	}, oidc.NewMemoryStateStore())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/auth/oidc/test", nil)
+	c.Params = gin.Params{{Key: "provider", Value: "test"}}
+
+	NewHandlerWithOIDC(&MockService{}, &MockAuthService{}, client).OIDCLogin(c)
+
+	require.Equal(t, http.StatusFound, w.Code)
+	cookie := w.Header().Get("Set-Cookie")
+	assert.Contains(t, cookie, oidcStateCookie+"=")
+	assert.NotContains(t, cookie, "Secure", "plain-HTTP development needs a cookie without Secure")
+}
+
+func TestHandler_OIDCCallback(t *testing.T) {
+	user := &User{ID: 1, Name: "Jane Doe", Email: "jane@example.com"}
+	identityMatcher := mock.MatchedBy(func(identity *oidc.Identity) bool {
+		return identity.Provider == "test" && identity.Subject == "user-1"
+	})
+
+	tests := []struct {
+		name           string
+		linkUserID     uint
+		binding        func(binding string) string
+		query          func(code, state string) string
+		setupMocks     func(*MockService, *MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:  "login",
+			query: func(code, state string) string { return "code=" + code + "&state=" + state },
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("LoginWithIdentity", mock.Anything, identityMatcher).Return(user, nil)
+				ms.On("StartMFAChallenge", mock.Anything, user).Return(nil, nil)
+				mas.On("GenerateTokenPair", mock.Anything, uint(1), "jane@example.com", "Jane Doe").Return(&auth.TokenPair{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", ExpiresIn: 900}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:       "link",
+			linkUserID: 7,
+			query:      func(code, state string) string { return "code=" + code + "&state=" + state },
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("LinkIdentity", mock.Anything, uint(7), identityMatcher).Return(&UserIdentity{UserID: 7, Provider: "test", Subject: "user-1"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "email used by another account",
+			query: func(code, state string) string { return "code=" + code + "&state=" + state },
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("LoginWithIdentity", mock.Anything, identityMatcher).Return(nil, ErrIdentityEmailInUse)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "denied at provider",
+			query:          func(code, state string) string { return "error=access_denied&state=" + state },
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown state",
+			query:          func(code, state string) string { return "code=" + code + "&state=forged" },
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing state cookie",
+			binding:        func(binding string) string { return "" },
+			query:          func(code, state string) string { return "code=" + code + "&state=" + state },
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "state cookie of another browser",
+			binding:        func(binding string) string { return oidc.StateBinding("another-state") },
+			query:          func(code, state string) string { return "code=" + code + "&state=" + state },
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing state",
+			query:          func(code, state string) string { return "code=" + code },
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := newTestOIDCClient(t)
+			authURL, binding, err := client.AuthCodeURL(context.Background(), "test", tt.linkUserID)
+			require.NoError(t, err)
+			callback := fake.Authorize(t, authURL)
+			if tt.binding != nil {
+				binding = tt.binding(binding)
+			}
+
+			mockService := &MockService{}
+			mockAuth := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuth)
+			handler := NewHandlerWithOIDC(mockService, mockAuth, client)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/auth/oidc/test/callback?"+tt.query(callback.Query().Get("code"), callback.Query().Get("state")), nil)
+			if binding != "" {
+				c.Request.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: binding})
+			}
+			c.Params = gin.Params{{Key: "provider", Value: "test"}}
+
+			handler.OIDCCallback(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+			mockAuth.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_UnlinkMyIdentity(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "unlinked", expectedStatus: http.StatusNoContent},
+		{name: "not linked", err: ErrIdentityNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("UnlinkIdentity", mock.Anything, uint(1), "test").Return(tt.err)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/users/me/identities/test", nil)
+			c.Params = gin.Params{{Key: "provider", Value: "test"}}
+			c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+
+			handler.UnlinkMyIdentity(c)
+			apiErrors.ErrorHandler()(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. Always succeeds so the response does not reveal whether the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetRequest true "Password reset request"
+// @Success 200 {object} errors.Response{success=bool,data=object} "Reset email sent if the account exists"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to request password reset"
+// @Router /api/v1/auth/password-reset/request [post]
+func (h *Handler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "If the email is registered, a password reset link has been sent"}))
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Confirm a password reset
+// @Description Set a new password with a reset token. The token can be used once, and all existing sessions of the user are revoked
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetConfirmRequest true "Password reset confirmation"
+// @Success 200 {object} errors.Response{success=bool,data=object} "Password has been reset"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, password policy violation or invalid reset token"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Rate limit exceeded"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to reset password"
+// @Router /api/v1/auth/password-reset/confirm [post]
+func (h *Handler) ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	userID, err := h.userService.ConfirmPasswordReset(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			_ = c.Error(apiErrors.BadRequest("Invalid or expired password reset token"))
+			return
+		}
+		var policyErr *PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			_ = c.Error(apiErrors.ValidationError(policyErr.Details()))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	// 密码已重置：撤销全部令牌，使可能泄露的旧会话立即失效
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(gin.H{"message": "Password has been reset"}))
+}
+
+// ChangeMyPassword godoc
+// @Summary Change my password
+// @Description Change the authenticated user's password. Unless revoke_other_sessions is false, every existing session is revoked and a fresh token pair is returned for the current device
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Password change request"
+// @Success 200 {object} errors.Response{success=bool,data=ChangePasswordResponse} "Password changed"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Validation error, password policy violation, incorrect current password or unchanged password"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 429 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Too many incorrect current passwords (ACCOUNT_LOCKED) or retried too soon after a failure (TOO_MANY_REQUESTS)"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to change password"
+// @Router /api/v1/users/me/password [put]
+func (h *Handler) ChangeMyPassword(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	user, err := h.userService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		var policyErr *PasswordPolicyError
+		var blocked *auth.LoginBlockedError
+		switch {
+		case errors.As(err, &policyErr):
+			_ = c.Error(apiErrors.ValidationError(policyErr.Details()))
+		case errors.As(err, &blocked):
+			h.loginBlocked(c, blocked)
+		case errors.Is(err, ErrIncorrectPassword):
+			_ = c.Error(apiErrors.BadRequest("Current password is incorrect"))
+		case errors.Is(err, ErrPasswordUnchanged):
+			_ = c.Error(apiErrors.BadRequest("New password must differ from the current password"))
+		case errors.Is(err, ErrUserNotFound):
+			_ = c.Error(apiErrors.NotFound("User not found"))
+		default:
+			_ = c.Error(apiErrors.InternalServerError(err))
+		}
+		return
+	}
+
+	if req.RevokeOtherSessions != nil && !*req.RevokeOtherSessions {
+		c.JSON(http.StatusOK, apiErrors.Success(ChangePasswordResponse{Message: "Password has been changed"}))
+		return
+	}
+
+	// WHY: 访问令牌不携带会话标识，无法只保留当前会话；撤销全部令牌后为当前设备重新签发
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	tokenPair, err := h.authService.GenerateTokenPair(clientContext(c), user.ID, user.Email, user.Name)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ChangePasswordResponse{
+		Message: "Password has been changed",
+		Tokens: &auth.TokenPairResponse{
+			AccessToken:  tokenPair.AccessToken,
+			RefreshToken: tokenPair.RefreshToken,
+			TokenType:    tokenPair.TokenType,
+			ExpiresIn:    tokenPair.ExpiresIn,
+		},
+	}))
+}
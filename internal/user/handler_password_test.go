@@ -0,0 +1,303 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_RequestPasswordReset(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           map[string]string
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name: "reset requested",
+			body: map[string]string{"email": "user@example.com"},
+			setupMocks: func(ms *MockService) {
+				ms.On("RequestPasswordReset", mock.Anything, "user@example.com").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid email",
+			body:           map[string]string{"email": "not-an-email"},
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			body: map[string]string{"email": "user@example.com"},
+			setupMocks: func(ms *MockService) {
+				ms.On("RequestPasswordReset", mock.Anything, "user@example.com").Return(errors.New("mail server down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockService)
+
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest("POST", "/auth/password-reset/request", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.RequestPasswordReset(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ConfirmPasswordReset(t *testing.T) {
+	validBody := map[string]string{"token": "reset-token", "new_password": "newpassword123"}
+
+	tests := []struct {
+		name            string
+		body            map[string]string
+		setupMocks      func(*MockService, *MockAuthService)
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name: "password reset revokes all tokens",
+			body: validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ConfirmPasswordReset", mock.Anything, "reset-token", "newpassword123").Return(uint(7), nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(7)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "invalid token",
+			body: validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ConfirmPasswordReset", mock.Anything, "reset-token", "newpassword123").Return(uint(0), ErrInvalidResetToken)
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Invalid or expired password reset token",
+		},
+		{
+			name: "password violates policy",
+			body: map[string]string{"token": "reset-token", "new_password": "short"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ConfirmPasswordReset", mock.Anything, "reset-token", "short").Return(uint(0), &PasswordPolicyError{
+					Violations: []PasswordViolation{{Rule: PasswordRuleMinLength, Message: "password must be at least 8 characters long"}},
+				})
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Validation failed",
+		},
+		{
+			name:           "missing password",
+			body:           map[string]string{"token": "reset-token"},
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "token revocation fails",
+			body: validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ConfirmPasswordReset", mock.Anything, "reset-token", "newpassword123").Return(uint(7), nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(7)).Return(errors.New("database down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuthService)
+
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest("POST", "/auth/password-reset/confirm", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.ConfirmPasswordReset(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMessage != "" {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, tt.expectedMessage, errorInfo["message"])
+			}
+
+			mockService.AssertExpectations(t)
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ChangeMyPassword(t *testing.T) {
+	validBody := map[string]interface{}{"current_password": "oldpassword123", "new_password": "newpassword123"}
+	changedUser := &User{ID: 1, Name: "John Doe", Email: "john@example.com"}
+
+	tests := []struct {
+		name            string
+		userID          uint
+		body            map[string]interface{}
+		setupMocks      func(*MockService, *MockAuthService)
+		expectedStatus  int
+		expectedMessage string
+		expectTokens    bool
+	}{
+		{
+			name:   "password changed and other sessions revoked",
+			userID: 1,
+			body:   validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(changedUser, nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(1)).Return(nil)
+				mas.On("GenerateTokenPair", mock.Anything, uint(1), "john@example.com", "John Doe").Return(&auth.TokenPair{
+					AccessToken:  "new-access",
+					RefreshToken: "new-refresh",
+					TokenType:    "Bearer",
+					ExpiresIn:    900,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectTokens:   true,
+		},
+		{
+			name:   "sessions kept when revocation is disabled",
+			userID: 1,
+			body: map[string]interface{}{
+				"current_password":      "oldpassword123",
+				"new_password":          "newpassword123",
+				"revoke_other_sessions": false,
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(changedUser, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unauthenticated",
+			userID:         0,
+			body:           validBody,
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "new password violates policy",
+			userID: 1,
+			body:   map[string]interface{}{"current_password": "oldpassword123", "new_password": "short"},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "short").Return(nil, &PasswordPolicyError{
+					Violations: []PasswordViolation{{Rule: PasswordRuleMinLength, Message: "password must be at least 8 characters long"}},
+				})
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Validation failed",
+		},
+		{
+			name:   "incorrect current password",
+			userID: 1,
+			body:   validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(nil, ErrIncorrectPassword)
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Current password is incorrect",
+		},
+		{
+			name:   "locked after too many incorrect passwords",
+			userID: 1,
+			body:   validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(nil, &auth.LoginBlockedError{RetryAfter: 15 * time.Minute, Locked: true})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:   "unchanged password",
+			userID: 1,
+			body:   validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(nil, ErrPasswordUnchanged)
+			},
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "New password must differ from the current password",
+		},
+		{
+			name:   "token revocation fails",
+			userID: 1,
+			body:   validBody,
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("ChangePassword", mock.Anything, uint(1), "oldpassword123", "newpassword123").Return(changedUser, nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(1)).Return(errors.New("database down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuthService)
+
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(tt.body)
+			c.Request = httptest.NewRequest("PUT", "/users/me/password", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.userID != 0 {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: tt.userID})
+			}
+
+			handler.ChangeMyPassword(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			if tt.expectedMessage != "" {
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, tt.expectedMessage, errorInfo["message"])
+			}
+			if tt.expectedStatus == http.StatusOK {
+				data := response["data"].(map[string]interface{})
+				_, hasTokens := data["tokens"]
+				assert.Equal(t, tt.expectTokens, hasTokens)
+			}
+
+			mockService.AssertExpectations(t)
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
@@ -229,6 +229,28 @@ func TestHandler_Logout(t *testing.T) {
 				assert.Equal(t, "Successfully logged out", data["message"])
 			},
 		},
+		{
+			name: "logout revokes current access token",
+			requestBody: auth.RefreshTokenRequest{
+				RefreshToken: "valid-refresh-token",
+			},
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeUserRefreshToken", mock.Anything, uint(1), "valid-refresh-token").Return(nil)
+				mas.On("RevokeAccessToken", mock.Anything, mock.MatchedBy(func(claims *auth.Claims) bool {
+					return claims.ID == "access-jti"
+				})).Return(nil)
+			},
+			setupContext: func(c *gin.Context) {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: 1, ID: "access-jti"})
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, true, response["success"])
+			},
+		},
 		{
 			name:        "missing refresh token",
 			requestBody: map[string]string{},
@@ -345,3 +367,78 @@ func TestHandler_Logout(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_LogoutAll(t *testing.T) {
+	tests := []struct {
+		name           string
+		setupMocks     func(*MockAuthService)
+		setupContext   func(*gin.Context)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "successful logout from all devices",
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(1)).Return(nil)
+			},
+			setupContext: func(c *gin.Context) {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "revocation failure",
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(1)).Return(errors.New("database error"))
+			},
+			setupContext: func(c *gin.Context) {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   "INTERNAL_ERROR",
+		},
+		{
+			name:           "unauthenticated user",
+			setupMocks:     func(mas *MockAuthService) {},
+			setupContext:   func(c *gin.Context) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "UNAUTHORIZED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mockAuthService := new(MockAuthService)
+			tt.setupMocks(mockAuthService)
+
+			handler := &Handler{
+				authService: mockAuthService,
+			}
+
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout-all", nil)
+			tt.setupContext(c)
+
+			handler.LogoutAll(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			if tt.expectedCode != "" {
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, tt.expectedCode, errorInfo["code"])
+			} else {
+				assert.Equal(t, true, response["success"])
+			}
+
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+	"github.com/yeegeek/go-rest-api-starter/internal/middleware"
+)
+
+// PermissionResolver returns the resolver used by the RequirePermission middleware
+func (h *Handler) PermissionResolver() auth.PermissionResolver {
+	return h.userService
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List every role with its permissions. Requires the roles:read permission
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]RoleResponse} "Roles"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list roles"
+// @Router /api/v1/admin/roles [get]
+func (h *Handler) ListRoles(c *gin.Context) {
+	roles, err := h.userService.ListRoles(c.Request.Context())
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	response := make([]RoleResponse, len(roles))
+	for i := range roles {
+		response[i] = ToRoleResponse(&roles[i])
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create a role with permissions of the form resource:action. Permissions missing from the catalog are added. Requires the roles:write permission
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateRoleRequest true "Role name, description and permissions"
+// @Success 201 {object} errors.Response{success=bool,data=RoleResponse} "Created role"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid role name or permission"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Role already exists"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to create role"
+// @Router /api/v1/admin/roles [post]
+func (h *Handler) CreateRole(c *gin.Context) {
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	role, err := h.userService.CreateRole(c.Request.Context(), req)
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiErrors.Success(ToRoleResponse(role)))
+}
+
+// GetRole godoc
+// @Summary Get a role
+// @Description Get a role and its permissions. Requires the roles:read permission
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} errors.Response{success=bool,data=RoleResponse} "Role"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Role not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to get role"
+// @Router /api/v1/admin/roles/{name} [get]
+func (h *Handler) GetRole(c *gin.Context) {
+	role, err := h.userService.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ToRoleResponse(role)))
+}
+
+// UpdateRole godoc
+// @Summary Update a role
+// @Description Replace the description and permissions of a role. The admin role must keep roles:read and roles:write. Requires the roles:write permission
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param request body UpdateRoleRequest true "Description and the complete list of permissions"
+// @Success 200 {object} errors.Response{success=bool,data=RoleResponse} "Updated role"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid permission"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Role not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "The admin role must keep role management"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to update role"
+// @Router /api/v1/admin/roles/{name} [put]
+func (h *Handler) UpdateRole(c *gin.Context) {
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(apiErrors.FromGinValidation(err))
+		return
+	}
+
+	role, err := h.userService.UpdateRole(c.Request.Context(), c.Param("name"), req)
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ToRoleResponse(role)))
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Delete a custom role. Built-in roles and roles still assigned to users cannot be deleted. Requires the roles:write permission
+// @Tags admin
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 204 "Role deleted"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Role not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Built-in role or role in use"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to delete role"
+// @Router /api/v1/admin/roles/{name} [delete]
+func (h *Handler) DeleteRole(c *gin.Context) {
+	if err := h.userService.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description List the permission catalog. Requires the roles:read permission
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]PermissionResponse} "Permissions"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list permissions"
+// @Router /api/v1/admin/permissions [get]
+func (h *Handler) ListPermissions(c *gin.Context) {
+	permissions, err := h.userService.ListPermissions(c.Request.Context())
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	response := make([]PermissionResponse, len(permissions))
+	for i := range permissions {
+		response[i] = ToPermissionResponse(&permissions[i])
+	}
+	c.JSON(http.StatusOK, apiErrors.Success(response))
+}
+
+// ListRoleUsers godoc
+// @Summary List users with a role
+// @Description Get a paginated list of the users a role is assigned to. Requires the roles:read and users:read permissions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page (max 100)" default(20)
+// @Param search query string false "Search by name or email"
+// @Param sort query string false "Sort by field (created_at, updated_at, name, email)" default(created_at)
+// @Param order query string false "Sort order (asc or desc)" default(desc)
+// @Success 200 {object} errors.Response{success=bool,data=UserListResponse} "Users with the role"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Missing permission"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Role not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list users"
+// @Router /api/v1/admin/roles/{name}/users [get]
+func (h *Handler) ListRoleUsers(c *gin.Context) {
+	role, err := h.userService.GetRole(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.roleError(c, err)
+		return
+	}
+
+	pagination := middleware.ParsePaginationParams(c)
+	filters := ParseUserFilters(c)
+	filters.Role = role.Name
+
+	users, total, err := h.userService.ListUsers(c.Request.Context(), filters, pagination.Page, pagination.PerPage)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(toUserListResponse(users, total, pagination)))
+}
+
+// GrantUserRole godoc
+// @Summary Grant a role to a user (Admin only)
+// @Description Assign a role to a user. Granting a role the user already has is a no-op. The user's tokens are revoked so the new role takes effect on the next login
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} errors.Response{success=bool,data=UserResponse} "Updated user"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User or role not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to grant role"
+// @Router /api/v1/admin/users/{id}/roles/{role} [post]
+func (h *Handler) GrantUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	user, changed, err := h.userService.GrantRole(c.Request.Context(), uint(id), c.Param("role"))
+	h.respondRoleAssignment(c, user, changed, err)
+}
+
+// RevokeUserRole godoc
+// @Summary Revoke a role from a user (Admin only)
+// @Description Remove a role from a user. Admins cannot revoke their own roles and the last admin cannot lose the admin role. The user's tokens are revoked so the change takes effect immediately
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} errors.Response{success=bool,data=UserResponse} "Updated user"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required or own role"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User or role not found"
+// @Failure 409 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Last admin"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to revoke role"
+// @Router /api/v1/admin/users/{id}/roles/{role} [delete]
+func (h *Handler) RevokeUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return
+	}
+
+	user, changed, err := h.userService.RevokeRole(c.Request.Context(), contextutil.GetUserID(c), uint(id), c.Param("role"))
+	h.respondRoleAssignment(c, user, changed, err)
+}
+
+// respondRoleAssignment revokes the user's tokens after a role change and writes the updated user
+func (h *Handler) respondRoleAssignment(c *gin.Context, user *User, changed bool, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			_ = c.Error(apiErrors.NotFound("User not found"))
+		case errors.Is(err, ErrSelfRoleRevocation):
+			_ = c.Error(apiErrors.Forbidden("You cannot revoke your own roles"))
+		case errors.Is(err, ErrLastAdmin):
+			_ = c.Error(apiErrors.Conflict("Cannot remove the admin role from the last admin"))
+		default:
+			h.roleError(c, err)
+		}
+		return
+	}
+
+	// WHY: 令牌中的角色在签发时确定，撤销全部令牌使角色变更立即生效
+	if changed {
+		if err := h.authService.RevokeAllUserTokens(c.Request.Context(), user.ID); err != nil {
+			_ = c.Error(apiErrors.InternalServerError(err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(ToUserResponse(user)))
+}
+
+// roleError maps role management errors to API errors
+func (h *Handler) roleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		_ = c.Error(apiErrors.NotFound("Role not found"))
+	case errors.Is(err, ErrRoleExists):
+		_ = c.Error(apiErrors.Conflict("Role already exists"))
+	case errors.Is(err, ErrRoleInUse):
+		_ = c.Error(apiErrors.Conflict("Role is assigned to users. Remove it from them first"))
+	case errors.Is(err, ErrProtectedRole):
+		_ = c.Error(apiErrors.Conflict("Built-in roles cannot be deleted and the admin role must keep roles:read and roles:write"))
+	case errors.Is(err, ErrInvalidRoleName):
+		_ = c.Error(apiErrors.BadRequest("Role name must start with a lowercase letter and contain only lowercase letters, digits, '-' and '_'"))
+	case errors.Is(err, ErrInvalidPermission):
+		_ = c.Error(apiErrors.BadRequest("Permissions must have the form resource:action, e.g. users:read"))
+	default:
+		_ = c.Error(apiErrors.InternalServerError(err))
+	}
+}
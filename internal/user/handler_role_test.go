@@ -0,0 +1,209 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_CreateRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		setupMocks     func(*MockService)
+		expectedStatus int
+	}{
+		{
+			name: "created",
+			body: `{"name":"support","permissions":["users:read"]}`,
+			setupMocks: func(ms *MockService) {
+				ms.On("CreateRole", mock.Anything, CreateRoleRequest{Name: "support", Permissions: []string{"users:read"}}).
+					Return(&Role{Name: "support", Permissions: []Permission{{Name: "users:read"}}, CreatedAt: time.Now()}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing name",
+			body:           `{"permissions":["users:read"]}`,
+			setupMocks:     func(ms *MockService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid permission",
+			body: `{"name":"support","permissions":["users"]}`,
+			setupMocks: func(ms *MockService) {
+				ms.On("CreateRole", mock.Anything, mock.Anything).Return(nil, ErrInvalidPermission)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "already exists",
+			body: `{"name":"admin"}`,
+			setupMocks: func(ms *MockService) {
+				ms.On("CreateRole", mock.Anything, mock.Anything).Return(nil, ErrRoleExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			tt.setupMocks(mockService)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/admin/roles", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.CreateRole(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data RoleResponse `json:"data"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "support", response.Data.Name)
+				assert.Equal(t, []string{"users:read"}, response.Data.Permissions)
+				assert.False(t, response.Data.Builtin)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_DeleteRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "deleted", expectedStatus: http.StatusNoContent},
+		{name: "not found", err: ErrRoleNotFound, expectedStatus: http.StatusNotFound},
+		{name: "built-in role", err: ErrProtectedRole, expectedStatus: http.StatusConflict},
+		{name: "assigned to users", err: ErrRoleInUse, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockService.On("DeleteRole", mock.Anything, "support").Return(tt.err)
+			handler := NewHandler(mockService, &MockAuthService{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/admin/roles/support", nil)
+			c.Params = gin.Params{{Key: "name", Value: "support"}}
+
+			handler.DeleteRole(c)
+			apiErrors.ErrorHandler()(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_RevokeUserRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		setupMocks     func(*MockService, *MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:   "revoked",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(7), "admin").Return(&User{ID: 7}, true, nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(7)).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "not assigned keeps tokens",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(7), "admin").Return(&User{ID: 7}, false, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "token revocation fails",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(7), "admin").Return(&User{ID: 7}, true, nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(7)).Return(errors.New("database down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:   "own role",
+			userID: "1",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(1), "admin").Return(nil, false, ErrSelfRoleRevocation)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "last admin",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(7), "admin").Return(nil, false, ErrLastAdmin)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "unknown role",
+			userID: "7",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("RevokeRole", mock.Anything, uint(1), uint(7), "admin").Return(nil, false, ErrRoleNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid user id",
+			userID:         "abc",
+			setupMocks:     func(ms *MockService, mas *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockService, mockAuthService)
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/admin/users/"+tt.userID+"/roles/admin", nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.userID}, {Key: "role", Value: "admin"}}
+			contextutil.SetUserID(c, 1)
+
+			handler.RevokeUserRole(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
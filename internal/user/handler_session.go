@@ -0,0 +1,155 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/contextutil"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+// ListMySessions godoc
+// @Summary List my sessions
+// @Description List the active sessions (devices) of the authenticated user
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]auth.Session} "Active sessions, most recently used first"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list sessions"
+// @Router /api/v1/users/me/sessions [get]
+func (h *Handler) ListMySessions(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	h.listSessions(c, userID)
+}
+
+// RevokeMySession godoc
+// @Summary Revoke one of my sessions
+// @Description Sign out a device by revoking its refresh-token family
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param family path string true "Session ID (token family UUID)"
+// @Security BearerAuth
+// @Success 204 "Session revoked"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid session ID"
+// @Failure 401 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Unauthorized"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Session not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to revoke session"
+// @Router /api/v1/users/me/sessions/{family} [delete]
+func (h *Handler) RevokeMySession(c *gin.Context) {
+	userID := contextutil.GetUserID(c)
+	if userID == 0 {
+		_ = c.Error(apiErrors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	h.revokeSession(c, userID)
+}
+
+// ListUserSessions godoc
+// @Summary List a user's sessions (Admin only)
+// @Description List the active sessions of any user (requires admin role)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Security BearerAuth
+// @Success 200 {object} errors.Response{success=bool,data=[]auth.Session} "Active sessions, most recently used first"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to list sessions"
+// @Router /api/v1/admin/users/{id}/sessions [get]
+func (h *Handler) ListUserSessions(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	h.listSessions(c, userID)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke a user's session (Admin only)
+// @Description Sign out one device of any user by revoking its refresh-token family (requires admin role)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param family path string true "Session ID (token family UUID)"
+// @Security BearerAuth
+// @Success 204 "Session revoked"
+// @Failure 400 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Invalid user or session ID"
+// @Failure 403 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Admin access required"
+// @Failure 404 {object} errors.Response{success=bool,error=errors.ErrorInfo} "User or session not found"
+// @Failure 500 {object} errors.Response{success=bool,error=errors.ErrorInfo} "Failed to revoke session"
+// @Router /api/v1/admin/users/{id}/sessions/{family} [delete]
+func (h *Handler) RevokeUserSession(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	h.revokeSession(c, userID)
+}
+
+// targetUserID parses the :id path parameter and checks that the user exists
+func (h *Handler) targetUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid user ID"))
+		return 0, false
+	}
+
+	if _, err := h.userService.GetUserByID(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			_ = c.Error(apiErrors.NotFound("User not found"))
+			return 0, false
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func (h *Handler) listSessions(c *gin.Context, userID uint) {
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, apiErrors.Success(sessions))
+}
+
+func (h *Handler) revokeSession(c *gin.Context, userID uint) {
+	family, err := uuid.Parse(c.Param("family"))
+	if err != nil {
+		_ = c.Error(apiErrors.BadRequest("Invalid session ID"))
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID, family); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			_ = c.Error(apiErrors.NotFound("Session not found"))
+			return
+		}
+		_ = c.Error(apiErrors.InternalServerError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
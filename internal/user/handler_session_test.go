@@ -0,0 +1,148 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
+)
+
+func TestHandler_ListMySessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := &MockService{}
+	mockAuthService := &MockAuthService{}
+	family := uuid.New()
+	mockAuthService.On("ListSessions", mock.Anything, uint(1)).
+		Return([]auth.Session{{ID: family, UserAgent: "Firefox/130", IPAddress: "198.51.100.1"}}, nil)
+
+	handler := NewHandler(mockService, mockAuthService)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/users/me/sessions", nil)
+	c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+
+	handler.ListMySessions(c)
+	apiErrors.ErrorHandler()(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	sessions, ok := response["data"].([]interface{})
+	assert.True(t, ok)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, family.String(), sessions[0].(map[string]interface{})["id"])
+
+	mockAuthService.AssertExpectations(t)
+}
+
+func TestHandler_RevokeMySession(t *testing.T) {
+	family := uuid.New()
+
+	tests := []struct {
+		name            string
+		family          string
+		setupMocks      func(*MockAuthService)
+		authenticated   bool
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:   "successful revocation",
+			family: family.String(),
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeSession", mock.Anything, uint(1), family).Return(nil)
+			},
+			authenticated:  true,
+			expectedStatus: http.StatusOK, // Note: Gin test recorder returns 200 for c.Status(204) without response body
+		},
+		{
+			name:            "unauthenticated",
+			family:          family.String(),
+			setupMocks:      func(mas *MockAuthService) {},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "user not authenticated",
+		},
+		{
+			name:            "invalid session ID",
+			family:          "not-a-uuid",
+			setupMocks:      func(mas *MockAuthService) {},
+			authenticated:   true,
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: "Invalid session ID",
+		},
+		{
+			name:   "session not found",
+			family: family.String(),
+			setupMocks: func(mas *MockAuthService) {
+				mas.On("RevokeSession", mock.Anything, uint(1), family).Return(auth.ErrSessionNotFound)
+			},
+			authenticated:   true,
+			expectedStatus:  http.StatusNotFound,
+			expectedMessage: "Session not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			mockAuthService := &MockAuthService{}
+			tt.setupMocks(mockAuthService)
+
+			handler := NewHandler(mockService, mockAuthService)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("DELETE", "/users/me/sessions/"+tt.family, nil)
+			c.Params = gin.Params{{Key: "family", Value: tt.family}}
+			if tt.authenticated {
+				c.Set(auth.KeyUser, &auth.Claims{UserID: 1})
+			}
+
+			handler.RevokeMySession(c)
+			apiErrors.ErrorHandler()(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedMessage != "" {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, tt.expectedMessage, errorInfo["message"])
+			}
+
+			mockAuthService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ListUserSessions_UserNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := &MockService{}
+	mockAuthService := &MockAuthService{}
+	mockService.On("GetUserByID", mock.Anything, uint(42)).Return(nil, ErrUserNotFound)
+
+	handler := NewHandler(mockService, mockAuthService)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/admin/users/42/sessions", nil)
+	c.Params = gin.Params{{Key: "id", Value: "42"}}
+
+	handler.ListUserSessions(c)
+	apiErrors.ErrorHandler()(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+	mockAuthService.AssertExpectations(t)
+}
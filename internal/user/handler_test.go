@@ -8,12 +8,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
 	apiErrors "github.com/yeegeek/go-rest-api-starter/internal/errors"
 )
 
@@ -30,6 +34,21 @@ func (m *MockAuthService) ValidateToken(tokenString string) (*auth.Claims, error
 	return args.Get(0).(*auth.Claims), args.Error(1)
 }
 
+func (m *MockAuthService) JWKS() auth.JWKSet {
+	args := m.Called()
+	return args.Get(0).(auth.JWKSet)
+}
+
+func (m *MockAuthService) RevokeAccessToken(ctx context.Context, claims *auth.Claims) error {
+	args := m.Called(ctx, claims)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ReloadKeys(cfg *config.JWTConfig) error {
+	args := m.Called(cfg)
+	return args.Error(0)
+}
+
 func (m *MockAuthService) GenerateToken(userID uint, email string, name string) (string, error) {
 	args := m.Called(userID, email, name)
 	return args.String(0), args.Error(1)
@@ -66,6 +85,37 @@ func (m *MockAuthService) RevokeAllUserTokens(ctx context.Context, userID uint)
 	return args.Error(0)
 }
 
+func (m *MockAuthService) ListSessions(ctx context.Context, userID uint) ([]auth.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]auth.Session), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(ctx context.Context, userID uint, family uuid.UUID) error {
+	args := m.Called(ctx, userID, family)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) GenerateClientToken(clientID string, scopes []string, ttl time.Duration) (string, error) {
+	args := m.Called(clientID, scopes, ttl)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) GenerateImpersonationToken(ctx context.Context, actor auth.Actor, userID uint, email string, name string, ttl time.Duration) (string, error) {
+	args := m.Called(ctx, actor, userID, email, name, ttl)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) ValidateClientToken(tokenString string) (*auth.ClientClaims, error) {
+	args := m.Called(tokenString)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.ClientClaims), args.Error(1)
+}
+
 func TestHandler_Register(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -88,6 +138,7 @@ func TestHandler_Register(t *testing.T) {
 					Email: "john@example.com",
 				}
 				ms.On("RegisterUser", mock.Anything, mock.AnythingOfType("user.RegisterRequest")).Return(user, nil)
+				ms.On("CheckLoginAllowed", user).Return(nil)
 				tokenPair := &auth.TokenPair{
 					AccessToken:  "mock-access-token",
 					RefreshToken: "mock-refresh-token",
@@ -199,6 +250,7 @@ func TestHandler_Register(t *testing.T) {
 					Email: "john@example.com",
 				}
 				ms.On("RegisterUser", mock.Anything, mock.AnythingOfType("user.RegisterRequest")).Return(user, nil)
+				ms.On("CheckLoginAllowed", user).Return(nil)
 				mas.On("GenerateTokenPair", mock.Anything, uint(1), "john@example.com", "John Doe").Return(nil, errors.New("token generation failed"))
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -212,6 +264,34 @@ func TestHandler_Register(t *testing.T) {
 				assert.Equal(t, "token generation failed", errorInfo["details"])
 			},
 		},
+		{
+			name: "email verification required before login",
+			requestBody: RegisterRequest{
+				Name:     "John Doe",
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				user := &User{
+					ID:    1,
+					Name:  "John Doe",
+					Email: "john@example.com",
+				}
+				ms.On("RegisterUser", mock.Anything, mock.AnythingOfType("user.RegisterRequest")).Return(user, nil)
+				ms.On("CheckLoginAllowed", user).Return(ErrEmailNotVerified)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				data, ok := response["data"].(map[string]interface{})
+				assert.True(t, ok, "data should be a map")
+				assert.NotContains(t, data, "access_token", "no tokens before the email is verified")
+				assert.Contains(t, data, "message")
+				assert.Contains(t, data, "user")
+			},
+		},
 		{
 			name:        "empty request body",
 			requestBody: `{}`,
@@ -475,6 +555,7 @@ func TestHandler_Login(t *testing.T) {
 					Email: "john@example.com",
 				}
 				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(user, nil)
+				ms.On("StartMFAChallenge", mock.Anything, user).Return(nil, nil)
 				tokenPair := &auth.TokenPair{
 					AccessToken:  "mock-access-token",
 					RefreshToken: "mock-refresh-token",
@@ -500,6 +581,67 @@ func TestHandler_Login(t *testing.T) {
 				assert.Equal(t, "john@example.com", user["email"])
 			},
 		},
+		{
+			name: "second factor required",
+			requestBody: LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				user := &User{ID: 1, Name: "John Doe", Email: "john@example.com"}
+				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(user, nil)
+				ms.On("StartMFAChallenge", mock.Anything, user).Return(&MFAPendingLogin{Token: "mfa-token", ExpiresAt: time.Now().Add(5 * time.Minute)}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				data := response["data"].(map[string]interface{})
+				assert.Equal(t, true, data["mfa_required"])
+				assert.Equal(t, false, data["enrollment_required"])
+				assert.Equal(t, "mfa-token", data["mfa_token"])
+				assert.InDelta(t, 300, data["expires_in"], 2)
+				assert.NotContains(t, data, "access_token", "tokens are issued only after the second factor")
+			},
+		},
+		{
+			name: "account locked",
+			requestBody: LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(nil, &auth.LoginBlockedError{RetryAfter: 15 * time.Minute, Locked: true})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "900", w.Header().Get("Retry-After"))
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				errorInfo := response["error"].(map[string]interface{})
+				assert.Equal(t, apiErrors.CodeAccountLocked, errorInfo["code"])
+				assert.Equal(t, float64(900), errorInfo["retry_after"])
+			},
+		},
+		{
+			name: "retried during progressive delay",
+			requestBody: LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(nil, &auth.LoginBlockedError{RetryAfter: 1500 * time.Millisecond})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "2", w.Header().Get("Retry-After"))
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				errorInfo := response["error"].(map[string]interface{})
+				assert.Equal(t, apiErrors.CodeTooManyRequests, errorInfo["code"])
+			},
+		},
 		{
 			name: "invalid credentials",
 			requestBody: LoginRequest{
@@ -520,6 +662,25 @@ func TestHandler_Login(t *testing.T) {
 				assert.Equal(t, "Invalid email or password", errorInfo["message"])
 			},
 		},
+		{
+			name: "email not verified",
+			requestBody: LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(nil, ErrEmailNotVerified)
+			},
+			expectedStatus: http.StatusForbidden,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, "Email address has not been verified", errorInfo["message"])
+			},
+		},
 		{
 			name: "service error",
 			requestBody: LoginRequest{
@@ -553,6 +714,7 @@ func TestHandler_Login(t *testing.T) {
 					Email: "john@example.com",
 				}
 				ms.On("AuthenticateUser", mock.Anything, mock.AnythingOfType("user.LoginRequest")).Return(user, nil)
+				ms.On("StartMFAChallenge", mock.Anything, user).Return(nil, nil)
 				mas.On("GenerateTokenPair", mock.Anything, uint(1), "john@example.com", "John Doe").Return(nil, errors.New("failed to generate token"))
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -855,7 +1017,8 @@ func TestHandler_DeleteUser(t *testing.T) {
 			name:   "successful deletion",
 			userID: "1",
 			setupMocks: func(ms *MockService, mas *MockAuthService) {
-				ms.On("DeleteUser", mock.Anything, uint(1)).Return(nil)
+				ms.On("DeleteUser", mock.Anything, uint(1), uint(1)).Return(nil)
+				mas.On("RevokeAllUserTokens", mock.Anything, uint(1)).Return(nil)
 			},
 			setupContext: func(c *gin.Context) {
 				claims := &auth.Claims{UserID: 1}
@@ -905,7 +1068,7 @@ func TestHandler_DeleteUser(t *testing.T) {
 			name:   "user not found",
 			userID: "1",
 			setupMocks: func(ms *MockService, mas *MockAuthService) {
-				ms.On("DeleteUser", mock.Anything, uint(1)).Return(ErrUserNotFound)
+				ms.On("DeleteUser", mock.Anything, uint(1), uint(1)).Return(ErrUserNotFound)
 			},
 			setupContext: func(c *gin.Context) {
 				claims := &auth.Claims{UserID: 1}
@@ -922,11 +1085,51 @@ func TestHandler_DeleteUser(t *testing.T) {
 				assert.Equal(t, "User not found", errorInfo["message"])
 			},
 		},
+		{
+			name:   "admin deletes own account",
+			userID: "1",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("DeleteUser", mock.Anything, uint(1), uint(1)).Return(ErrSelfDeletion)
+			},
+			setupContext: func(c *gin.Context) {
+				claims := &auth.Claims{UserID: 1}
+				c.Set(auth.KeyUser, claims)
+			},
+			expectedStatus: http.StatusForbidden,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, "You cannot delete your own admin account", errorInfo["message"])
+			},
+		},
+		{
+			name:   "last admin",
+			userID: "1",
+			setupMocks: func(ms *MockService, mas *MockAuthService) {
+				ms.On("DeleteUser", mock.Anything, uint(1), uint(1)).Return(ErrLastAdmin)
+			},
+			setupContext: func(c *gin.Context) {
+				claims := &auth.Claims{UserID: 1}
+				c.Set(auth.KeyUser, claims)
+			},
+			expectedStatus: http.StatusConflict,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				errorInfo, ok := response["error"].(map[string]interface{})
+				assert.True(t, ok, "error should be a map")
+				assert.Equal(t, "Cannot delete the last admin", errorInfo["message"])
+			},
+		},
 		{
 			name:   "service error",
 			userID: "1",
 			setupMocks: func(ms *MockService, mas *MockAuthService) {
-				ms.On("DeleteUser", mock.Anything, uint(1)).Return(errors.New("failed to delete user"))
+				ms.On("DeleteUser", mock.Anything, uint(1), uint(1)).Return(errors.New("failed to delete user"))
 			},
 			setupContext: func(c *gin.Context) {
 				claims := &auth.Claims{UserID: 1}
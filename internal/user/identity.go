@@ -0,0 +1,256 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+)
+
+var (
+	// ErrIdentityNotFound is returned when the user has no identity linked for a provider
+	ErrIdentityNotFound = errors.New("linked identity not found")
+	// ErrIdentityLinkedElsewhere is returned when linking a provider account that belongs to another user
+	ErrIdentityLinkedElsewhere = errors.New("identity is linked to another account")
+	// ErrProviderAlreadyLinked is returned when the user already linked a different account of the same provider
+	ErrProviderAlreadyLinked = errors.New("another account of this provider is already linked")
+	// ErrIdentityEmailInUse is returned on first login when a local account already uses the email and may not be linked automatically
+	ErrIdentityEmailInUse = errors.New("an account with this email already exists")
+	// ErrIdentityEmailRequired is returned on first login when the provider did not share an email address
+	ErrIdentityEmailRequired = errors.New("provider did not return an email address")
+)
+
+// UserIdentity links an account at an OpenID Connect provider to a user
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:uq_user_identities_user_provider"`
+	Provider  string `gorm:"type:varchar(64);not null;uniqueIndex:uq_user_identities_provider_subject;uniqueIndex:uq_user_identities_user_provider"`
+	Subject   string `gorm:"type:varchar(255);not null;uniqueIndex:uq_user_identities_provider_subject"`
+	Email     string `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName specifies the table name for UserIdentity model
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// LoginWithIdentity signs in the user linked to a provider identity.
+// On the first login the identity is linked to the account with the same email when the provider allows it
+// and both sides verified the address; otherwise a new user is created without a usable password.
+func (s *service) LoginWithIdentity(ctx context.Context, identity *oidc.Identity) (*User, error) {
+	linked, err := s.repo.FindIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	if linked != nil {
+		user, err := s.findUser(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.TouchIdentity(ctx, linked.ID, identity.Email); err != nil {
+			slog.WarnContext(ctx, "Failed to update identity", "identity_id", linked.ID, "error", err)
+		}
+		if err := s.CheckLoginAllowed(user); err != nil {
+			return nil, err
+		}
+		s.recordIdentityLogin(ctx, user, identity)
+		return user, nil
+	}
+
+	if identity.Email == "" {
+		return nil, ErrIdentityEmailRequired
+	}
+
+	existing, err := s.repo.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing email: %w", err)
+	}
+	if existing != nil {
+		// WHY: 本地账号未验证邮箱时可能是他人抢注的，自动关联会让抢注者保留密码登录的能力
+		if !identity.LinkByEmail || !identity.EmailVerified || !existing.IsEmailVerified() {
+			return nil, ErrIdentityEmailInUse
+		}
+		if _, err := s.linkIdentity(ctx, existing, identity); err != nil {
+			return nil, err
+		}
+		if err := s.CheckLoginAllowed(existing); err != nil {
+			return nil, err
+		}
+		s.recordIdentityLogin(ctx, existing, identity)
+		return existing, nil
+	}
+
+	return s.createIdentityUser(ctx, identity)
+}
+
+// LinkIdentity links a provider identity to a signed-in user
+func (s *service) LinkIdentity(ctx context.Context, userID uint, identity *oidc.Identity) (*UserIdentity, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := s.repo.FindIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	if linked != nil {
+		if linked.UserID != user.ID {
+			return nil, ErrIdentityLinkedElsewhere
+		}
+		return linked, nil
+	}
+
+	return s.linkIdentity(ctx, user, identity)
+}
+
+// ListIdentities returns the provider identities linked to a user
+func (s *service) ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error) {
+	if _, err := s.findUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes the identity of a provider from a user
+func (s *service) UnlinkIdentity(ctx context.Context, userID uint, provider string) error {
+	if err := s.repo.DeleteIdentity(ctx, userID, provider); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrIdentityNotFound
+		}
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+
+	event := userEvent(audit.ActionIdentityUnlink, userID)
+	event.Metadata = map[string]interface{}{"provider": provider}
+	s.recordAudit(ctx, event)
+	return nil
+}
+
+// linkIdentity stores a new identity for user, allowing one account per provider
+func (s *service) linkIdentity(ctx context.Context, user *User, identity *oidc.Identity) (*UserIdentity, error) {
+	identities, err := s.repo.ListIdentities(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, existing := range identities {
+		if existing.Provider == identity.Provider {
+			return nil, ErrProviderAlreadyLinked
+		}
+	}
+
+	linked := &UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := s.repo.CreateIdentity(ctx, linked); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	// WHY: 回调请求未经认证，操作者是被关联的用户本人
+	event := userEvent(audit.ActionIdentityLink, user.ID)
+	event.ActorID = user.ID
+	event.Metadata = map[string]interface{}{"provider": identity.Provider, "subject": identity.Subject}
+	s.recordAudit(ctx, event)
+	return linked, nil
+}
+
+// recordIdentityLogin records a successful login through a provider identity
+func (s *service) recordIdentityLogin(ctx context.Context, user *User, identity *oidc.Identity) {
+	event := userEvent(audit.ActionLogin, user.ID)
+	event.ActorID = user.ID
+	event.Metadata = map[string]interface{}{"method": "oidc", "provider": identity.Provider}
+	s.recordAudit(ctx, event)
+}
+
+// createIdentityUser registers a user for a provider identity seen for the first time.
+// The password is random and never disclosed; the user can set one through password reset.
+func (s *service) createIdentityUser(ctx context.Context, identity *oidc.Identity) (*User, error) {
+	password, err := generateOneTimeToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	user := &User{
+		Name:         name,
+		Email:        identity.Email,
+		PasswordHash: hashedPassword,
+	}
+	if identity.EmailVerified {
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.Create(txCtx, user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		if err := s.repo.CreateIdentity(txCtx, &UserIdentity{
+			UserID:   user.ID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}); err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+
+		// restrict_roles 策略下默认角色在邮箱验证后授予
+		if s.emailVerification.GetPolicy() == config.EmailVerificationPolicyRestrictRoles && !identity.EmailVerified {
+			return nil
+		}
+		if err := s.repo.AssignRole(txCtx, user.ID, RoleUser); err != nil {
+			return fmt.Errorf("failed to assign default role: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = s.findUser(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reload user: %w", err)
+	}
+
+	event := userEvent(audit.ActionUserCreate, user.ID)
+	event.Changes = audit.Diff(nil, auditUserFields(user))
+	event.Metadata = map[string]interface{}{"provider": identity.Provider, "subject": identity.Subject}
+	s.recordAudit(ctx, event)
+
+	if s.emailVerification.Enabled && !user.IsEmailVerified() {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			slog.WarnContext(ctx, "Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	if err := s.CheckLoginAllowed(user); err != nil {
+		return nil, err
+	}
+	s.recordIdentityLogin(ctx, user, identity)
+	return user, nil
+}
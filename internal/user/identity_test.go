@@ -0,0 +1,159 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+)
+
+func setupIdentityTest(t *testing.T, verificationCfg config.EmailVerificationConfig) (Service, Repository, *recordingSender) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&UserIdentity{}, &EmailVerificationToken{}))
+
+	repo := NewRepository(db)
+	sender := &recordingSender{}
+	svc := NewService(repo, WithMailer(sender), WithEmailVerification(&verificationCfg), WithPasswordHasher(NewPasswordHasher(testBcryptConfig)))
+	return svc, repo, sender
+}
+
+func TestService_LoginWithIdentity_FirstLogin(t *testing.T) {
+	svc, _, _ := setupIdentityTest(t, config.EmailVerificationConfig{})
+	ctx := context.Background()
+	identity := &oidc.Identity{Provider: "google", Subject: "sub-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"}
+
+	user, err := svc.LoginWithIdentity(ctx, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", user.Name)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.True(t, user.IsEmailVerified(), "the provider verified the email")
+	assert.True(t, user.HasRole(RoleUser))
+	assert.NotEmpty(t, user.PasswordHash)
+
+	again, err := svc.LoginWithIdentity(ctx, identity)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, again.ID)
+
+	identities, err := svc.ListIdentities(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Equal(t, "google", identities[0].Provider)
+	assert.Equal(t, "sub-1", identities[0].Subject)
+
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "jane@example.com", Password: ""})
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "users created through a provider have no known password")
+
+	_, err = svc.LoginWithIdentity(ctx, &oidc.Identity{Provider: "google", Subject: "sub-2"})
+	assert.ErrorIs(t, err, ErrIdentityEmailRequired)
+
+	nameless, err := svc.LoginWithIdentity(ctx, &oidc.Identity{Provider: "google", Subject: "sub-3", Email: "john@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "john", nameless.Name)
+	assert.False(t, nameless.IsEmailVerified())
+}
+
+func TestService_LoginWithIdentity_ExistingEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		linkByEmail   bool
+		emailVerified bool
+		localVerified bool
+		wantLinked    bool
+	}{
+		{name: "linking disabled", linkByEmail: false, emailVerified: true, localVerified: true},
+		{name: "email not verified by provider", linkByEmail: true, emailVerified: false, localVerified: true},
+		{name: "local account not verified", linkByEmail: true, emailVerified: true, localVerified: false},
+		{name: "linked by verified email", linkByEmail: true, emailVerified: true, localVerified: true, wantLinked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, _ := setupIdentityTest(t, config.EmailVerificationConfig{})
+			ctx := context.Background()
+
+			local, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Jane", Email: "jane@example.com", Password: "password123"})
+			require.NoError(t, err)
+			if tt.localVerified {
+				require.NoError(t, svc.MarkEmailVerified(ctx, local.ID))
+			}
+
+			user, err := svc.LoginWithIdentity(ctx, &oidc.Identity{
+				Provider:      "google",
+				Subject:       "sub-1",
+				Email:         "jane@example.com",
+				EmailVerified: tt.emailVerified,
+				LinkByEmail:   tt.linkByEmail,
+			})
+			if !tt.wantLinked {
+				assert.ErrorIs(t, err, ErrIdentityEmailInUse)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, local.ID, user.ID)
+
+			identities, err := svc.ListIdentities(ctx, local.ID)
+			require.NoError(t, err)
+			assert.Len(t, identities, 1)
+		})
+	}
+}
+
+func TestService_LoginWithIdentity_EmailVerification(t *testing.T) {
+	svc, _, sender := setupIdentityTest(t, config.EmailVerificationConfig{Enabled: true, Policy: config.EmailVerificationPolicyRestrictRoles})
+	ctx := context.Background()
+
+	unverified, err := svc.LoginWithIdentity(ctx, &oidc.Identity{Provider: "github", Subject: "1", Email: "jane@example.com"})
+	require.NoError(t, err)
+	assert.False(t, unverified.HasRole(RoleUser), "restrict_roles withholds the default role until the email is verified")
+	assert.Len(t, sender.messages, 1)
+
+	verified, err := svc.LoginWithIdentity(ctx, &oidc.Identity{Provider: "github", Subject: "2", Email: "john@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	assert.True(t, verified.HasRole(RoleUser))
+	assert.Len(t, sender.messages, 1, "no verification email for addresses the provider verified")
+}
+
+func TestService_LinkAndUnlinkIdentity(t *testing.T) {
+	svc, _, _ := setupIdentityTest(t, config.EmailVerificationConfig{})
+	ctx := context.Background()
+
+	jane, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Jane", Email: "jane@example.com", Password: "password123"})
+	require.NoError(t, err)
+	john, err := svc.RegisterUser(ctx, RegisterRequest{Name: "John", Email: "john@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	identity := &oidc.Identity{Provider: "google", Subject: "sub-1", Email: "jane.doe@gmail.com"}
+	linked, err := svc.LinkIdentity(ctx, jane.ID, identity)
+	require.NoError(t, err)
+	assert.Equal(t, jane.ID, linked.UserID)
+
+	again, err := svc.LinkIdentity(ctx, jane.ID, identity)
+	require.NoError(t, err, "linking the same identity again is a no-op")
+	assert.Equal(t, linked.ID, again.ID)
+
+	_, err = svc.LinkIdentity(ctx, john.ID, identity)
+	assert.ErrorIs(t, err, ErrIdentityLinkedElsewhere)
+
+	_, err = svc.LinkIdentity(ctx, jane.ID, &oidc.Identity{Provider: "google", Subject: "sub-2"})
+	assert.ErrorIs(t, err, ErrProviderAlreadyLinked)
+
+	user, err := svc.LoginWithIdentity(ctx, identity)
+	require.NoError(t, err)
+	assert.Equal(t, jane.ID, user.ID, "a linked identity signs in to the linked account whatever its email")
+
+	_, err = svc.LinkIdentity(ctx, 999, identity)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, svc.UnlinkIdentity(ctx, jane.ID, "google"))
+	assert.ErrorIs(t, svc.UnlinkIdentity(ctx, jane.ID, "google"), ErrIdentityNotFound)
+
+	identities, err := svc.ListIdentities(ctx, jane.ID)
+	require.NoError(t, err)
+	assert.Empty(t, identities)
+}
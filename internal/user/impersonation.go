@@ -0,0 +1,44 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSelfImpersonation is returned when an admin tries to impersonate themselves
+	ErrSelfImpersonation = errors.New("cannot impersonate yourself")
+	// ErrImpersonateAdmin is returned when the impersonation target is an admin
+	ErrImpersonateAdmin = errors.New("cannot impersonate an admin")
+)
+
+// Impersonation describes an admin session acting as another user
+type Impersonation struct {
+	Actor    *User
+	User     *User
+	TokenTTL time.Duration
+}
+
+// StartImpersonation checks that actorID may act as userID and returns both users
+// Admins cannot be impersonated, so an impersonated session never holds admin privileges
+func (s *service) StartImpersonation(ctx context.Context, actorID, userID uint) (*Impersonation, error) {
+	if actorID == userID {
+		return nil, ErrSelfImpersonation
+	}
+
+	actor, err := s.GetUserByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsAdmin() {
+		return nil, ErrImpersonateAdmin
+	}
+
+	return &Impersonation{Actor: actor, User: user, TokenTTL: s.impersonationTTL}, nil
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestService_StartImpersonation(t *testing.T) {
+	repo := NewRepository(setupTestDB(t))
+	svc := NewService(repo, WithImpersonation(&config.ImpersonationConfig{TokenTTL: 5 * time.Minute}))
+	ctx := context.Background()
+
+	admin := &User{Name: "Ada", Email: "ada@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, admin))
+	require.NoError(t, repo.AssignRole(ctx, admin.ID, RoleAdmin))
+	otherAdmin := &User{Name: "Grace", Email: "grace@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, otherAdmin))
+	require.NoError(t, repo.AssignRole(ctx, otherAdmin.ID, RoleAdmin))
+	member := &User{Name: "Max", Email: "max@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, member))
+
+	impersonation, err := svc.StartImpersonation(ctx, admin.ID, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, impersonation.Actor.ID)
+	assert.Equal(t, member.ID, impersonation.User.ID)
+	assert.Equal(t, 5*time.Minute, impersonation.TokenTTL)
+
+	_, err = svc.StartImpersonation(ctx, admin.ID, admin.ID)
+	assert.ErrorIs(t, err, ErrSelfImpersonation)
+
+	_, err = svc.StartImpersonation(ctx, admin.ID, otherAdmin.ID)
+	assert.ErrorIs(t, err, ErrImpersonateAdmin)
+
+	_, err = svc.StartImpersonation(ctx, admin.ID, 999)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,553 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/totp"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued at a time
+	recoveryCodeCount = 10
+	// recoveryCodeBytes gives 80 bits per code, formatted as four groups of four base32 characters
+	recoveryCodeBytes = 10
+	// totpSkew accepts codes from one step before and after the current one to tolerate clock drift
+	totpSkew = 1
+)
+
+var (
+	// ErrMFAAlreadyEnabled is returned when enrolling an account that already has two-factor authentication
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnabled is returned when an operation needs two-factor authentication but it is not enabled
+	ErrMFANotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrMFANotEnrolling is returned when confirming an enrollment that was never started
+	ErrMFANotEnrolling = errors.New("no two-factor authentication enrollment in progress")
+	// ErrInvalidMFACode is returned when a TOTP or recovery code is wrong, expired or already used
+	ErrInvalidMFACode = errors.New("invalid two-factor authentication code")
+	// ErrInvalidMFAChallenge is returned when an mfa pending token is unknown, expired or used up
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa token")
+	// ErrMFARequired is returned when disabling two-factor authentication that the admin policy requires
+	ErrMFARequired = errors.New("two-factor authentication is required for this account")
+	// ErrMFAEnrollmentRequired is returned when a login must enroll two-factor authentication before completing
+	ErrMFAEnrollmentRequired = errors.New("two-factor authentication must be enrolled to complete login")
+)
+
+// UserTOTP is the TOTP authenticator of a user; it is pending until confirmed with a valid code
+type UserTOTP struct {
+	UserID       uint   `gorm:"primaryKey;autoIncrement:false"`
+	Secret       string `gorm:"type:varchar(64);not null"`
+	ConfirmedAt  *time.Time
+	LastUsedStep int64 `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TableName specifies the table name for UserTOTP model
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+// IsConfirmed reports whether enrollment was confirmed, i.e. two-factor authentication is enabled
+func (t *UserTOTP) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// MFARecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost
+type MFARecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:varchar(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TableName specifies the table name for MFARecoveryCode model
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// MFAChallenge is a login that passed the password check and waits for a second factor
+type MFAChallenge struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"not null;index"`
+	TokenHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Enrollment bool      `gorm:"not null;default:false"`
+	Attempts   int       `gorm:"not null;default:0"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	CreatedAt  time.Time
+}
+
+// TableName specifies the table name for MFAChallenge model
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// TOTPEnrollment is the secret of a pending enrollment, shown once so it can be added to an authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAPendingLogin is returned instead of tokens when a login needs a second factor
+type MFAPendingLogin struct {
+	Token     string
+	ExpiresAt time.Time
+	// EnrollmentRequired is true when the admin policy requires enrolling TOTP before the login completes
+	EnrollmentRequired bool
+}
+
+// MFAStatus describes the two-factor authentication state of a user
+type MFAStatus struct {
+	Enabled                bool
+	Required               bool
+	RecoveryCodesRemaining int64
+}
+
+// GetMFAStatus returns whether two-factor authentication is enabled and required for a user
+func (s *service) GetMFAStatus(ctx context.Context, userID uint) (*MFAStatus, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := s.repo.FindUserTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find authenticator: %w", err)
+	}
+
+	status := &MFAStatus{Required: s.mfaRequired(user)}
+	if authenticator != nil && authenticator.IsConfirmed() {
+		status.Enabled = true
+		status.RecoveryCodesRemaining, err = s.repo.CountUnusedRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for a user, replacing any unconfirmed one
+func (s *service) BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginTOTPEnrollment(ctx, user)
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves the secret works,
+// and returns freshly generated recovery codes; only their hashes are stored
+func (s *service) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.confirmTOTPEnrollment(ctx, user, code)
+}
+
+// DisableTOTP turns off two-factor authentication after checking a TOTP or recovery code
+// Returns *auth.LoginBlockedError while the user's second-factor checks are locked after wrong codes
+func (s *service) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.mfaRequired(user) {
+		return ErrMFARequired
+	}
+	if err := s.verifySessionSecondFactor(ctx, user, code); err != nil {
+		return err
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeleteRecoveryCodes(txCtx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := s.repo.DeleteUserTOTP(txCtx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete authenticator: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, userEvent(audit.ActionMFADisable, user.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user after checking a TOTP or recovery code
+// Returns *auth.LoginBlockedError while the user's second-factor checks are locked after wrong codes
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySessionSecondFactor(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		codes, err = s.replaceRecoveryCodes(txCtx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// StartMFAChallenge creates an mfa pending token for a user who passed the password check.
+// It returns nil when the user has no second factor and the admin policy does not require one.
+func (s *service) StartMFAChallenge(ctx context.Context, user *User) (*MFAPendingLogin, error) {
+	authenticator, err := s.repo.FindUserTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find authenticator: %w", err)
+	}
+
+	enrollment := false
+	switch {
+	case authenticator != nil && authenticator.IsConfirmed():
+	case s.mfaRequired(user):
+		enrollment = true
+	default:
+		return nil, nil
+	}
+
+	token, err := generateOneTimeToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	expiresAt := time.Now().Add(s.mfa.GetChallengeTTL())
+
+	// 新的登录作废该用户此前未完成的验证，避免过期记录堆积
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeleteMFAChallenges(txCtx, user.ID); err != nil {
+			return err
+		}
+		return s.repo.CreateMFAChallenge(txCtx, &MFAChallenge{
+			UserID:     user.ID,
+			TokenHash:  auth.HashToken(token),
+			Enrollment: enrollment,
+			ExpiresAt:  expiresAt,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+
+	return &MFAPendingLogin{Token: token, ExpiresAt: expiresAt, EnrollmentRequired: enrollment}, nil
+}
+
+// VerifyMFAChallenge completes a pending login with a TOTP or recovery code and returns the user to issue tokens for
+func (s *service) VerifyMFAChallenge(ctx context.Context, token, code string) (*User, error) {
+	challenge, user, err := s.loadMFAChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Enrollment {
+		return nil, ErrMFAEnrollmentRequired
+	}
+	// WHY: 每次密码登录都会签发新的待验证令牌，只按令牌计数时知道密码就能无限猜测验证码
+	if err := s.loginGuard.CheckSecondFactor(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return nil, s.mfaChallengeFailed(ctx, challenge)
+		}
+		return nil, err
+	}
+
+	if err := s.loginGuard.RecordSecondFactorSuccess(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// BeginChallengeEnrollment starts TOTP enrollment for a login that the admin policy holds until enrollment
+func (s *service) BeginChallengeEnrollment(ctx context.Context, token string) (*TOTPEnrollment, error) {
+	challenge, user, err := s.loadMFAChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.Enrollment {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	return s.beginTOTPEnrollment(ctx, user)
+}
+
+// CompleteChallengeEnrollment confirms the enrollment started with BeginChallengeEnrollment and completes the login.
+// It returns the user to issue tokens for and the new recovery codes.
+func (s *service) CompleteChallengeEnrollment(ctx context.Context, token, code string) (*User, []string, error) {
+	challenge, user, err := s.loadMFAChallenge(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !challenge.Enrollment {
+		return nil, nil, ErrMFAAlreadyEnabled
+	}
+	if err := s.loginGuard.CheckSecondFactor(ctx, user.ID); err != nil {
+		return nil, nil, err
+	}
+
+	codes, err := s.confirmTOTPEnrollment(ctx, user, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return nil, nil, s.mfaChallengeFailed(ctx, challenge)
+		}
+		return nil, nil, err
+	}
+
+	if err := s.loginGuard.RecordSecondFactorSuccess(ctx, user.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := s.consumeMFAChallenge(ctx, challenge); err != nil {
+		return nil, nil, err
+	}
+	return user, codes, nil
+}
+
+// mfaRequired reports whether the admin policy requires two-factor authentication for a user
+func (s *service) mfaRequired(user *User) bool {
+	return s.mfa.RequireForAdmins && user.IsAdmin()
+}
+
+func (s *service) findUser(ctx context.Context, id uint) (*User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *service) beginTOTPEnrollment(ctx context.Context, user *User) (*TOTPEnrollment, error) {
+	authenticator, err := s.repo.FindUserTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find authenticator: %w", err)
+	}
+	if authenticator != nil && authenticator.IsConfirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeleteUserTOTP(txCtx, user.ID); err != nil {
+			return err
+		}
+		return s.repo.CreateUserTOTP(txCtx, &UserTOTP{UserID: user.ID, Secret: secret})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store authenticator: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.KeyURI(s.mfa.GetIssuer(), user.Email, secret, totp.Options{}),
+	}, nil
+}
+
+func (s *service) confirmTOTPEnrollment(ctx context.Context, user *User, code string) ([]string, error) {
+	authenticator, err := s.repo.FindUserTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find authenticator: %w", err)
+	}
+	if authenticator == nil {
+		return nil, ErrMFANotEnrolling
+	}
+	if authenticator.IsConfirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := checkTOTP(authenticator, code)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.ConfirmUserTOTP(txCtx, user.ID, time.Now(), step); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrMFAAlreadyEnabled
+			}
+			return fmt.Errorf("failed to confirm authenticator: %w", err)
+		}
+		codes, err = s.replaceRecoveryCodes(txCtx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, userEvent(audit.ActionMFAEnable, user.ID))
+	return codes, nil
+}
+
+// verifySessionSecondFactor checks a code submitted from a signed-in session
+// Returns *auth.LoginBlockedError after too many wrong codes for the user
+// WHY: 登录挑战按待验证令牌限制次数，会话内的操作没有挑战令牌，需按用户限制，否则被盗的会话可以穷举验证码
+func (s *service) verifySessionSecondFactor(ctx context.Context, user *User, code string) error {
+	if err := s.loginGuard.CheckSecondFactor(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.loginGuard.RecordSecondFactorFailure(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	return s.loginGuard.RecordSecondFactorSuccess(ctx, user.ID)
+}
+
+// verifySecondFactor accepts a TOTP code for a step after the last used one, or an unused recovery code
+func (s *service) verifySecondFactor(ctx context.Context, user *User, code string) error {
+	authenticator, err := s.repo.FindUserTOTP(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find authenticator: %w", err)
+	}
+	if authenticator == nil || !authenticator.IsConfirmed() {
+		return ErrMFANotEnabled
+	}
+
+	if step, ok := checkTOTP(authenticator, code); ok {
+		// WHY: 条件更新保证同一时间步的验证码只能使用一次，并发重放时只有一个请求成功
+		if err := s.repo.UpdateTOTPLastUsedStep(ctx, user.ID, step); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidMFACode
+			}
+			return fmt.Errorf("failed to record used code: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return nil
+}
+
+// loadMFAChallenge finds a pending login by token and its user
+func (s *service) loadMFAChallenge(ctx context.Context, token string) (*MFAChallenge, *User, error) {
+	challenge, err := s.repo.FindMFAChallenge(ctx, auth.HashToken(token))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find mfa challenge: %w", err)
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= s.mfa.GetMaxAttempts() {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.repo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	return challenge, user, nil
+}
+
+// mfaChallengeFailed counts a wrong code; the challenge stops working after max_attempts
+func (s *service) mfaChallengeFailed(ctx context.Context, challenge *MFAChallenge) error {
+	if err := s.repo.IncrementMFAChallengeAttempts(ctx, challenge.ID); err != nil {
+		return fmt.Errorf("failed to record mfa attempt: %w", err)
+	}
+	// 同时按用户计数，失败次数跨待验证令牌累计
+	if err := s.loginGuard.RecordSecondFactorFailure(ctx, challenge.UserID); err != nil {
+		return err
+	}
+	return ErrInvalidMFACode
+}
+
+// consumeMFAChallenge deletes a challenge so its token completes at most one login
+func (s *service) consumeMFAChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	if err := s.repo.DeleteMFAChallenge(ctx, challenge.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidMFAChallenge
+		}
+		return fmt.Errorf("failed to delete mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores the hashes of new ones
+func (s *service) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]MFARecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = MFARecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}
+	}
+
+	if err := s.repo.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if err := s.repo.CreateRecoveryCodes(ctx, records); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// checkTOTP validates a code against an authenticator and rejects steps that were already used
+func checkTOTP(authenticator *UserTOTP, code string) (int64, bool) {
+	key, err := totp.DecodeSecret(authenticator.Secret)
+	if err != nil {
+		return 0, false
+	}
+	step, ok := totp.Validate(key, code, time.Now(), totpSkew, totp.Options{})
+	if !ok || int64(step) <= authenticator.LastUsedStep {
+		return 0, false
+	}
+	return int64(step), true
+}
+
+// generateRecoveryCodes returns random codes formatted as xxxx-xxxx-xxxx-xxxx
+func generateRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code ignoring case, spaces and dashes as typed by users
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return auth.HashToken(normalized)
+}
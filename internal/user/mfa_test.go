@@ -0,0 +1,312 @@
+package user
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/totp"
+)
+
+func setupMFATest(t *testing.T, mfaCfg config.MFAConfig) (Service, Repository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&UserTOTP{}, &MFARecoveryCode{}, &MFAChallenge{}))
+
+	repo := NewRepository(db)
+	svc := NewService(repo, WithMailer(&recordingSender{}), WithPasswordHasher(NewPasswordHasher(testBcryptConfig)), WithMFA(&mfaCfg))
+	return svc, repo
+}
+
+func registerMFATestUser(t *testing.T, svc Service, repo Repository, email string, admin bool) *User {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Test User", Email: email, Password: "password123"})
+	require.NoError(t, err)
+	if admin {
+		require.NoError(t, repo.AssignRole(ctx, user.ID, RoleAdmin))
+		user, err = repo.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+	}
+	return user
+}
+
+// totpCode returns the code an authenticator app would show at the given time
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := totp.DecodeSecret(secret)
+	require.NoError(t, err)
+	code, err := totp.Code(key, at, totp.Options{})
+	require.NoError(t, err)
+	return code
+}
+
+func TestService_TOTPEnrollmentAndLogin(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{Issuer: "Example"})
+	ctx := context.Background()
+	user := registerMFATestUser(t, svc, repo, "jane@example.com", false)
+
+	pending, err := svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	assert.Nil(t, pending, "users without 2FA log in with their password alone")
+
+	_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, "123456")
+	assert.ErrorIs(t, err, ErrMFANotEnrolling)
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	uri, err := url.Parse(enrollment.URI)
+	require.NoError(t, err)
+	assert.Equal(t, "/Example:jane@example.com", uri.Path)
+	assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+
+	pending, err = svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	assert.Nil(t, pending, "unconfirmed enrollments do not affect login")
+
+	_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	now := time.Now()
+	recoveryCodes, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(t, enrollment.Secret, now))
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, recoveryCodes[0])
+
+	_, err = svc.BeginTOTPEnrollment(ctx, user.ID)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	status, err := svc.GetMFAStatus(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &MFAStatus{Enabled: true, RecoveryCodesRemaining: recoveryCodeCount}, status)
+
+	pending, err = svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.False(t, pending.EnrollmentRequired)
+
+	_, err = svc.VerifyMFAChallenge(ctx, pending.Token, totpCode(t, enrollment.Secret, now))
+	assert.ErrorIs(t, err, ErrInvalidMFACode, "the code used for confirmation cannot be replayed")
+
+	verified, err := svc.VerifyMFAChallenge(ctx, pending.Token, totpCode(t, enrollment.Secret, now.Add(totp.DefaultPeriod)))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, verified.ID)
+
+	_, err = svc.VerifyMFAChallenge(ctx, pending.Token, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge, "mfa tokens complete a single login")
+
+	pending, err = svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	verified, err = svc.VerifyMFAChallenge(ctx, pending.Token, strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", " ")))
+	require.NoError(t, err, "recovery codes work in place of a TOTP code, ignoring case and separators")
+	assert.Equal(t, user.ID, verified.ID)
+
+	stored, err := repo.FindMFAChallenge(ctx, auth.HashToken(pending.Token))
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	pending, err = svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	_, err = svc.VerifyMFAChallenge(ctx, pending.Token, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidMFACode, "recovery codes are single-use")
+
+	status, err = svc.GetMFAStatus(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(recoveryCodeCount-1), status.RecoveryCodesRemaining)
+}
+
+func TestService_MFAChallenge_Limits(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{MaxAttempts: 2})
+	ctx := context.Background()
+	user := registerMFATestUser(t, svc, repo, "jane@example.com", false)
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(t, enrollment.Secret, now))
+	require.NoError(t, err)
+	validCode := totpCode(t, enrollment.Secret, now.Add(totp.DefaultPeriod))
+
+	t.Run("too many wrong codes", func(t *testing.T) {
+		pending, err := svc.StartMFAChallenge(ctx, user)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = svc.VerifyMFAChallenge(ctx, pending.Token, "000000")
+			assert.ErrorIs(t, err, ErrInvalidMFACode)
+		}
+		_, err = svc.VerifyMFAChallenge(ctx, pending.Token, validCode)
+		assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		pending, err := svc.StartMFAChallenge(ctx, user)
+		require.NoError(t, err)
+
+		stored, err := repo.FindMFAChallenge(ctx, auth.HashToken(pending.Token))
+		require.NoError(t, err)
+		require.NoError(t, repo.DeleteMFAChallenge(ctx, stored.ID))
+		stored.ID = 0
+		stored.ExpiresAt = time.Now().Add(-time.Second)
+		require.NoError(t, repo.CreateMFAChallenge(ctx, stored))
+
+		_, err = svc.VerifyMFAChallenge(ctx, pending.Token, validCode)
+		assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+	})
+
+	t.Run("new login replaces pending token", func(t *testing.T) {
+		first, err := svc.StartMFAChallenge(ctx, user)
+		require.NoError(t, err)
+		_, err = svc.StartMFAChallenge(ctx, user)
+		require.NoError(t, err)
+
+		_, err = svc.VerifyMFAChallenge(ctx, first.Token, validCode)
+		assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+	})
+
+	_, err = svc.VerifyMFAChallenge(ctx, "unknown-token", validCode)
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+}
+
+func TestService_DisableTOTPAndRegenerateRecoveryCodes(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{})
+	ctx := context.Background()
+	user := registerMFATestUser(t, svc, repo, "jane@example.com", false)
+
+	assert.ErrorIs(t, svc.DisableTOTP(ctx, user.ID, "123456"), ErrMFANotEnabled)
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	oldCodes, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(t, enrollment.Secret, time.Now()))
+	require.NoError(t, err)
+
+	_, err = svc.RegenerateRecoveryCodes(ctx, user.ID, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	newCodes, err := svc.RegenerateRecoveryCodes(ctx, user.ID, oldCodes[0])
+	require.NoError(t, err)
+	require.Len(t, newCodes, recoveryCodeCount)
+	assert.ErrorIs(t, svc.DisableTOTP(ctx, user.ID, oldCodes[1]), ErrInvalidMFACode, "old recovery codes are replaced")
+
+	require.NoError(t, svc.DisableTOTP(ctx, user.ID, newCodes[0]))
+	status, err := svc.GetMFAStatus(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &MFAStatus{}, status)
+
+	pending, err := svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	assert.Nil(t, pending)
+}
+
+func TestService_SessionSecondFactorLockout(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{})
+	ctx := context.Background()
+	user := registerMFATestUser(t, svc, repo, "jane@example.com", false)
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	codes, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(t, enrollment.Secret, time.Now()))
+	require.NoError(t, err)
+
+	// 交替调用两个端点，失败次数按用户累计
+	for i := 0; i < config.DefaultLoginMaxAttempts; i++ {
+		if i%2 == 0 {
+			assert.ErrorIs(t, svc.DisableTOTP(ctx, user.ID, "000000"), ErrInvalidMFACode)
+		} else {
+			_, err := svc.RegenerateRecoveryCodes(ctx, user.ID, "000000")
+			assert.ErrorIs(t, err, ErrInvalidMFACode)
+		}
+	}
+
+	var blocked *auth.LoginBlockedError
+	assert.ErrorAs(t, svc.DisableTOTP(ctx, user.ID, codes[0]), &blocked, "valid codes are rejected while locked")
+	_, err = svc.RegenerateRecoveryCodes(ctx, user.ID, codes[0])
+	assert.ErrorAs(t, err, &blocked)
+
+	status, err := svc.GetMFAStatus(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+}
+
+func TestService_MFARequiredForAdmins(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{RequireForAdmins: true})
+	ctx := context.Background()
+	admin := registerMFATestUser(t, svc, repo, "admin@example.com", true)
+	member := registerMFATestUser(t, svc, repo, "member@example.com", false)
+
+	pending, err := svc.StartMFAChallenge(ctx, member)
+	require.NoError(t, err)
+	assert.Nil(t, pending, "the policy only applies to admins")
+
+	pending, err = svc.StartMFAChallenge(ctx, admin)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.True(t, pending.EnrollmentRequired)
+
+	_, err = svc.VerifyMFAChallenge(ctx, pending.Token, "123456")
+	assert.ErrorIs(t, err, ErrMFAEnrollmentRequired)
+
+	enrollment, err := svc.BeginChallengeEnrollment(ctx, pending.Token)
+	require.NoError(t, err)
+
+	_, _, err = svc.CompleteChallengeEnrollment(ctx, pending.Token, "000000")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	user, codes, err := svc.CompleteChallengeEnrollment(ctx, pending.Token, totpCode(t, enrollment.Secret, time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, user.ID)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	_, err = svc.BeginChallengeEnrollment(ctx, pending.Token)
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge, "the token is consumed once the login completes")
+
+	status, err := svc.GetMFAStatus(ctx, admin.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.True(t, status.Required)
+
+	assert.ErrorIs(t, svc.DisableTOTP(ctx, admin.ID, codes[0]), ErrMFARequired)
+
+	pending, err = svc.StartMFAChallenge(ctx, admin)
+	require.NoError(t, err)
+	assert.False(t, pending.EnrollmentRequired, "enrolled admins verify a code like everyone else")
+	_, err = svc.BeginChallengeEnrollment(ctx, pending.Token)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+}
+
+func TestService_MFAChallengeLockoutAcrossLogins(t *testing.T) {
+	svc, repo := setupMFATest(t, config.MFAConfig{})
+	ctx := context.Background()
+	user := registerMFATestUser(t, svc, repo, "jane@example.com", false)
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, totpCode(t, enrollment.Secret, time.Now()))
+	require.NoError(t, err)
+
+	// 每次密码登录获得新的待验证令牌，每个令牌只猜一次也会累计到用户的失败次数
+	for i := 0; i < config.DefaultLoginMaxAttempts; i++ {
+		pending, err := svc.StartMFAChallenge(ctx, user)
+		require.NoError(t, err)
+		_, err = svc.VerifyMFAChallenge(ctx, pending.Token, "000000")
+		assert.ErrorIs(t, err, ErrInvalidMFACode)
+	}
+
+	pending, err := svc.StartMFAChallenge(ctx, user)
+	require.NoError(t, err)
+	var blocked *auth.LoginBlockedError
+	_, err = svc.VerifyMFAChallenge(ctx, pending.Token, totpCode(t, enrollment.Secret, time.Now().Add(30*time.Second)))
+	require.ErrorAs(t, err, &blocked, "valid codes are rejected while locked")
+	assert.True(t, blocked.Locked)
+
+	assert.ErrorAs(t, svc.DisableTOTP(ctx, user.ID, "000000"), &blocked, "the lock is shared with signed-in sessions")
+}
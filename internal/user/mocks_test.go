@@ -2,8 +2,12 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
 )
 
 // MockService is a mock implementation of the user service for testing handlers
@@ -43,8 +47,16 @@ func (m *MockService) UpdateUser(ctx context.Context, id uint, req UpdateUserReq
 	return args.Get(0).(*User), args.Error(1)
 }
 
-func (m *MockService) DeleteUser(ctx context.Context, id uint) error {
-	args := m.Called(ctx, id)
+func (m *MockService) ChangePassword(ctx context.Context, id uint, currentPassword, newPassword string) (*User, error) {
+	args := m.Called(ctx, id, currentPassword, newPassword)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockService) DeleteUser(ctx context.Context, actorID, id uint) error {
+	args := m.Called(ctx, actorID, id)
 	return args.Error(0)
 }
 
@@ -61,6 +73,261 @@ func (m *MockService) PromoteToAdmin(ctx context.Context, userID uint) error {
 	return args.Error(0)
 }
 
+func (m *MockService) UnlockUser(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockService) ConfirmPasswordReset(ctx context.Context, token, newPassword string) (uint, error) {
+	args := m.Called(ctx, token, newPassword)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *MockService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockService) ResendVerificationEmail(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockService) MarkEmailVerified(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockService) CheckLoginAllowed(user *User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockService) GetMFAStatus(ctx context.Context, userID uint) (*MFAStatus, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAStatus), args.Error(1)
+}
+
+func (m *MockService) BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) StartMFAChallenge(ctx context.Context, user *User) (*MFAPendingLogin, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAPendingLogin), args.Error(1)
+}
+
+func (m *MockService) VerifyMFAChallenge(ctx context.Context, token, code string) (*User, error) {
+	args := m.Called(ctx, token, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockService) BeginChallengeEnrollment(ctx context.Context, token string) (*TOTPEnrollment, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) CompleteChallengeEnrollment(ctx context.Context, token, code string) (*User, []string, error) {
+	args := m.Called(ctx, token, code)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*User), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockService) LoginWithIdentity(ctx context.Context, identity *oidc.Identity) (*User, error) {
+	args := m.Called(ctx, identity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockService) LinkIdentity(ctx context.Context, userID uint, identity *oidc.Identity) (*UserIdentity, error) {
+	args := m.Called(ctx, userID, identity)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserIdentity), args.Error(1)
+}
+
+func (m *MockService) ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]UserIdentity), args.Error(1)
+}
+
+func (m *MockService) UnlinkIdentity(ctx context.Context, userID uint, provider string) error {
+	args := m.Called(ctx, userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockService) CreateAPIKey(ctx context.Context, userID uint, req CreateAPIKeyRequest) (*APIKey, string, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*APIKey), args.String(1), args.Error(2)
+}
+
+func (m *MockService) ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]APIKey), args.Error(1)
+}
+
+func (m *MockService) GetAPIKey(ctx context.Context, userID, id uint) (*APIKey, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*APIKey), args.Error(1)
+}
+
+func (m *MockService) UpdateAPIKey(ctx context.Context, userID, id uint, req UpdateAPIKeyRequest) (*APIKey, error) {
+	args := m.Called(ctx, userID, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*APIKey), args.Error(1)
+}
+
+func (m *MockService) RevokeAPIKey(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKeyPrincipal, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.APIKeyPrincipal), args.Error(1)
+}
+
+func (m *MockService) ListRoles(ctx context.Context) ([]Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Role), args.Error(1)
+}
+
+func (m *MockService) GetRole(ctx context.Context, name string) (*Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Role), args.Error(1)
+}
+
+func (m *MockService) CreateRole(ctx context.Context, req CreateRoleRequest) (*Role, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Role), args.Error(1)
+}
+
+func (m *MockService) UpdateRole(ctx context.Context, name string, req UpdateRoleRequest) (*Role, error) {
+	args := m.Called(ctx, name, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Role), args.Error(1)
+}
+
+func (m *MockService) DeleteRole(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+func (m *MockService) ListPermissions(ctx context.Context) ([]Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Permission), args.Error(1)
+}
+
+func (m *MockService) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	args := m.Called(ctx, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) GrantRole(ctx context.Context, userID uint, roleName string) (*User, bool, error) {
+	args := m.Called(ctx, userID, roleName)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockService) RevokeRole(ctx context.Context, actorID, userID uint, roleName string) (*User, bool, error) {
+	args := m.Called(ctx, actorID, userID, roleName)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockService) StartImpersonation(ctx context.Context, actorID, userID uint) (*Impersonation, error) {
+	args := m.Called(ctx, actorID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Impersonation), args.Error(1)
+}
+
 // MockRepository is a mock implementation of the user repository for testing services
 type MockRepository struct {
 	mock.Mock
@@ -92,6 +359,11 @@ func (m *MockRepository) Update(ctx context.Context, user *User) error {
 	return args.Error(0)
 }
 
+func (m *MockRepository) UpdatePasswordHash(ctx context.Context, userID uint, currentHash, newHash string) error {
+	args := m.Called(ctx, userID, currentHash, newHash)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -135,3 +407,267 @@ func (m *MockRepository) Transaction(ctx context.Context, fn func(context.Contex
 	// Execute the transaction function directly for testing
 	return fn(ctx)
 }
+
+func (m *MockRepository) CreatePasswordResetToken(ctx context.Context, token *PasswordResetToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindPasswordResetToken(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PasswordResetToken), args.Error(1)
+}
+
+func (m *MockRepository) MarkPasswordResetTokenUsed(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) MarkEmailVerified(ctx context.Context, userID uint, verifiedAt time.Time) error {
+	args := m.Called(ctx, userID, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateEmailVerificationToken(ctx context.Context, token *EmailVerificationToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindEmailVerificationToken(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*EmailVerificationToken), args.Error(1)
+}
+
+func (m *MockRepository) DeleteEmailVerificationTokens(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindUserTOTP(ctx context.Context, userID uint) (*UserTOTP, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserTOTP), args.Error(1)
+}
+
+func (m *MockRepository) CreateUserTOTP(ctx context.Context, totp *UserTOTP) error {
+	args := m.Called(ctx, totp)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ConfirmUserTOTP(ctx context.Context, userID uint, confirmedAt time.Time, step int64) error {
+	args := m.Called(ctx, userID, confirmedAt, step)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateTOTPLastUsedStep(ctx context.Context, userID uint, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteUserTOTP(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateRecoveryCodes(ctx context.Context, codes []MFARecoveryCode) error {
+	args := m.Called(ctx, codes)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateMFAChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindMFAChallenge(ctx context.Context, tokenHash string) (*MFAChallenge, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*MFAChallenge), args.Error(1)
+}
+
+func (m *MockRepository) IncrementMFAChallengeAttempts(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteMFAChallenge(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteMFAChallenges(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	args := m.Called(ctx, provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserIdentity), args.Error(1)
+}
+
+func (m *MockRepository) ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]UserIdentity), args.Error(1)
+}
+
+func (m *MockRepository) CreateIdentity(ctx context.Context, identity *UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockRepository) TouchIdentity(ctx context.Context, id uint, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteIdentity(ctx context.Context, userID uint, provider string) error {
+	args := m.Called(ctx, userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]APIKey), args.Error(1)
+}
+
+func (m *MockRepository) CountActiveAPIKeys(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	args := m.Called(ctx, userID, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) FindAPIKey(ctx context.Context, userID, id uint) (*APIKey, error) {
+	args := m.Called(ctx, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*APIKey), args.Error(1)
+}
+
+func (m *MockRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*APIKey), args.Error(1)
+}
+
+func (m *MockRepository) UpdateAPIKey(ctx context.Context, key *APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time, ipAddress string) error {
+	args := m.Called(ctx, id, usedAt, ipAddress)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteAPIKey(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListRoles(ctx context.Context) ([]Role, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Role), args.Error(1)
+}
+
+func (m *MockRepository) FindRoleWithPermissions(ctx context.Context, name string) (*Role, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Role), args.Error(1)
+}
+
+func (m *MockRepository) CreateRole(ctx context.Context, role *Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateRole(ctx context.Context, role *Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteRole(ctx context.Context, role *Role) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CountUsersWithRole(ctx context.Context, roleID uint) (int64, error) {
+	args := m.Called(ctx, roleID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) LockUsersWithRole(ctx context.Context, roleID uint) (int64, error) {
+	args := m.Called(ctx, roleID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) SetRolePermissions(ctx context.Context, role *Role, permissions []string) error {
+	args := m.Called(ctx, role, permissions)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListPermissions(ctx context.Context) ([]Permission, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Permission), args.Error(1)
+}
+
+func (m *MockRepository) GetRolePermissions(ctx context.Context, roleName string) ([]string, error) {
+	args := m.Called(ctx, roleName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
@@ -8,14 +8,15 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           uint           `gorm:"primaryKey" json:"id"`
-	Name         string         `gorm:"not null" json:"name"`
-	Email        string         `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string         `gorm:"not null" json:"-"`
-	Roles        []Role         `gorm:"many2many:user_roles;" json:"-"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Name            string         `gorm:"not null" json:"name"`
+	Email           string         `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash    string         `gorm:"not null" json:"-"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	Roles           []Role         `gorm:"many2many:user_roles;" json:"-"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName specifies the table name for User model
@@ -33,6 +34,11 @@ func (u *User) HasRole(roleName string) bool {
 	return false
 }
 
+// IsEmailVerified reports whether the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 // IsAdmin checks if user has admin role
 func (u *User) IsAdmin() bool {
 	return u.HasRole(RoleAdmin)
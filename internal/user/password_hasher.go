@@ -0,0 +1,164 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	// ErrPasswordMismatch is returned when a password does not match the stored hash
+	ErrPasswordMismatch = errors.New("password does not match")
+	// ErrUnsupportedHash is returned when a stored hash uses an unknown or malformed format
+	ErrUnsupportedHash = errors.New("unsupported password hash format")
+)
+
+// PasswordHasher hashes and verifies passwords
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a password using the configured algorithm and parameters
+	Hash(password string) (string, error)
+	// Verify checks a password against an encoded hash of any supported algorithm
+	Verify(encodedHash, password string) error
+	// NeedsRehash reports whether an encoded hash uses a different algorithm or parameters than configured
+	NeedsRehash(encodedHash string) bool
+}
+
+// argon2Params are the argon2id cost parameters stored in a PHC string
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// passwordHasher hashes with the configured algorithm and verifies bcrypt and argon2id hashes.
+// argon2id hashes use the PHC string format ($argon2id$v=19$m=...,t=...,p=...$salt$hash);
+// bcrypt hashes keep their standard modular crypt format ($2a$cost$...), which PHC adopts as-is.
+type passwordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+// NewPasswordHasher creates a password hasher from config
+func NewPasswordHasher(cfg *config.PasswordHashConfig) PasswordHasher {
+	return &passwordHasher{
+		algorithm:  cfg.GetAlgorithm(),
+		bcryptCost: cfg.GetBcryptCost(),
+		argon2: argon2Params{
+			memory:      uint32(cfg.GetArgon2Memory()),
+			iterations:  uint32(cfg.GetArgon2Iterations()),
+			parallelism: uint8(cfg.GetArgon2Parallelism()),
+		},
+	}
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == config.PasswordHashArgon2id {
+		return hashArgon2id(password, h.argon2)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *passwordHasher) Verify(encodedHash, password string) error {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return verifyArgon2id(encodedHash, password)
+	case isBcryptHash(encodedHash):
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	default:
+		return ErrUnsupportedHash
+	}
+}
+
+func (h *passwordHasher) NeedsRehash(encodedHash string) bool {
+	if h.algorithm == config.PasswordHashArgon2id {
+		params, _, key, err := decodeArgon2id(encodedHash)
+		return err != nil || params != h.argon2 || len(key) != argon2KeyLength
+	}
+
+	if !isBcryptHash(encodedHash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != h.bcryptCost
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func hashArgon2id(password string, params argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func verifyArgon2id(encodedHash, password string) error {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return err
+	}
+
+	// 使用哈希中记录的参数计算，参数变更前生成的哈希仍可验证
+	computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// decodeArgon2id parses a PHC string of the form $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func decodeArgon2id(encodedHash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnsupportedHash
+	}
+	return params, salt, key, nil
+}
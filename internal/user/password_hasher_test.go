@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// 测试使用较低的参数，避免拖慢测试
+var (
+	testBcryptConfig   = &config.PasswordHashConfig{Algorithm: config.PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}
+	testArgon2idConfig = &config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}
+)
+
+func TestPasswordHasher_HashAndVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *config.PasswordHashConfig
+		prefix string
+	}{
+		{name: "bcrypt", cfg: testBcryptConfig, prefix: "$2a$04$"},
+		{name: "argon2id", cfg: testArgon2idConfig, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher := NewPasswordHasher(tt.cfg)
+
+			hashed, err := hasher.Hash("password123")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(hashed, tt.prefix), "unexpected hash format: %s", hashed)
+
+			assert.NoError(t, hasher.Verify(hashed, "password123"))
+			assert.ErrorIs(t, hasher.Verify(hashed, "wrongpassword"), ErrPasswordMismatch)
+			assert.False(t, hasher.NeedsRehash(hashed))
+
+			other, err := hasher.Hash("password123")
+			require.NoError(t, err)
+			assert.NotEqual(t, hashed, other, "every hash uses a fresh salt")
+		})
+	}
+}
+
+func TestPasswordHasher_VerifiesEveryAlgorithm(t *testing.T) {
+	bcryptHash, err := NewPasswordHasher(testBcryptConfig).Hash("password123")
+	require.NoError(t, err)
+	argon2Hash, err := NewPasswordHasher(testArgon2idConfig).Hash("password123")
+	require.NoError(t, err)
+
+	for _, hasher := range []PasswordHasher{NewPasswordHasher(testBcryptConfig), NewPasswordHasher(testArgon2idConfig)} {
+		assert.NoError(t, hasher.Verify(bcryptHash, "password123"))
+		assert.NoError(t, hasher.Verify(argon2Hash, "password123"))
+	}
+}
+
+func TestPasswordHasher_VerifyRejectsMalformedHashes(t *testing.T) {
+	hasher := NewPasswordHasher(testArgon2idConfig)
+
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=64,t=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$not base64!$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		assert.ErrorIs(t, hasher.Verify(encoded, "password123"), ErrUnsupportedHash, encoded)
+	}
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	lowCost, err := NewPasswordHasher(testBcryptConfig).Hash("password123")
+	require.NoError(t, err)
+	argon2Hash, err := NewPasswordHasher(testArgon2idConfig).Hash("password123")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cfg      *config.PasswordHashConfig
+		hash     string
+		expected bool
+	}{
+		{name: "bcrypt with same cost", cfg: testBcryptConfig, hash: lowCost, expected: false},
+		{name: "bcrypt cost raised", cfg: &config.PasswordHashConfig{BcryptCost: 5}, hash: lowCost, expected: true},
+		{name: "argon2id hash while bcrypt configured", cfg: testBcryptConfig, hash: argon2Hash, expected: true},
+		{name: "bcrypt hash while argon2id configured", cfg: testArgon2idConfig, hash: lowCost, expected: true},
+		{name: "argon2id with same parameters", cfg: testArgon2idConfig, hash: argon2Hash, expected: false},
+		{name: "argon2id memory raised", cfg: &config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2Memory: 128, Argon2Iterations: 1, Argon2Parallelism: 1}, hash: argon2Hash, expected: true},
+		{name: "argon2id iterations raised", cfg: &config.PasswordHashConfig{Algorithm: config.PasswordHashArgon2id, Argon2Memory: 64, Argon2Iterations: 2, Argon2Parallelism: 1}, hash: argon2Hash, expected: true},
+		{name: "unknown format", cfg: testBcryptConfig, hash: "plaintext", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewPasswordHasher(tt.cfg).NeedsRehash(tt.hash))
+		})
+	}
+}
+
+func TestService_AuthenticateUser_RehashesOutdatedHash(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+	ctx := context.Background()
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{})
+	newService := func(cfg *config.PasswordHashConfig) Service {
+		return NewService(repo, WithPasswordPolicy(policy), WithPasswordHasher(NewPasswordHasher(cfg)))
+	}
+
+	registered, err := newService(testBcryptConfig).RegisterUser(ctx, RegisterRequest{Name: "Hash User", Email: "hash@example.com", Password: "password123"})
+	require.NoError(t, err)
+	bcryptHash := registered.PasswordHash
+
+	svc := newService(testArgon2idConfig)
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "hash@example.com", Password: "wrongpassword"})
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	stored, err := repo.FindByID(ctx, registered.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bcryptHash, stored.PasswordHash, "failed logins never rehash")
+
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "hash@example.com", Password: "password123"})
+	require.NoError(t, err)
+	stored, err = repo.FindByID(ctx, registered.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.PasswordHash, "$argon2id$"), "outdated hash is upgraded on login")
+	argon2Hash := stored.PasswordHash
+
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "hash@example.com", Password: "password123"})
+	require.NoError(t, err)
+	stored, err = repo.FindByID(ctx, registered.ID)
+	require.NoError(t, err)
+	assert.Equal(t, argon2Hash, stored.PasswordHash, "current hashes are left alone")
+}
+
+func TestRepository_UpdatePasswordHash_IsConditional(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	user := &User{Name: "Hash User", Email: "hash@example.com", PasswordHash: "changed-concurrently"}
+	require.NoError(t, repo.Create(ctx, user))
+
+	require.NoError(t, repo.UpdatePasswordHash(ctx, user.ID, "stale-hash", "rehashed"))
+	stored, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "changed-concurrently", stored.PasswordHash)
+
+	require.NoError(t, repo.UpdatePasswordHash(ctx, user.ID, "changed-concurrently", "rehashed"))
+	stored, err = repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rehashed", stored.PasswordHash)
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+// 密码规则名称，作为校验失败时 details 中的键
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleDigit        = "digit"
+	PasswordRuleSpecial      = "special"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleBreached     = "breached"
+)
+
+// minPersonalInfoLength is the shortest email local part or name word checked against the password,
+// so short names like "Al" do not reject unrelated passwords
+const minPersonalInfoLength = 3
+
+// PasswordViolation is a single rule a password failed
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+// PasswordPolicyError is returned when a password breaks one or more policy rules
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Details returns the violations keyed by rule name, for apiErrors.ValidationError
+func (e *PasswordPolicyError) Details() map[string]string {
+	details := make(map[string]string, len(e.Violations))
+	for _, v := range e.Violations {
+		details[v.Rule] = v.Message
+	}
+	return details
+}
+
+// PasswordPolicy validates passwords against the configured rules
+type PasswordPolicy struct {
+	minLength            int
+	maxLength            int
+	requireUppercase     bool
+	requireLowercase     bool
+	requireDigit         bool
+	requireSpecial       bool
+	disallowPersonalInfo bool
+	breached             map[string]struct{}
+}
+
+// NewPasswordPolicy creates a password policy without a breached-password list
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) *PasswordPolicy {
+	return &PasswordPolicy{
+		minLength:            cfg.GetMinLength(),
+		maxLength:            cfg.GetMaxLength(),
+		requireUppercase:     cfg.RequireUppercase,
+		requireLowercase:     cfg.RequireLowercase,
+		requireDigit:         cfg.RequireDigit,
+		requireSpecial:       cfg.RequireSpecial,
+		disallowPersonalInfo: cfg.DisallowPersonalInfo,
+	}
+}
+
+// LoadPasswordPolicy creates a password policy and loads the breached-password list, if configured.
+// The list has one password per line; blank lines and lines starting with # are ignored.
+func LoadPasswordPolicy(cfg *config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	policy := NewPasswordPolicy(cfg)
+	if cfg.BreachedListPath == "" {
+		return policy, nil
+	}
+
+	f, err := os.Open(cfg.BreachedListPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	policy.breached = make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return policy, nil
+}
+
+// MinLength returns the minimum password length in characters
+func (p *PasswordPolicy) MinLength() int {
+	return p.minLength
+}
+
+// Validate checks a password against every rule and returns a *PasswordPolicyError listing all violations.
+// email and name are the account's own details, rejected as part of the password when configured.
+func (p *PasswordPolicy) Validate(password, email, name string) error {
+	var violations []PasswordViolation
+	fail := func(rule, message string) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		fail(PasswordRuleMinLength, fmt.Sprintf("password must be at least %d characters long", p.minLength))
+	}
+	// WHY: bcrypt 只使用前 72 字节，超出部分不参与校验，因此按字节而非字符计算
+	if len(password) > p.maxLength {
+		fail(PasswordRuleMaxLength, fmt.Sprintf("password must be at most %d bytes long", p.maxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+	if p.requireUppercase && !hasUpper {
+		fail(PasswordRuleUppercase, "password must contain at least one uppercase letter")
+	}
+	if p.requireLowercase && !hasLower {
+		fail(PasswordRuleLowercase, "password must contain at least one lowercase letter")
+	}
+	if p.requireDigit && !hasDigit {
+		fail(PasswordRuleDigit, "password must contain at least one digit")
+	}
+	if p.requireSpecial && !hasSpecial {
+		fail(PasswordRuleSpecial, "password must contain at least one special character")
+	}
+
+	lower := strings.ToLower(password)
+	if p.disallowPersonalInfo && containsPersonalInfo(lower, email, name) {
+		fail(PasswordRulePersonalInfo, "password must not contain your email address or name")
+	}
+	if _, found := p.breached[lower]; found {
+		fail(PasswordRuleBreached, "password is too common or has appeared in a data breach")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// containsPersonalInfo reports whether a lowercased password contains the email local part or a name word
+func containsPersonalInfo(password, email, name string) bool {
+	candidates := strings.Fields(strings.ToLower(name))
+	if local, _, found := strings.Cut(strings.ToLower(email), "@"); found {
+		candidates = append(candidates, local)
+	}
+
+	for _, c := range candidates {
+		if utf8.RuneCountInString(c) >= minPersonalInfoLength && strings.Contains(password, c) {
+			return true
+		}
+	}
+	return false
+}
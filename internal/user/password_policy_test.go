@@ -0,0 +1,112 @@
+package user
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	strict := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:            10,
+		RequireUppercase:     true,
+		RequireLowercase:     true,
+		RequireDigit:         true,
+		RequireSpecial:       true,
+		DisallowPersonalInfo: true,
+	})
+
+	tests := []struct {
+		name          string
+		policy        *PasswordPolicy
+		password      string
+		email         string
+		userName      string
+		expectedRules []string
+	}{
+		{name: "default policy accepts long password", policy: NewPasswordPolicy(&config.PasswordPolicyConfig{}), password: "password123"},
+		{name: "default policy rejects short password", policy: NewPasswordPolicy(&config.PasswordPolicyConfig{}), password: "pass123", expectedRules: []string{PasswordRuleMinLength}},
+		{name: "default policy rejects passwords over bcrypt limit", policy: NewPasswordPolicy(&config.PasswordPolicyConfig{}), password: strings.Repeat("a", 73), expectedRules: []string{PasswordRuleMaxLength}},
+		{name: "strict policy accepts complex password", policy: strict, password: "Tr0ub4dor&3x", email: "john@example.com", userName: "John Doe"},
+		{name: "missing uppercase", policy: strict, password: "tr0ub4dor&3x", expectedRules: []string{PasswordRuleUppercase}},
+		{name: "missing lowercase", policy: strict, password: "TR0UB4DOR&3X", expectedRules: []string{PasswordRuleLowercase}},
+		{name: "missing digit", policy: strict, password: "Troubador&xx", expectedRules: []string{PasswordRuleDigit}},
+		{name: "missing special character", policy: strict, password: "Tr0ub4dor3xx", expectedRules: []string{PasswordRuleSpecial}},
+		{name: "contains email local part", policy: strict, password: "Johnsmith#2024", email: "johnsmith@example.com", expectedRules: []string{PasswordRulePersonalInfo}},
+		{name: "contains name", policy: strict, password: "Secret-Doe-123", userName: "John Doe", expectedRules: []string{PasswordRulePersonalInfo}},
+		{name: "short name parts are ignored", policy: strict, password: "Alpine#Route99", userName: "Al Li"},
+		{name: "reports every violation", policy: strict, password: "abc", expectedRules: []string{PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleSpecial}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password, tt.email, tt.userName)
+			if len(tt.expectedRules) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			details := policyErr.Details()
+			assert.Len(t, details, len(tt.expectedRules))
+			for _, rule := range tt.expectedRules {
+				assert.Contains(t, details, rule)
+			}
+		})
+	}
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# common passwords\n\nPassword123\nqwertyuiop\n"), 0o600))
+
+	policy, err := LoadPasswordPolicy(&config.PasswordPolicyConfig{BreachedListPath: path})
+	require.NoError(t, err)
+
+	var policyErr *PasswordPolicyError
+	require.ErrorAs(t, policy.Validate("password123", "", ""), &policyErr, "the list is matched case-insensitively")
+	assert.Contains(t, policyErr.Details(), PasswordRuleBreached)
+	assert.Error(t, policy.Validate("QwertyUIOP", "", ""))
+	assert.NoError(t, policy.Validate("# common passwords", "", ""), "comment lines are not loaded")
+	assert.NoError(t, policy.Validate("correct horse battery", "", ""))
+
+	_, err = LoadPasswordPolicy(&config.PasswordPolicyConfig{BreachedListPath: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
+
+func TestService_PasswordPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PasswordResetToken{}))
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{DisallowPersonalInfo: true})
+	sender := &recordingSender{}
+	svc := NewService(NewRepository(db), WithMailer(sender), WithPasswordReset(&config.PasswordResetConfig{URL: "https://app.example.com/reset-password"}), WithPasswordPolicy(policy))
+	ctx := context.Background()
+
+	var policyErr *PasswordPolicyError
+	_, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Jane Roe", Email: "jane@example.com", Password: "short"})
+	require.ErrorAs(t, err, &policyErr)
+	_, err = svc.RegisterUser(ctx, RegisterRequest{Name: "Jane Roe", Email: "jane@example.com", Password: "jane-secret-1"})
+	require.ErrorAs(t, err, &policyErr)
+	assert.Contains(t, policyErr.Details(), PasswordRulePersonalInfo)
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Jane Roe", Email: "jane@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "roe-password")
+	require.ErrorAs(t, err, &policyErr)
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, user.Email))
+	token := tokenFromLink(t, sender.messages[len(sender.messages)-1].Body)
+	_, err = svc.ConfirmPasswordReset(ctx, token, "tiny")
+	require.ErrorAs(t, err, &policyErr)
+	_, err = svc.ConfirmPasswordReset(ctx, token, "newpassword123")
+	assert.NoError(t, err, "a rejected password leaves the reset token usable")
+}
@@ -0,0 +1,162 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+// ErrInvalidResetToken is returned when a reset token is unknown, expired or already used
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetToken is a single-use token that allows setting a new password
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TableName specifies the table name for PasswordResetToken model
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// RequestPasswordReset emails a reset link to the account with the given email.
+// Unknown emails return nil so the endpoint does not reveal which accounts exist.
+func (s *service) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, err := generateOneTimeToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	// 新令牌生效时作废该用户此前未使用的令牌，只有最近一封邮件中的链接有效
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeletePasswordResetTokens(txCtx, user.ID); err != nil {
+			return err
+		}
+		return s.repo.CreatePasswordResetToken(txCtx, &PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(s.passwordResetTTL),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	// WHY: 只有存在的账户才会发信，返回发送错误会让调用方据此判断邮箱是否已注册
+	if err := s.mailer.Send(ctx, passwordResetMessage(user, token, s.passwordResetURL, s.passwordResetTTL)); err != nil {
+		slog.ErrorContext(ctx, "Failed to send password reset email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ConfirmPasswordReset consumes a reset token and sets the new password.
+// Returns the user ID so callers can revoke the user's existing sessions.
+func (s *service) ConfirmPasswordReset(ctx context.Context, token, newPassword string) (uint, error) {
+	var userID uint
+	err := s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		resetToken, err := s.repo.FindPasswordResetToken(txCtx, auth.HashToken(token))
+		if err != nil {
+			return fmt.Errorf("failed to find reset token: %w", err)
+		}
+		if resetToken == nil || resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		user, err := s.repo.FindByID(txCtx, resetToken.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			return ErrInvalidResetToken
+		}
+
+		// 密码不符合策略时令牌保持可用，用户可以换一个密码重试
+		if err := s.passwordPolicy.Validate(newPassword, user.Email, user.Name); err != nil {
+			return err
+		}
+
+		// WHY: 条件更新保证并发请求中只有一个能使用该令牌
+		if err := s.repo.MarkPasswordResetTokenUsed(txCtx, resetToken.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken
+			}
+			return fmt.Errorf("failed to mark reset token used: %w", err)
+		}
+
+		hashedPassword, err := s.hasher.Hash(newPassword)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.PasswordHash = hashedPassword
+		if err := s.repo.Update(txCtx, user); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if err := s.repo.DeletePasswordResetTokens(txCtx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete reset tokens: %w", err)
+		}
+
+		userID = user.ID
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 请求未经认证，持有重置令牌即证明了对账号邮箱的控制，因此记为用户本人的操作
+	event := userEvent(audit.ActionPasswordReset, userID)
+	event.ActorID = userID
+	s.recordAudit(ctx, event)
+	return userID, nil
+}
+
+// generateOneTimeToken returns a random URL-safe token with 256 bits of entropy
+func generateOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func passwordResetMessage(user *User, token, resetURL string, ttl time.Duration) mail.Message {
+	link := token
+	if resetURL != "" {
+		link = resetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s\n\nIf you did not request a password reset, you can ignore this email.\n",
+			user.Name, ttl, link,
+		),
+	}
+}
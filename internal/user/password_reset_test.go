@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
+)
+
+// recordingSender keeps sent messages in memory
+type recordingSender struct {
+	messages []mail.Message
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg mail.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// failingSender fails every delivery, e.g. an unreachable SMTP server
+type failingSender struct{}
+
+func (failingSender) Send(ctx context.Context, msg mail.Message) error {
+	return errors.New("smtp: connection refused")
+}
+
+// tokenFromLink extracts the reset token from the link in a reset email
+func tokenFromLink(t *testing.T, body string) string {
+	t.Helper()
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link, err := url.Parse(line)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no reset link in email body: %s", body)
+	return ""
+}
+
+func setupPasswordResetTest(t *testing.T) (Service, Repository, *recordingSender, *User) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PasswordResetToken{}))
+
+	repo := NewRepository(db)
+	sender := &recordingSender{}
+	svc := NewService(repo, WithMailer(sender), WithPasswordReset(&config.PasswordResetConfig{
+		TokenTTL: 30 * time.Minute,
+		URL:      "https://app.example.com/reset-password",
+	}))
+
+	hashed, err := NewPasswordHasher(&config.PasswordHashConfig{}).Hash("oldpassword123")
+	require.NoError(t, err)
+	user := &User{Name: "Reset User", Email: "reset@example.com", PasswordHash: hashed}
+	require.NoError(t, repo.Create(context.Background(), user))
+
+	return svc, repo, sender, user
+}
+
+func TestService_RequestPasswordReset(t *testing.T) {
+	svc, repo, sender, user := setupPasswordResetTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, "unknown@example.com"))
+	assert.Empty(t, sender.messages, "unknown emails must not send mail")
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, user.Email))
+	require.Len(t, sender.messages, 1)
+	assert.Equal(t, user.Email, sender.messages[0].To)
+	assert.Contains(t, sender.messages[0].Body, "30m0s")
+
+	token := tokenFromLink(t, sender.messages[0].Body)
+	assert.NotEmpty(t, token)
+
+	stored, err := repo.FindPasswordResetToken(ctx, auth.HashToken(token))
+	require.NoError(t, err)
+	require.NotNil(t, stored, "only the hash of the token is stored")
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), stored.ExpiresAt, time.Minute)
+}
+
+func TestService_MailFailureDoesNotRevealAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PasswordResetToken{}, &EmailVerificationToken{}))
+	repo := NewRepository(db)
+	svc := NewService(repo, WithMailer(failingSender{}), WithEmailVerification(&config.EmailVerificationConfig{
+		Enabled: true,
+		Policy:  config.EmailVerificationPolicyNone,
+	}))
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &User{Name: "Jane", Email: "jane@example.com", PasswordHash: "hash"}))
+
+	for _, email := range []string{"jane@example.com", "unknown@example.com"} {
+		assert.NoError(t, svc.RequestPasswordReset(ctx, email), email)
+		assert.NoError(t, svc.ResendVerificationEmail(ctx, email), email)
+	}
+}
+
+func TestService_ConfirmPasswordReset(t *testing.T) {
+	svc, _, sender, user := setupPasswordResetTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.RequestPasswordReset(ctx, user.Email))
+	require.NoError(t, svc.RequestPasswordReset(ctx, user.Email))
+	require.Len(t, sender.messages, 2)
+	superseded := tokenFromLink(t, sender.messages[0].Body)
+	token := tokenFromLink(t, sender.messages[1].Body)
+
+	_, err := svc.ConfirmPasswordReset(ctx, superseded, "newpassword123")
+	assert.ErrorIs(t, err, ErrInvalidResetToken, "a newer request invalidates earlier tokens")
+
+	_, err = svc.ConfirmPasswordReset(ctx, "not-a-token", "newpassword123")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	userID, err := svc.ConfirmPasswordReset(ctx, token, "newpassword123")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: user.Email, Password: "oldpassword123"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: user.Email, Password: "newpassword123"})
+	assert.NoError(t, err)
+
+	_, err = svc.ConfirmPasswordReset(ctx, token, "anotherpassword123")
+	assert.ErrorIs(t, err, ErrInvalidResetToken, "tokens are single-use")
+}
+
+func TestService_ConfirmPasswordReset_Expired(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&PasswordResetToken{}))
+	repo := NewRepository(db)
+	svc := NewService(repo, WithMailer(&recordingSender{}))
+	ctx := context.Background()
+
+	user := &User{Name: "Expired User", Email: "expired@example.com", PasswordHash: "unused"}
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.CreatePasswordResetToken(ctx, &PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken("expired-token"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	_, err := svc.ConfirmPasswordReset(ctx, "expired-token", "newpassword123")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
@@ -18,6 +18,7 @@ type Repository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id uint) (*User, error)
 	Update(ctx context.Context, user *User) error
+	UpdatePasswordHash(ctx context.Context, userID uint, currentHash, newHash string) error
 	Delete(ctx context.Context, id uint) error
 	ListAllUsers(ctx context.Context, filters UserFilterParams, page, perPage int) ([]User, int64, error)
 	AssignRole(ctx context.Context, userID uint, roleName string) error
@@ -25,6 +26,51 @@ type Repository interface {
 	FindRoleByName(ctx context.Context, name string) (*Role, error)
 	GetUserRoles(ctx context.Context, userID uint) ([]Role, error)
 	Transaction(ctx context.Context, fn func(context.Context) error) error
+	CreatePasswordResetToken(ctx context.Context, token *PasswordResetToken) error
+	FindPasswordResetToken(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(ctx context.Context, id uint) error
+	DeletePasswordResetTokens(ctx context.Context, userID uint) error
+	MarkEmailVerified(ctx context.Context, userID uint, verifiedAt time.Time) error
+	CreateEmailVerificationToken(ctx context.Context, token *EmailVerificationToken) error
+	FindEmailVerificationToken(ctx context.Context, tokenHash string) (*EmailVerificationToken, error)
+	DeleteEmailVerificationTokens(ctx context.Context, userID uint) error
+	FindUserTOTP(ctx context.Context, userID uint) (*UserTOTP, error)
+	CreateUserTOTP(ctx context.Context, totp *UserTOTP) error
+	ConfirmUserTOTP(ctx context.Context, userID uint, confirmedAt time.Time, step int64) error
+	UpdateTOTPLastUsedStep(ctx context.Context, userID uint, step int64) error
+	DeleteUserTOTP(ctx context.Context, userID uint) error
+	CreateRecoveryCodes(ctx context.Context, codes []MFARecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error
+	CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userID uint) error
+	CreateMFAChallenge(ctx context.Context, challenge *MFAChallenge) error
+	FindMFAChallenge(ctx context.Context, tokenHash string) (*MFAChallenge, error)
+	IncrementMFAChallengeAttempts(ctx context.Context, id uint) error
+	DeleteMFAChallenge(ctx context.Context, id uint) error
+	DeleteMFAChallenges(ctx context.Context, userID uint) error
+	FindIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *UserIdentity) error
+	TouchIdentity(ctx context.Context, id uint, email string) error
+	DeleteIdentity(ctx context.Context, userID uint, provider string) error
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error)
+	CountActiveAPIKeys(ctx context.Context, userID uint, now time.Time) (int64, error)
+	FindAPIKey(ctx context.Context, userID, id uint) (*APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *APIKey) error
+	TouchAPIKey(ctx context.Context, id uint, usedAt time.Time, ipAddress string) error
+	DeleteAPIKey(ctx context.Context, userID, id uint) error
+	ListRoles(ctx context.Context) ([]Role, error)
+	FindRoleWithPermissions(ctx context.Context, name string) (*Role, error)
+	CreateRole(ctx context.Context, role *Role) error
+	UpdateRole(ctx context.Context, role *Role) error
+	DeleteRole(ctx context.Context, role *Role) error
+	CountUsersWithRole(ctx context.Context, roleID uint) (int64, error)
+	LockUsersWithRole(ctx context.Context, roleID uint) (int64, error)
+	SetRolePermissions(ctx context.Context, role *Role, permissions []string) error
+	ListPermissions(ctx context.Context) ([]Permission, error)
+	GetRolePermissions(ctx context.Context, roleName string) ([]string, error)
 }
 
 type repository struct {
@@ -82,13 +128,21 @@ func (r *repository) FindByID(ctx context.Context, id uint) (*User, error) {
 // Update updates a user in the database
 func (r *repository) Update(ctx context.Context, user *User) error {
 	// WHY: Save() syncs associations, potentially clearing roles
-	result := r.getDB(ctx).WithContext(ctx).Select("name", "email", "password_hash", "updated_at").Save(user)
+	result := r.getDB(ctx).WithContext(ctx).Select("name", "email", "password_hash", "email_verified_at", "updated_at").Save(user)
 	if result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
+// UpdatePasswordHash replaces a password hash only if it still equals currentHash,
+// so upgrading a hash on login never overwrites a concurrent password change
+func (r *repository) UpdatePasswordHash(ctx context.Context, userID uint, currentHash, newHash string) error {
+	return r.getDB(ctx).WithContext(ctx).Model(&User{}).
+		Where("id = ? AND password_hash = ?", userID, currentHash).
+		Update("password_hash", newHash).Error
+}
+
 // Delete soft deletes a user from the database
 func (r *repository) Delete(ctx context.Context, id uint) error {
 	result := r.getDB(ctx).WithContext(ctx).Delete(&User{}, id)
@@ -224,3 +278,454 @@ func (r *repository) Transaction(ctx context.Context, fn func(context.Context) e
 		return fn(txCtx)
 	})
 }
+
+// CreatePasswordResetToken stores a hashed password reset token
+func (r *repository) CreatePasswordResetToken(ctx context.Context, token *PasswordResetToken) error {
+	return r.getDB(ctx).WithContext(ctx).Create(token).Error
+}
+
+// FindPasswordResetToken finds a password reset token by its hash
+func (r *repository) FindPasswordResetToken(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	var token PasswordResetToken
+	result := r.getDB(ctx).WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkPasswordResetTokenUsed marks an unused token as used
+// Returns gorm.ErrRecordNotFound if the token was already used
+func (r *repository) MarkPasswordResetTokenUsed(ctx context.Context, id uint) error {
+	result := r.getDB(ctx).WithContext(ctx).
+		Model(&PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeletePasswordResetTokens deletes every password reset token of a user
+func (r *repository) DeletePasswordResetTokens(ctx context.Context, userID uint) error {
+	return r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Delete(&PasswordResetToken{}).Error
+}
+
+// MarkEmailVerified records when a user confirmed their email address
+func (r *repository) MarkEmailVerified(ctx context.Context, userID uint, verifiedAt time.Time) error {
+	result := r.getDB(ctx).WithContext(ctx).Model(&User{}).Where("id = ?", userID).Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateEmailVerificationToken stores a hashed email verification token
+func (r *repository) CreateEmailVerificationToken(ctx context.Context, token *EmailVerificationToken) error {
+	return r.getDB(ctx).WithContext(ctx).Create(token).Error
+}
+
+// FindEmailVerificationToken finds an email verification token by its hash
+func (r *repository) FindEmailVerificationToken(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	var token EmailVerificationToken
+	result := r.getDB(ctx).WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// DeleteEmailVerificationTokens deletes every email verification token of a user
+func (r *repository) DeleteEmailVerificationTokens(ctx context.Context, userID uint) error {
+	return r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Delete(&EmailVerificationToken{}).Error
+}
+
+// FindUserTOTP finds the TOTP authenticator of a user, confirmed or not
+func (r *repository) FindUserTOTP(ctx context.Context, userID uint) (*UserTOTP, error) {
+	var totp UserTOTP
+	result := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).First(&totp)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &totp, nil
+}
+
+// CreateUserTOTP stores a pending TOTP authenticator
+func (r *repository) CreateUserTOTP(ctx context.Context, totp *UserTOTP) error {
+	return r.getDB(ctx).WithContext(ctx).Create(totp).Error
+}
+
+// ConfirmUserTOTP marks a pending authenticator as confirmed and records the step of the confirming code
+// Returns gorm.ErrRecordNotFound if there is no pending authenticator
+func (r *repository) ConfirmUserTOTP(ctx context.Context, userID uint, confirmedAt time.Time, step int64) error {
+	result := r.getDB(ctx).WithContext(ctx).
+		Model(&UserTOTP{}).
+		Where("user_id = ? AND confirmed_at IS NULL", userID).
+		Updates(map[string]interface{}{"confirmed_at": confirmedAt, "last_used_step": step})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateTOTPLastUsedStep records the step of an accepted code if it is later than the last one
+// Returns gorm.ErrRecordNotFound if the step was already used
+func (r *repository) UpdateTOTPLastUsedStep(ctx context.Context, userID uint, step int64) error {
+	result := r.getDB(ctx).WithContext(ctx).
+		Model(&UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteUserTOTP deletes the TOTP authenticator of a user
+func (r *repository) DeleteUserTOTP(ctx context.Context, userID uint) error {
+	return r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Delete(&UserTOTP{}).Error
+}
+
+// CreateRecoveryCodes stores hashed recovery codes
+func (r *repository) CreateRecoveryCodes(ctx context.Context, codes []MFARecoveryCode) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	return r.getDB(ctx).WithContext(ctx).Create(&codes).Error
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used
+// Returns gorm.ErrRecordNotFound if no unused code matches
+func (r *repository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) error {
+	result := r.getDB(ctx).WithContext(ctx).
+		Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes a user has left
+func (r *repository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.getDB(ctx).WithContext(ctx).
+		Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteRecoveryCodes deletes every recovery code of a user
+func (r *repository) DeleteRecoveryCodes(ctx context.Context, userID uint) error {
+	return r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error
+}
+
+// CreateMFAChallenge stores a pending login with its hashed token
+func (r *repository) CreateMFAChallenge(ctx context.Context, challenge *MFAChallenge) error {
+	return r.getDB(ctx).WithContext(ctx).Create(challenge).Error
+}
+
+// FindMFAChallenge finds a pending login by its token hash
+func (r *repository) FindMFAChallenge(ctx context.Context, tokenHash string) (*MFAChallenge, error) {
+	var challenge MFAChallenge
+	result := r.getDB(ctx).WithContext(ctx).Where("token_hash = ?", tokenHash).First(&challenge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &challenge, nil
+}
+
+// IncrementMFAChallengeAttempts counts a wrong code submitted for a pending login
+func (r *repository) IncrementMFAChallengeAttempts(ctx context.Context, id uint) error {
+	return r.getDB(ctx).WithContext(ctx).
+		Model(&MFAChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeleteMFAChallenge deletes a pending login
+// Returns gorm.ErrRecordNotFound if it was already deleted
+func (r *repository) DeleteMFAChallenge(ctx context.Context, id uint) error {
+	result := r.getDB(ctx).WithContext(ctx).Delete(&MFAChallenge{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteMFAChallenges deletes every pending login of a user
+func (r *repository) DeleteMFAChallenges(ctx context.Context, userID uint) error {
+	return r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Delete(&MFAChallenge{}).Error
+}
+
+// FindIdentity finds the identity issued by a provider for a subject
+func (r *repository) FindIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	result := r.getDB(ctx).WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+// ListIdentities returns the identities linked to a user, ordered by provider
+func (r *repository) ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error) {
+	var identities []UserIdentity
+	err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Order("provider").Find(&identities).Error
+	return identities, err
+}
+
+// CreateIdentity links a provider identity to a user
+func (r *repository) CreateIdentity(ctx context.Context, identity *UserIdentity) error {
+	return r.getDB(ctx).WithContext(ctx).Create(identity).Error
+}
+
+// TouchIdentity records a login with an identity and the email the provider reported for it
+func (r *repository) TouchIdentity(ctx context.Context, id uint, email string) error {
+	return r.getDB(ctx).WithContext(ctx).
+		Model(&UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "updated_at": time.Now()}).Error
+}
+
+// DeleteIdentity removes the identity of a provider from a user
+// Returns gorm.ErrRecordNotFound if none is linked
+func (r *repository) DeleteIdentity(ctx context.Context, userID uint, provider string) error {
+	result := r.getDB(ctx).WithContext(ctx).Where("user_id = ? AND provider = ?", userID, provider).Delete(&UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey stores a new API key
+func (r *repository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return r.getDB(ctx).WithContext(ctx).Create(key).Error
+}
+
+// ListAPIKeys returns the API keys of a user, newest first
+func (r *repository) ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.getDB(ctx).WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// CountActiveAPIKeys counts the API keys of a user that have not expired at now
+func (r *repository) CountActiveAPIKeys(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := r.getDB(ctx).WithContext(ctx).
+		Model(&APIKey{}).
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&count).Error
+	return count, err
+}
+
+// FindAPIKey finds an API key of a user by ID
+func (r *repository) FindAPIKey(ctx context.Context, userID, id uint) (*APIKey, error) {
+	var key APIKey
+	result := r.getDB(ctx).WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// FindAPIKeyByHash finds an API key by the hash of its secret
+func (r *repository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	var key APIKey
+	result := r.getDB(ctx).WithContext(ctx).Where("key_hash = ?", keyHash).First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// UpdateAPIKey saves the name and scopes of an API key
+func (r *repository) UpdateAPIKey(ctx context.Context, key *APIKey) error {
+	return r.getDB(ctx).WithContext(ctx).Select("name", "scopes", "updated_at").Save(key).Error
+}
+
+// TouchAPIKey records when and from where an API key was last used
+func (r *repository) TouchAPIKey(ctx context.Context, id uint, usedAt time.Time, ipAddress string) error {
+	return r.getDB(ctx).WithContext(ctx).
+		Model(&APIKey{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ipAddress}).Error
+}
+
+// DeleteAPIKey removes an API key of a user
+// Returns gorm.ErrRecordNotFound if the user has no such key
+func (r *repository) DeleteAPIKey(ctx context.Context, userID, id uint) error {
+	result := r.getDB(ctx).WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListRoles returns every role with its permissions, ordered by name
+func (r *repository) ListRoles(ctx context.Context) ([]Role, error) {
+	var roles []Role
+	err := r.getDB(ctx).WithContext(ctx).
+		Preload("Permissions", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Order("name").
+		Find(&roles).Error
+	return roles, err
+}
+
+// FindRoleWithPermissions finds a role by name and loads its permissions
+func (r *repository) FindRoleWithPermissions(ctx context.Context, name string) (*Role, error) {
+	var role Role
+	result := r.getDB(ctx).WithContext(ctx).
+		Preload("Permissions", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Where("name = ?", name).
+		First(&role)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &role, nil
+}
+
+// CreateRole stores a new role without permissions
+func (r *repository) CreateRole(ctx context.Context, role *Role) error {
+	return r.getDB(ctx).WithContext(ctx).Omit("Permissions").Create(role).Error
+}
+
+// UpdateRole saves the description of a role
+func (r *repository) UpdateRole(ctx context.Context, role *Role) error {
+	return r.getDB(ctx).WithContext(ctx).
+		Model(&Role{}).
+		Where("id = ?", role.ID).
+		Updates(map[string]interface{}{"description": role.Description, "updated_at": time.Now()}).Error
+}
+
+// DeleteRole removes a role and its permission grants
+func (r *repository) DeleteRole(ctx context.Context, role *Role) error {
+	db := r.getDB(ctx).WithContext(ctx)
+	if err := db.Exec("DELETE FROM role_permissions WHERE role_id = ?", role.ID).Error; err != nil {
+		return err
+	}
+	return db.Delete(&Role{}, role.ID).Error
+}
+
+// CountUsersWithRole counts the users a role is assigned to
+func (r *repository) CountUsersWithRole(ctx context.Context, roleID uint) (int64, error) {
+	var count int64
+	err := r.getDB(ctx).WithContext(ctx).Table("user_roles").Where("role_id = ?", roleID).Count(&count).Error
+	return count, err
+}
+
+// LockUsersWithRole locks the assignments of a role and their users until the transaction ends and counts them
+// Soft-deleted users keep their assignments but are not counted
+// WHY: 先 COUNT 再 DELETE 在 READ COMMITTED 下会让两个并发撤销都看到旧的计数；
+// FOR UPDATE 使后到的事务等待先到的提交，再基于最新的行计数。SQLite 忽略该子句，其写事务本身是串行的
+func (r *repository) LockUsersWithRole(ctx context.Context, roleID uint) (int64, error) {
+	var userIDs []uint
+	err := r.getDB(ctx).WithContext(ctx).
+		Table("user_roles").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_roles.role_id = ? AND users.deleted_at IS NULL", roleID).
+		Pluck("user_roles.user_id", &userIDs).Error
+	return int64(len(userIDs)), err
+}
+
+// SetRolePermissions replaces the permissions of a role
+// Permissions missing from the catalog are created
+func (r *repository) SetRolePermissions(ctx context.Context, role *Role, permissions []string) error {
+	db := r.getDB(ctx).WithContext(ctx)
+
+	now := time.Now()
+	for _, name := range permissions {
+		// Works with both PostgreSQL and SQLite
+		if err := db.Exec(`
+			INSERT INTO permissions (name, description, created_at)
+			VALUES (?, '', ?)
+			ON CONFLICT (name) DO NOTHING
+		`, name, now).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := db.Exec("DELETE FROM role_permissions WHERE role_id = ?", role.ID).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	return db.Exec(`
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT ?, id FROM permissions WHERE name IN ?
+	`, role.ID, permissions).Error
+}
+
+// ListPermissions returns the permission catalog ordered by name
+func (r *repository) ListPermissions(ctx context.Context) ([]Permission, error) {
+	var permissions []Permission
+	err := r.getDB(ctx).WithContext(ctx).Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetRolePermissions returns the permission names granted to a role, empty for unknown roles
+func (r *repository) GetRolePermissions(ctx context.Context, roleName string) ([]string, error) {
+	permissions := []string{}
+	err := r.getDB(ctx).WithContext(ctx).
+		Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", roleName).
+		Order("permissions.name").
+		Pluck("permissions.name", &permissions).Error
+	return permissions, err
+}
@@ -7,12 +7,18 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	return openTestDB(t, ":memory:")
+}
+
+// openTestDB opens dsn and creates the schema; use a file DSN when connections run concurrently
+func openTestDB(t *testing.T, dsn string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
 
 	sqlDB, err := db.DB()
@@ -24,6 +30,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 			name TEXT NOT NULL,
 			email TEXT UNIQUE NOT NULL,
 			password_hash TEXT NOT NULL,
+			email_verified_at DATETIME,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			deleted_at DATETIME
@@ -715,3 +722,19 @@ func TestRepository_GetUserRoles_Error(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, roles)
 }
+
+func TestRepository_LockUsersWithRole_PostgresSQL(t *testing.T) {
+	// DryRun 只生成 SQL，不连接数据库
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	var statements []string
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}))
+
+	_, err = NewRepository(db).LockUsersWithRole(context.Background(), 2)
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	assert.Equal(t, `SELECT "user_roles"."user_id" FROM "user_roles" JOIN users ON users.id = user_roles.user_id WHERE user_roles.role_id = $1 AND users.deleted_at IS NULL FOR UPDATE`, statements[0])
+}
@@ -1,22 +1,333 @@
 package user
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+)
 
 const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
 )
 
+// Built-in permissions, seeded by the create_permissions_tables migration and granted to the admin role
+const (
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+	PermissionRolesRead  = "roles:read"
+	PermissionRolesWrite = "roles:write"
+)
+
+// permissionCacheTTL bounds how long a replica serves role permissions changed on another replica
+const permissionCacheTTL = time.Minute
+
+var (
+	// ErrRoleNotFound is returned when the role doesn't exist
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrRoleExists is returned when creating a role whose name is taken
+	ErrRoleExists = errors.New("role already exists")
+	// ErrRoleInUse is returned when deleting a role that is still assigned to users
+	ErrRoleInUse = errors.New("role is assigned to users")
+	// ErrProtectedRole is returned when deleting a built-in role or removing role management from the admin role
+	ErrProtectedRole = errors.New("built-in role cannot be changed this way")
+	// ErrInvalidRoleName is returned when a role name is malformed
+	ErrInvalidRoleName = errors.New("invalid role name")
+	// ErrInvalidPermission is returned when a permission is not of the form resource:action
+	ErrInvalidPermission = errors.New("invalid permission")
+)
+
+var (
+	roleNamePattern   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+	permissionPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[a-z][a-z0-9_-]*$`)
+)
+
 // Role represents a user role in the system
 type Role struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
 // TableName specifies the table name for Role model
 func (Role) TableName() string {
 	return "roles"
 }
+
+// PermissionNames returns the names of the role's permissions, sorted
+func (r *Role) PermissionNames() []string {
+	names := make([]string, len(r.Permissions))
+	for i, permission := range r.Permissions {
+		names[i] = permission.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBuiltin reports whether the role is one of the roles the application relies on
+func (r *Role) IsBuiltin() bool {
+	return r.Name == RoleUser || r.Name == RoleAdmin
+}
+
+// Permission is a named capability such as users:read, granted to users through their roles
+type Permission struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for Permission model
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// ListRoles returns every role with its permissions
+func (s *service) ListRoles(ctx context.Context) ([]Role, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetRole returns a role with its permissions
+func (s *service) GetRole(ctx context.Context, name string) (*Role, error) {
+	role, err := s.repo.FindRoleWithPermissions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find role: %w", err)
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// CreateRole creates a role with the given permissions; unknown permissions are added to the catalog
+func (s *service) CreateRole(ctx context.Context, req CreateRoleRequest) (*Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrInvalidRoleName
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindRoleByName(ctx, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing role: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrRoleExists
+	}
+
+	role := &Role{Name: req.Name, Description: req.Description}
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.CreateRole(txCtx, role); err != nil {
+			return err
+		}
+		return s.repo.SetRolePermissions(txCtx, role, permissions)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	s.permissionCache.invalidate(role.Name)
+	created, err := s.GetRole(ctx, role.Name)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionRoleCreate,
+		TargetType: audit.TargetRole,
+		TargetID:   created.Name,
+		Changes:    audit.Diff(nil, auditRoleFields(created)),
+	})
+	return created, nil
+}
+
+// UpdateRole replaces a role's description and permissions
+func (s *service) UpdateRole(ctx context.Context, name string, req UpdateRoleRequest) (*Role, error) {
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.GetRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	// WHY: 管理员角色失去角色管理权限后将无人能恢复
+	if role.Name == RoleAdmin && !(containsString(permissions, PermissionRolesRead) && containsString(permissions, PermissionRolesWrite)) {
+		return nil, ErrProtectedRole
+	}
+
+	before := auditRoleFields(role)
+	role.Description = req.Description
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.UpdateRole(txCtx, role); err != nil {
+			return err
+		}
+		return s.repo.SetRolePermissions(txCtx, role, permissions)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	s.permissionCache.invalidate(role.Name)
+	updated, err := s.GetRole(ctx, role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if changes := audit.Diff(before, auditRoleFields(updated)); changes != nil {
+		s.recordAudit(ctx, audit.Event{
+			Action:     audit.ActionRoleUpdate,
+			TargetType: audit.TargetRole,
+			TargetID:   updated.Name,
+			Changes:    changes,
+		})
+	}
+	return updated, nil
+}
+
+// DeleteRole deletes a custom role that is no longer assigned to any user
+func (s *service) DeleteRole(ctx context.Context, name string) error {
+	role, err := s.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.IsBuiltin() {
+		return ErrProtectedRole
+	}
+
+	count, err := s.repo.CountUsersWithRole(ctx, role.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count role users: %w", err)
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.repo.DeleteRole(ctx, role); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	s.permissionCache.invalidate(role.Name)
+	s.recordAudit(ctx, audit.Event{
+		Action:     audit.ActionRoleDelete,
+		TargetType: audit.TargetRole,
+		TargetID:   role.Name,
+		Changes:    audit.Diff(auditRoleFields(role), nil),
+	})
+	return nil
+}
+
+// ListPermissions returns the permission catalog
+func (s *service) ListPermissions(ctx context.Context) ([]Permission, error) {
+	permissions, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// RolePermissions returns the permissions granted to a role, empty for unknown roles
+// Results are cached per role for permissionCacheTTL and invalidated when this replica changes the role
+func (s *service) RolePermissions(ctx context.Context, role string) ([]string, error) {
+	if permissions, ok := s.permissionCache.get(role); ok {
+		return permissions, nil
+	}
+
+	permissions, err := s.repo.GetRolePermissions(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+
+	s.permissionCache.set(role, permissions)
+	return permissions, nil
+}
+
+var _ auth.PermissionResolver = (*service)(nil)
+
+// normalizePermissions validates permission names and returns them sorted without duplicates
+func normalizePermissions(permissions []string) ([]string, error) {
+	unique := make(map[string]struct{}, len(permissions))
+	for _, permission := range permissions {
+		if len(permission) > 100 || !permissionPattern.MatchString(permission) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPermission, permission)
+		}
+		unique[permission] = struct{}{}
+	}
+
+	normalized := make([]string, 0, len(unique))
+	for permission := range unique {
+		normalized = append(normalized, permission)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionCache caches role permissions in process memory
+type permissionCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]permissionCacheEntry
+}
+
+type permissionCacheEntry struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]permissionCacheEntry),
+	}
+}
+
+func (c *permissionCache) get(role string) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[role]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.permissions, true
+}
+
+func (c *permissionCache) set(role string, permissions []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[role] = permissionCacheEntry{permissions: permissions, expiresAt: c.now().Add(c.ttl)}
+}
+
+func (c *permissionCache) invalidate(role string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, role)
+}
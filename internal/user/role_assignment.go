@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+)
+
+var (
+	// ErrLastAdmin is returned when revoking the admin role from the only remaining admin
+	ErrLastAdmin = errors.New("cannot remove the last admin")
+	// ErrSelfRoleRevocation is returned when an admin revokes one of their own roles
+	ErrSelfRoleRevocation = errors.New("cannot revoke your own role")
+	// ErrSelfDeletion is returned when an admin deletes their own account
+	ErrSelfDeletion = errors.New("cannot delete your own admin account")
+)
+
+// GrantRole assigns a role to a user
+// Returns the updated user and whether the role was newly assigned
+func (s *service) GrantRole(ctx context.Context, userID uint, roleName string) (*User, bool, error) {
+	user, role, err := s.findUserAndRole(ctx, userID, roleName)
+	if err != nil {
+		return nil, false, err
+	}
+	if user.HasRole(role.Name) {
+		return user, false, nil
+	}
+
+	if err := s.repo.AssignRole(ctx, userID, role.Name); err != nil {
+		return nil, false, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	before := auditUserFields(user)
+	user, err = s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	s.recordRoleAssignment(ctx, audit.ActionRoleGrant, role.Name, before, user)
+	return user, true, nil
+}
+
+// RevokeRole removes a role from a user on behalf of actorID
+// Admins cannot revoke their own roles, and the last admin keeps the admin role
+// Returns the updated user and whether the role was removed
+func (s *service) RevokeRole(ctx context.Context, actorID, userID uint, roleName string) (*User, bool, error) {
+	user, role, err := s.findUserAndRole(ctx, userID, roleName)
+	if err != nil {
+		return nil, false, err
+	}
+	if !user.HasRole(role.Name) {
+		return user, false, nil
+	}
+	if actorID == userID {
+		return nil, false, ErrSelfRoleRevocation
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if role.Name == RoleAdmin {
+			count, err := s.repo.LockUsersWithRole(txCtx, role.ID)
+			if err != nil {
+				return fmt.Errorf("failed to count admins: %w", err)
+			}
+			if count <= 1 {
+				return ErrLastAdmin
+			}
+		}
+		return s.repo.RemoveRole(txCtx, userID, role.Name)
+	})
+	if err != nil {
+		if errors.Is(err, ErrLastAdmin) {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("failed to remove role: %w", err)
+	}
+
+	before := auditUserFields(user)
+	user, err = s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	s.recordRoleAssignment(ctx, audit.ActionRoleRevoke, role.Name, before, user)
+	return user, true, nil
+}
+
+// recordRoleAssignment records a role grant or revocation with the user's roles before and after
+func (s *service) recordRoleAssignment(ctx context.Context, action, roleName string, before map[string]interface{}, user *User) {
+	event := userEvent(action, user.ID)
+	event.Changes = audit.Diff(before, auditUserFields(user))
+	event.Metadata = map[string]interface{}{"role": roleName}
+	s.recordAudit(ctx, event)
+}
+
+// findUserAndRole loads the user and role a role assignment refers to
+func (s *service) findUserAndRole(ctx context.Context, userID uint, roleName string) (*User, *Role, error) {
+	role, err := s.repo.FindRoleByName(ctx, roleName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find role: %w", err)
+	}
+	if role == nil {
+		return nil, nil, ErrRoleNotFound
+	}
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, role, nil
+}
@@ -0,0 +1,259 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRoleTest(t *testing.T) (Service, Repository) {
+	t.Helper()
+
+	db := setupTestDB(t)
+	require.NoError(t, db.Exec(`
+		CREATE TABLE permissions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`).Error)
+	require.NoError(t, db.Exec(`
+		CREATE TABLE role_permissions (
+			role_id INTEGER NOT NULL,
+			permission_id INTEGER NOT NULL,
+			PRIMARY KEY (role_id, permission_id)
+		)
+	`).Error)
+
+	repo := NewRepository(db)
+	require.NoError(t, repo.SetRolePermissions(context.Background(), &Role{ID: 2},
+		[]string{PermissionRolesRead, PermissionRolesWrite, PermissionUsersRead, PermissionUsersWrite}))
+	return NewService(repo), repo
+}
+
+func TestService_RoleLifecycle(t *testing.T) {
+	svc, repo := setupRoleTest(t)
+	ctx := context.Background()
+
+	role, err := svc.CreateRole(ctx, CreateRoleRequest{Name: "support", Description: "Support staff", Permissions: []string{"users:read", "tickets:write", "users:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tickets:write", "users:read"}, role.PermissionNames())
+	assert.False(t, role.IsBuiltin())
+
+	_, err = svc.CreateRole(ctx, CreateRoleRequest{Name: "support"})
+	assert.ErrorIs(t, err, ErrRoleExists)
+	_, err = svc.CreateRole(ctx, CreateRoleRequest{Name: "Support Staff"})
+	assert.ErrorIs(t, err, ErrInvalidRoleName)
+	_, err = svc.CreateRole(ctx, CreateRoleRequest{Name: "auditor", Permissions: []string{"users"}})
+	assert.ErrorIs(t, err, ErrInvalidPermission)
+
+	permissions, err := svc.ListPermissions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, permissions, 5, "tickets:write was added to the catalog")
+
+	granted, err := svc.RolePermissions(ctx, "support")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tickets:write", "users:read"}, granted)
+
+	updated, err := svc.UpdateRole(ctx, "support", UpdateRoleRequest{Description: "Tier 1", Permissions: []string{"users:read"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Tier 1", updated.Description)
+	assert.Equal(t, []string{"users:read"}, updated.PermissionNames())
+
+	granted, err = svc.RolePermissions(ctx, "support")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read"}, granted, "updates invalidate the cached permissions")
+
+	roles, err := svc.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 3)
+	assert.Equal(t, "admin", roles[0].Name)
+	assert.Len(t, roles[0].Permissions, 4)
+
+	user := &User{Name: "Sam", Email: "sam@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.AssignRole(ctx, user.ID, "support"))
+	assert.ErrorIs(t, svc.DeleteRole(ctx, "support"), ErrRoleInUse)
+
+	require.NoError(t, repo.RemoveRole(ctx, user.ID, "support"))
+	require.NoError(t, svc.DeleteRole(ctx, "support"))
+	_, err = svc.GetRole(ctx, "support")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	granted, err = svc.RolePermissions(ctx, "support")
+	require.NoError(t, err)
+	assert.Empty(t, granted)
+}
+
+func TestService_ProtectedRoles(t *testing.T) {
+	svc, _ := setupRoleTest(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, svc.DeleteRole(ctx, RoleUser), ErrProtectedRole)
+	assert.ErrorIs(t, svc.DeleteRole(ctx, RoleAdmin), ErrProtectedRole)
+
+	_, err := svc.UpdateRole(ctx, RoleAdmin, UpdateRoleRequest{Permissions: []string{PermissionRolesRead}})
+	assert.ErrorIs(t, err, ErrProtectedRole, "admins must keep role management")
+
+	role, err := svc.UpdateRole(ctx, RoleAdmin, UpdateRoleRequest{Permissions: []string{PermissionRolesRead, PermissionRolesWrite}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{PermissionRolesRead, PermissionRolesWrite}, role.PermissionNames())
+}
+
+func TestService_RolePermissions_Cached(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetRolePermissions", mock.Anything, "support").Return([]string{"users:read"}, nil).Once()
+	svc := NewService(mockRepo).(*service)
+	now := time.Now()
+	svc.permissionCache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		permissions, err := svc.RolePermissions(context.Background(), "support")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"users:read"}, permissions)
+	}
+	mockRepo.AssertNumberOfCalls(t, "GetRolePermissions", 1)
+
+	now = now.Add(permissionCacheTTL)
+	mockRepo.On("GetRolePermissions", mock.Anything, "support").Return([]string{}, nil).Once()
+	permissions, err := svc.RolePermissions(context.Background(), "support")
+	require.NoError(t, err)
+	assert.Empty(t, permissions, "entries expire after the cache TTL")
+}
+
+func TestService_GrantAndRevokeRole(t *testing.T) {
+	svc, repo := setupRoleTest(t)
+	ctx := context.Background()
+
+	admin := &User{Name: "Ada", Email: "ada@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, admin))
+	require.NoError(t, repo.AssignRole(ctx, admin.ID, RoleAdmin))
+	member := &User{Name: "Max", Email: "max@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, member))
+
+	_, _, err := svc.GrantRole(ctx, member.ID, "auditor")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+	_, _, err = svc.GrantRole(ctx, 999, RoleAdmin)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	updated, changed, err := svc.GrantRole(ctx, member.ID, RoleAdmin)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, updated.IsAdmin())
+
+	_, changed, err = svc.GrantRole(ctx, member.ID, RoleAdmin)
+	require.NoError(t, err)
+	assert.False(t, changed, "granting an assigned role is a no-op")
+
+	_, _, err = svc.RevokeRole(ctx, admin.ID, admin.ID, RoleAdmin)
+	assert.ErrorIs(t, err, ErrSelfRoleRevocation)
+
+	updated, changed, err = svc.RevokeRole(ctx, admin.ID, member.ID, RoleAdmin)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.False(t, updated.IsAdmin())
+
+	_, changed, err = svc.RevokeRole(ctx, admin.ID, member.ID, RoleAdmin)
+	require.NoError(t, err)
+	assert.False(t, changed, "revoking a missing role is a no-op")
+
+	_, _, err = svc.RevokeRole(ctx, member.ID, admin.ID, RoleAdmin)
+	assert.ErrorIs(t, err, ErrLastAdmin)
+	remaining, err := svc.GetUserByID(ctx, admin.ID)
+	require.NoError(t, err)
+	assert.True(t, remaining.IsAdmin())
+}
+
+func TestService_DeleteUser_AdminSafeguards(t *testing.T) {
+	svc, repo := setupRoleTest(t)
+	ctx := context.Background()
+
+	admin := &User{Name: "Ada", Email: "ada@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, admin))
+	require.NoError(t, repo.AssignRole(ctx, admin.ID, RoleAdmin))
+	other := &User{Name: "Bob", Email: "bob@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, other))
+	require.NoError(t, repo.AssignRole(ctx, other.ID, RoleAdmin))
+	member := &User{Name: "Max", Email: "max@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, member))
+
+	t.Run("admin cannot delete own account", func(t *testing.T) {
+		assert.ErrorIs(t, svc.DeleteUser(ctx, admin.ID, admin.ID), ErrSelfDeletion)
+		_, err := svc.GetUserByID(ctx, admin.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("last admin cannot be deleted", func(t *testing.T) {
+		require.NoError(t, svc.DeleteUser(ctx, admin.ID, other.ID), "another admin remains")
+		assert.ErrorIs(t, svc.DeleteUser(ctx, member.ID, admin.ID), ErrLastAdmin)
+		remaining, err := svc.GetUserByID(ctx, admin.ID)
+		require.NoError(t, err)
+		assert.True(t, remaining.IsAdmin())
+	})
+
+	t.Run("non-admin can delete own account", func(t *testing.T) {
+		require.NoError(t, svc.DeleteUser(ctx, member.ID, member.ID))
+		_, err := svc.GetUserByID(ctx, member.ID)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestService_RevokeRole_ConcurrentLastAdmin(t *testing.T) {
+	for round := 0; round < 5; round++ {
+		t.Run(fmt.Sprintf("round %d", round), func(t *testing.T) {
+			// 文件数据库让两个请求使用不同连接；_txlock=immediate 让写事务像 PostgreSQL 的 FOR UPDATE 一样排队等待
+			db := openTestDB(t, "file:"+filepath.Join(t.TempDir(), "roles.db")+"?_busy_timeout=5000&_txlock=immediate")
+			repo := NewRepository(db)
+			svc := NewService(repo)
+			ctx := context.Background()
+
+			first := &User{Name: "Ada", Email: "ada@example.com", PasswordHash: "hash"}
+			require.NoError(t, repo.Create(ctx, first))
+			require.NoError(t, repo.AssignRole(ctx, first.ID, RoleAdmin))
+			second := &User{Name: "Bob", Email: "bob@example.com", PasswordHash: "hash"}
+			require.NoError(t, repo.Create(ctx, second))
+			require.NoError(t, repo.AssignRole(ctx, second.ID, RoleAdmin))
+
+			// 两个管理员同时撤销对方的管理员角色
+			var wg sync.WaitGroup
+			errs := make([]error, 2)
+			for i, pair := range [][2]uint{{first.ID, second.ID}, {second.ID, first.ID}} {
+				wg.Add(1)
+				go func(i int, actorID, userID uint) {
+					defer wg.Done()
+					_, _, errs[i] = svc.RevokeRole(ctx, actorID, userID, RoleAdmin)
+				}(i, pair[0], pair[1])
+			}
+			wg.Wait()
+
+			adminRole, err := repo.FindRoleByName(ctx, RoleAdmin)
+			require.NoError(t, err)
+			count, err := repo.CountUsersWithRole(ctx, adminRole.ID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count, "one admin always remains")
+
+			var revoked, refused int
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					revoked++
+				case errors.Is(err, ErrLastAdmin):
+					refused++
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			assert.Equal(t, 1, revoked)
+			assert.Equal(t, 1, refused)
+		})
+	}
+}
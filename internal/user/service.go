@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
 )
 
 var (
@@ -18,6 +25,12 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	// ErrInvalidRole is returned when role is invalid
 	ErrInvalidRole = errors.New("invalid role")
+	// ErrIncorrectPassword is returned when the current password does not match
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	// ErrPasswordUnchanged is returned when the new password equals the current one
+	ErrPasswordUnchanged = errors.New("new password must differ from the current password")
+	// ErrEmailNotVerified is returned when login requires a verified email address
+	ErrEmailNotVerified = errors.New("email address not verified")
 )
 
 // Service defines user service interface
@@ -26,20 +39,164 @@ type Service interface {
 	AuthenticateUser(ctx context.Context, req LoginRequest) (*User, error)
 	GetUserByID(ctx context.Context, id uint) (*User, error)
 	UpdateUser(ctx context.Context, id uint, req UpdateUserRequest) (*User, error)
-	DeleteUser(ctx context.Context, id uint) error
+	ChangePassword(ctx context.Context, id uint, currentPassword, newPassword string) (*User, error)
+	DeleteUser(ctx context.Context, actorID, id uint) error
 	ListUsers(ctx context.Context, filters UserFilterParams, page, perPage int) ([]User, int64, error)
 	PromoteToAdmin(ctx context.Context, userID uint) error
+	UnlockUser(ctx context.Context, id uint) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ConfirmPasswordReset(ctx context.Context, token, newPassword string) (uint, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	MarkEmailVerified(ctx context.Context, id uint) error
+	CheckLoginAllowed(user *User) error
+	GetMFAStatus(ctx context.Context, userID uint) (*MFAStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uint, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+	StartMFAChallenge(ctx context.Context, user *User) (*MFAPendingLogin, error)
+	VerifyMFAChallenge(ctx context.Context, token, code string) (*User, error)
+	BeginChallengeEnrollment(ctx context.Context, token string) (*TOTPEnrollment, error)
+	CompleteChallengeEnrollment(ctx context.Context, token, code string) (*User, []string, error)
+	LoginWithIdentity(ctx context.Context, identity *oidc.Identity) (*User, error)
+	LinkIdentity(ctx context.Context, userID uint, identity *oidc.Identity) (*UserIdentity, error)
+	ListIdentities(ctx context.Context, userID uint) ([]UserIdentity, error)
+	UnlinkIdentity(ctx context.Context, userID uint, provider string) error
+	CreateAPIKey(ctx context.Context, userID uint, req CreateAPIKeyRequest) (*APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID uint) ([]APIKey, error)
+	GetAPIKey(ctx context.Context, userID, id uint) (*APIKey, error)
+	UpdateAPIKey(ctx context.Context, userID, id uint, req UpdateAPIKeyRequest) (*APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id uint) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKeyPrincipal, error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	GetRole(ctx context.Context, name string) (*Role, error)
+	CreateRole(ctx context.Context, req CreateRoleRequest) (*Role, error)
+	UpdateRole(ctx context.Context, name string, req UpdateRoleRequest) (*Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	ListPermissions(ctx context.Context) ([]Permission, error)
+	RolePermissions(ctx context.Context, role string) ([]string, error)
+	GrantRole(ctx context.Context, userID uint, roleName string) (*User, bool, error)
+	RevokeRole(ctx context.Context, actorID, userID uint, roleName string) (*User, bool, error)
+	StartImpersonation(ctx context.Context, actorID, userID uint) (*Impersonation, error)
 }
 
 type service struct {
-	repo Repository
+	repo              Repository
+	mailer            mail.Sender
+	passwordResetTTL  time.Duration
+	passwordResetURL  string
+	emailVerification config.EmailVerificationConfig
+	passwordPolicy    *PasswordPolicy
+	hasher            PasswordHasher
+	loginGuard        *auth.LoginGuard
+	mfa               config.MFAConfig
+	apiKeys           config.APIKeyConfig
+	impersonationTTL  time.Duration
+	permissionCache   *permissionCache
+	auditor           audit.Recorder
+}
+
+// Option configures optional dependencies and settings of the user service
+type Option func(*service)
+
+// WithMailer delivers password reset and verification emails through mailer
+func WithMailer(mailer mail.Sender) Option {
+	return func(s *service) {
+		s.mailer = mailer
+	}
+}
+
+// WithPasswordReset sets the lifetime and link of password reset tokens
+func WithPasswordReset(cfg *config.PasswordResetConfig) Option {
+	return func(s *service) {
+		if cfg.TokenTTL > 0 {
+			s.passwordResetTTL = cfg.TokenTTL
+		}
+		s.passwordResetURL = cfg.URL
+	}
+}
+
+// WithEmailVerification applies the email verification settings
+func WithEmailVerification(cfg *config.EmailVerificationConfig) Option {
+	return func(s *service) {
+		s.emailVerification = *cfg
+	}
+}
+
+// WithPasswordPolicy checks new passwords against policy
+func WithPasswordPolicy(policy *PasswordPolicy) Option {
+	return func(s *service) {
+		s.passwordPolicy = policy
+	}
+}
+
+// WithPasswordHasher hashes passwords with hasher
+func WithPasswordHasher(hasher PasswordHasher) Option {
+	return func(s *service) {
+		s.hasher = hasher
+	}
+}
+
+// WithLoginGuard throttles failed logins and second-factor checks with guard
+func WithLoginGuard(guard *auth.LoginGuard) Option {
+	return func(s *service) {
+		s.loginGuard = guard
+	}
+}
+
+// WithMFA applies the two-factor authentication settings
+func WithMFA(cfg *config.MFAConfig) Option {
+	return func(s *service) {
+		s.mfa = *cfg
+	}
+}
+
+// WithAPIKeys limits personal API keys with the given settings
+func WithAPIKeys(cfg *config.APIKeyConfig) Option {
+	return func(s *service) {
+		s.apiKeys = *cfg
+	}
+}
+
+// WithImpersonation applies the admin impersonation settings
+func WithImpersonation(cfg *config.ImpersonationConfig) Option {
+	return func(s *service) {
+		s.impersonationTTL = cfg.GetTokenTTL()
+	}
+}
+
+// WithAuditRecorder records logins, account changes and role changes with auditor
+func WithAuditRecorder(auditor audit.Recorder) Option {
+	return func(s *service) {
+		s.auditor = auditor
+	}
 }
 
 // NewService creates a new user service
-func NewService(repo Repository) Service {
-	return &service{
-		repo: repo,
+// By default emails are written to the application log, email verification is disabled, passwords are
+// checked against the default policy and hashed with bcrypt (cost 13), login attempts are not throttled,
+// two-factor authentication is optional and audit events are discarded
+func NewService(repo Repository, opts ...Option) Service {
+	s := &service{
+		repo:             repo,
+		mailer:           mail.NewLogSender(nil, ""),
+		passwordResetTTL: defaultPasswordResetTTL,
+		passwordPolicy:   NewPasswordPolicy(&config.PasswordPolicyConfig{}),
+		hasher:           NewPasswordHasher(&config.PasswordHashConfig{}),
+		loginGuard:       auth.NewLoginGuard(&config.LoginProtectionConfig{}, auth.NewMemoryLoginAttemptStore()),
+		impersonationTTL: (&config.ImpersonationConfig{}).GetTokenTTL(),
+		permissionCache:  newPermissionCache(permissionCacheTTL),
+		auditor:          audit.Discard,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.emailVerification.TokenTTL <= 0 {
+		s.emailVerification.TokenTTL = defaultEmailVerificationTTL
+	}
+	return s
 }
 
 // RegisterUser registers a new user
@@ -52,7 +209,11 @@ func (s *service) RegisterUser(ctx context.Context, req RegisterRequest) (*User,
 		return nil, ErrEmailExists
 	}
 
-	hashedPassword, err := hashPassword(req.Password)
+	if err := s.passwordPolicy.Validate(req.Password, req.Email, req.Name); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -69,6 +230,10 @@ func (s *service) RegisterUser(ctx context.Context, req RegisterRequest) (*User,
 			return fmt.Errorf("failed to create user: %w", err)
 		}
 
+		// restrict_roles 策略下默认角色在邮箱验证后授予
+		if s.emailVerification.GetPolicy() == config.EmailVerificationPolicyRestrictRoles {
+			return nil
+		}
 		if err := s.repo.AssignRole(txCtx, user.ID, RoleUser); err != nil {
 			return fmt.Errorf("failed to assign default role: %w", err)
 		}
@@ -89,26 +254,107 @@ func (s *service) RegisterUser(ctx context.Context, req RegisterRequest) (*User,
 		return nil, fmt.Errorf("failed to reload user: user not found after creation")
 	}
 
+	event := userEvent(audit.ActionUserCreate, user.ID)
+	event.Changes = audit.Diff(nil, auditUserFields(user))
+	s.recordAudit(ctx, event)
+
+	if s.emailVerification.Enabled {
+		// WHY: 用户已创建，邮件发送失败不应让注册失败；用户可重新请求验证邮件
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			slog.WarnContext(ctx, "Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
 	return user, nil
 }
 
 // AuthenticateUser authenticates a user with email and password
+// Returns *auth.LoginBlockedError when the account or client IP is throttled after failed attempts
 func (s *service) AuthenticateUser(ctx context.Context, req LoginRequest) (*User, error) {
+	clientInfo, _ := auth.ClientInfoFromContext(ctx)
+	if err := s.loginGuard.Check(ctx, req.Email, clientInfo.IPAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 	if user == nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.loginFailed(ctx, nil, req.Email, clientInfo.IPAddress)
+	}
+
+	if err := s.hasher.Verify(user.PasswordHash, req.Password); err != nil {
+		return nil, s.loginFailed(ctx, user, req.Email, clientInfo.IPAddress)
 	}
 
-	if err := verifyPassword(user.PasswordHash, req.Password); err != nil {
-		return nil, ErrInvalidCredentials
+	if err := s.loginGuard.RecordSuccess(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckLoginAllowed(user); err != nil {
+		return nil, err
 	}
 
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+
+	event := userEvent(audit.ActionLogin, user.ID)
+	event.ActorID = user.ID
+	event.Metadata = map[string]interface{}{"method": "password"}
+	s.recordAudit(ctx, event)
+
 	return user, nil
 }
 
+// loginFailed records a failed login and returns the error to report for it
+// user is nil when no account has the email
+func (s *service) loginFailed(ctx context.Context, user *User, email, ip string) error {
+	event := audit.Event{Action: audit.ActionLoginFailed, TargetType: audit.TargetUser, Metadata: map[string]interface{}{"email": email}}
+	if user != nil {
+		event.TargetID = audit.ID(user.ID)
+	}
+	s.recordAudit(ctx, event)
+
+	if err := s.loginGuard.RecordFailure(ctx, email, ip); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// UnlockUser clears failed login attempts and any lock on a user's account
+func (s *service) UnlockUser(ctx context.Context, id uint) error {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.loginGuard.Unlock(ctx, user.Email); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, userEvent(audit.ActionUserUnlock, user.ID))
+	return nil
+}
+
+// rehashPassword upgrades a hash made with an outdated algorithm or parameters.
+// Failures are only logged: the user has already authenticated, and the next login retries.
+func (s *service) rehashPassword(ctx context.Context, user *User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to rehash password", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := s.repo.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, hashedPassword); err != nil {
+		slog.WarnContext(ctx, "Failed to store rehashed password", "user_id", user.ID, "error", err)
+		return
+	}
+	user.PasswordHash = hashedPassword
+}
+
 // GetUserByID retrieves a user by ID
 func (s *service) GetUserByID(ctx context.Context, id uint) (*User, error) {
 	user, err := s.repo.FindByID(ctx, id)
@@ -131,10 +377,12 @@ func (s *service) UpdateUser(ctx context.Context, id uint, req UpdateUserRequest
 		return nil, ErrUserNotFound
 	}
 
+	before := auditUserFields(user)
 	if req.Name != "" {
 		user.Name = req.Name
 	}
-	if req.Email != "" {
+	emailChanged := false
+	if req.Email != "" && req.Email != user.Email {
 		existingUser, err := s.repo.FindByEmail(ctx, req.Email)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check existing email: %w", err)
@@ -143,23 +391,117 @@ func (s *service) UpdateUser(ctx context.Context, id uint, req UpdateUserRequest
 			return nil, ErrEmailExists
 		}
 		user.Email = req.Email
+		emailChanged = true
+		if s.emailVerification.Enabled {
+			user.EmailVerifiedAt = nil
+		}
 	}
 
 	if err := s.repo.Update(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	if changes := audit.Diff(before, auditUserFields(user)); changes != nil {
+		event := userEvent(audit.ActionUserUpdate, user.ID)
+		event.Changes = changes
+		s.recordAudit(ctx, event)
+	}
+
+	if emailChanged && s.emailVerification.Enabled {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			slog.WarnContext(ctx, "Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
 	return user, nil
 }
 
-// DeleteUser deletes a user
-func (s *service) DeleteUser(ctx context.Context, id uint) error {
-	if err := s.repo.Delete(ctx, id); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+// ChangePassword replaces a user's password after verifying the current one
+// Returns *auth.LoginBlockedError when the account is throttled after failed attempts
+// WHY: 修改密码同样验证当前密码，不限流时被盗的会话可以绕过登录锁定穷举密码；失败与登录计入同一账户
+func (s *service) ChangePassword(ctx context.Context, id uint, currentPassword, newPassword string) (*User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.loginGuard.Check(ctx, user.Email, ""); err != nil {
+		return nil, err
+	}
+	if err := s.hasher.Verify(user.PasswordHash, currentPassword); err != nil {
+		if err := s.loginGuard.RecordFailure(ctx, user.Email, ""); err != nil {
+			return nil, err
+		}
+		return nil, ErrIncorrectPassword
+	}
+	if err := s.loginGuard.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	if newPassword == currentPassword {
+		return nil, ErrPasswordUnchanged
+	}
+	if err := s.passwordPolicy.Validate(newPassword, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.PasswordHash = hashedPassword
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	s.recordAudit(ctx, userEvent(audit.ActionPasswordChange, user.ID))
+	return user, nil
+}
+
+// DeleteUser deletes a user on behalf of actorID
+// Admins cannot delete their own account, and the last admin cannot be deleted
+func (s *service) DeleteUser(ctx context.Context, actorID, id uint) error {
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() && actorID == id {
+		return ErrSelfDeletion
+	}
+
+	err = s.repo.Transaction(ctx, func(txCtx context.Context) error {
+		if user.IsAdmin() {
+			role, err := s.repo.FindRoleByName(txCtx, RoleAdmin)
+			if err != nil {
+				return fmt.Errorf("failed to find role: %w", err)
+			}
+			if role == nil {
+				return ErrRoleNotFound
+			}
+			count, err := s.repo.LockUsersWithRole(txCtx, role.ID)
+			if err != nil {
+				return fmt.Errorf("failed to count admins: %w", err)
+			}
+			if count <= 1 {
+				return ErrLastAdmin
+			}
+		}
+		return s.repo.Delete(txCtx, id)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrLastAdmin):
+			return err
+		case errors.Is(err, gorm.ErrRecordNotFound):
 			return ErrUserNotFound
 		}
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+
+	s.recordAudit(ctx, userEvent(audit.ActionUserDelete, id))
 	return nil
 }
 
@@ -177,7 +519,13 @@ func (s *service) ListUsers(ctx context.Context, filters UserFilterParams, page,
 	}
 
 	if filters.Role != "" && filters.Role != RoleUser && filters.Role != RoleAdmin {
-		return nil, 0, ErrInvalidRole
+		role, err := s.repo.FindRoleByName(ctx, filters.Role)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to find role: %w", err)
+		}
+		if role == nil {
+			return nil, 0, ErrInvalidRole
+		}
 	}
 
 	users, total, err := s.repo.ListAllUsers(ctx, filters, page, perPage)
@@ -206,21 +554,12 @@ func (s *service) PromoteToAdmin(ctx context.Context, userID uint) error {
 		return fmt.Errorf("failed to assign admin role: %w", err)
 	}
 
+	event := userEvent(audit.ActionRoleGrant, userID)
+	event.Changes = audit.Diff(
+		map[string]interface{}{"roles": audit.SortedStrings(user.GetRoleNames())},
+		map[string]interface{}{"roles": audit.SortedStrings(append(user.GetRoleNames(), RoleAdmin))},
+	)
+	event.Metadata = map[string]interface{}{"role": RoleAdmin}
+	s.recordAudit(ctx, event)
 	return nil
 }
-
-// hashPassword hashes a plain text password using bcrypt
-// 使用 cost 13 提供更高的安全性
-func hashPassword(password string) (string, error) {
-	const bcryptCost = 13 // 提升安全性，默认为 10
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedBytes), nil
-}
-
-// verifyPassword verifies a password against a hash
-func verifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
 )
 
 func TestNewService(t *testing.T) {
@@ -132,6 +137,8 @@ func TestService_AuthenticateUser(t *testing.T) {
 					PasswordHash: string(hashedPassword),
 				}
 				m.On("FindByEmail", mock.Anything, "john@example.com").Return(user, nil)
+				// bcrypt.DefaultCost 低于服务默认的 cost，登录成功后升级哈希
+				m.On("UpdatePasswordHash", mock.Anything, uint(1), string(hashedPassword), mock.AnythingOfType("string")).Return(nil)
 			},
 			expectedErr: nil,
 		},
@@ -333,6 +340,82 @@ func TestService_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestService_ChangePassword(t *testing.T) {
+	hasher := NewPasswordHasher(&config.PasswordHashConfig{})
+	currentHash, err := hasher.Hash("oldpassword123")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		userID      uint
+		current     string
+		newPassword string
+		setupMock   func(*MockRepository)
+		expectedErr error
+	}{
+		{
+			name:        "successful change",
+			userID:      1,
+			current:     "oldpassword123",
+			newPassword: "newpassword123",
+			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1, PasswordHash: currentHash}, nil)
+				m.On("Update", mock.Anything, mock.AnythingOfType("*user.User")).Return(nil)
+			},
+		},
+		{
+			name:        "user not found",
+			userID:      999,
+			current:     "oldpassword123",
+			newPassword: "newpassword123",
+			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(999)).Return(nil, nil)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name:        "incorrect current password",
+			userID:      1,
+			current:     "wrongpassword",
+			newPassword: "newpassword123",
+			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1, PasswordHash: currentHash}, nil)
+			},
+			expectedErr: ErrIncorrectPassword,
+		},
+		{
+			name:        "new password equals current",
+			userID:      1,
+			current:     "oldpassword123",
+			newPassword: "oldpassword123",
+			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1, PasswordHash: currentHash}, nil)
+			},
+			expectedErr: ErrPasswordUnchanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			tt.setupMock(mockRepo)
+
+			service := NewService(mockRepo)
+			user, err := service.ChangePassword(context.Background(), tt.userID, tt.current, tt.newPassword)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, user)
+			} else {
+				require.NoError(t, err)
+				assert.NoError(t, hasher.Verify(user.PasswordHash, tt.newPassword), "new password is hashed and stored")
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 func TestService_DeleteUser(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -344,6 +427,7 @@ func TestService_DeleteUser(t *testing.T) {
 			name:   "successful deletion",
 			userID: 1,
 			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1}, nil)
 				m.On("Delete", mock.Anything, uint(1)).Return(nil)
 			},
 			expectedErr: nil,
@@ -352,6 +436,15 @@ func TestService_DeleteUser(t *testing.T) {
 			name:   "user not found",
 			userID: 1,
 			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(nil, nil)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name:   "deleted concurrently",
+			userID: 1,
+			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1}, nil)
 				m.On("Delete", mock.Anything, uint(1)).Return(gorm.ErrRecordNotFound)
 			},
 			expectedErr: ErrUserNotFound,
@@ -360,6 +453,7 @@ func TestService_DeleteUser(t *testing.T) {
 			name:   "repository error",
 			userID: 1,
 			setupMock: func(m *MockRepository) {
+				m.On("FindByID", mock.Anything, uint(1)).Return(&User{ID: 1}, nil)
 				m.On("Delete", mock.Anything, uint(1)).Return(errors.New("delete error"))
 			},
 			expectedErr: errors.New("failed to delete user: delete error"),
@@ -372,7 +466,7 @@ func TestService_DeleteUser(t *testing.T) {
 			tt.setupMock(mockRepo)
 
 			service := NewService(mockRepo)
-			err := service.DeleteUser(context.Background(), tt.userID)
+			err := service.DeleteUser(context.Background(), 0, tt.userID)
 
 			if tt.expectedErr != nil {
 				assert.Error(t, err)
@@ -391,29 +485,31 @@ func TestService_DeleteUser(t *testing.T) {
 }
 
 func TestHashPassword(t *testing.T) {
+	hasher := NewPasswordHasher(&config.PasswordHashConfig{})
 	password := "testpassword123"
-	hashedPassword, err := hashPassword(password)
+	hashedPassword, err := hasher.Hash(password)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, hashedPassword)
 	assert.NotEqual(t, password, hashedPassword)
 
-	err = verifyPassword(hashedPassword, password)
+	err = hasher.Verify(hashedPassword, password)
 	assert.NoError(t, err)
 }
 
 func TestVerifyPassword(t *testing.T) {
+	hasher := NewPasswordHasher(&config.PasswordHashConfig{})
 	password := "testpassword123"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 
 	t.Run("correct password", func(t *testing.T) {
-		err := verifyPassword(string(hashedPassword), password)
+		err := hasher.Verify(string(hashedPassword), password)
 		assert.NoError(t, err)
 	})
 
 	t.Run("incorrect password", func(t *testing.T) {
-		err := verifyPassword(string(hashedPassword), "wrongpassword")
-		assert.Error(t, err)
+		err := hasher.Verify(string(hashedPassword), "wrongpassword")
+		assert.ErrorIs(t, err, ErrPasswordMismatch)
 	})
 }
 
@@ -504,9 +600,11 @@ func TestService_ListUsers(t *testing.T) {
 				Sort:  "created_at",
 				Order: "desc",
 			},
-			page:          1,
-			perPage:       20,
-			setupMocks:    func(m *MockRepository) {},
+			page:    1,
+			perPage: 20,
+			setupMocks: func(m *MockRepository) {
+				m.On("FindRoleByName", mock.Anything, "invalid_role").Return(nil, nil)
+			},
 			expectedUsers: nil,
 			expectedTotal: 0,
 			expectedErr:   ErrInvalidRole,
@@ -886,3 +984,71 @@ func TestService_UpdateUser_ErrorPaths(t *testing.T) {
 		})
 	}
 }
+
+func TestService_AuthenticateUser_LoginProtection(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+	guard := auth.NewLoginGuard(&config.LoginProtectionConfig{
+		Enabled:     true,
+		MaxAttempts: 2,
+		BaseDelay:   time.Nanosecond,
+		MaxDelay:    time.Nanosecond,
+	}, auth.NewMemoryLoginAttemptStore())
+	svc := NewService(repo, WithMailer(&recordingSender{}), WithPasswordHasher(NewPasswordHasher(&config.PasswordHashConfig{BcryptCost: bcrypt.MinCost})), WithLoginGuard(guard))
+	ctx := auth.WithClientInfo(context.Background(), "test-agent", "203.0.113.7")
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Locked User", Email: "locked@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond) // 等待纳秒级的渐进延迟结束
+		_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "locked@example.com", Password: "wrongpassword"})
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	var blocked *auth.LoginBlockedError
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "locked@example.com", Password: "password123"})
+	require.ErrorAs(t, err, &blocked, "the correct password is rejected while locked")
+	assert.True(t, blocked.Locked)
+
+	assert.ErrorIs(t, svc.UnlockUser(ctx, 9999), ErrUserNotFound)
+	require.NoError(t, svc.UnlockUser(ctx, user.ID))
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "locked@example.com", Password: "password123"})
+	assert.NoError(t, err)
+}
+
+func TestService_ChangePassword_LoginProtection(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+	guard := auth.NewLoginGuard(&config.LoginProtectionConfig{
+		Enabled:     true,
+		MaxAttempts: 2,
+		BaseDelay:   time.Nanosecond,
+		MaxDelay:    time.Nanosecond,
+	}, auth.NewMemoryLoginAttemptStore())
+	svc := NewService(repo, WithMailer(&recordingSender{}), WithPasswordHasher(NewPasswordHasher(&config.PasswordHashConfig{BcryptCost: bcrypt.MinCost})), WithLoginGuard(guard))
+	ctx := context.Background()
+
+	user, err := svc.RegisterUser(ctx, RegisterRequest{Name: "Change User", Email: "change@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond) // 等待纳秒级的渐进延迟结束
+		_, err = svc.ChangePassword(ctx, user.ID, "wrongpassword", "newpassword123")
+		require.ErrorIs(t, err, ErrIncorrectPassword)
+	}
+
+	var blocked *auth.LoginBlockedError
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "newpassword123")
+	require.ErrorAs(t, err, &blocked, "the correct current password is rejected while locked")
+	assert.True(t, blocked.Locked)
+
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "change@example.com", Password: "password123"})
+	assert.ErrorAs(t, err, &blocked, "failures count against the same account as logins")
+
+	require.NoError(t, svc.UnlockUser(ctx, user.ID))
+	_, err = svc.ChangePassword(ctx, user.ID, "password123", "newpassword123")
+	require.NoError(t, err)
+	_, err = svc.AuthenticateUser(ctx, LoginRequest{Email: "change@example.com", Password: "newpassword123"})
+	assert.NoError(t, err)
+}
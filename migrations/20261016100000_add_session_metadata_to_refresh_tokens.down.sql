@@ -0,0 +1,9 @@
+-- Migration: add_session_metadata_to_refresh_tokens (rollback)
+-- Description: Drops device metadata columns from refresh_tokens
+
+BEGIN;
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS user_agent;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: add_session_metadata_to_refresh_tokens
+-- Description: Records the device that created each refresh token so token families can be listed as sessions
+
+BEGIN;
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+
+COMMENT ON COLUMN refresh_tokens.user_agent IS 'User-Agent of the client that obtained the token';
+COMMENT ON COLUMN refresh_tokens.ip_address IS 'Client IP address that obtained the token';
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration: create_password_reset_tokens_table (rollback)
+-- Description: Drops password_reset_tokens table
+
+BEGIN;
+
+DROP TABLE IF EXISTS password_reset_tokens;
+
+COMMIT;
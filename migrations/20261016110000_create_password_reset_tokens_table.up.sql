@@ -0,0 +1,26 @@
+-- Migration: create_password_reset_tokens_table
+-- Description: Creates password_reset_tokens table for single-use, time-limited password reset tokens
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_expires_at ON password_reset_tokens(expires_at);
+
+COMMENT ON TABLE password_reset_tokens IS 'Single-use password reset tokens';
+COMMENT ON COLUMN password_reset_tokens.id IS 'Primary key';
+COMMENT ON COLUMN password_reset_tokens.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN password_reset_tokens.token_hash IS 'SHA256 hash of the reset token';
+COMMENT ON COLUMN password_reset_tokens.expires_at IS 'Expiration timestamp';
+COMMENT ON COLUMN password_reset_tokens.used_at IS 'Timestamp when token was used (NULL if unused)';
+COMMENT ON COLUMN password_reset_tokens.created_at IS 'Timestamp when token was created';
+
+COMMIT;
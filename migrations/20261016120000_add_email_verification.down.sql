@@ -0,0 +1,10 @@
+-- Migration: add_email_verification (rollback)
+-- Description: Drops email_verification_tokens table and users.email_verified_at
+
+BEGIN;
+
+DROP TABLE IF EXISTS email_verification_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+
+COMMIT;
@@ -0,0 +1,31 @@
+-- Migration: add_email_verification
+-- Description: Adds users.email_verified_at and the email_verification_tokens table
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing accounts predate email verification; treat them as verified so enabling a policy does not lock them out
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+COMMENT ON COLUMN users.email_verified_at IS 'Timestamp when the email address was verified (NULL if unverified)';
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_expires_at ON email_verification_tokens(expires_at);
+
+COMMENT ON TABLE email_verification_tokens IS 'Tokens proving ownership of a user email address';
+COMMENT ON COLUMN email_verification_tokens.id IS 'Primary key';
+COMMENT ON COLUMN email_verification_tokens.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN email_verification_tokens.token_hash IS 'SHA256 hash of the verification token';
+COMMENT ON COLUMN email_verification_tokens.expires_at IS 'Expiration timestamp';
+COMMENT ON COLUMN email_verification_tokens.created_at IS 'Timestamp when token was created';
+
+COMMIT;
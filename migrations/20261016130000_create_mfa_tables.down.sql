@@ -0,0 +1,10 @@
+-- Migration: create_mfa_tables (rollback)
+-- Description: Drops two-factor authentication tables
+
+BEGIN;
+
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+
+COMMIT;
@@ -0,0 +1,62 @@
+-- Migration: create_mfa_tables
+-- Description: Creates tables for TOTP two-factor authentication, recovery codes and pending login challenges
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE user_totp IS 'TOTP authenticator secrets, one per user';
+COMMENT ON COLUMN user_totp.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN user_totp.secret IS 'Base32 TOTP secret shared with the authenticator app';
+COMMENT ON COLUMN user_totp.confirmed_at IS 'Timestamp when enrollment was confirmed with a valid code (NULL while enrolling)';
+COMMENT ON COLUMN user_totp.last_used_step IS 'Time step of the last accepted code, codes for this or earlier steps are rejected';
+COMMENT ON COLUMN user_totp.created_at IS 'Timestamp when enrollment started';
+COMMENT ON COLUMN user_totp.updated_at IS 'Timestamp when the row was last updated';
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+COMMENT ON TABLE mfa_recovery_codes IS 'Single-use recovery codes for accounts with two-factor authentication';
+COMMENT ON COLUMN mfa_recovery_codes.id IS 'Primary key';
+COMMENT ON COLUMN mfa_recovery_codes.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN mfa_recovery_codes.code_hash IS 'SHA256 hash of the normalized recovery code';
+COMMENT ON COLUMN mfa_recovery_codes.used_at IS 'Timestamp when the code was used (NULL if unused)';
+COMMENT ON COLUMN mfa_recovery_codes.created_at IS 'Timestamp when the code was generated';
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    enrollment BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges(user_id);
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+COMMENT ON TABLE mfa_challenges IS 'Pending logins waiting for a second factor';
+COMMENT ON COLUMN mfa_challenges.id IS 'Primary key';
+COMMENT ON COLUMN mfa_challenges.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN mfa_challenges.token_hash IS 'SHA256 hash of the mfa pending token';
+COMMENT ON COLUMN mfa_challenges.enrollment IS 'TRUE when the user must enroll TOTP before completing login';
+COMMENT ON COLUMN mfa_challenges.attempts IS 'Number of wrong codes submitted for this challenge';
+COMMENT ON COLUMN mfa_challenges.expires_at IS 'Expiration timestamp';
+COMMENT ON COLUMN mfa_challenges.created_at IS 'Timestamp when the challenge was created';
+
+COMMIT;
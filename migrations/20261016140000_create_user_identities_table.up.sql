@@ -0,0 +1,27 @@
+-- Migration: create_user_identities_table
+-- Description: Creates user_identities table linking OpenID Connect provider accounts to users
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_user_identities_provider_subject UNIQUE (provider, subject),
+    CONSTRAINT uq_user_identities_user_provider UNIQUE (user_id, provider)
+);
+
+COMMENT ON TABLE user_identities IS 'External OpenID Connect accounts linked to users, at most one per provider and user';
+COMMENT ON COLUMN user_identities.id IS 'Primary key';
+COMMENT ON COLUMN user_identities.user_id IS 'Foreign key to users table';
+COMMENT ON COLUMN user_identities.provider IS 'Provider name from the oidc.providers configuration';
+COMMENT ON COLUMN user_identities.subject IS 'Stable subject identifier (sub claim) issued by the provider';
+COMMENT ON COLUMN user_identities.email IS 'Email reported by the provider at the last login, for display only';
+COMMENT ON COLUMN user_identities.created_at IS 'Timestamp when the identity was linked';
+COMMENT ON COLUMN user_identities.updated_at IS 'Timestamp of the last login with this identity';
+
+COMMIT;
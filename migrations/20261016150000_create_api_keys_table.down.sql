@@ -0,0 +1,8 @@
+-- Migration: create_api_keys_table (rollback)
+-- Description: Drops api_keys table
+
+BEGIN;
+
+DROP TABLE IF EXISTS api_keys;
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- Migration: create_api_keys_table
+-- Description: Creates api_keys table for personal API keys used by scripts and integrations
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(45),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_api_keys_key_hash UNIQUE (key_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+COMMENT ON TABLE api_keys IS 'Personal API keys; the secret is only shown at creation';
+COMMENT ON COLUMN api_keys.id IS 'Primary key';
+COMMENT ON COLUMN api_keys.user_id IS 'Foreign key to users table, the owner of the key';
+COMMENT ON COLUMN api_keys.name IS 'Label chosen by the user';
+COMMENT ON COLUMN api_keys.prefix IS 'First characters of the key, stored in clear to tell keys apart';
+COMMENT ON COLUMN api_keys.key_hash IS 'SHA-256 hash of the key';
+COMMENT ON COLUMN api_keys.scopes IS 'Space-separated granted scopes (read, write)';
+COMMENT ON COLUMN api_keys.expires_at IS 'Expiration timestamp, NULL if the key never expires';
+COMMENT ON COLUMN api_keys.last_used_at IS 'Last time the key authenticated a request, recorded at most once per minute';
+COMMENT ON COLUMN api_keys.last_used_ip IS 'Client IP of the last use';
+COMMENT ON COLUMN api_keys.created_at IS 'Timestamp when the key was created';
+COMMENT ON COLUMN api_keys.updated_at IS 'Timestamp when the name or scopes were last changed';
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration: create_oauth_clients_table (rollback)
+-- Description: Drops oauth_clients table
+
+BEGIN;
+
+DROP TABLE IF EXISTS oauth_clients;
+
+COMMIT;
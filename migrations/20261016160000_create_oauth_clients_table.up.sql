@@ -0,0 +1,26 @@
+-- Migration: create_oauth_clients_table
+-- Description: Creates oauth_clients table for service clients using the client credentials grant
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    secret_hash VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    scopes VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_oauth_clients_client_id UNIQUE (client_id)
+);
+
+COMMENT ON TABLE oauth_clients IS 'OAuth2 service clients; the secret is only shown at registration';
+COMMENT ON COLUMN oauth_clients.id IS 'Primary key';
+COMMENT ON COLUMN oauth_clients.client_id IS 'Public client identifier used with the token endpoint';
+COMMENT ON COLUMN oauth_clients.secret_hash IS 'SHA-256 hash of the client secret';
+COMMENT ON COLUMN oauth_clients.name IS 'Label chosen by the administrator';
+COMMENT ON COLUMN oauth_clients.scopes IS 'Space-separated scopes the client may request';
+COMMENT ON COLUMN oauth_clients.created_at IS 'Timestamp when the client was registered';
+COMMENT ON COLUMN oauth_clients.updated_at IS 'Timestamp when the client was last updated';
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Migration: create_permissions_tables (rollback)
+-- Description: Drops role_permissions and permissions tables
+
+BEGIN;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+
+COMMIT;
@@ -0,0 +1,44 @@
+-- Migration: create_permissions_tables
+-- Description: Creates permissions and role_permissions tables and grants the built-in permissions to the admin role
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_permissions_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions(permission_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'View user accounts'),
+    ('users:write', 'Modify and delete user accounts'),
+    ('roles:read', 'View roles and permissions'),
+    ('roles:write', 'Manage roles and their permissions')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles CROSS JOIN permissions
+WHERE roles.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+COMMENT ON TABLE permissions IS 'Permission catalog; names have the form resource:action';
+COMMENT ON COLUMN permissions.id IS 'Primary key';
+COMMENT ON COLUMN permissions.name IS 'Permission name, e.g. users:read';
+COMMENT ON COLUMN permissions.description IS 'Human readable description';
+COMMENT ON COLUMN permissions.created_at IS 'Timestamp when the permission was added';
+
+COMMENT ON TABLE role_permissions IS 'Permissions granted to each role';
+COMMENT ON COLUMN role_permissions.role_id IS 'Foreign key to roles table';
+COMMENT ON COLUMN role_permissions.permission_id IS 'Foreign key to permissions table';
+
+COMMIT;
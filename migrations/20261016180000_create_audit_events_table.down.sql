@@ -0,0 +1,8 @@
+-- Migration: create_audit_events_table (rollback)
+-- Description: Drops audit_events table
+
+BEGIN;
+
+DROP TABLE IF EXISTS audit_events;
+
+COMMIT;
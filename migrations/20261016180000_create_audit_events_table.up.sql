@@ -0,0 +1,40 @@
+-- Migration: create_audit_events_table
+-- Description: Creates audit_events table recording who changed what, for the database audit sink
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id VARCHAR(36) PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    actor_id BIGINT NOT NULL DEFAULT 0,
+    on_behalf_of BIGINT NOT NULL DEFAULT 0,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    changes JSONB,
+    metadata JSONB,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+
+COMMENT ON TABLE audit_events IS 'Audit log of security-relevant actions; rows are never updated';
+COMMENT ON COLUMN audit_events.id IS 'Event ID (time-ordered UUID)';
+COMMENT ON COLUMN audit_events.action IS 'Action name, e.g. user.role_grant or auth.token_reuse';
+COMMENT ON COLUMN audit_events.actor_id IS 'User who performed the action (the admin during impersonation), 0 when anonymous';
+COMMENT ON COLUMN audit_events.on_behalf_of IS 'Impersonated user the action was performed as, 0 otherwise';
+COMMENT ON COLUMN audit_events.target_type IS 'Kind of resource acted on: user, role, session or api_key';
+COMMENT ON COLUMN audit_events.target_id IS 'ID or name of the resource acted on';
+COMMENT ON COLUMN audit_events.changes IS 'Changed fields as {"field": {"before": ..., "after": ...}}';
+COMMENT ON COLUMN audit_events.metadata IS 'Additional action details';
+COMMENT ON COLUMN audit_events.request_id IS 'X-Request-ID of the request that triggered the action';
+COMMENT ON COLUMN audit_events.ip_address IS 'Client IP address';
+COMMENT ON COLUMN audit_events.user_agent IS 'Client User-Agent header';
+COMMENT ON COLUMN audit_events.created_at IS 'Timestamp when the action happened';
+
+COMMIT;
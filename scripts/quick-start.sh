@@ -64,6 +64,23 @@ else
     echo -e "${GREEN}✅ JWT_SECRET already configured${NC}"
 fi
 
+echo ""
+echo "🔐 Checking GATEWAY_SIGNING_SECRET..."
+
+# gateway 和 both 模式必须配置签名密钥，否则配置校验失败
+if ! grep -q "^GATEWAY_SIGNING_SECRET=.\+" .env 2>/dev/null; then
+    echo -e "${YELLOW}⚡ Generating secure GATEWAY_SIGNING_SECRET...${NC}"
+    if make generate-gateway-secret > /dev/null 2>&1; then
+        echo -e "${GREEN}✅ GATEWAY_SIGNING_SECRET generated and added to .env${NC}"
+    else
+        echo -e "${RED}❌ Failed to generate GATEWAY_SIGNING_SECRET${NC}"
+        echo -e "${YELLOW}Please run 'make generate-gateway-secret' manually to see the error${NC}"
+        exit 1
+    fi
+else
+    echo -e "${GREEN}✅ GATEWAY_SIGNING_SECRET already configured${NC}"
+fi
+
 echo ""
 echo "Reading .env file..."
 echo ""
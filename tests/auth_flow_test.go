@@ -0,0 +1,1355 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
+	"github.com/yeegeek/go-rest-api-starter/internal/auth"
+	"github.com/yeegeek/go-rest-api-starter/internal/config"
+	"github.com/yeegeek/go-rest-api-starter/internal/db"
+	"github.com/yeegeek/go-rest-api-starter/internal/mail"
+	"github.com/yeegeek/go-rest-api-starter/internal/middleware"
+	"github.com/yeegeek/go-rest-api-starter/internal/oauth"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc"
+	"github.com/yeegeek/go-rest-api-starter/internal/oidc/oidctest"
+	"github.com/yeegeek/go-rest-api-starter/internal/server"
+	"github.com/yeegeek/go-rest-api-starter/internal/totp"
+	"github.com/yeegeek/go-rest-api-starter/internal/user"
+)
+
+// setupJWTTestRouter creates a router that validates Bearer tokens itself (no gateway)
+func setupJWTTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userRepo := user.NewRepository(database)
+	userService := user.NewService(userRepo)
+	userHandler := user.NewHandler(userService, authService)
+
+	return server.SetupRouter(userHandler, authService, testCfg, database)
+}
+
+// doJSON sends a JSON request and decodes the response envelope
+func doJSON(t *testing.T, router *gin.Engine, method, path, accessToken string, payload interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	var body bytes.Buffer
+	if payload != nil {
+		require.NoError(t, json.NewEncoder(&body).Encode(payload))
+	}
+
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+	}
+	return w.Code, response
+}
+
+// tokensFrom extracts the access and refresh tokens from a success response
+func tokensFrom(t *testing.T, response map[string]interface{}) (string, string) {
+	t.Helper()
+
+	data, ok := response["data"].(map[string]interface{})
+	require.True(t, ok, "expected data object in response")
+	accessToken, _ := data["access_token"].(string)
+	refreshToken, _ := data["refresh_token"].(string)
+	require.NotEmpty(t, accessToken)
+	require.NotEmpty(t, refreshToken)
+	return accessToken, refreshToken
+}
+
+func TestAuthFlow_RegisterLoginRefreshReuse(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	credentials := map[string]string{
+		"email":    "flow@example.com",
+		"password": "flowpassword123",
+	}
+
+	status, _ := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Flow User",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	accessToken, refreshToken := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	me := response["data"].(map[string]interface{})
+	assert.Equal(t, credentials["email"], me["email"])
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	require.Equal(t, http.StatusOK, status)
+	rotatedAccessToken, rotatedRefreshToken := tokensFrom(t, response)
+	assert.NotEqual(t, refreshToken, rotatedRefreshToken)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/auth/me", rotatedAccessToken, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// 重放已使用的刷新令牌：触发重用检测并撤销整个令牌家族
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, false, response["success"])
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": rotatedRefreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthFlow_Logout(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Logout User",
+		"email":    "logout@example.com",
+		"password": "logoutpassword123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, refreshToken := tokensFrom(t, response)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/logout", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status, "logout requires authentication")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/logout", accessToken, map[string]string{
+		"refresh_token": refreshToken,
+	})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "access token is revoked on logout")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthFlow_LogoutAll(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	credentials := map[string]string{
+		"email":    "everywhere@example.com",
+		"password": "everywhere123",
+	}
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Everywhere User",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, firstRefreshToken := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	_, secondRefreshToken := tokensFrom(t, response)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/logout-all", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "logout-all requires authentication")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/logout-all", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "access token is revoked on logout-all")
+
+	for _, refreshToken := range []string{firstRefreshToken, secondRefreshToken} {
+		status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+			"refresh_token": refreshToken,
+		})
+		assert.Equal(t, http.StatusUnauthorized, status)
+	}
+}
+
+func TestAuthFlow_Sessions(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	credentials := map[string]string{
+		"email":    "sessions@example.com",
+		"password": "sessions123",
+	}
+
+	status, _ := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Session User",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	accessToken, refreshToken := tokensFrom(t, response)
+
+	// 注册和登录各产生一个会话
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/sessions", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	sessions, ok := response["data"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, sessions, 2)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	require.Equal(t, http.StatusOK, status)
+	_, rotatedRefreshToken := tokensFrom(t, response)
+
+	// 会话按最近使用时间倒序，刚刷新的会话排在第一位
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/sessions", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	family := response["data"].([]interface{})[0].(map[string]interface{})["id"].(string)
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/users/me/sessions/not-a-uuid", accessToken, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/users/me/sessions/"+family, accessToken, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": rotatedRefreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status, "refresh token of a revoked session is rejected")
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/sessions", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, response["data"], 1)
+}
+
+// outbox records emails so tests can follow links
+type outbox struct {
+	messages []mail.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mail.Message) error {
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func TestAuthFlow_PasswordReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	sent := &outbox{}
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database), user.WithMailer(sent), user.WithPasswordReset(&config.PasswordResetConfig{
+		URL: "https://app.example.com/reset-password",
+	}))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Forgetful User",
+		"email":    "forgetful@example.com",
+		"password": "forgotten123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, refreshToken := tokensFrom(t, response)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/password-reset/request", "", map[string]string{
+		"email": "nobody@example.com",
+	})
+	assert.Equal(t, http.StatusOK, status, "unknown emails get the same response")
+	assert.Empty(t, sent.messages)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/password-reset/request", "", map[string]string{
+		"email": "forgetful@example.com",
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, sent.messages, 1)
+
+	link := regexp.MustCompile(`https://\S+`).FindString(sent.messages[0].Body)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	resetToken := parsed.Query().Get("token")
+	require.NotEmpty(t, resetToken)
+
+	confirm := map[string]string{"token": resetToken, "new_password": "remembered123"}
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/password-reset/confirm", "", confirm)
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/password-reset/confirm", "", confirm)
+	assert.Equal(t, http.StatusBadRequest, status, "reset tokens are single-use")
+
+	// 重置后旧会话全部失效
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": refreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "forgetful@example.com",
+		"password": "forgotten123",
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "forgetful@example.com",
+		"password": "remembered123",
+	})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestAuthFlow_ChangePassword(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Careful User",
+		"email":    "careful@example.com",
+		"password": "original123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "careful@example.com",
+		"password": "original123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	_, otherRefreshToken := tokensFrom(t, response)
+
+	status, _ = doJSON(t, router, http.MethodPut, "/api/v1/users/me/password", accessToken, map[string]string{
+		"current_password": "wrong-password",
+		"new_password":     "changed123",
+	})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, response = doJSON(t, router, http.MethodPut, "/api/v1/users/me/password", accessToken, map[string]string{
+		"current_password": "original123",
+		"new_password":     "changed123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	data := response["data"].(map[string]interface{})
+	newAccessToken, newRefreshToken := tokensFrom(t, map[string]interface{}{"data": data["tokens"]})
+
+	// 其他会话与旧令牌失效，当前设备使用新签发的令牌继续访问
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": otherRefreshToken,
+	})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", newAccessToken, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, response = doJSON(t, router, http.MethodPut, "/api/v1/users/me/password", newAccessToken, map[string]interface{}{
+		"current_password":      "changed123",
+		"new_password":          "changedagain123",
+		"revoke_other_sessions": false,
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Nil(t, response["data"].(map[string]interface{})["tokens"])
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{
+		"refresh_token": newRefreshToken,
+	})
+	assert.Equal(t, http.StatusOK, status, "sessions are kept when revoke_other_sessions is false")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    "careful@example.com",
+		"password": "changedagain123",
+	})
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestAuthFlow_EmailVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	sent := &outbox{}
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database), user.WithMailer(sent), user.WithEmailVerification(&config.EmailVerificationConfig{
+		Enabled: true,
+		Policy:  config.EmailVerificationPolicyBlockLogin,
+		URL:     "https://api.example.com/api/v1/public/verify-email",
+	}))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	credentials := map[string]string{"email": "unverified@example.com", "password": "unverified123"}
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Unverified User",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+	})
+	require.Equal(t, http.StatusOK, status)
+	data := response["data"].(map[string]interface{})
+	assert.NotContains(t, data, "access_token", "no tokens before verification")
+	assert.Equal(t, false, data["user"].(map[string]interface{})["email_verified"])
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/verify-email/resend", "", map[string]string{
+		"email": credentials["email"],
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, sent.messages, 2)
+
+	// 重新发送后只有最新的链接有效
+	tokenFrom := func(body string) string {
+		parsed, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(body))
+		require.NoError(t, err)
+		return parsed.Query().Get("token")
+	}
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/public/verify-email?token="+url.QueryEscape(tokenFrom(sent.messages[0].Body)), "", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/public/verify-email?token="+url.QueryEscape(tokenFrom(sent.messages[1].Body)), "", nil)
+	require.Equal(t, http.StatusOK, status)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, response["data"].(map[string]interface{})["email_verified"])
+}
+
+func TestAuthFlow_EmailVerificationRestrictRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	sent := &outbox{}
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database), user.WithMailer(sent), user.WithEmailVerification(&config.EmailVerificationConfig{
+		Enabled: true,
+		Policy:  config.EmailVerificationPolicyRestrictRoles,
+		URL:     "https://api.example.com/api/v1/public/verify-email",
+	}))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+	ctx := context.Background()
+
+	login := func(email, password string) string {
+		status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, status)
+		accessToken, _ := tokensFrom(t, response)
+		return accessToken
+	}
+
+	admin, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Admin", Email: "admin@example.com", Password: "adminpassword123"})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(ctx, admin.ID))
+	adminToken := login("admin@example.com", "adminpassword123")
+
+	// 授予默认角色一个权限，验证未验证邮箱的账户拿不到它
+	status, _ := doJSON(t, router, http.MethodPut, "/api/v1/admin/roles/user", adminToken, map[string]interface{}{
+		"description": "Default role", "permissions": []string{"roles:read"},
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "Unverified User", "email": "unverified@example.com", "password": "unverified123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, sent.messages, 2)
+
+	unverifiedToken := login("unverified@example.com", "unverified123")
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", unverifiedToken, nil)
+	assert.Equal(t, http.StatusOK, status, "restrict_roles still allows login")
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles", unverifiedToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "unverified accounts do not get the default role")
+
+	parsed, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(sent.messages[1].Body))
+	require.NoError(t, err)
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/public/verify-email?token="+url.QueryEscape(parsed.Query().Get("token")), "", nil)
+	require.Equal(t, http.StatusOK, status)
+
+	verifiedToken := login("unverified@example.com", "unverified123")
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles", verifiedToken, nil)
+	assert.Equal(t, http.StatusOK, status, "the default role is granted once the email is verified")
+}
+
+func TestAuthFlow_LoginLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const gatewaySecret = "gateway-signing-secret-32-chars!"
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeBoth
+	testCfg.Gateway.SigningSecret = gatewaySecret
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	guard := auth.NewLoginGuard(&config.LoginProtectionConfig{
+		Enabled:     true,
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	}, auth.NewMemoryLoginAttemptStore())
+	userService := user.NewService(user.NewRepository(database), user.WithMailer(&outbox{}), user.WithLoginGuard(guard))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "Lockout User",
+		"email":    "lockout@example.com",
+		"password": "lockout123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	userID := int(response["data"].(map[string]interface{})["user"].(map[string]interface{})["id"].(float64))
+
+	wrong := map[string]string{"email": "lockout@example.com", "password": "wrongpassword"}
+	for i := 0; i < 3; i++ {
+		time.Sleep(2 * time.Millisecond)
+		status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", wrong)
+		require.Equal(t, http.StatusUnauthorized, status)
+	}
+
+	correct := map[string]string{"email": "lockout@example.com", "password": "lockout123"}
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", correct)
+	require.Equal(t, http.StatusTooManyRequests, status)
+	errorInfo := response["error"].(map[string]interface{})
+	assert.Equal(t, "ACCOUNT_LOCKED", errorInfo["code"])
+	assert.Equal(t, float64(config.DefaultLoginLockDuration/time.Second), errorInfo["retry_after"])
+
+	// 管理员通过网关头调用解锁端点
+	unlockPath := fmt.Sprintf("/api/v1/admin/users/%d/unlock", userID)
+	timestamp := time.Now().Unix()
+	req, _ := http.NewRequest(http.MethodPost, unlockPath, nil)
+	req.Header.Set(middleware.HeaderUserID, "999")
+	req.Header.Set(middleware.HeaderUserRole, "admin")
+	req.Header.Set(middleware.HeaderUserTimestamp, fmt.Sprint(timestamp))
+	req.Header.Set(middleware.HeaderUserSignature, middleware.GatewaySignature(gatewaySecret, http.MethodPost, unlockPath, "999", "admin", timestamp))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", correct)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// totpCode returns the code an authenticator app would show for secret at the given time
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := totp.DecodeSecret(secret)
+	require.NoError(t, err)
+	code, err := totp.Code(key, at, totp.Options{})
+	require.NoError(t, err)
+	return code
+}
+
+func TestAuthFlow_TOTP(t *testing.T) {
+	router := setupJWTTestRouter(t)
+
+	credentials := map[string]string{"email": "totp@example.com", "password": "totppassword123"}
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name":     "TOTP User",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/users/me/mfa/totp", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	enrollment := response["data"].(map[string]interface{})
+	secret := enrollment["secret"].(string)
+	assert.Contains(t, enrollment["otpauth_uri"], "otpauth://totp/")
+
+	now := time.Now()
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/users/me/mfa/totp/confirm", accessToken, map[string]string{"code": totpCode(t, secret, now)})
+	require.Equal(t, http.StatusOK, status)
+	recoveryCodes := response["data"].(map[string]interface{})["recovery_codes"].([]interface{})
+	assert.Len(t, recoveryCodes, 10)
+
+	// 启用两步验证后，登录只返回待验证令牌
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	pending := response["data"].(map[string]interface{})
+	assert.Equal(t, true, pending["mfa_required"])
+	assert.NotContains(t, pending, "access_token")
+	mfaToken := pending["mfa_token"].(string)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", mfaToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "mfa pending tokens are not access tokens")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/mfa/verify", "", map[string]string{"mfa_token": mfaToken, "code": "000000"})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/mfa/verify", "", map[string]string{
+		"mfa_token": mfaToken,
+		"code":      totpCode(t, secret, now.Add(totp.DefaultPeriod)),
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ = tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/mfa", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"enabled": true, "required": false, "recovery_codes_remaining": float64(10)}, response["data"])
+}
+
+func TestAuthFlow_AdminMFAEnrollmentRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database), user.WithMailer(&outbox{}), user.WithMFA(&config.MFAConfig{RequireForAdmins: true}))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	credentials := map[string]string{"email": "admin@example.com", "password": "adminpassword123"}
+	admin, err := userService.RegisterUser(context.Background(), user.RegisterRequest{Name: "Admin", Email: credentials["email"], Password: credentials["password"]})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(context.Background(), admin.ID))
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	pending := response["data"].(map[string]interface{})
+	assert.Equal(t, true, pending["enrollment_required"])
+	mfaToken := pending["mfa_token"].(string)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/mfa/enroll", "", map[string]string{"mfa_token": mfaToken})
+	require.Equal(t, http.StatusOK, status)
+	secret := response["data"].(map[string]interface{})["secret"].(string)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/mfa/enroll/confirm", "", map[string]string{
+		"mfa_token": mfaToken,
+		"code":      totpCode(t, secret, time.Now()),
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+	assert.Len(t, response["data"].(map[string]interface{})["recovery_codes"], 10)
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/users/me/mfa/totp", accessToken, map[string]string{"code": totpCode(t, secret, time.Now().Add(totp.DefaultPeriod))})
+	assert.Equal(t, http.StatusForbidden, status, "admins cannot disable 2FA the policy requires")
+}
+
+func TestAuthFlow_OIDC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	fake := oidctest.NewProvider(t, "api-client", "api-secret")
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+	testCfg.OIDC = config.OIDCConfig{
+		Enabled:   true,
+		Providers: []config.OIDCProviderConfig{fake.Config("fake", "http://localhost:8080/api/v1/auth/oidc/fake/callback")},
+	}
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	oidcClient := oidc.NewClient(&testCfg.OIDC, oidc.NewMemoryStateStore())
+	router := server.SetupRouter(user.NewHandlerWithOIDC(userService, authService, oidcClient), authService, testCfg, database)
+
+	// start plays the browser starting a flow and returns the response carrying the oidc_state cookie
+	start := func(method, path, accessToken string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// followCallback plays the same browser: follow the redirect to the provider, then call back with code, state and the cookie
+	followCallback := func(authURL string, started *httptest.ResponseRecorder) (int, map[string]interface{}) {
+		callback := fake.Authorize(t, authURL)
+		req, _ := http.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+		for _, cookie := range started.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+		return w.Code, response
+	}
+
+	status, response := doJSON(t, router, http.MethodGet, "/api/v1/auth/oidc", "", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{"fake"}, response["data"].(map[string]interface{})["providers"])
+
+	w := start(http.MethodGet, "/api/v1/auth/oidc/fake", "")
+	require.Equal(t, http.StatusFound, w.Code)
+
+	// 回调必须来自发起流程的浏览器：攻击者发起的流程不能由受害者的浏览器完成
+	status, _ = followCallback(w.Header().Get("Location"), httptest.NewRecorder())
+	assert.Equal(t, http.StatusBadRequest, status, "a callback without the state cookie is rejected")
+
+	// 首次登录自动创建用户
+	w = start(http.MethodGet, "/api/v1/auth/oidc/fake", "")
+	status, response = followCallback(w.Header().Get("Location"), w)
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+	created := response["data"].(map[string]interface{})["user"].(map[string]interface{})
+	assert.Equal(t, "jane@example.com", created["email"])
+	assert.Equal(t, true, created["email_verified"])
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/identities", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	identities := response["data"].([]interface{})
+	require.Len(t, identities, 1)
+	assert.Equal(t, "fake", identities[0].(map[string]interface{})["provider"])
+
+	// 已有密码账号的用户登录后手动关联
+	_, err = userService.RegisterUser(context.Background(), user.RegisterRequest{Name: "John", Email: "john@example.com", Password: "password123"})
+	require.NoError(t, err)
+	fake.SetUser(oidctest.User{Subject: "john-sub", Email: "john@example.com", EmailVerified: true, Name: "John"})
+
+	w = start(http.MethodGet, "/api/v1/auth/oidc/fake", "")
+	status, _ = followCallback(w.Header().Get("Location"), w)
+	assert.Equal(t, http.StatusConflict, status, "an existing account is not taken over by its email")
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "john@example.com", "password": "password123"})
+	require.Equal(t, http.StatusOK, status)
+	johnToken, _ := tokensFrom(t, response)
+
+	w = start(http.MethodPost, "/api/v1/users/me/identities/fake", johnToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	status, response = followCallback(response["data"].(map[string]interface{})["authorization_url"].(string), w)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "fake", response["data"].(map[string]interface{})["provider"])
+
+	w = start(http.MethodGet, "/api/v1/auth/oidc/fake", "")
+	status, response = followCallback(w.Header().Get("Location"), w)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "john@example.com", response["data"].(map[string]interface{})["user"].(map[string]interface{})["email"])
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/users/me/identities/fake", johnToken, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestAuthFlow_APIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+	testCfg.APIKeys.Enabled = true
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	// doAPIKey sends a request authenticated with an API key
+	doAPIKey := func(method, path, key string, payload interface{}) (int, map[string]interface{}) {
+		var body bytes.Buffer
+		if payload != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req, _ := http.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "ApiKey "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		if w.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+		}
+		return w.Code, response
+	}
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "CI Bot", "email": "ci@example.com", "password": "password123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, _ := tokensFrom(t, response)
+	ciUserID := uint(response["data"].(map[string]interface{})["user"].(map[string]interface{})["id"].(float64))
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/users/me/api-keys", accessToken, map[string]interface{}{
+		"name": "ci", "scopes": []string{"read"},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	created := response["data"].(map[string]interface{})
+	readKey := created["key"].(string)
+	assert.Regexp(t, `^sk_`, readKey)
+	assert.Equal(t, readKey[:11], created["prefix"])
+
+	status, response = doAPIKey(http.MethodGet, "/api/v1/users/me", readKey, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ci@example.com", response["data"].(map[string]interface{})["email"])
+
+	status, _ = doAPIKey(http.MethodPut, fmt.Sprintf("/api/v1/users/%v", response["data"].(map[string]interface{})["id"]), readKey, map[string]string{"name": "Renamed"})
+	assert.Equal(t, http.StatusForbidden, status, "a read key cannot modify resources")
+
+	status, _ = doAPIKey(http.MethodGet, "/api/v1/users/me/api-keys", readKey, nil)
+	assert.Equal(t, http.StatusForbidden, status, "keys are managed from a login session only")
+
+	// 管理员的密钥同样携带 admin 角色，但不能访问管理员端点和账户安全设置
+	require.NoError(t, userService.PromoteToAdmin(context.Background(), ciUserID))
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/users/me/api-keys", accessToken, map[string]interface{}{
+		"name": "admin", "scopes": []string{"read", "write"},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	adminKey := response["data"].(map[string]interface{})["key"].(string)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/v1/admin/users"},
+		{http.MethodPost, "/api/v1/admin/users/1/roles/admin"},
+		{http.MethodGet, "/api/v1/admin/roles"},
+		{http.MethodPost, "/api/v1/users/me/api-keys"},
+		{http.MethodPut, "/api/v1/users/me/password"},
+		{http.MethodPost, "/api/v1/users/me/mfa/totp"},
+		{http.MethodDelete, "/api/v1/users/me/mfa/totp"},
+	} {
+		status, _ = doAPIKey(tc.method, tc.path, adminKey, map[string]string{})
+		assert.Equal(t, http.StatusForbidden, status, "%s %s", tc.method, tc.path)
+	}
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/users/me/api-keys", accessToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	keys := response["data"].([]interface{})
+	require.Len(t, keys, 2)
+	listed := keys[len(keys)-1].(map[string]interface{})
+	assert.NotContains(t, listed, "key", "the secret is only returned at creation")
+	assert.NotEmpty(t, listed["last_used_at"])
+
+	keyPath := fmt.Sprintf("/api/v1/users/me/api-keys/%v", listed["id"])
+	status, _ = doJSON(t, router, http.MethodDelete, keyPath, accessToken, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = doAPIKey(http.MethodGet, "/api/v1/users/me", readKey, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "revoked keys are rejected immediately")
+
+	status, _ = doAPIKey(http.MethodGet, "/api/v1/users/me", "sk_unknown", nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAuthFlow_ClientCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+	testCfg.OAuth.Enabled = true
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	credentials := map[string]string{"email": "admin@example.com", "password": "adminpassword123"}
+	admin, err := userService.RegisterUser(context.Background(), user.RegisterRequest{Name: "Admin", Email: credentials["email"], Password: credentials["password"]})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(context.Background(), admin.ID))
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", credentials)
+	require.Equal(t, http.StatusOK, status)
+	adminToken, _ := tokensFrom(t, response)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/admin/oauth/clients", adminToken, map[string]interface{}{
+		"name": "reports", "scopes": []string{"users:read"},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	registered := response["data"].(map[string]interface{})
+	clientID, secret := registered["client_id"].(string), registered["client_secret"].(string)
+
+	// requestToken calls the token endpoint with HTTP Basic client authentication
+	requestToken := func(form url.Values, secret string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPost, "/oauth/token", bytes.NewBufferString(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+		return w.Code, response
+	}
+
+	status, response = requestToken(url.Values{"grant_type": {"client_credentials"}}, secret)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "users:read", response["scope"])
+	claims, err := authService.ValidateClientToken(response["access_token"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, clientID, claims.ClientID)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", response["access_token"].(string), nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "client tokens are not user tokens")
+
+	status, response = requestToken(url.Values{"grant_type": {"client_credentials"}, "scope": {"users:write"}}, secret)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_scope", response["error"])
+
+	status, response = requestToken(url.Values{"grant_type": {"client_credentials"}}, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", response["error"])
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/admin/oauth/clients/"+clientID, adminToken, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = requestToken(url.Values{"grant_type": {"client_credentials"}}, secret)
+	assert.Equal(t, http.StatusUnauthorized, status, "deleted clients cannot obtain tokens")
+}
+
+func TestAuthFlow_TokenIntrospectionAndRevocation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+	testCfg.OAuth.Enabled = true
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+
+	client, secret, err := oauth.NewService(oauth.NewRepository(database), authService, auth.NewRefreshTokenRepository(database), &testCfg.OAuth).
+		RegisterClient(context.Background(), oauth.RegisterClientRequest{Name: "gateway", Scopes: []string{oauth.ScopeTokenIntrospect, oauth.ScopeTokenRevoke}})
+	require.NoError(t, err)
+
+	// postForm calls an OAuth endpoint authenticated as the gateway client
+	postForm := func(path string, form url.Values) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(url.QueryEscape(client.ClientID), url.QueryEscape(secret))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		if w.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+		}
+		return w.Code, response
+	}
+
+	status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"name": "Jane", "email": "jane@example.com", "password": "password123",
+	})
+	require.Equal(t, http.StatusOK, status)
+	accessToken, refreshToken := tokensFrom(t, response)
+
+	status, response = postForm("/oauth/introspect", url.Values{"token": {accessToken}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, response["active"])
+	assert.Equal(t, "jane@example.com", response["username"])
+
+	status, response = postForm("/oauth/introspect", url.Values{"token": {refreshToken}, "token_type_hint": {"refresh_token"}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, response["active"])
+
+	status, _ = postForm("/oauth/revoke", url.Values{"token": {refreshToken}, "token_type_hint": {"refresh_token"}})
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": refreshToken})
+	assert.Equal(t, http.StatusUnauthorized, status, "a revoked session cannot be refreshed")
+
+	status, _ = postForm("/oauth/revoke", url.Values{"token": {accessToken}})
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", accessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, response = postForm("/oauth/introspect", url.Values{"token": {accessToken}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"active": false}, response)
+}
+
+func TestAuthFlow_Roles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userRepo := user.NewRepository(database)
+	userService := user.NewService(userRepo)
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+	ctx := context.Background()
+
+	login := func(email, password string) string {
+		status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, status)
+		accessToken, _ := tokensFrom(t, response)
+		return accessToken
+	}
+
+	admin, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Admin", Email: "admin@example.com", Password: "adminpassword123"})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(ctx, admin.ID))
+	adminToken := login("admin@example.com", "adminpassword123")
+
+	status, response := doJSON(t, router, http.MethodGet, "/api/v1/admin/permissions", adminToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, response["data"], 4)
+
+	status, response = doJSON(t, router, http.MethodPost, "/api/v1/admin/roles", adminToken, map[string]interface{}{
+		"name": "support", "description": "Support staff", "permissions": []string{"roles:read", "tickets:read"},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, []interface{}{"roles:read", "tickets:read"}, response["data"].(map[string]interface{})["permissions"])
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/admin/roles", adminToken, map[string]interface{}{"name": "support"})
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/admin/roles/admin", adminToken, nil)
+	assert.Equal(t, http.StatusConflict, status, "built-in roles cannot be deleted")
+
+	agent, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Agent", Email: "agent@example.com", Password: "agentpassword123"})
+	require.NoError(t, err)
+	agentToken := login("agent@example.com", "agentpassword123")
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles", agentToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "the user role has no permissions")
+
+	require.NoError(t, userRepo.AssignRole(ctx, agent.ID, "support"))
+	agentToken = login("agent@example.com", "agentpassword123")
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles", agentToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, response["data"], 3)
+
+	status, _ = doJSON(t, router, http.MethodPut, "/api/v1/admin/roles/support", agentToken, map[string]interface{}{"permissions": []string{"roles:write"}})
+	assert.Equal(t, http.StatusForbidden, status, "roles:read does not grant roles:write")
+
+	status, _ = doJSON(t, router, http.MethodDelete, "/api/v1/admin/roles/support", adminToken, nil)
+	assert.Equal(t, http.StatusConflict, status, "the role is still assigned")
+}
+
+func TestAuthFlow_SignedGatewayHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const secret = "gateway-signing-secret-32-chars!"
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeGateway
+	testCfg.Gateway.SigningSecret = secret
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	router := server.SetupRouter(user.NewHandler(user.NewService(user.NewRepository(database)), authService), authService, testCfg, database)
+
+	// doGateway calls the admin user list as the gateway would, optionally signing the identity headers
+	// for signedPath, which is the requested path unless a replayed signature is being simulated
+	doGateway := func(roles string, timestamp int64, sign bool, signedPath string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+		req.Header.Set(middleware.HeaderUserID, "999")
+		req.Header.Set(middleware.HeaderUserRole, roles)
+		if sign {
+			req.Header.Set(middleware.HeaderUserTimestamp, fmt.Sprint(timestamp))
+			req.Header.Set(middleware.HeaderUserSignature, middleware.GatewaySignature(secret, http.MethodGet, signedPath, "999", roles, timestamp))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	now := time.Now().Unix()
+	const path = "/api/v1/admin/users"
+	assert.Equal(t, http.StatusOK, doGateway("user,admin", now, true, path))
+	assert.Equal(t, http.StatusForbidden, doGateway("user", now, true, path), "signed headers still need the admin role")
+	assert.Equal(t, http.StatusUnauthorized, doGateway("admin", now, false, path), "unsigned headers are rejected")
+	assert.Equal(t, http.StatusUnauthorized, doGateway("admin", now-int64(config.DefaultGatewayMaxClockSkew/time.Second)-5, true, path), "stale signatures are rejected")
+	assert.Equal(t, http.StatusUnauthorized, doGateway("admin", now, true, "/api/v1/admin/roles"), "signatures for another path are rejected")
+}
+
+func TestAuthFlow_RoleAssignment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+	ctx := context.Background()
+
+	login := func(email, password string) string {
+		status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, status)
+		accessToken, _ := tokensFrom(t, response)
+		return accessToken
+	}
+
+	admin, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Admin", Email: "admin@example.com", Password: "adminpassword123"})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(ctx, admin.ID))
+	adminToken := login("admin@example.com", "adminpassword123")
+
+	member, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Member", Email: "member@example.com", Password: "memberpassword123"})
+	require.NoError(t, err)
+	memberToken := login("member@example.com", "memberpassword123")
+
+	memberRolePath := fmt.Sprintf("/api/v1/admin/users/%d/roles/admin", member.ID)
+	status, response := doJSON(t, router, http.MethodPost, memberRolePath, adminToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.ElementsMatch(t, []interface{}{"user", "admin"}, response["data"].(map[string]interface{})["roles"])
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", memberToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "role changes revoke the user's tokens")
+	memberToken = login("member@example.com", "memberpassword123")
+
+	status, response = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles/admin/users", memberToken, nil)
+	require.Equal(t, http.StatusOK, status, "the new role takes effect on the next login")
+	assert.Equal(t, float64(2), response["data"].(map[string]interface{})["total"])
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/admin/roles/auditor/users", adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = doJSON(t, router, http.MethodDelete, fmt.Sprintf("/api/v1/admin/users/%d/roles/admin", admin.ID), adminToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "admins cannot demote themselves")
+
+	status, _ = doJSON(t, router, http.MethodDelete, fmt.Sprintf("/api/v1/admin/users/%d/roles/admin", admin.ID), memberToken, nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/admin/users", adminToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "the demoted admin's tokens are revoked")
+
+	status, _ = doJSON(t, router, http.MethodDelete, memberRolePath, memberToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "the last admin cannot demote themselves either")
+}
+
+func TestAuthFlow_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+	testCfg.Impersonation.Enabled = true
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	authService := auth.NewServiceWithRepo(&testCfg.JWT, database)
+	userService := user.NewService(user.NewRepository(database))
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database)
+	ctx := context.Background()
+
+	login := func(email, password string) string {
+		status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, status)
+		accessToken, _ := tokensFrom(t, response)
+		return accessToken
+	}
+
+	admin, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Admin", Email: "admin@example.com", Password: "adminpassword123"})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(ctx, admin.ID))
+	adminToken := login("admin@example.com", "adminpassword123")
+
+	member, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Member", Email: "member@example.com", Password: "memberpassword123"})
+	require.NoError(t, err)
+	memberToken := login("member@example.com", "memberpassword123")
+
+	impersonate := func() string {
+		status, response := doJSON(t, router, http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%d/impersonate", member.ID), adminToken, nil)
+		require.Equal(t, http.StatusOK, status)
+		data := response["data"].(map[string]interface{})
+		assert.NotContains(t, data, "refresh_token", "impersonation tokens cannot be refreshed")
+		assert.Equal(t, float64(15*60), data["expires_in"])
+		assert.Equal(t, "admin@example.com", data["impersonated_by"].(map[string]interface{})["email"])
+		return data["access_token"].(string)
+	}
+
+	status, _ := doJSON(t, router, http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%d/impersonate", admin.ID), memberToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "only admins can impersonate")
+	status, _ = doJSON(t, router, http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%d/impersonate", admin.ID), adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, status, "admins cannot impersonate themselves")
+
+	token := impersonate()
+	claims, err := authService.ValidateToken(token)
+	require.NoError(t, err)
+	require.True(t, claims.IsImpersonation())
+	assert.Equal(t, admin.ID, claims.Actor.UserID)
+
+	status, response := doJSON(t, router, http.MethodGet, "/api/v1/users/me", token, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "member@example.com", response["data"].(map[string]interface{})["email"])
+
+	status, _ = doJSON(t, router, http.MethodPut, "/api/v1/users/me/password", token, map[string]string{"current_password": "memberpassword123", "new_password": "newmemberpassword456"})
+	assert.Equal(t, http.StatusForbidden, status, "impersonated sessions cannot change passwords")
+	status, _ = doJSON(t, router, http.MethodPut, fmt.Sprintf("/api/v1/users/%d", member.ID), token, map[string]string{"email": "takeover@example.com"})
+	assert.Equal(t, http.StatusForbidden, status, "impersonated sessions cannot change the email address")
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/admin/roles", token, map[string]interface{}{"name": "support"})
+	assert.Equal(t, http.StatusForbidden, status, "impersonated sessions cannot manage roles")
+
+	// 修改请求（包括被拒绝的）以管理员为操作者写入审计日志，只读请求不记录
+	events, _, err := audit.NewRepository(database).List(ctx, audit.Filter{Action: audit.ActionImpersonatedRequest}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, admin.ID, event.ActorID)
+		assert.Equal(t, member.ID, event.OnBehalfOf)
+		assert.Equal(t, float64(http.StatusForbidden), event.Metadata["status"])
+	}
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/impersonation/stop", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, status, "the admin's own session is not an impersonation")
+
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/impersonation/stop", token, nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "stopping revokes the impersonation token")
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", adminToken, nil)
+	assert.Equal(t, http.StatusOK, status, "the admin stays logged in")
+
+	token = impersonate()
+	require.NoError(t, authService.RevokeAllUserTokens(ctx, admin.ID))
+	status, _ = doJSON(t, router, http.MethodGet, "/api/v1/users/me", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, status, "revoking the admin's tokens ends their impersonation sessions")
+}
+
+func TestAuthFlow_AuditLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCfg := config.NewTestConfig()
+	testCfg.Auth.Mode = config.AuthModeJWT
+
+	database, err := db.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	createTestSchema(t, database)
+
+	auditService := audit.NewService(audit.NewRepository(database))
+	authService := auth.NewService(&testCfg.JWT, auth.WithDatabase(database), auth.WithAuditRecorder(auditService))
+	userService := user.NewService(user.NewRepository(database),
+		user.WithPasswordReset(&testCfg.PasswordReset),
+		user.WithEmailVerification(&testCfg.EmailVerification),
+		user.WithPasswordPolicy(user.NewPasswordPolicy(&testCfg.PasswordPolicy)),
+		user.WithPasswordHasher(user.NewPasswordHasher(&testCfg.PasswordHash)),
+		user.WithLoginGuard(auth.NewLoginGuard(&testCfg.LoginProtection, auth.NewMemoryLoginAttemptStore())),
+		user.WithMFA(&testCfg.MFA),
+		user.WithAPIKeys(&testCfg.APIKeys),
+		user.WithImpersonation(&testCfg.Impersonation),
+		user.WithAuditRecorder(auditService),
+	)
+	router := server.SetupRouter(user.NewHandler(userService, authService), authService, testCfg, database, server.WithAuditService(auditService))
+	ctx := context.Background()
+
+	login := func(email, password string) (string, string) {
+		status, response := doJSON(t, router, http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, status)
+		return tokensFrom(t, response)
+	}
+	listEvents := func(token, query string) []interface{} {
+		status, response := doJSON(t, router, http.MethodGet, "/api/v1/admin/audit-events"+query, token, nil)
+		require.Equal(t, http.StatusOK, status)
+		return response["data"].(map[string]interface{})["events"].([]interface{})
+	}
+
+	admin, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Admin", Email: "admin@example.com", Password: "adminpassword123"})
+	require.NoError(t, err)
+	require.NoError(t, userService.PromoteToAdmin(ctx, admin.ID))
+	adminToken, _ := login("admin@example.com", "adminpassword123")
+
+	member, err := userService.RegisterUser(ctx, user.RegisterRequest{Name: "Member", Email: "member@example.com", Password: "memberpassword123"})
+	require.NoError(t, err)
+	memberToken, memberRefresh := login("member@example.com", "memberpassword123")
+
+	status, _ := doJSON(t, router, http.MethodGet, "/api/v1/admin/audit-events", memberToken, nil)
+	assert.Equal(t, http.StatusForbidden, status, "only admins can read the audit log")
+
+	// 刷新令牌被重放：整个会话被撤销并记录审计事件
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": memberRefresh})
+	require.Equal(t, http.StatusOK, status)
+	status, _ = doJSON(t, router, http.MethodPost, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": memberRefresh})
+	require.Equal(t, http.StatusForbidden, status)
+
+	status, _ = doJSON(t, router, http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%d/roles/admin", member.ID), adminToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = doJSON(t, router, http.MethodDelete, fmt.Sprintf("/api/v1/admin/users/%d", member.ID), adminToken, nil)
+	require.Equal(t, http.StatusNoContent, status)
+
+	events := listEvents(adminToken, "?action=user.role_grant&target_id="+audit.ID(member.ID))
+	require.Len(t, events, 1)
+	grant := events[0].(map[string]interface{})
+	assert.Equal(t, float64(admin.ID), grant["actor_id"])
+	assert.NotEmpty(t, grant["request_id"])
+	assert.Equal(t, map[string]interface{}{"before": []interface{}{"user"}, "after": []interface{}{"admin", "user"}}, grant["changes"].(map[string]interface{})["roles"])
+
+	events = listEvents(adminToken, "?action=auth.token_reuse")
+	require.Len(t, events, 1)
+	reuse := events[0].(map[string]interface{})
+	assert.Equal(t, audit.ID(member.ID), reuse["target_id"])
+	assert.NotEmpty(t, reuse["metadata"].(map[string]interface{})["token_family"])
+
+	events = listEvents(adminToken, "?target_type=user&target_id="+audit.ID(member.ID))
+	actions := make([]string, len(events))
+	for i, event := range events {
+		actions[i] = event.(map[string]interface{})["action"].(string)
+	}
+	assert.Equal(t, []string{audit.ActionTokensRevoked, audit.ActionUserDelete, audit.ActionTokensRevoked, audit.ActionRoleGrant, audit.ActionTokenReuse, audit.ActionLogin, audit.ActionUserCreate}, actions, "newest first")
+
+	status, response := doJSON(t, router, http.MethodGet, fmt.Sprintf("/api/v1/admin/audit-events?actor_id=%d&per_page=2", admin.ID), adminToken, nil)
+	require.Equal(t, http.StatusOK, status)
+	data := response["data"].(map[string]interface{})
+	assert.Len(t, data["events"], 2)
+	assert.Greater(t, data["total_pages"], float64(1))
+}
@@ -243,13 +243,30 @@ func TestGetUserName(t *testing.T) {
 func TestHasRole(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	t.Run("returns false when user is present (roles not implemented)", func(t *testing.T) {
+	t.Run("returns false when user has no roles", func(t *testing.T) {
 		c, _ := gin.CreateTestContext(nil)
 		claims := &auth.Claims{UserID: 42, Email: "test@example.com", Name: "John Doe"}
 		c.Set(auth.KeyUser, claims)
 
 		hasRole := contextutil.HasRole(c, "admin")
-		assert.False(t, hasRole) // Currently returns false as roles are not implemented
+		assert.False(t, hasRole)
+	})
+
+	t.Run("checks membership in JWT claim roles", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		c.Set(auth.KeyUser, &auth.Claims{UserID: 42, Roles: []string{"user", "editor"}})
+
+		assert.True(t, contextutil.HasRole(c, "editor"))
+		assert.False(t, contextutil.HasRole(c, "admin"))
+	})
+
+	t.Run("checks membership in the role set", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		contextutil.SetUserRoles(c, []string{"user", "Admin"})
+
+		assert.True(t, contextutil.HasRole(c, "admin"))
+		assert.True(t, contextutil.IsAdmin(c))
+		assert.True(t, contextutil.HasRole(c, "user"))
 	})
 
 	t.Run("returns false when user is not present", func(t *testing.T) {
@@ -267,3 +284,82 @@ func TestHasRole(t *testing.T) {
 		assert.False(t, hasRole)
 	})
 }
+
+func TestGetRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("returns the role set", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		contextutil.SetUserRoles(c, []string{"user", "editor", "admin"})
+
+		assert.Equal(t, []string{"user", "editor", "admin"}, contextutil.GetRoles(c))
+		assert.Equal(t, "admin", contextutil.GetUserRole(c))
+	})
+
+	t.Run("falls back to a single role", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		contextutil.SetUserRole(c, "editor")
+
+		assert.Equal(t, []string{"editor"}, contextutil.GetRoles(c))
+	})
+
+	t.Run("falls back to JWT claim roles", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		c.Set(auth.KeyUser, &auth.Claims{UserID: 42, Roles: []string{"editor", "user"}})
+
+		assert.Equal(t, []string{"editor", "user"}, contextutil.GetRoles(c))
+		assert.Equal(t, "editor", contextutil.GetUserRole(c))
+	})
+
+	t.Run("returns empty when not authenticated", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+
+		assert.Empty(t, contextutil.GetRoles(c))
+		assert.Empty(t, contextutil.GetUserRole(c))
+	})
+}
+
+func TestPrimaryRole(t *testing.T) {
+	assert.Equal(t, "admin", contextutil.PrimaryRole([]string{"user", "admin"}))
+	assert.Equal(t, "editor", contextutil.PrimaryRole([]string{"editor", "user"}))
+	assert.Equal(t, "", contextutil.PrimaryRole(nil))
+}
+
+func TestGetActorID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("impersonated session from JWT claims", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		c.Set(auth.KeyUser, &auth.Claims{UserID: 42, Actor: &auth.Actor{UserID: 1}})
+
+		assert.Equal(t, uint(1), contextutil.GetActorID(c))
+		assert.True(t, contextutil.IsImpersonating(c))
+		assert.Equal(t, uint(42), contextutil.GetUserID(c))
+		assert.Equal(t, uint(1), contextutil.GetActingUserID(c))
+	})
+
+	t.Run("actor set by middleware", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		contextutil.SetUserID(c, 42)
+		contextutil.SetActorID(c, 3)
+
+		assert.Equal(t, uint(3), contextutil.GetActorID(c))
+		assert.Equal(t, uint(3), contextutil.GetActingUserID(c))
+	})
+
+	t.Run("regular session", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+		c.Set(auth.KeyUser, &auth.Claims{UserID: 42})
+
+		assert.Equal(t, uint(0), contextutil.GetActorID(c))
+		assert.False(t, contextutil.IsImpersonating(c))
+		assert.Equal(t, uint(42), contextutil.GetActingUserID(c))
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(nil)
+
+		assert.Equal(t, uint(0), contextutil.GetActorID(c))
+		assert.Equal(t, uint(0), contextutil.GetActingUserID(c))
+	})
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -14,9 +15,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 
+	"github.com/yeegeek/go-rest-api-starter/internal/audit"
 	"github.com/yeegeek/go-rest-api-starter/internal/auth"
 	"github.com/yeegeek/go-rest-api-starter/internal/config"
 	"github.com/yeegeek/go-rest-api-starter/internal/db"
+	"github.com/yeegeek/go-rest-api-starter/internal/oauth"
 	"github.com/yeegeek/go-rest-api-starter/internal/server"
 	"github.com/yeegeek/go-rest-api-starter/internal/user"
 )
@@ -25,7 +28,8 @@ import (
 func createTestSchema(t *testing.T, database *gorm.DB) {
 	t.Helper()
 
-	err := database.AutoMigrate(&user.User{}, &user.Role{}, &auth.RefreshToken{})
+	err := database.AutoMigrate(&user.User{}, &user.Role{}, &user.Permission{}, &auth.RefreshToken{}, &user.PasswordResetToken{}, &user.EmailVerificationToken{},
+		&user.UserTOTP{}, &user.MFARecoveryCode{}, &user.MFAChallenge{}, &user.UserIdentity{}, &user.APIKey{}, &oauth.Client{}, &audit.Event{})
 	assert.NoError(t, err)
 
 	// Drop the auto-created user_roles table (created by GORM for many2many)
@@ -57,6 +61,12 @@ func createTestSchema(t *testing.T, database *gorm.DB) {
 			t.Fatalf("Failed to create role %s: %v", role.Name, result.Error)
 		}
 	}
+
+	// Grant the built-in permissions to admin, as the create_permissions_tables migration does
+	err = user.NewRepository(database).SetRolePermissions(context.Background(), &user.Role{ID: 2}, []string{
+		user.PermissionUsersRead, user.PermissionUsersWrite, user.PermissionRolesRead, user.PermissionRolesWrite,
+	})
+	assert.NoError(t, err)
 }
 
 func setupTestRouter(t *testing.T) *gin.Engine {
@@ -182,6 +192,28 @@ func TestRegisterHandler(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "password violates policy",
+			payload: map[string]string{
+				"name":     "Weak Password",
+				"email":    "weak@example.com",
+				"password": "short",
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body map[string]interface{}) {
+				errorInfo, ok := body["error"].(map[string]interface{})
+				if !ok {
+					t.Fatal("Expected error object in response")
+				}
+				details, ok := errorInfo["details"].(map[string]interface{})
+				if !ok {
+					t.Fatal("Expected validation details in response")
+				}
+				if _, ok := details["min_length"]; !ok {
+					t.Errorf("Expected min_length violation, got %v", details)
+				}
+			},
+		},
 		{
 			name: "missing required fields",
 			payload: map[string]string{
@@ -446,3 +478,39 @@ func TestRateLimit_BlocksThenAllows(t *testing.T) {
 	// If we get here, rate limiting didn't work
 	t.Fatalf("expected rate limiting to trigger, but completed %d requests without 429", successCount)
 }
+
+func TestRegisterRoute(t *testing.T) {
+	r := setupRateLimitTestRouter(t)
+
+	// register posts a registration from a fixed client address and returns the response
+	register := func(path, email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{
+			"name":     "Route User",
+			"email":    email,
+			"password": "secret123",
+		})
+		req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.200:1234"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// 旧的 /public/register 仍可用，且与 /auth/register 共用同一限流额度
+	rr := register("/api/v1/public/register", "route-public@example.com")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "9", rr.Header().Get("X-RateLimit-Remaining"))
+
+	rr = register("/api/v1/auth/register", "route-auth@example.com")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "8", rr.Header().Get("X-RateLimit-Remaining"))
+
+	// 已认证端点不经过认证限流
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.RemoteAddr = "203.0.113.200:1234"
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, rr.Header().Get("X-RateLimit-Limit"))
+}